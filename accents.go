@@ -0,0 +1,79 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+// accentTable lists the precomposed Latin letters folded by FoldAccents,
+// following the base letter they are folded to.
+var accentTable = []string{
+	"AÀÁÂÃÄÅĀĂĄ", "aàáâãäåāăą",
+	"CÇĆĈĊČ", "cçćĉċč",
+	"DĎĐ", "dďđ",
+	"EÈÉÊËĒĔĖĘĚ", "eèéêëēĕėęě",
+	"GĜĞĠĢ", "gĝğġģ",
+	"HĤĦ", "hĥħ",
+	"IÌÍÎÏĨĪĬĮİ", "iìíîïĩīĭįı",
+	"JĴ", "jĵ",
+	"KĶ", "kķ",
+	"LĹĻĽĿŁ", "lĺļľŀł",
+	"NÑŃŅŇ", "nñńņň",
+	"OÒÓÔÕÖØŌŎŐ", "oòóôõöøōŏő",
+	"RŔŖŘ", "rŕŗř",
+	"SŚŜŞŠ", "sśŝşš",
+	"TŢŤŦ", "tţťŧ",
+	"UÙÚÛÜŨŪŬŮŰŲ", "uùúûüũūŭůűų",
+	"WŴ", "wŵ",
+	"YÝŶŸ", "yýÿŷ",
+	"ZŹŻŽ", "zźżž",
+}
+
+// accentFolds returns the map from each accented letter in accentTable to
+// its base letter.
+func accentFolds() map[rune]rune {
+	folds := make(map[rune]rune)
+	for _, letters := range accentTable {
+		rs := []rune(letters)
+		for _, r := range rs[1:] {
+			folds[r] = rs[0]
+		}
+	}
+	return folds
+}
+
+// FoldAccents is a flag, which can be passed to Generate, to specify that
+// common precomposed Latin letters with diacritics, such as those used in
+// Western and Central European languages, should be matched as their base
+// letter.  For example, "café", "cafè", and "cafe" all match the key "cafe"
+// (or "café").  Case is preserved, so À is matched as A; add Insensitive to
+// also ignore case.
+//
+// The letters are replaced in both the input and the keys, so this
+// allocates memory when the input contains one.  Only single letters are
+// folded: ligatures such as ß, æ, and œ, and letters followed by a combining
+// diacritic, match exactly.  (Use NFC first to compose the latter.)
+var FoldAccents = unicodeFlag(accentFolds())
@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestAccentTable tests that each accented letter in the table is folded to
+// an ASCII letter of the same case, and is only listed once.
+func TestAccentTable(t *testing.T) {
+	seen := make(map[rune]bool)
+	for _, letters := range accentTable {
+		rs := []rune(letters)
+		if !unicode.IsLetter(rs[0]) || rs[0] >= utf8.RuneSelf {
+			t.Errorf("%q is not an ASCII letter", rs[0])
+		}
+		for _, r := range rs[1:] {
+			if seen[r] {
+				t.Errorf("%q is listed more than once", r)
+			}
+			seen[r] = true
+			if unicode.IsUpper(r) != unicode.IsUpper(rs[0]) {
+				t.Errorf("%q is folded to %q, which is a different case", r, rs[0])
+			}
+			if !unicode.IsLetter(r) || r < utf8.RuneSelf {
+				t.Errorf("%q is not an accented letter", r)
+			}
+		}
+	}
+}
+
+// TestFoldAccents tests matching with FoldAccents.
+func TestFoldAccents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{FoldAccents},
+		{FoldAccents, Insensitive},
+		{FoldAccents, Strategy(LinearStrategy)},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"café":   "1",
+			"naive":  "2",
+			"École":  "3",
+			"straße": "4",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "café", "1")
+		expectMatch(t, "cafe", "1")
+		expectMatch(t, "cafè", "1")
+		expectMatch(t, "naïve", "2")
+		expectMatch(t, "Ecole", "3")
+		expectMatch(t, "Écolé", "3")
+		expectMatch(t, "straße", "4")
+		expectMatch(t, "strasse", "0")
+		expectMatch(t, "cafë!", "0")
+		if flags[len(flags)-1] == Insensitive {
+			expectMatch(t, "CAFÉ", "1")
+			expectMatch(t, "école", "3")
+		} else {
+			expectMatch(t, "CAFÉ", "0")
+			expectMatch(t, "école", "0")
+		}
+		cleanup()
+	}
+
+	if name := flagName(FoldAccents); name != "FoldAccents" {
+		t.Errorf("expected flag name FoldAccents, got %q", name)
+	}
+}
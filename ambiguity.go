@@ -30,6 +30,7 @@ package fastmatch
 
 import (
 	"bytes"
+	"context"
 	"sort"
 	"strconv"
 )
@@ -71,6 +72,21 @@ func (e *ErrAmbiguous) add(backToOrig map[string][]string, keys ...string) {
 	}
 }
 
+// rename replaces keys in the error according to names.  Keys which are not
+// found in names are left as-is.
+func (e *ErrAmbiguous) rename(names map[string]string) {
+	for n, group := range e.keys {
+		renamed := make(map[string]bool, len(group))
+		for key := range group {
+			if name, found := names[key]; found {
+				key = name
+			}
+			renamed[key] = true
+		}
+		e.keys[n] = renamed
+	}
+}
+
 // sliceOfStringSlices implements strings.Sortable on a slice of string
 // slices.  The first-level slice is sorted according to the first element in
 // each second-level slice.
@@ -153,6 +169,10 @@ func (sc seenCases) collapse() ([]string, []string) {
 type disambiguate struct {
 	cases map[uint64]map[rune]seenCases
 	keys  map[string]bool
+
+	// noTrailingMark is true if keys which would otherwise be ambiguous
+	// are distinguished by the NoTrailingMark flag.
+	noTrailingMark bool
 }
 
 // foreach iterates over unique final states, calling the supplied function
@@ -189,9 +209,19 @@ func (d *disambiguate) add(sum uint64, r rune, ret, key string) {
 }
 
 // indexNoMore iterates over the final states for partial matches in a
-// stateMachine, adding them to the disambiguation index.
-func (d *disambiguate) indexNoMore(state *stateMachine, cases map[string]string) {
+// stateMachine, adding them to the disambiguation index.  Each key is
+// compared against every longer key, so this is checked for cancellation as
+// it goes; if ctx is canceled, ctx.Err() is returned.
+func (d *disambiguate) indexNoMore(ctx context.Context, state *stateMachine, cases map[string]string) error {
+	var err error
 	state.foreachNoMore(func(_ int, r rune, key string) {
+		if err != nil {
+			return
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
 		sum := state.finalState(key)
 		d.add(sum, r, cases[key], key)
 
@@ -227,9 +257,14 @@ func (d *disambiguate) indexNoMore(state *stateMachine, cases map[string]string)
 				continue // different intermediate state
 			}
 
+			if d.noTrailingMark && continuationExtendsCluster(key, other) {
+				continue // not a match on a cluster boundary
+			}
+
 			d.add(sum, r, cases[other], other)
 		}
 	})
+	return err
 }
 
 // indexFinal iterates over the final states in a stateMachine.  States which
@@ -244,11 +279,13 @@ func (d *disambiguate) indexFinal(state *stateMachine, cases map[string]string)
 	}
 }
 
-// shortestString returns the shortest string from a list of strings.
+// shortestString returns the shortest string from a list of strings.  Ties
+// go to the string which sorts first, so the result doesn't depend on the
+// order of the list.
 func shortestString(ss []string) string {
 	var shortest string
 	for _, s := range ss {
-		if shortest == "" || len(s) < len(shortest) {
+		if shortest == "" || len(s) < len(shortest) || (len(s) == len(shortest) && s < shortest) {
 			shortest = s
 		}
 	}
@@ -257,7 +294,11 @@ func shortestString(ss []string) string {
 
 // checkAmbiguity verifies there is exactly one possible return value for each
 // final state, returning an error if any matches are ambiguous.
-func (state *stateMachine) checkAmbiguity(cases, origCases map[string]string, backToOrig map[string][]string) error {
+//
+// If noTrailingMark is true, a shorter key is not considered to be a prefix
+// of a longer key if the latter extends the former's final grapheme cluster.
+// If ctx is canceled, checking stops and ctx.Err() is returned.
+func (state *stateMachine) checkAmbiguity(ctx context.Context, cases, origCases map[string]string, backToOrig map[string][]string, noTrailingMark bool) error {
 	e := new(ErrAmbiguous)
 
 	// Keys which got mangled or truncated to the same value (due to
@@ -278,8 +319,13 @@ func (state *stateMachine) checkAmbiguity(cases, origCases map[string]string, ba
 
 	// Now perform a more exhaustive search.
 	for {
-		d := new(disambiguate)
-		d.indexNoMore(state, cases)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d := &disambiguate{noTrailingMark: noTrailingMark}
+		if err := d.indexNoMore(ctx, state, cases); err != nil {
+			return err
+		}
 		if state.continued == nil {
 			d.indexFinal(state, cases)
 		}
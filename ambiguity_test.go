@@ -92,7 +92,6 @@ var ambiguityTestCases = []struct {
 	flags     []*Flag
 	cases     map[string]string
 	ambiguous sliceOfStringSlices
-	maxState  uint64
 }{
 	{
 		descr: "Inensitive",
@@ -132,7 +131,7 @@ var ambiguityTestCases = []struct {
 		},
 	}, {
 		descr: "Inensitive (chained state machine)",
-		flags: []*Flag{Insensitive},
+		flags: []*Flag{Insensitive, MaxState(0xff)},
 		cases: map[string]string{
 			"abcdefghijklmnop": "1", "ABCdefghijklmnop": "2",
 			"ponmlkjihgfedcba": "3", "ponmlkjihgfedCBA": "4",
@@ -142,7 +141,6 @@ var ambiguityTestCases = []struct {
 			[]string{"abcdefghijklmnop", "ABCdefghijklmnop"},
 			[]string{"ponmlkjihgfedcba", "ponmlkjihgfedCBA"},
 		},
-		maxState: 0xff,
 	}, {
 		descr: "HasPrefix",
 		flags: []*Flag{HasPrefix},
@@ -171,7 +169,7 @@ var ambiguityTestCases = []struct {
 		},
 	}, {
 		descr: "HasPrefix (chained state machine)",
-		flags: []*Flag{HasPrefix},
+		flags: []*Flag{HasPrefix, MaxState(0xff)},
 		cases: map[string]string{
 			"abcdefghijklmnop": "1", "abcdefghijklm": "2",
 			"ponmlkjihgfedcba": "3", "po": "4",
@@ -181,7 +179,6 @@ var ambiguityTestCases = []struct {
 			[]string{"abcdefghijklmnop", "abcdefghijklm"},
 			[]string{"ponmlkjihgfedcba", "po"},
 		},
-		maxState: 0xff,
 	}, {
 		descr: "HasSuffix (different final rune)",
 		flags: []*Flag{HasSuffix},
@@ -246,11 +243,6 @@ func TestAmbiguity(t *testing.T) {
 	for _, testCase := range ambiguityTestCases {
 		testCase.ambiguous.sort()
 
-		oldMaxState := maxState
-		if testCase.maxState != 0 {
-			maxState = testCase.maxState
-		}
-
 		if err := Generate(ioutil.Discard, testCase.cases, "0", testCase.flags...); err == nil {
 			t.Errorf("failed to detect %s ambiguity", testCase.descr)
 		} else if err, ok := err.(*ErrAmbiguous); !ok {
@@ -267,9 +259,18 @@ func TestAmbiguity(t *testing.T) {
 		if err := Generate(ioutil.Discard, nonAmbiguous, "0", testCase.flags...); err != nil {
 			t.Errorf("error from non-ambiguous %s cases: %s", testCase.descr, err.Error())
 		}
+	}
+}
 
-		if testCase.maxState != 0 {
-			maxState = oldMaxState
+// TestShortestString tests that ties between keys of the same length don't
+// depend on their order.
+func TestShortestString(t *testing.T) {
+	for _, ss := range [][]string{
+		{"ab", "aB", "Ab", "abc"},
+		{"abc", "Ab", "aB", "ab"},
+	} {
+		if s := shortestString(ss); s != "Ab" {
+			t.Errorf("expected \"Ab\" from shortestString(%q), got %q", ss, s)
 		}
 	}
 }
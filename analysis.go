@@ -0,0 +1,152 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OverlapKind describes how two keys passed to AnalyzeOverlap relate to each
+// other.
+type OverlapKind int
+
+const (
+	// OverlapIdentical means both keys are indistinguishable once the
+	// flags have been applied.
+	OverlapIdentical OverlapKind = iota
+
+	// OverlapPrefix means Key is a prefix of Other.
+	OverlapPrefix
+
+	// OverlapSuffix means Key is a suffix of Other.
+	OverlapSuffix
+
+	// OverlapSubstring means Key appears within Other, but is neither a
+	// prefix nor a suffix of it.
+	OverlapSubstring
+)
+
+// String returns a human-readable description of an OverlapKind.
+func (kind OverlapKind) String() string {
+	switch kind {
+	case OverlapIdentical:
+		return "identical to"
+	case OverlapPrefix:
+		return "prefix of"
+	case OverlapSuffix:
+		return "suffix of"
+	case OverlapSubstring:
+		return "substring of"
+	}
+	return "unknown"
+}
+
+// Overlap is a single relationship reported by AnalyzeOverlap.
+type Overlap struct {
+	Key   string
+	Other string
+	Kind  OverlapKind
+}
+
+// String formats an Overlap as a sentence, e.g. `"foo" is prefix of
+// "foobar"`.
+func (o Overlap) String() string {
+	return strconv.Quote(o.Key) + " is " + o.Kind.String() + " " + strconv.Quote(o.Other)
+}
+
+// AnalyzeOverlap reports which keys in cases are prefixes, suffixes, or
+// substrings of other keys, and which become identical once flags (such as
+// Insensitive, Equivalent, Ignore, or StopUpon) are applied.  Only the most
+// specific relationship is reported for each pair of keys, with identical
+// keys taking precedence over prefixes, prefixes over suffixes, and suffixes
+// over substrings.
+//
+// Unlike Generate, this does not consider whether an overlap is actually
+// ambiguous; overlapping keys with the same value, or keys which only
+// overlap as substrings, are still reported.  It is intended as a hygiene
+// check for maintainers of large tables of keys.
+//
+// The result is sorted by Key, then by Other.  An error is returned only if
+// the flags themselves are invalid.
+func AnalyzeOverlap(cases map[string]string, flags ...*Flag) ([]Overlap, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.foldCase(cases, flags)
+
+	keys := make([]string, 0, len(cases))
+	canonical := make(map[string]string, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+		canonical[key] = fs.canonicalize(key)
+	}
+	sort.Strings(keys)
+
+	var overlaps []Overlap
+	for i, key := range keys {
+		for j, other := range keys {
+			if i == j {
+				continue
+			}
+			a, b := canonical[key], canonical[other]
+			switch {
+			case a == b:
+				if i < j {
+					overlaps = append(overlaps, Overlap{key, other, OverlapIdentical})
+				}
+			case len(a) >= len(b):
+				continue
+			case strings.HasPrefix(b, a):
+				overlaps = append(overlaps, Overlap{key, other, OverlapPrefix})
+			case strings.HasSuffix(b, a):
+				overlaps = append(overlaps, Overlap{key, other, OverlapSuffix})
+			case strings.Contains(b, a):
+				overlaps = append(overlaps, Overlap{key, other, OverlapSubstring})
+			}
+		}
+	}
+	return overlaps, nil
+}
+
+// canonicalize returns the form of a key used for comparison by
+// AnalyzeOverlap: normalized, with stop and ignored runes applied, and with
+// each rune replaced by the lowest rune it is equivalent to.
+func (fs *flagSet) canonicalize(key string) string {
+	key = fs.mangle(fs.normalizeKey(key))
+	if len(fs.equiv) == 0 {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		return fs.equiv.lookup(r)[0]
+	}, key)
+}
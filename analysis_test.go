@@ -0,0 +1,95 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAnalyzeOverlap tests reporting of prefix, suffix, and substring
+// relationships between keys.
+func TestAnalyzeOverlap(t *testing.T) {
+	overlaps, err := AnalyzeOverlap(map[string]string{
+		"foo":    "1",
+		"foobar": "2",
+		"barfoo": "3",
+		"xfooy":  "4",
+		"baz":    "5",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Overlap{
+		{"foo", "barfoo", OverlapSuffix},
+		{"foo", "foobar", OverlapPrefix},
+		{"foo", "xfooy", OverlapSubstring},
+	}
+	if !reflect.DeepEqual(expect, overlaps) {
+		t.Errorf("expected %v, got %v", expect, overlaps)
+	}
+}
+
+// TestAnalyzeOverlapFlags tests that keys which only become identical once
+// flags are applied are reported, even if their values are the same.
+func TestAnalyzeOverlapFlags(t *testing.T) {
+	overlaps, err := AnalyzeOverlap(map[string]string{
+		"Foo":     "1",
+		"f-o-o":   "1",
+		"foo bar": "2",
+	}, Insensitive, Ignore('-'), StopUpon(' '))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Overlap{
+		{"Foo", "f-o-o", OverlapIdentical},
+		{"Foo", "foo bar", OverlapIdentical},
+		{"f-o-o", "foo bar", OverlapIdentical},
+	}
+	if !reflect.DeepEqual(expect, overlaps) {
+		t.Errorf("expected %v, got %v", expect, overlaps)
+	}
+}
+
+// TestAnalyzeOverlapBadFlags tests that invalid flags are reported.
+func TestAnalyzeOverlapBadFlags(t *testing.T) {
+	if _, err := AnalyzeOverlap(map[string]string{"a": "1"}, HasPrefix, HasSuffix); err == nil {
+		t.Error("expected error combining HasPrefix and HasSuffix")
+	}
+}
+
+// TestOverlapString tests the human-readable form of an Overlap.
+func TestOverlapString(t *testing.T) {
+	o := Overlap{"foo", "foobar", OverlapPrefix}
+	if expect := `"foo" is prefix of "foobar"`; o.String() != expect {
+		t.Errorf("expected %q, got %q", expect, o.String())
+	}
+}
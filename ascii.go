@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ASCIIOnly is a flag, which can be passed to Generate, to specify that only
+// ASCII input can match.  The generated code begins by checking whether the
+// input contains any bytes outside the ASCII range, and returns the none
+// value if it does.  This avoids matching just part of the input when the
+// rest of it is multi-byte UTF-8, such as "cafe" matching the beginning of
+// "cafe\u0301" (ending with a combining accent) when HasPrefix is specified.
+// NoTrailingMark handles that particular case, but not, for instance, "caf"
+// matching the beginning of "caf\u00e9" (ending with a precomposed letter).
+//
+// Generate returns an error if any of the keys are not ASCII, since they
+// could never match.  The check is performed on the input as supplied,
+// before any NormalizeInput functions are applied.
+//
+// The check examines every byte of the input, regardless of its contents, so
+// it may be combined with ConstantTime.  (The time taken will, however,
+// reveal whether the input was ASCII.)  With NULTerminated, it stops at the
+// first NUL instead.  It is not supported by Inline.
+var ASCIIOnly = new(Flag)
+
+// asciiGuardCode is emitted at the beginning of the generated code when
+// ASCIIOnly is specified.  The first line of the loop (see forEachIndex) is
+// substituted for %[1]s, and the none value for %[2]s.
+const asciiGuardCode = `	var fastmatchHigh byte
+	%[1]s
+		fastmatchHigh |= input[i]
+	}
+	if fastmatchHigh >= 0x80 {
+		return %[2]s
+	}
+`
+
+// writeASCIIGuard checks that the keys in cases are ASCII, then outputs
+// asciiGuardCode.  Any lines of the generated code recorded for coverage
+// thereafter are offset by the lines in the guard.
+func writeASCIIGuard(w io.Writer, cases map[string]string, none string, fs *flagSet) error {
+	if err := checkASCIIKeys(cases); err != nil {
+		return err
+	}
+
+	guard := fmt.Sprintf(asciiGuardCode, fs.forEachIndex("input"), none)
+	if stopsAtNUL(fs.stop) {
+		guard = fmt.Sprintf(nulASCIIGuardCode, none)
+	}
+	fs.coverOffset += strings.Count(guard, "\n")
+	_, err := io.WriteString(w, guard)
+	return err
+}
+
+// checkASCIIKeys returns an error listing any keys in cases which are not
+// ASCII, and thus can't match with ASCIIOnly.
+func checkASCIIKeys(cases map[string]string) error {
+	var keys []string
+	for key := range cases {
+		for i := 0; i < len(key); i++ {
+			if key[i] >= utf8.RuneSelf {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		for n, key := range keys {
+			keys[n] = strconv.Quote(key)
+		}
+		return fmt.Errorf("keys cannot match with ASCIIOnly: %s", strings.Join(keys, ", "))
+	}
+	return nil
+}
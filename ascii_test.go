@@ -0,0 +1,111 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestASCIIOnly tests that non-ASCII input doesn't match, even if it begins
+// with a key and HasPrefix is specified.
+func TestASCIIOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{ASCIIOnly, HasPrefix},
+		{ASCIIOnly, HasPrefix, Strategy(TrieStrategy)},
+		{ASCIIOnly, HasPrefix, Strategy(LinearStrategy)},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"cafe": "1",
+			"bar":  "2",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "cafe", "1")
+		expectMatch(t, "cafes", "1")
+		expectMatch(t, "bar", "2")
+		expectMatch(t, "cafe\u0301", "0")
+		expectMatch(t, "bar\u00e9", "0")
+		expectMatch(t, "\u00e9bar", "0")
+		cleanup()
+	}
+}
+
+// TestASCIIOnlyConstantTime tests that the guard is compatible with
+// ConstantTime.
+func TestASCIIOnlyConstantTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo": "1",
+		"bar": "2",
+	}, "0", ASCIIOnly, ConstantTime, Insensitive)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "FOO", "1")
+	expectMatch(t, "bar", "2")
+	expectMatch(t, "b\u00e1r", "0")
+}
+
+// TestASCIIOnlyOutput tests the emitted guard, and that keys which could
+// never match are rejected.
+func TestASCIIOnlyOutput(t *testing.T) {
+	var b bytes.Buffer
+	if err := Generate(&b, map[string]string{"foo": "1"}, "-1", ASCIIOnly); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "\tif fastmatchHigh >= 0x80 {\n\t\treturn -1\n\t}\n"; !strings.HasPrefix(b.String(), "\tvar fastmatchHigh byte\n") || !strings.Contains(b.String(), expect) {
+		t.Errorf("expected guard in output:\n%s", b.String())
+	}
+
+	err := Generate(&b, map[string]string{"foo": "1", "na\u00efve": "2", "\u00fcber": "3"}, "0", ASCIIOnly)
+	if err == nil {
+		t.Fatal("expected error from non-ASCII keys")
+	}
+	if expect := "keys cannot match with ASCIIOnly: \"na\u00efve\", \"\u00fcber\""; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+
+	if err := Generate(&b, map[string]string{"foo": "1"}, "0", ASCIIOnly, Inline); err == nil {
+		t.Error("expected error combining ASCIIOnly and Inline")
+	}
+}
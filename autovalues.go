@@ -0,0 +1,146 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+	"unicode"
+)
+
+// GenerateAutoValues is for callers which don't care what the values are,
+// just that each key has a different one, such as when interning a fixed set
+// of strings.  The keys are numbered sequentially, starting at one, in the
+// order supplied, and the following are output:
+//
+//	const (
+//		prefixKey1 typ = iota + 1
+//		prefixKey2
+//		...
+//	)
+//
+//	func fn(input string) typ
+//
+// The generated function returns the constant for the key it matches, or
+// zero if there is no match.  The mapping of keys to values is also
+// returned, for use by the caller at generation time.
+//
+// typ must be an integer type, which the caller is responsible for
+// declaring (unless it is a predeclared type such as int).  The name of
+// each constant is prefix followed by the key, with its first letter (and
+// the first letter following any punctuation) capitalized and the
+// punctuation removed.  For instance, with a prefix of "Method", the
+// constant for "GET" is named MethodGET, and for "x-custom" is named
+// MethodXCustom.  An error is returned if this doesn't produce a valid
+// identifier, or if two keys would produce the same identifier.
+//
+// The flags are passed to Generate.  Keys which are ambiguous with each
+// other when the flags are applied (for example, "get" and "GET" with
+// Insensitive) are an error, since they would need to have the same value.
+func GenerateAutoValues(w io.Writer, fn, typ, prefix string, keys []string, flags ...*Flag) (map[string]int, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int, len(keys))
+	cases := make(map[string]string, len(keys))
+	consts := make([]string, len(keys))
+	seen := make(map[string]string, len(keys))
+	for n, key := range keys {
+		if _, found := values[key]; found {
+			return nil, fmt.Errorf("duplicate key: %s", strconv.Quote(key))
+		}
+		consts[n] = appendIdent(prefix, key)
+		if !token.IsIdentifier(consts[n]) || consts[n] == prefix {
+			return nil, fmt.Errorf("no constant name for key %s", strconv.Quote(key))
+		}
+		if other, found := seen[consts[n]]; found {
+			return nil, fmt.Errorf("keys %s and %s would both be named %s",
+				strconv.Quote(other), strconv.Quote(key), consts[n])
+		}
+		seen[consts[n]] = key
+		values[key] = n + 1
+		cases[key] = consts[n]
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if len(keys) > 0 {
+		if _, err := fmt.Fprintln(w, "const ("); err != nil {
+			return nil, err
+		}
+		for n, c := range consts {
+			if n == 0 {
+				fmt.Fprintf(w, "\t%s %s = iota + 1", c, typ)
+			} else {
+				fmt.Fprintf(w, "\t%s", c)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, ")")
+		fmt.Fprintln(w)
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) %s {", fn, fs.extraParams(), typ); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w)
+
+	if err := fs.generateNested(w, lines, cases, "0", internalFlags(flags)); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// appendIdent returns prefix followed by the letters and digits of s, with
+// the first of each run of them capitalized, for building identifiers from
+// arbitrary strings.
+func appendIdent(prefix, s string) string {
+	name := []rune(prefix)
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name = append(name, r)
+	}
+	return string(name)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestAutoValues tests that the keys are numbered in order, and that the
+// generated function returns the constants.
+func TestAutoValues(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	var values map[string]int
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type Method uint8")
+		fmt.Fprintln(w)
+		var err error
+		values, err = GenerateAutoValues(w, "parseMethod", "Method", "Method", []string{"GET", "POST", "x-custom"}, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tm := parseMethod(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(m, m == MethodGET, m == MethodXCustom)")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 3 || values["GET"] != 1 || values["POST"] != 2 || values["x-custom"] != 3 {
+		t.Errorf("unexpected values: %v", values)
+	}
+	expectMatch(t, "get", "1 true false")
+	expectMatch(t, "POST", "2 false false")
+	expectMatch(t, "X-Custom", "3 false true")
+	expectMatch(t, "PUT", "0 false false")
+}
+
+// TestAutoValuesErrors tests keys which can't be given constants.
+func TestAutoValuesErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		keys   []string
+		expect string
+	}{
+		{[]string{"a", "b", "a"}, `duplicate key: "a"`},
+		{[]string{"a-b", "a_b"}, `keys "a-b" and "a_b" would both be named TAB`},
+		{[]string{"+"}, `no constant name for key "+"`},
+		{[]string{"ab", "aB"}, "ambiguous"},
+	} {
+		values, err := GenerateAutoValues(ioutil.Discard, "f", "int", "T", testCase.keys, Insensitive)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("%q: expected error containing %q, got %v", testCase.keys, testCase.expect, err)
+		}
+		if values != nil {
+			t.Errorf("%q: expected no values on error", testCase.keys)
+		}
+	}
+
+	if _, err := GenerateAutoValues(ioutil.Discard, "f", "int", "", []string{"1st"}); err == nil {
+		t.Error("expected error for key beginning with a digit and no prefix")
+	}
+}
+
+// TestAutoValuesCoverage tests that coverage refers to the lines of the
+// generated function.
+func TestAutoValuesCoverage(t *testing.T) {
+	m := &CoverageManifest{Line: 1}
+	var b bytes.Buffer
+	if _, err := GenerateAutoValues(&b, "f", "int", "K", []string{"a", "b"}, Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if len(m.Keys) != 2 {
+		t.Fatalf("expected 2 coverage entries, got %d", len(m.Keys))
+	}
+	for _, key := range m.Keys {
+		if line := lines[key.StartLine-1]; strings.TrimSpace(line) != "return K"+strings.ToUpper(key.Key) {
+			t.Errorf("line %d for %q is %q", key.StartLine, key.Key, line)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+)
+
+// parallelBatchThreshold is the smallest batch which the function output by
+// GenerateParallelBatch will split between goroutines.  Below this, the
+// cost of starting goroutines outweighs the benefit.
+const parallelBatchThreshold = 4096
+
+// GenerateBatch outputs a complete Go function named batchFn, which calls
+// the matcher fn (as generated by Generate) on each element of a slice of
+// strings.  retType is the return type of fn.  The generated function has
+// the signature:
+//
+//	func batchFn(inputs []string) []retType
+//
+// This is a convenience for bulk classification, such as mapping each
+// column header in a CSV file.  Calling the matcher in a tight loop also
+// tends to help the CPU's branch predictor, compared to interleaving calls
+// with other work.
+func GenerateBatch(w io.Writer, batchFn, fn, retType string) error {
+	if _, err := fmt.Fprintf(w, "func %s(inputs []string) []%s {", batchFn, retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tresults := make([]%s, len(inputs))", retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tfor n, input := range inputs {")
+	fmt.Fprintf(w, "\t\tresults[n] = %s(input)", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn results")
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// GenerateParallelBatch is like GenerateBatch, except that large batches
+// are split between one goroutine per CPU (as reported by
+// runtime.GOMAXPROCS).  The caller must import the runtime and sync
+// packages.
+//
+// Each goroutine writes to a separate portion of the result slice, so no
+// locking is needed beyond waiting for the goroutines to finish.  Batches
+// smaller than a few thousand elements are processed in the calling
+// goroutine.
+func GenerateParallelBatch(w io.Writer, batchFn, fn, retType string) error {
+	if _, err := fmt.Fprintf(w, "func %s(inputs []string) []%s {", batchFn, retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tresults := make([]%s, len(inputs))", retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tworkers := runtime.GOMAXPROCS(0)")
+	fmt.Fprintf(w, "\tif len(inputs) < %d || workers < 2 {", parallelBatchThreshold)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\tfor n, input := range inputs {")
+	fmt.Fprintf(w, "\t\t\tresults[n] = %s(input)", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\treturn results")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tchunk := (len(inputs) + workers - 1) / workers")
+	fmt.Fprintln(w, "\tvar wg sync.WaitGroup")
+	fmt.Fprintln(w, "\tfor start := 0; start < len(inputs); start += chunk {")
+	fmt.Fprintln(w, "\t\tend := start + chunk")
+	fmt.Fprintln(w, "\t\tif end > len(inputs) {")
+	fmt.Fprintln(w, "\t\t\tend = len(inputs)")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\twg.Add(1)")
+	fmt.Fprintf(w, "\t\tgo func(inputs []string, results []%s) {", retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\tdefer wg.Done()")
+	fmt.Fprintln(w, "\t\t\tfor n, input := range inputs {")
+	fmt.Fprintf(w, "\t\t\t\tresults[n] = %s(input)", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\t}")
+	fmt.Fprintln(w, "\t\t}(inputs[start:end], results[start:end])")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\twg.Wait()")
+	fmt.Fprintln(w, "\treturn results")
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// generateBatch creates a runnable program which classifies each of its
+// command-line arguments using a function output by GenerateBatch or
+// GenerateParallelBatch.
+func generateBatch(parallel bool) (func(), error) {
+	return generateProgram([]string{"fmt", "os", "runtime", "strings", "sync"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) int {")
+		if err := Generate(w, map[string]string{
+			"foo": "1",
+			"bar": "2",
+		}, "0"); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		if parallel {
+			if err := GenerateParallelBatch(w, "matchAll", "match", "int"); err != nil {
+				return err
+			}
+		} else {
+			if err := GenerateBatch(w, "matchAll", "match", "int"); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "var _ = runtime.GOMAXPROCS")
+		fmt.Fprintln(w, "var _ sync.WaitGroup")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tinputs := strings.Split(os.Args[1], \",\")")
+		fmt.Fprintln(w, "\tif inputs[0] == \"many\" {")
+		fmt.Fprintln(w, "\t\tn := len(inputs) - 1")
+		fmt.Fprintln(w, "\t\tfor len(inputs) < 10000 {")
+		fmt.Fprintln(w, "\t\t\tinputs = append(inputs, inputs[1:n+1]...)")
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t}")
+		fmt.Fprintln(w, "\tsum := 0")
+		fmt.Fprintln(w, "\tfor _, result := range matchAll(inputs) {")
+		fmt.Fprintln(w, "\t\tsum += result")
+		fmt.Fprintln(w, "\t}")
+		fmt.Fprintln(w, "\tfmt.Println(matchAll(inputs)[:3], sum)")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+}
+
+// TestBatch tests the functions output by GenerateBatch and
+// GenerateParallelBatch.
+func TestBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, parallel := range []bool{false, true} {
+		cleanup, err := generateBatch(parallel)
+		defer cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectMatch(t, "foo,baz,bar", "[1 0 2] 3")
+		// 3333 copies of "foo,bar,x" plus "many", which is
+		// large enough to be split between goroutines:
+		expectMatch(t, "many,foo,bar,x", "[0 1 2] 9999")
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TooShort returns a flag, which can be passed to Generate, to specify an
+// expression to return instead of none when the input is shorter than any
+// of the keys.  Along with TooLong, this allows the caller to report a
+// more precise error (such as ErrTooShort rather than ErrUnknownKeyword)
+// without examining the input a second time:
+//
+//	fastmatch.Generate(w, cases, "0, ErrUnknownKeyword",
+//		fastmatch.TooShort("0, ErrTooShort"),
+//		fastmatch.TooLong("0, ErrTooLong"))
+//
+// The length of the input is compared (in bytes) before anything else is
+// done with it, so TooShort cannot be combined with flags which may make
+// the input longer than what it's compared against, such as NormalizeInput
+// or Equivalent runes which are encoded using different numbers of bytes,
+// nor with NULTerminated, which ends the input at the first NUL.  If
+// ValueType is specified, the expression is checked as if it were the
+// none value.
+func TooShort(expr string) *Flag {
+	return &Flag{tooShort: expr}
+}
+
+// TooLong returns a flag, which can be passed to Generate, to specify an
+// expression to return instead of none when the input is longer than any of
+// the keys.  See TooShort.
+//
+// In addition to the flags which cannot be combined with TooShort, TooLong
+// cannot be combined with flags which allow the input to contain more than
+// a key, such as HasPrefix, StopUpon, or Ignore.
+func TooLong(expr string) *Flag {
+	return &Flag{tooLong: expr}
+}
+
+// TrustLength is a flag, which can be passed to Generate, to specify that
+// the caller guarantees the length of the input (in bytes, after any
+// NormalizeInput functions are applied) is the same as that of one of the
+// keys.  This is an expert option, for callers which have already
+// validated the input, such as fixed-width fields in a binary protocol.
+//
+// Normally, the generated code begins by switching on the length of the
+// input, and returns none if it doesn't match the length of any key.  With
+// TrustLength, the shortest keys are instead matched by the default case,
+// so if all of the keys are the same length, no comparison is made at all.
+// A comment documenting this contract is included in the generated code.
+// If the contract is violated, the generated code may return the wrong
+// value, or panic.
+//
+// TrustLength cannot be combined with flags which allow the input to
+// contain more than a key, such as HasPrefix, StopUpon, or Ignore, nor with
+// TooShort or TooLong.  It has no effect on strategies which don't
+// partition the keys by length, such as LinearStrategy, ConstantTime, or
+// the lookup table used for single-byte keys.
+var TrustLength = new(Flag)
+
+// trustLengthComment returns the comment output by Generate when
+// TrustLength is specified, for keys with the supplied lengths, which are
+// in descending order.
+func trustLengthComment(lengths []int) string {
+	var b strings.Builder
+	b.WriteString("\t// TrustLength: the caller guarantees len(input) is ")
+	for n := range lengths {
+		if n > 0 {
+			if len(lengths) > 2 {
+				b.WriteString(",")
+			}
+			if n == len(lengths)-1 {
+				b.WriteString(" or")
+			}
+			b.WriteString(" ")
+		}
+		b.WriteString(strconv.Itoa(lengths[len(lengths)-1-n]))
+	}
+	b.WriteString(".\n\t// Other lengths may return the wrong value or panic.\n")
+	return b.String()
+}
+
+// writeLengthGuard outputs checks for the TooShort and TooLong flags, if
+// specified, against the shortest and longest keys in cases.  Any lines of
+// the generated code recorded for coverage thereafter are offset by the
+// lines in the guard.
+func writeLengthGuard(w io.Writer, cases map[string]string, fs *flagSet, flags []*Flag) error {
+	var cannotCombine []string
+	if len(fs.normalize) > 0 {
+		cannotCombine = append(cannotCombine, flagName(fs.normalize[0]))
+	}
+	for r, rs := range fs.equiv {
+		if !sameRuneLen(r, rs) {
+			cannotCombine = append(cannotCombine, "Equivalent")
+			break
+		}
+	}
+	if _, shapes := splitShapes(cases); len(shapes) > 0 {
+		cannotCombine = append(cannotCombine, "token shapes")
+	}
+	for _, flag := range flags {
+		if stopsAtNUL(flag.stop) || (fs.tooLong != "" && (flag == HasPrefix || flag == HasSuffix ||
+			len(flag.stop) > 0 || len(flag.ignore) > 0 || len(flag.ignoreExcept) > 0)) {
+			cannotCombine = append(cannotCombine, flagName(flag))
+		}
+	}
+	if len(cannotCombine) > 0 {
+		name := "TooShort"
+		if fs.tooLong != "" {
+			name = "TooLong"
+		}
+		return &ErrBadFlags{cannotCombine: append(cannotCombine, name)}
+	}
+
+	// Keys are compared after removing ignored runes, etc.  An AnyDigit
+	// placeholder matches a single byte.
+	shortest, longest := -1, 0
+	for key := range cases {
+		key = fs.mangle(key)
+		if fs.anyDigit != 0 {
+			key = strings.Replace(key, string(fs.anyDigit), "0", -1)
+		}
+		if shortest == -1 || len(key) < shortest {
+			shortest = len(key)
+		}
+		if len(key) > longest {
+			longest = len(key)
+		}
+	}
+	if shortest == -1 {
+		return nil
+	}
+
+	var b strings.Builder
+	if fs.tooShort != "" && shortest > 0 {
+		fmt.Fprintf(&b, "\tif len(input) < %d {\n\t\treturn %s\n\t}\n", shortest, fs.tooShort)
+	}
+	if fs.tooLong != "" {
+		fmt.Fprintf(&b, "\tif len(input) > %d {\n\t\treturn %s\n\t}\n", longest, fs.tooLong)
+	}
+	fs.coverOffset += strings.Count(b.String(), "\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sameRuneLen returns true if r and each of rs are encoded in UTF-8 using
+// the same number of bytes.
+func sameRuneLen(r rune, rs []rune) bool {
+	for _, r2 := range rs {
+		if utf8.RuneLen(r2) != utf8.RuneLen(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestTooShortTooLong tests that input outside the range of key lengths
+// returns the expressions from TooShort and TooLong.
+func TestTooShortTooLong(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{TooShort("-1"), TooLong("-2")},
+		{TooShort("-1"), TooLong("-2"), Insensitive, Strategy(TrieStrategy)},
+		{TooShort("-1"), TooLong("-2"), ConstantTime},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"foo":    "1",
+			"foobar": "2",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "foo", "1")
+		expectMatch(t, "foobar", "2")
+		expectMatch(t, "fo", "-1")
+		expectMatch(t, "", "-1")
+		expectMatch(t, "foobarx", "-2")
+		expectMatch(t, "fooba", "0")
+		expectMatch(t, "bar", "0")
+		cleanup()
+	}
+}
+
+// TestTooShortIgnore tests that TooShort compares against keys after ignored
+// runes have been removed.
+func TestTooShortIgnore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"f-o-o": "1",
+		"quux":  "2",
+	}, "0", TooShort("-1"), Ignore('-'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "-f-o-o-", "1")
+	expectMatch(t, "f-o", "0")
+	expectMatch(t, "fo", "-1")
+}
+
+// TestTooShortTooLongOutput tests the emitted checks, and that incompatible
+// flags are rejected.
+func TestTooShortTooLongOutput(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{"ab": "1", "abcd": "2"}, "0", TooShort("ErrShort"), TooLong("ErrLong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "\tif len(input) < 2 {\n\t\treturn ErrShort\n\t}\n\tif len(input) > 4 {\n\t\treturn ErrLong\n\t}\n"; !strings.HasPrefix(b.String(), expect) {
+		t.Errorf("expected output to begin with %q:\n%s", expect, b.String())
+	}
+
+	b.Reset()
+	if err := Generate(&b, map[string]string{"": "1", "ab": "2"}, "0", TooShort("ErrShort")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "ErrShort") {
+		t.Errorf("unexpected check with empty key:\n%s", b.String())
+	}
+
+	for _, flags := range [][]*Flag{
+		{TooLong("-1"), HasPrefix},
+		{TooLong("-1"), StopUpon('.')},
+		{TooLong("-1"), Ignore('-')},
+		{TooShort("-1"), IgnorePlural},
+		{TooShort("-1"), Equivalent('o', '\u00f6')},
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+
+	err = Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", TooShort("x"), ValueType("int"))
+	if _, ok := err.(*ErrValueType); !ok {
+		t.Errorf("expected *ErrValueType, got %v", err)
+	}
+}
+
+// TestTrustLength tests matching input of the length of one of the keys
+// without checking it.
+func TestTrustLength(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{TrustLength},
+		{TrustLength, Insensitive},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"foo":    "1",
+			"bar":    "2",
+			"foobar": "3",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "foo", "1")
+		expectMatch(t, "bar", "2")
+		expectMatch(t, "foobar", "3")
+		expectMatch(t, "baz", "0")
+		expectMatch(t, "foobaz", "0")
+		cleanup()
+	}
+}
+
+// TestTrustLengthOutput tests the emitted comment and length switch, and
+// that incompatible flags are rejected.
+func TestTrustLengthOutput(t *testing.T) {
+	for _, testCase := range []struct {
+		cases  map[string]string
+		expect []string
+	}{
+		{map[string]string{"foo": "1", "bar": "2"}, []string{
+			"\t// TrustLength: the caller guarantees len(input) is 3.\n",
+			"\tswitch len(input) {\n\tdefault: // 3\n",
+		}},
+		{map[string]string{"ab": "1", "abcd": "2"}, []string{
+			"len(input) is 2 or 4.\n",
+			"\tcase 4:\n",
+			"\tdefault: // 2\n",
+		}},
+		{map[string]string{"ab": "1", "abc": "2", "abcd": "3"}, []string{
+			"len(input) is 2, 3, or 4.\n",
+		}},
+	} {
+		var b bytes.Buffer
+		if err := Generate(&b, testCase.cases, "0", TrustLength); err != nil {
+			t.Fatal(err)
+		}
+		for _, expect := range testCase.expect {
+			if !strings.Contains(b.String(), expect) {
+				t.Errorf("expected output to contain %q:\n%s", expect, b.String())
+			}
+		}
+	}
+
+	for _, flags := range [][]*Flag{
+		{TrustLength, HasPrefix},
+		{TrustLength, StopUpon('.')},
+		{TrustLength, Ignore('-')},
+		{TrustLength, TooShort("-1")},
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+}
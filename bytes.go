@@ -0,0 +1,75 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+)
+
+// GenerateBytes outputs Go code for a function which matches a []byte
+// against cases, as Generate does for a string.  The function has the
+// signature:
+//
+//	func fn(input []byte) retType
+//
+// This is for lexers and parsers which work on byte slices, such as those
+// using bufio.Scanner or reading from the network, so that they can match
+// without converting the input to a string and allocating memory.  (The Go
+// compiler avoids the allocation for map lookups of string(b), but not for
+// calls to a function taking a string.)
+//
+// The generated code always uses StateMachineStrategy, which only indexes
+// the input.  Flags which apply functions to the input as a string, such as
+// NormalizeInput, FoldEquivalents, NoTrailingMark, Preprocess, or Chain, are
+// not supported, nor are ConstantTime, Inline, or other strategies.
+func GenerateBytes(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.normalizeFunc != nil || flag.preprocess != "" || flag.chain != "" ||
+			flag == FoldEquivalents || flag == InsensitiveUnicode || flag == InsensitiveTurkish ||
+			flag == NoTrailingMark || flag == ConstantTime || flag == Inline ||
+			(flag.strategy != AutoStrategy && flag.strategy != StateMachineStrategy) {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateBytes"}
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input []byte%s) %s {", fn, fs.extraParams(), retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return Generate(w, cases, none, append(append([]*Flag(nil), flags...), Strategy(StateMachineStrategy))...)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestGenerateBytes tests matching a []byte, and that doing so doesn't
+// allocate.
+func TestGenerateBytes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		flags   []*Flag
+		matches map[string]string
+	}{
+		{nil, map[string]string{"foo": "1", "barbaz": "2", "Foo": "0", "fo": "0"}},
+		{[]*Flag{Insensitive, HasPrefix}, map[string]string{"FOO": "1", "fooBAR": "1", "BarBazQux": "2", "barfoo": "0"}},
+		{[]*Flag{HasSuffix}, map[string]string{"xfoo": "1", "foobarbaz": "2", "foox": "0"}},
+		{[]*Flag{StopUpon('.'), Ignore('-', '‐')}, map[string]string{"f-o‐o.x": "1", "bar-baz": "2", "f_oo": "0"}},
+		{[]*Flag{TooShort("-1"), TooLong("-2")}, map[string]string{"fo": "-1", "barbazx": "-2", "bar": "0"}},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os", "testing"}, func(w io.Writer) error {
+			err := GenerateBytes(w, "match", "int", map[string]string{
+				"foo":    "1",
+				"barbaz": "2",
+			}, "0", testCase.flags...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tbuf := []byte(os.Args[1])")
+			fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+			fmt.Fprintln(w, "\t\tmatch(buf)")
+			fmt.Fprintln(w, "\t})")
+			fmt.Fprintln(w, "\tfmt.Println(match(buf), allocs)")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+
+		for input, expect := range testCase.matches {
+			expectMatch(t, input, expect+" 0")
+		}
+		cleanup()
+	}
+}
+
+// TestGenerateBytesErrors tests unsupported flags.
+func TestGenerateBytesErrors(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{HasPrefix, NoTrailingMark},
+		{ChompLine},
+		{FoldEquivalents},
+		{Preprocess("_ = input")},
+		{Chain("other")},
+		{Strategy(LinearStrategy)},
+	} {
+		err := GenerateBytes(ioutil.Discard, "match", "int", map[string]string{"foo": "1"}, "0", flags...)
+		if err == nil || !strings.Contains(err.Error(), "GenerateBytes does not support flags") {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+}
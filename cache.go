@@ -0,0 +1,171 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheVersion is included in every fingerprint.  It needs to be changed
+// whenever a change to this package changes the code generated for a given
+// set of inputs, so that stale output is not reused.
+const cacheVersion = 1
+
+// cacheSuffix is appended to the fingerprint to name each file in the cache.
+// It's deliberately not ".go", in case the cache is inside a source tree.
+const cacheSuffix = ".fastmatch"
+
+// CacheDir returns a flag, which can be passed to Generate, to store the
+// generated code in dir, and to reuse it (instead of generating it again)
+// the next time Generate is called with the same cases, none value, and
+// flags.  This is intended for builds which regenerate the same matchers
+// repeatedly, where generating code for a large number of keys would
+// otherwise dominate the build time.  The directory is created if needed.
+//
+// Each file in the cache is named for the Fingerprint of its inputs.  Stale
+// entries can be removed with InvalidateCache, or by deleting the directory.
+//
+// The cache is bypassed if the Coverage flag is given, since the line numbers
+// are not stored.
+func CacheDir(dir string) *Flag {
+	return &Flag{cacheDir: dir}
+}
+
+// Fingerprint returns a string which identifies the code Generate would
+// output for the supplied cases, none value, and flags.  Flags which don't
+// affect the generated code, such as CacheDir, Coverage, and OnProgress, are
+// not taken into account.
+//
+// Flags which contain a function, such as NormalizeInput, are identified by
+// the expression the generated code uses to call it; the function itself is
+// assumed not to change.  TransformKeys has no such expression, so the keys
+// are instead fingerprinted as transformed.
+func Fingerprint(cases map[string]string, none string, flags ...*Flag) string {
+	var transforms []func(string) string
+	for _, flag := range flags {
+		if flag.transform != nil {
+			transforms = append(transforms, flag.transform)
+		}
+	}
+	if len(transforms) > 0 {
+		transformed := make(map[string]string, len(cases))
+		for key, value := range cases {
+			transformed[transformKey(key, transforms)] = value
+		}
+		cases = transformed
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "fastmatch %d\n", cacheVersion)
+	for _, key := range keys {
+		fmt.Fprintf(h, "case %q %q\n", key, cases[key])
+	}
+	fmt.Fprintf(h, "none %q\n", none)
+	for _, flag := range flags {
+		if flag.coverage != nil || flag.ctx != nil || flag.streamSize != 0 ||
+			flag.onProgress != nil || flag.cacheDir != "" || flag.result != nil ||
+			flag.maxOutput != 0 || flag.helpers != nil {
+			continue
+		}
+		fmt.Fprintf(h, "flag %s %q %q %q %q %q %q %q %d %q %d %d %d %q %q %q %q %q %q %q %q %q %q\n",
+			flagName(flag), flag.equivalent, flag.stop, flag.ignore,
+			flag.ignoreExcept, flag.normalizeExpr, flag.normalizeImport,
+			flag.goVersion, flag.strategy, flag.anyDigit, flag.maxFanOut, flag.maxDepth, flag.maxState,
+			flag.valueType, flag.valueDecls, flag.sentinel, flag.params,
+			flag.tooShort, flag.tooLong, flag.preprocess, flag.comparer, flag.chain, flag.exactOnly)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// InvalidateCache removes the code with the supplied Fingerprint from the
+// cache in dir.  It is not an error if there is no such code.
+func InvalidateCache(dir, fingerprint string) error {
+	err := os.Remove(filepath.Join(dir, fingerprint+cacheSuffix))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// generateCached implements Generate when the CacheDir flag is specified.
+func generateCached(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	path := filepath.Join(fs.cacheDir, Fingerprint(cases, none, flags...)+cacheSuffix)
+	if b, err := ioutil.ReadFile(path); err == nil {
+		fs.reportStrategy("cached", len(cases))
+		return fs.reportWhole(len(cases), func() error {
+			_, err := w.Write(b)
+			return err
+		})
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := generateCases(&b, cases, none, fs, flags); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent builds never
+	// see a partial entry.
+	if err := os.MkdirAll(fs.cacheDir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(fs.cacheDir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	_, err = b.WriteTo(w)
+	return err
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCacheDir tests that generated code is stored in and reused from the
+// cache.
+func TestCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fastmatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{"foo": "1", "bar": "2"}
+	flags := []*Flag{Insensitive, CacheDir(dir)}
+	fp := Fingerprint(cases, "0", flags...)
+	path := filepath.Join(dir, fp+cacheSuffix)
+
+	var first bytes.Buffer
+	if err := Generate(&first, cases, "0", flags...); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cached) != first.String() {
+		t.Error("cached code differs from output")
+	}
+
+	// Tamper with the cache entry, to prove it's used.
+	if err := ioutil.WriteFile(path, []byte("cached\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := Generate(&second, cases, "0", flags...); err != nil {
+		t.Fatal(err)
+	}
+	if second.String() != "cached\n" {
+		t.Errorf("expected cached output, got %q", second.String())
+	}
+
+	if err := InvalidateCache(dir, fp); err != nil {
+		t.Fatal(err)
+	}
+	if err := InvalidateCache(dir, fp); err != nil {
+		t.Errorf("unexpected error invalidating missing entry: %s", err)
+	}
+	var third bytes.Buffer
+	if err := Generate(&third, cases, "0", flags...); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(third.String(), "switch") {
+		t.Errorf("expected generated code, got %q", third.String())
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected 1 file in cache, got %d", len(files))
+	}
+}
+
+// TestFingerprint tests that the fingerprint changes along with anything
+// which affects the generated code, and only then.
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{"foo": "1", "bar": "2"}
+	base := Fingerprint(cases, "0", Insensitive)
+
+	for _, same := range []string{
+		Fingerprint(map[string]string{"bar": "2", "foo": "1"}, "0", Insensitive),
+		Fingerprint(cases, "0", Insensitive, CacheDir("x"), OnProgress(func(Progress) {})),
+	} {
+		if same != base {
+			t.Error("fingerprint changed unexpectedly")
+		}
+	}
+
+	for _, different := range []string{
+		Fingerprint(map[string]string{"foo": "1", "bar": "3"}, "0", Insensitive),
+		Fingerprint(map[string]string{"foo": "1", "baz": "2"}, "0", Insensitive),
+		Fingerprint(cases, "-1", Insensitive),
+		Fingerprint(cases, "0"),
+		Fingerprint(cases, "0", HasPrefix),
+		Fingerprint(cases, "0", Insensitive, Ignore('-')),
+		Fingerprint(cases, "0", Insensitive, Ignore('_')),
+		Fingerprint(cases, "0", Insensitive, NormalizeInput("strings.ToLower", strings.ToLower)),
+	} {
+		if different == base {
+			t.Error("fingerprint did not change")
+		}
+	}
+}
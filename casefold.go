@@ -0,0 +1,142 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// InsensitiveUnicode is a flag, which can be passed to Generate, to specify
+// that matching should be case-insensitive for all of Unicode, rather than
+// just the ASCII letters a-z.  For example, "À" matches "à", "ẞ" matches
+// "ß", and the Kelvin sign matches "k".  Runes are compared using Unicode
+// simple case folding, so a single rune never matches several ("ß" does not
+// match "ss").
+//
+// Multi-byte runes are folded in the input before matching, in the same
+// way as for FoldEquivalents.  To keep the generated code small, only
+// runes which are case-equivalent to one in the keys (or in StopUpon,
+// Ignore, IgnoreExcept, or Equivalent) are folded.  This flag implies
+// Insensitive, and cannot be combined with ASCIIOnly.
+var InsensitiveUnicode = new(Flag)
+
+// InsensitiveTurkish is like InsensitiveUnicode, but uses the Turkish and
+// Azerbaijani rules for the letter I: "i" matches "İ" (dotted capital I),
+// and "I" matches "ı" (dotless small i), but "i" and "I" do not match each
+// other.  It cannot be combined with Insensitive or IdentifierCase, which
+// would make "i" and "I" equivalent.
+var InsensitiveTurkish = new(Flag)
+
+// turkishOrbit returns the runes which InsensitiveTurkish considers
+// equivalent to r (including r itself), in ascending order.
+func turkishOrbit(r rune) []rune {
+	switch r {
+	case 'i', 'İ':
+		return []rune{'i', 'İ'}
+	case 'I', 'ı':
+		return []rune{'I', 'ı'}
+	}
+	return caseOrbit(r)
+}
+
+// caseFolds returns a map from each multi-byte rune which is case-equivalent
+// to one of rs, to the rune which replaces it.  The replacement is an ASCII
+// rune if possible, since those are matched by the Insensitive equivalents,
+// and otherwise the lowest lowercase rune.
+func caseFolds(rs []rune, turkish bool) map[rune]rune {
+	folds := make(map[rune]rune)
+	for _, r := range rs {
+		orbit := caseOrbit(r)
+		if turkish {
+			orbit = turkishOrbit(r)
+		}
+		if len(orbit) < 2 {
+			continue
+		}
+		to, best := orbit[0], -1
+		for _, r2 := range orbit {
+			score := 0
+			if r2 < utf8.RuneSelf {
+				score += 2
+			}
+			if unicode.IsLower(r2) {
+				score++
+			}
+			if score > best {
+				to, best = r2, score
+			}
+		}
+		for _, r2 := range orbit {
+			if r2 >= utf8.RuneSelf && r2 != to {
+				folds[r2] = to
+			}
+		}
+	}
+	return folds
+}
+
+// foldCase implements InsensitiveUnicode and InsensitiveTurkish, by adding a
+// NormalizeInput flag for the runes in cases and flags.  This needs the
+// keys, so it is done once they are known, rather than in parseFlags.
+func (fs *flagSet) foldCase(cases map[string]string, flags []*Flag) {
+	if !fs.caseFold {
+		return
+	}
+	fs.caseFold = false
+
+	var rs []rune
+	for key := range cases {
+		rs = append(rs, []rune(key)...)
+	}
+	rs = append(append(append(rs, fs.stop...), fs.ignore...), fs.ignoreExcept...)
+	for _, flag := range flags {
+		rs = append(rs, flag.equivalent...)
+	}
+	folds := caseFolds(rs, fs.turkish)
+
+	// FoldEquivalents' folds have already been applied to the input
+	// by the time these are, so a rune must be folded straight to the
+	// rune which replaces its replacement.
+	if fs.foldEquivalents {
+		unicodeFolds := unicodeFolds(makeEquivalents(flags...))
+		for r, to := range folds {
+			if to2, found := unicodeFolds[to]; found {
+				folds[r] = to2
+			}
+		}
+	}
+
+	if flag := unicodeFlag(folds); flag != nil {
+		fs.normalize = append(fs.normalize, flag)
+		fs.stop = foldRunes(fs.stop, folds)
+		fs.ignore = foldRunes(fs.ignore, folds)
+		fs.ignoreExcept = foldRunes(fs.ignoreExcept, folds)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// TestCaseFolds tests the replacement chosen for each multi-byte rune.
+func TestCaseFolds(t *testing.T) {
+	for _, test := range []struct {
+		rs      []rune
+		turkish bool
+		expect  map[rune]rune
+	}{
+		{[]rune("abc"), false, map[rune]rune{}},
+		{[]rune("À"), false, map[rune]rune{'À': 'à'}},
+		{[]rune("ß"), false, map[rune]rune{'ẞ': 'ß'}},
+		{[]rune("K"), false, map[rune]rune{'K': 'k'}},
+		{[]rune("ς"), false, map[rune]rune{'Σ': 'ς', 'σ': 'ς'}},
+		{[]rune("iı"), false, map[rune]rune{}},
+		{[]rune("iı"), true, map[rune]rune{'İ': 'i', 'ı': 'I'}},
+	} {
+		if got := caseFolds(test.rs, test.turkish); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("caseFolds(%q, %t): expected %q, got %q", string(test.rs), test.turkish, test.expect, got)
+		}
+	}
+}
+
+// TestInsensitiveUnicode tests case-insensitive matching of keys containing
+// multi-byte runes.
+func TestInsensitiveUnicode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{InsensitiveUnicode},
+		{InsensitiveUnicode, Strategy(LinearStrategy)},
+		{InsensitiveUnicode, HasPrefix},
+		{InsensitiveUnicode, FoldEquivalents, Equivalent('e', 'é')},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"straße": "1",
+			"été":    "2",
+			"kelvin": "3",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "straße", "1")
+		expectMatch(t, "STRAẞE", "1")
+		expectMatch(t, "été", "2")
+		expectMatch(t, "ÉTÉ", "2")
+		expectMatch(t, "KELVIN", "3")
+		expectMatch(t, "strasse", "0")
+		expectMatch(t, "", "0")
+		cleanup()
+	}
+}
+
+// TestInsensitiveTurkish tests the Turkish rules for dotted and dotless I.
+func TestInsensitiveTurkish(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"istanbul": "1",
+		"ırmak":    "2",
+	}, "0", InsensitiveTurkish)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "İSTANBUL", "1")
+	expectMatch(t, "istanbul", "1")
+	expectMatch(t, "ISTANBUL", "0")
+	expectMatch(t, "IRMAK", "2")
+	expectMatch(t, "ırmak", "2")
+	expectMatch(t, "irmak", "0")
+}
+
+// TestInsensitiveUnicodeErrors tests flags which cannot be combined with
+// InsensitiveUnicode or InsensitiveTurkish.
+func TestInsensitiveUnicodeErrors(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{InsensitiveUnicode, ASCIIOnly},
+		{InsensitiveTurkish, Insensitive},
+		{InsensitiveTurkish, IdentifierCase},
+		{InsensitiveTurkish, InsensitiveUnicode},
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"a": "1"}, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// CaseSet is a named cases map, for use with Merge.  The name is used to
+// report where conflicting keys came from.
+type CaseSet struct {
+	Name  string
+	Cases map[string]string
+}
+
+// Conflict specifies how Merge should resolve keys which appear in more than
+// one CaseSet with different values.
+type Conflict int
+
+const (
+	// ConflictError causes Merge to return an error.
+	ConflictError Conflict = iota
+
+	// ConflictFirst keeps the value from the earliest CaseSet.
+	ConflictFirst
+
+	// ConflictLast keeps the value from the latest CaseSet.
+	ConflictLast
+)
+
+// ErrConflict is returned by Merge when CaseSets cannot be combined.
+type ErrConflict struct {
+	// values maps a conflicting key to a list of "set (value)" strings.
+	values map[string][]string
+
+	// ambiguous holds groups of keys which are different, but would
+	// be ambiguous if passed to Generate with the supplied flags.  Each
+	// key is annotated with the set it came from.
+	ambiguous [][]string
+}
+
+func (e *ErrConflict) Error() string {
+	var b bytes.Buffer
+
+	keys := make([]string, 0, len(e.values))
+	for key := range e.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if b.Len() == 0 {
+			b.WriteString("conflicting values: ")
+		} else {
+			b.Write([]byte{';', ' '})
+		}
+		b.WriteString(strconv.Quote(key))
+		b.WriteString(" in ")
+		for n, source := range e.values[key] {
+			if n > 0 {
+				writeListSeparator(&b, n, len(e.values[key])-1)
+			}
+			b.WriteString(source)
+		}
+	}
+
+	for n, group := range e.ambiguous {
+		if n == 0 {
+			if b.Len() != 0 {
+				b.Write([]byte{';', ' '})
+			}
+			b.WriteString("ambiguous matches: ")
+		} else {
+			b.Write([]byte{';', ' '})
+		}
+		for m, key := range group {
+			if m > 0 {
+				b.Write([]byte{',', ' '})
+			}
+			b.WriteString(key)
+		}
+	}
+
+	return b.String()
+}
+
+// Merge combines several CaseSets into a single cases map, suitable for
+// passing to Generate.  (For example, a set of base keywords plus
+// extensions for a particular dialect.)
+//
+// The provenance return value maps each key in the result to the names of
+// the CaseSets which supplied it.
+//
+// If the same key appears in more than one CaseSet with different values,
+// policy determines whether to return an error or which value to keep.  If
+// flags are supplied, keys which are not identical but which would be
+// ambiguous when passed to Generate with those flags are treated as
+// conflicting in the same manner.  (If the ambiguous keys come from the same
+// CaseSet, they cannot be resolved by policy, and an error is returned.)
+func Merge(policy Conflict, sets []CaseSet, flags ...*Flag) (cases map[string]string, provenance map[string][]string, err error) {
+	cases = make(map[string]string)
+	provenance = make(map[string][]string)
+	winner := make(map[string]int) // key -> index into sets
+	e := &ErrConflict{values: make(map[string][]string)}
+
+	for n, set := range sets {
+		keys := make([]string, 0, len(set.Cases))
+		for key := range set.Cases {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := set.Cases[key]
+			provenance[key] = append(provenance[key], set.Name)
+
+			existing, found := cases[key]
+			if !found {
+				cases[key] = value
+				winner[key] = n
+				continue
+			}
+			if existing == value {
+				continue
+			}
+
+			switch policy {
+			case ConflictFirst:
+				// keep existing
+			case ConflictLast:
+				cases[key] = value
+				winner[key] = n
+			default:
+				if len(e.values[key]) == 0 {
+					e.values[key] = append(e.values[key], sets[winner[key]].Name+" ("+existing+")")
+				}
+				e.values[key] = append(e.values[key], set.Name+" ("+value+")")
+			}
+		}
+	}
+	if len(e.values) > 0 {
+		return nil, nil, e
+	}
+
+	if len(flags) == 0 {
+		return cases, provenance, nil
+	}
+
+	// Resolving an ambiguity may reveal another (since Generate prunes
+	// redundant keys before reporting), so repeat until there's nothing
+	// left to resolve.
+	for {
+		err := Generate(ioutil.Discard, cases, `""`, internalFlags(flags)...)
+		if err == nil {
+			return cases, provenance, nil
+		}
+		ambiguous, ok := err.(*ErrAmbiguous)
+		if !ok {
+			return nil, nil, err
+		}
+
+		resolved := false
+		for _, group := range ambiguous.sortedKeys() {
+			keep := winner[group[0]]
+			for _, key := range group[1:] {
+				if (policy == ConflictFirst && winner[key] < keep) ||
+					(policy == ConflictLast && winner[key] > keep) {
+					keep = winner[key]
+				}
+			}
+
+			var losers []string
+			for _, key := range group {
+				if winner[key] != keep {
+					losers = append(losers, key)
+				}
+			}
+			if policy == ConflictError || len(losers) == 0 {
+				annotated := make([]string, len(group))
+				for n, key := range group {
+					annotated[n] = strconv.Quote(key) + " (" + sets[winner[key]].Name + ")"
+				}
+				e.ambiguous = append(e.ambiguous, annotated)
+				continue
+			}
+			for _, key := range losers {
+				delete(cases, key)
+				delete(provenance, key)
+			}
+			resolved = true
+		}
+
+		if len(e.ambiguous) > 0 {
+			return nil, nil, e
+		}
+		if !resolved {
+			// Shouldn't happen, but guards against looping forever.
+			return nil, nil, ambiguous
+		}
+	}
+}
+
+// Subtract returns a new cases map containing the keys from cases which are
+// not present in any of the maps in remove.
+func Subtract(cases map[string]string, remove ...map[string]string) map[string]string {
+	result := make(map[string]string, len(cases))
+nextKey:
+	for key, value := range cases {
+		for _, other := range remove {
+			if _, found := other[key]; found {
+				continue nextKey
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// Intersect returns a new cases map containing only the keys which are
+// present in every one of the supplied maps.  Values are taken from the first
+// map.
+func Intersect(sets ...map[string]string) map[string]string {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(sets[0]))
+nextKey:
+	for key, value := range sets[0] {
+		for _, other := range sets[1:] {
+			if _, found := other[key]; !found {
+				continue nextKey
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// GenerateGroups outputs Go code to match a string against several groups of
+// cases, returning both the group and the value.  This allows a parser to
+// distinguish e.g. keywords from builtins from operators using a single
+// table, instead of maintaining overlapping ones.
+//
+// The Name of each CaseSet is the expression to return for the group, and
+// noneGroup is the group expression to return if no match is found.  The
+// function signature written by the caller must thus have two return values:
+//
+//	groups := []fastmatch.CaseSet{
+//		{Name: "Keyword", Cases: map[string]string{"if": "If", "for": "For"}},
+//		{Name: "Builtin", Cases: map[string]string{"len": "Len"}},
+//	}
+//	fmt.Fprintln(w, "func lookup(input string) (Class, Token) {")
+//	fastmatch.GenerateGroups(w, groups, "None", "Illegal")
+//
+// Separate matchers for each group can be generated from the same
+// declaration by passing each CaseSet's Cases to Generate.
+//
+// Keys which appear in more than one group, or which would be ambiguous when
+// combined, result in an ErrConflict naming the groups involved.  Otherwise,
+// this behaves the same as Generate, except that ValueType is not supported.
+func GenerateGroups(w io.Writer, groups []CaseSet, noneGroup, none string, flags ...*Flag) error {
+	for _, flag := range flags {
+		if flag.valueType != "" {
+			return &ErrBadFlags{unsupported: []string{"ValueType"}, unsupportedBy: "GenerateGroups"}
+		}
+	}
+
+	sets := make([]CaseSet, len(groups))
+	for n, group := range groups {
+		sets[n].Name = group.Name
+		sets[n].Cases = make(map[string]string, len(group.Cases))
+		for key, value := range group.Cases {
+			sets[n].Cases[key] = group.Name + ", " + value
+		}
+	}
+
+	cases, _, err := Merge(ConflictError, sets, flags...)
+	if err != nil {
+		return err
+	}
+
+	return Generate(w, cases, noneGroup+", "+none, flags...)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+var mergeBase = CaseSet{
+	Name: "base",
+	Cases: map[string]string{
+		"select": "1",
+		"from":   "2",
+		"limit":  "3",
+	},
+}
+
+var mergeDialect = CaseSet{
+	Name: "dialect",
+	Cases: map[string]string{
+		"select": "1",
+		"LIMIT":  "4",
+		"top":    "5",
+	},
+}
+
+// TestMerge tests combining CaseSets without conflicts.
+func TestMerge(t *testing.T) {
+	cases, provenance, err := Merge(ConflictError, []CaseSet{mergeBase, mergeDialect})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"select": "1",
+		"from":   "2",
+		"limit":  "3",
+		"LIMIT":  "4",
+		"top":    "5",
+	}
+	if !reflect.DeepEqual(cases, expect) {
+		t.Errorf("expected %v, got %v", expect, cases)
+	}
+	if !reflect.DeepEqual(provenance["select"], []string{"base", "dialect"}) {
+		t.Errorf("wrong provenance for \"select\": %q", provenance["select"])
+	}
+	if !reflect.DeepEqual(provenance["top"], []string{"dialect"}) {
+		t.Errorf("wrong provenance for \"top\": %q", provenance["top"])
+	}
+}
+
+// TestMergeConflict tests conflict detection and resolution when the same key
+// has different values.
+func TestMergeConflict(t *testing.T) {
+	other := CaseSet{Name: "other", Cases: map[string]string{"from": "6"}}
+
+	_, _, err := Merge(ConflictError, []CaseSet{mergeBase, other})
+	if _, ok := err.(*ErrConflict); !ok {
+		t.Fatalf("expected *ErrConflict, got %v", err)
+	}
+	expectStr := `conflicting values: "from" in base (2) and other (6)`
+	if err.Error() != expectStr {
+		t.Errorf("expected %q, got %q", expectStr, err.Error())
+	}
+
+	cases, _, err := Merge(ConflictFirst, []CaseSet{mergeBase, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cases["from"] != "2" {
+		t.Errorf("expected ConflictFirst to keep 2, got %s", cases["from"])
+	}
+
+	cases, _, err = Merge(ConflictLast, []CaseSet{mergeBase, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cases["from"] != "6" {
+		t.Errorf("expected ConflictLast to keep 6, got %s", cases["from"])
+	}
+}
+
+// TestMergeAmbiguous tests conflict detection and resolution when keys
+// become ambiguous due to flags.
+func TestMergeAmbiguous(t *testing.T) {
+	_, _, err := Merge(ConflictError, []CaseSet{mergeBase, mergeDialect}, Insensitive)
+	if _, ok := err.(*ErrConflict); !ok {
+		t.Fatalf("expected *ErrConflict, got %v", err)
+	}
+	expectStr := `ambiguous matches: "LIMIT" (dialect), "limit" (base)`
+	if err.Error() != expectStr {
+		t.Errorf("expected %q, got %q", expectStr, err.Error())
+	}
+
+	cases, provenance, err := Merge(ConflictLast, []CaseSet{mergeBase, mergeDialect}, Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := cases["limit"]; found {
+		t.Error("expected ConflictLast to discard \"limit\"")
+	}
+	if _, found := provenance["limit"]; found {
+		t.Error("expected ConflictLast to discard provenance for \"limit\"")
+	}
+	if cases["LIMIT"] != "4" {
+		t.Errorf("expected ConflictLast to keep \"LIMIT\", got %q", cases["LIMIT"])
+	}
+}
+
+// TestSubtract tests removing keys from a cases map.
+func TestSubtract(t *testing.T) {
+	result := Subtract(mergeBase.Cases, mergeDialect.Cases, map[string]string{"from": "0"})
+	expect := map[string]string{"limit": "3"}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("expected %v, got %v", expect, result)
+	}
+}
+
+// TestIntersect tests finding keys common to several cases maps.
+func TestIntersect(t *testing.T) {
+	result := Intersect(mergeBase.Cases, mergeDialect.Cases)
+	expect := map[string]string{"select": "1"}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("expected %v, got %v", expect, result)
+	}
+
+	if result := Intersect(); result != nil {
+		t.Errorf("expected nil, got %v", result)
+	}
+}
+
+// TestGenerateGroups tests a matcher which returns both a group and a value.
+func TestGenerateGroups(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) (string, int) {")
+		err := GenerateGroups(w, []CaseSet{
+			{Name: `"keyword"`, Cases: map[string]string{"if": "1", "for": "2"}},
+			{Name: `"builtin"`, Cases: map[string]string{"len": "3", "cap": "4"}},
+		}, `"none"`, "0", Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "if", "keyword 1")
+	expectMatch(t, "FOR", "keyword 2")
+	expectMatch(t, "len", "builtin 3")
+	expectMatch(t, "Cap", "builtin 4")
+	expectMatch(t, "while", "none 0")
+}
+
+// TestGenerateGroupsConflict tests that a key present in multiple groups is
+// reported as a conflict.
+func TestGenerateGroupsConflict(t *testing.T) {
+	err := GenerateGroups(ioutil.Discard, []CaseSet{
+		{Name: "Keyword", Cases: map[string]string{"print": "Print"}},
+		{Name: "Builtin", Cases: map[string]string{"PRINT": "Print"}},
+	}, "None", "Illegal", Insensitive)
+	if _, ok := err.(*ErrConflict); !ok {
+		t.Errorf("expected *ErrConflict, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+)
+
+// chainInput is the variable in which the generated code saves the input,
+// when Chain is specified and the input is modified before matching.
+const chainInput = "fastmatchInput"
+
+// Chain returns a flag, which can be passed to Generate, to specify a
+// function to call when the input doesn't match any of the keys, instead of
+// returning none.  This allows matchers to be layered, such as a fast
+// matcher for the most common keys, which falls back to a slower one (or a
+// map lookup) for the rest:
+//
+//	fmt.Fprintln(w, "func matchCommon(input string) Token {")
+//	fastmatch.Generate(w, common, "", fastmatch.Chain("matchRare"))
+//
+// The generated code returns fn(input), followed by the names of any
+// parameters from Params.  fn therefore needs to have the same signature as
+// the generated function.  The input is passed as supplied, even if it is
+// modified by the generated code before matching, such as by
+// NormalizeInput, Preprocess, or MaxDepth.
+//
+// none must be "" when Chain is specified.  fn must be a valid Go
+// expression, such as a function name or method value.  If ValueType is
+// specified, the call is checked as if it were the none value.
+func Chain(fn string) *Flag {
+	return &Flag{chain: fn}
+}
+
+// modifiesInput returns true if the code output by Generate assigns to
+// input before matching it.
+func (fs *flagSet) modifiesInput() bool {
+	return len(fs.normalize) > 0 || len(fs.preprocess) > 0 || fs.maxDepth > 0
+}
+
+// chainCall returns the none expression for the Chain flag, which calls the
+// function with input as the first argument.
+func (fs *flagSet) chainCall(input string) (string, error) {
+	if _, err := parser.ParseExpr(fs.chain); err != nil {
+		return "", fmt.Errorf("invalid Chain function %q: %s", fs.chain, err.Error())
+	}
+	args := []string{input}
+	if fs.params != "" {
+		names, err := fs.paramNames()
+		if err != nil {
+			return "", err
+		}
+		args = append(args, names...)
+	}
+	return fs.chain + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// paramNames returns the names of the parameters from Params.  Variadic
+// parameters are passed with "...".
+func (fs *flagSet) paramNames() ([]string, error) {
+	expr, err := parser.ParseExpr("func(" + fs.params + ") {}")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Params %q: %s", fs.params, err.Error())
+	}
+	var names []string
+	for _, field := range expr.(*ast.FuncLit).Type.Params.List {
+		if len(field.Names) == 0 {
+			return nil, fmt.Errorf("parameters in Params %q must be named", fs.params)
+		}
+		_, variadic := field.Type.(*ast.Ellipsis)
+		for _, name := range field.Names {
+			if variadic {
+				names = append(names, name.Name+"...")
+			} else {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// resolveChain replaces none with the call to the function from Chain, and
+// removes the Chain flag, so that the flags can be passed to Generate
+// internally along with the new none value.  check is the none value to
+// type-check with ValueType, which refers to the input as "input".
+func (fs *flagSet) resolveChain(none string, flags []*Flag) (newNone, check string, newFlags []*Flag, err error) {
+	if none != "" {
+		return "", "", nil, errors.New("none must be empty when Chain is specified")
+	}
+	input := "input"
+	if fs.modifiesInput() {
+		input = chainInput
+	}
+	if newNone, err = fs.chainCall(input); err != nil {
+		return "", "", nil, err
+	}
+	if check, err = fs.chainCall("input"); err != nil {
+		return "", "", nil, err
+	}
+	newFlags = make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag.chain == "" {
+			newFlags = append(newFlags, flag)
+		}
+	}
+	return newNone, check, newFlags, nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestChain tests falling back to another matcher, with the input as
+// supplied.
+func TestChain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{Insensitive, IgnorePlural},
+		{HasPrefix, MaxDepth(3), Preprocess("input = input[1:]")},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+			fmt.Fprintln(w, "func matchRare(input string, n int) string {")
+			fmt.Fprintln(w, "\treturn fmt.Sprintf(\"rare(%s, %d)\", input, n)")
+			fmt.Fprintln(w, "}")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func matchCommon(input string, n int) string {")
+			err := Generate(w, map[string]string{"foo": `"foo"`, "bar": `"bar"`}, "",
+				append(flags, Chain("matchRare"), Params("n int"))...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tfmt.Println(matchCommon(os.Args[1], 7))")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		if len(flags) > 0 && flags[0] == HasPrefix {
+			expectMatch(t, "xfoox", "foo")
+			expectMatch(t, "xbaz", "rare(xbaz, 7)")
+		} else {
+			expectMatch(t, "foo", "foo")
+			expectMatch(t, "baz", "rare(baz, 7)")
+		}
+		if len(flags) > 0 && flags[0] == Insensitive {
+			expectMatch(t, "BARS", "bar")
+			expectMatch(t, "BAZS", "rare(BAZS, 7)")
+		}
+		cleanup()
+	}
+}
+
+// TestChainOutput tests the emitted call, and invalid arguments.
+func TestChainOutput(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{"foo": "1"}, "", Chain("p.next"), Params("a, b int, c ...string"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "return p.next(input, a, b, c...)\n"; !strings.Contains(b.String(), expect) {
+		t.Errorf("expected %q in output:\n%s", expect, b.String())
+	}
+	if strings.Contains(b.String(), chainInput) {
+		t.Errorf("unexpected %s in output:\n%s", chainInput, b.String())
+	}
+
+	b.Reset()
+	if err := Generate(&b, map[string]string{"foo": "1"}, "", Chain("next"), ChompLine); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"\tfastmatchInput := input\n", "return next(fastmatchInput)\n"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	err = Generate(ioutil.Discard, map[string]string{"foo": "1"}, "", Chain("next"), ValueType("int", "func next(string) int"))
+	if err != nil {
+		t.Errorf("unexpected error checking value type: %v", err)
+	}
+
+	for _, testCase := range []struct {
+		none   string
+		flags  []*Flag
+		expect string
+	}{
+		{"0", []*Flag{Chain("next")}, "none must be empty when Chain is specified"},
+		{"", []*Flag{Chain("next(")}, `invalid Chain function "next("`},
+		{"", []*Flag{Chain("next"), Params("int")}, `parameters in Params "int" must be named`},
+		{"", []*Flag{Chain("next"), ValueType("int", "func next(string) string")}, "none value (next(input))"},
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, testCase.none, testCase.flags...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+# Copyright (c) 2014-2016 Dave Pifke.
+#
+# Redistribution and use in source and binary forms, with or without
+# modification, is permitted provided that the following conditions are met:
+#
+# 1. Redistributions of source code must retain the above copyright notice,
+#    this list of conditions and the following disclaimer.
+#
+# 2. Redistributions in binary form must reproduce the above copyright notice,
+#    this list of conditions and the following disclaimer in the documentation
+#    and/or other materials provided with the distribution.
+#
+# 3. Neither the name of the copyright holder nor the names of its
+#    contributors may be used to endorse or promote products derived from
+#    this software without specific prior written permission.
+#
+# THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+# AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+# IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+# ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+# LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+# CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+# SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+# INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+# CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+# ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+# POSSIBILITY OF SUCH DAMAGE.
+
+"""Reference Bazel rule for generating Go matchers with fastmatch.
+
+This wraps the fastmatch command; see its documentation for the format of the
+cases file.  The generated file can be added to the srcs of a go_library:
+
+    load("@fastmatch//cmd/fastmatch:fastmatch.bzl", "fastmatch")
+
+    fastmatch(
+        name = "methods",
+        cases = "methods.txt",
+        package = "http",
+        func = "parseMethod",
+        type = "Method",
+        none = "MethodUnknown",
+        flags = ["insensitive"],
+    )
+
+    go_library(
+        name = "http",
+        srcs = ["http.go", ":methods"],
+    )
+
+The fastmatch command is expected to be buildable as
+"//cmd/fastmatch" within the repository containing this file, for
+instance using BUILD files generated by Gazelle.  Override the tool
+attribute to use a different build of it.
+"""
+
+_FLAGS = ["insensitive", "prefix", "suffix"]
+
+def _fastmatch_impl(ctx):
+    out = ctx.outputs.out
+    args = ctx.actions.args()
+    args.add("-package", ctx.attr.package)
+    args.add("-func", ctx.attr.func)
+    args.add("-type", ctx.attr.type)
+    args.add("-none", ctx.attr.none)
+    for flag in ctx.attr.flags:
+        if flag not in _FLAGS:
+            fail("unknown flag %r; expected one of %r" % (flag, _FLAGS), "flags")
+        args.add("-" + flag)
+    args.add("-in", ctx.file.cases)
+    args.add("-o", out)
+
+    ctx.actions.run(
+        executable = ctx.executable.tool,
+        arguments = [args],
+        inputs = [ctx.file.cases],
+        outputs = [out],
+        mnemonic = "FastmatchGenerate",
+        progress_message = "Generating matcher %s" % out.short_path,
+    )
+    return [DefaultInfo(files = depset([out]))]
+
+fastmatch = rule(
+    implementation = _fastmatch_impl,
+    doc = "Generates a Go source file containing a fastmatch matcher.",
+    attrs = {
+        "cases": attr.label(
+            doc = "File containing the cases, one tab-separated key and value per line.",
+            allow_single_file = True,
+            mandatory = True,
+        ),
+        "package": attr.string(
+            doc = "Package name for the generated file.",
+            mandatory = True,
+        ),
+        "func": attr.string(
+            doc = "Name of the generated function.",
+            mandatory = True,
+        ),
+        "type": attr.string(
+            doc = "Return type of the generated function.",
+            mandatory = True,
+        ),
+        "none": attr.string(
+            doc = "Expression to return if the input is not matched.",
+            mandatory = True,
+        ),
+        "flags": attr.string_list(
+            doc = "Matching flags, without the leading dash: " + ", ".join(_FLAGS) + ".",
+        ),
+        "tool": attr.label(
+            doc = "The fastmatch command.",
+            default = Label("//cmd/fastmatch"),
+            executable = True,
+            cfg = "exec",
+        ),
+    },
+    outputs = {"out": "%{name}.go"},
+)
@@ -0,0 +1,429 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Command fastmatch generates a Go source file containing a function which
+// matches its input against a fixed set of strings, using the
+// pifke.org/fastmatch package.  It is intended to be wrapped by build
+// systems, such as Bazel or Please, so that generated matchers can be built
+// hermetically without each project writing its own generator program.
+//
+// Usage:
+//
+//	fastmatch [-package NAME] -func NAME -type TYPE [-none EXPR] [flags] [-in FILE] [-o FILE]
+//
+// If -package is not given, the package named by the GOPACKAGE environment
+// variable is used, which is set when the command is run by go generate.  If
+// -none is not given, the zero value of TYPE is returned for unmatched input.
+// This allows a matcher to be generated by a single directive:
+//
+//	//go:generate fastmatch -in keywords.json -func matchKeyword -type Token
+//
+// The cases are read from the file named by -in, or from standard input if
+// -in is not given or is "-".  By default, each line consists of a key and
+// the Go expression to return when the key is matched, separated by a tab.
+// If the key begins with a double quote, it is interpreted as a quoted Go
+// string, which allows keys containing tabs, newlines, or leading '#'
+// characters.  Blank lines and lines beginning with '#' are ignored:
+//
+//	# HTTP methods
+//	GET	MethodGet
+//	POST	MethodPost
+//	"\tindented"	MethodOther
+//
+// If -in ends in ".csv", or -format is "csv", the cases are instead read as
+// comma-separated values, with the key in the first field and the
+// expression in the second.  Quoting follows RFC 4180, and lines beginning
+// with '#' are ignored:
+//
+//	GET,MethodGet
+//	"a,b",MethodOther
+//
+// If -in ends in ".json", or -format is "json", the cases are read from a
+// JSON object, whose members map each key to its expression.  An expression
+// may be given as a JSON number instead of a string:
+//
+//	{"GET": "MethodGet", "POST": "MethodPost", "PUT": 3}
+//
+// A complete, gofmt-formatted Go source file is written to the file named by
+// -o, or to standard output if -o is not given or is "-".  The file begins
+// with the standard "Code generated ... DO NOT EDIT." comment, followed by
+// the package clause, any imports required by the flags, and the function:
+//
+//	func NAME(input string) TYPE
+//
+// The following flags modify how the input is matched; see the documentation
+// of the corresponding flags in the fastmatch package:
+//
+//	-insensitive  Insensitive
+//	-prefix       HasPrefix
+//	-suffix       HasSuffix
+//
+// This interface is stable: later versions will accept the same input and
+// flags, and the output depends only on the input and flags, so it can be
+// cached by the build system.  (The output may change between versions of
+// this command, as the code generator improves.)  The exit status is 0 on
+// success, 1 if the cases could not be read or no matcher could be
+// generated for them (such as if they are ambiguous), and 2 if the command
+// line is invalid.  Errors are reported on standard error.  The output file
+// is only written if successful.
+//
+// A reference Bazel rule, which wraps this command, is in fastmatch.bzl.
+//
+// # Migrating existing code
+//
+// The migrate subcommand finds code in existing Go source files which could
+// be replaced by a generated matcher:
+//
+//	fastmatch migrate [-w] FILE...
+//
+// Two forms of code are recognized.  The first is a function which takes a
+// single string parameter, and whose body is a switch on that parameter,
+// where each case is one or more string literals followed by a return
+// statement, and the default (or a return following the switch) supplies the
+// value for unmatched input:
+//
+//	func parseMethod(s string) Method {
+//		switch s {
+//		case "GET":
+//			return MethodGet
+//		case "POST":
+//			return MethodPost
+//		default:
+//			return MethodUnknown
+//		}
+//	}
+//
+// The second is an unexported package-level variable initialized with a map
+// literal with string keys, which is only ever indexed (and not modified, or
+// used in the comma-ok form) elsewhere in the package.  The map is replaced
+// by a function of the same name, so each lookup m[key] becomes m(key),
+// which returns the zero value for unmatched input.
+//
+// Without -w, the candidates found are listed, along with the reason any
+// others could not be migrated (such as values which refer to imported
+// packages, which the generated file would not import).  With -w, each
+// candidate NAME is replaced by a go:generate directive which runs this
+// command, and the cases are written to NAME_fastmatch.tsv, in the format
+// described above, and the generated code to NAME_fastmatch.go.  Rewritten
+// files are formatted with gofmt.  Comments within the replaced code are
+// not preserved.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pifke.org/fastmatch"
+)
+
+// Exit statuses.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run implements the command, returning the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "migrate" {
+		return runMigrate(args[1:], stdout, stderr)
+	}
+
+	flags := flag.NewFlagSet("fastmatch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "package `name` for the generated file (default $GOPACKAGE)")
+	fn := flags.String("func", "", "`name` of the generated function (required)")
+	retType := flags.String("type", "", "return `type` of the generated function (required)")
+	none := flags.String("none", "", "`expression` to return if the input is not matched (default the zero value of -type)")
+	in := flags.String("in", "-", "`file` to read cases from")
+	format := flags.String("format", "", "`format` of the cases: tsv, csv, or json (default from the -in extension, or tsv)")
+	out := flags.String("o", "-", "`file` to write the generated code to")
+	insensitive := flags.Bool("insensitive", false, "match case-insensitively")
+	prefix := flags.Bool("prefix", false, "match keys which are a prefix of the input")
+	suffix := flags.Bool("suffix", false, "match keys which are a suffix of the input")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	for _, required := range []struct{ name, value string }{
+		{"package", *pkg},
+		{"func", *fn},
+		{"type", *retType},
+	} {
+		if required.value == "" {
+			fmt.Fprintf(stderr, "fastmatch: -%s is required\n", required.name)
+			flags.Usage()
+			return exitUsage
+		}
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "fastmatch: unexpected argument %q\n", flags.Arg(0))
+		flags.Usage()
+		return exitUsage
+	}
+	if *none == "" {
+		*none = "*new(" + *retType + ")"
+	}
+
+	read, err := caseReader(*format, *in)
+	if err != nil {
+		fmt.Fprintln(stderr, "fastmatch:", err)
+		flags.Usage()
+		return exitUsage
+	}
+
+	var matchFlags []*fastmatch.Flag
+	for _, f := range []struct {
+		set  bool
+		flag *fastmatch.Flag
+	}{
+		{*insensitive, fastmatch.Insensitive},
+		{*prefix, fastmatch.HasPrefix},
+		{*suffix, fastmatch.HasSuffix},
+	} {
+		if f.set {
+			matchFlags = append(matchFlags, f.flag)
+		}
+	}
+
+	r := stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			fmt.Fprintln(stderr, "fastmatch:", err)
+			return exitError
+		}
+		defer f.Close()
+		r = f
+	}
+	cases, err := read(r)
+	if err != nil {
+		fmt.Fprintf(stderr, "fastmatch: %s: %s\n", *in, err)
+		return exitError
+	}
+
+	src, err := generate(*pkg, *fn, *retType, cases, *none, matchFlags)
+	if err != nil {
+		fmt.Fprintln(stderr, "fastmatch:", err)
+		return exitError
+	}
+
+	if *out == "-" {
+		_, err = stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(*out, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "fastmatch:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// caseReader returns the function which parses cases in the named format.
+// If format is empty, it is chosen based on the extension of the input file,
+// with files having other extensions read as tab-separated values.
+func caseReader(format, in string) (func(io.Reader) (map[string]string, error), error) {
+	explicit := format != ""
+	if !explicit {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(in)), ".")
+	}
+	switch format {
+	case "csv":
+		return readCSV, nil
+	case "json":
+		return readJSON, nil
+	case "tsv":
+		return readCases, nil
+	}
+	if explicit {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return readCases, nil
+}
+
+// readCases parses the tab-separated format described in the package
+// documentation.
+func readCases(r io.Reader) (map[string]string, error) {
+	cases := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		var key, value string
+		if text[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted key", line)
+			}
+			key, _ = strconv.Unquote(quoted)
+			value = text[len(quoted):]
+			if !strings.HasPrefix(value, "\t") {
+				return nil, fmt.Errorf("line %d: expected tab after key", line)
+			}
+			value = value[1:]
+		} else {
+			n := strings.IndexByte(text, '\t')
+			if n == -1 {
+				return nil, fmt.Errorf("line %d: expected tab after key", line)
+			}
+			key, value = text[:n], text[n+1:]
+		}
+
+		if value = strings.TrimSpace(value); value == "" {
+			return nil, fmt.Errorf("line %d: missing value for %q", line, key)
+		}
+		if _, found := cases[key]; found {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line, key)
+		}
+		cases[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+	return cases, nil
+}
+
+// readCSV parses cases from comma-separated values.
+func readCSV(r io.Reader) (map[string]string, error) {
+	cases := make(map[string]string)
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		key, value := record[0], strings.TrimSpace(record[1])
+		if value == "" {
+			return nil, fmt.Errorf("line %d: missing value for %q", line, key)
+		}
+		if _, found := cases[key]; found {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line, key)
+		}
+		cases[key] = value
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+	return cases, nil
+}
+
+// readJSON parses cases from a JSON object.  Unlike json.Unmarshal, which
+// keeps the last of any duplicate keys, duplicates are reported as errors.
+func readJSON(r io.Reader) (map[string]string, error) {
+	cases := make(map[string]string)
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, errors.New("expected JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string) // object keys are always strings
+
+		if tok, err = dec.Token(); err != nil {
+			return nil, err
+		}
+		var value string
+		switch v := tok.(type) {
+		case string:
+			value = strings.TrimSpace(v)
+		case json.Number:
+			value = v.String()
+		default:
+			return nil, fmt.Errorf("value for %q is not a string or number", key)
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("missing value for %q", key)
+		}
+		if _, found := cases[key]; found {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		cases[key] = value
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON object")
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+	return cases, nil
+}
+
+// generate returns the formatted source file.
+func generate(pkg, fn, retType string, cases map[string]string, none string, flags []*fastmatch.Flag) ([]byte, error) {
+	var b bytes.Buffer
+	err := fastmatch.GenerateFile(&b, fastmatch.FileSpec{
+		Package:    pkg,
+		FuncName:   fn,
+		ReturnType: retType,
+		Cases:      cases,
+		None:       none,
+		Flags:      flags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCases = `# comment
+GET	MethodGet
+
+POST	MethodPost
+"\tx"	MethodOther
+`
+
+// TestRun tests generating a file, and that the output is deterministic.
+func TestRun(t *testing.T) {
+	args := []string{"-package", "http", "-func", "parseMethod", "-type", "Method", "-none", "MethodUnknown", "-insensitive"}
+
+	var first string
+	for n := 0; n < 3; n++ {
+		var stdout, stderr bytes.Buffer
+		if status := run(args, strings.NewReader(testCases), &stdout, &stderr); status != exitOK {
+			t.Fatalf("exit status %d: %s", status, stderr.String())
+		}
+		if n == 0 {
+			first = stdout.String()
+		} else if stdout.String() != first {
+			t.Error("output differs between runs")
+		}
+	}
+
+	for _, expect := range []string{
+		"// Code generated by fastmatch. DO NOT EDIT.\n\npackage http\n",
+		"func parseMethod(input string) Method {\n",
+		"return MethodGet\n",
+		"return MethodOther\n",
+	} {
+		if !strings.Contains(first, expect) {
+			t.Errorf("expected %q in output:\n%s", expect, first)
+		}
+	}
+}
+
+// TestRunFiles tests reading and writing named files, and that the output
+// file is not written on error.
+func TestRunFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fastmatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "cases.txt"), filepath.Join(dir, "out.go")
+	if err := ioutil.WriteFile(in, []byte("a\t1\nA\t2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-package", "p", "-func", "f", "-type", "int", "-none", "0", "-in", in, "-o", out}
+
+	var stderr bytes.Buffer
+	if status := run(args, nil, ioutil.Discard, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Remove(out)
+	stderr.Reset()
+	if status := run(append(args, "-insensitive"), nil, ioutil.Discard, &stderr); status != exitError {
+		t.Errorf("expected exit status %d from ambiguous cases, got %d", exitError, status)
+	}
+	if !strings.Contains(stderr.String(), "ambiguous") {
+		t.Errorf("expected error about ambiguity, got %q", stderr.String())
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("output file written despite error")
+	}
+}
+
+// TestRunErrors tests the exit status for invalid input and command lines.
+func TestRunErrors(t *testing.T) {
+	args := []string{"-package", "p", "-func", "f", "-type", "int", "-none", "0"}
+	for _, testCase := range []struct {
+		args   []string
+		input  string
+		status int
+	}{
+		{args[:4], "a\t1\n", exitUsage},
+		{args[2:], "a\t1\n", exitUsage},
+		{append(args, "-format", "xml"), "a\t1\n", exitUsage},
+		{append(args, "-bogus"), "a\t1\n", exitUsage},
+		{append(args, "extra"), "a\t1\n", exitUsage},
+		{args, "", exitError},
+		{args, "a 1\n", exitError},
+		{args, "a\t\n", exitError},
+		{args, "a\t1\na\t2\n", exitError},
+		{args, "\"a\t1\n", exitError},
+		{args, "a\t1 +\n", exitError},
+		{append(args, "-in", "/nonexistent"), "", exitError},
+		{append(args, "-format", "csv"), "a\n", exitError},
+		{append(args, "-format", "csv"), "a,1\na,2\n", exitError},
+		{append(args, "-format", "json"), `["a", "1"]`, exitError},
+		{append(args, "-format", "json"), `{"a": true}`, exitError},
+		{append(args, "-format", "json"), `{"a": "1", "a": "2"}`, exitError},
+		{append(args, "-format", "json"), `{"a": "1"} {}`, exitError},
+		{append(args, "-format", "json"), `{}`, exitError},
+	} {
+		os.Unsetenv("GOPACKAGE")
+		var stderr bytes.Buffer
+		if status := run(testCase.args, strings.NewReader(testCase.input), ioutil.Discard, &stderr); status != testCase.status {
+			t.Errorf("%q with input %q: expected exit status %d, got %d", testCase.args, testCase.input, testCase.status, status)
+		}
+		if stderr.Len() == 0 {
+			t.Errorf("%q with input %q: no error message", testCase.args, testCase.input)
+		}
+	}
+}
+
+// TestRunFormats tests reading cases as CSV and JSON, and the defaults used
+// when run by go generate.
+func TestRunFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fastmatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, testCase := range []struct {
+		file, input string
+	}{
+		{"cases.csv", "# comment\nGET,MethodGet\n\"a,b\", MethodOther\n"},
+		{"cases.json", `{"GET": "MethodGet", "a,b": "MethodOther", "PUT": 3}`},
+		{"cases.tsv", "GET\tMethodGet\na,b\tMethodOther\n"},
+	} {
+		in := filepath.Join(dir, testCase.file)
+		if err := ioutil.WriteFile(in, []byte(testCase.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		os.Setenv("GOPACKAGE", "http")
+		var stdout, stderr bytes.Buffer
+		status := run([]string{"-in", in, "-func", "parseMethod", "-type", "Method"}, nil, &stdout, &stderr)
+		os.Unsetenv("GOPACKAGE")
+		if status != exitOK {
+			t.Errorf("%s: exit status %d: %s", testCase.file, status, stderr.String())
+			continue
+		}
+
+		for _, expect := range []string{
+			"\npackage http\n",
+			"return MethodGet\n",
+			"return MethodOther\n",
+			"return *new(Method)\n",
+		} {
+			if !strings.Contains(stdout.String(), expect) {
+				t.Errorf("%s: expected %q in output:\n%s", testCase.file, expect, stdout.String())
+			}
+		}
+	}
+}
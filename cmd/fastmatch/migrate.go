@@ -0,0 +1,626 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// migrateSuffix is appended to the name of each migrated function or map to
+// form the names of the files written by migrate.
+const migrateSuffix = "_fastmatch"
+
+// candidate is a function or map which migrate has found in a source file.
+type candidate struct {
+	pos  token.Position
+	name string
+	kind string // "switch" or "map"
+
+	pkg, retType, none string
+	cases              map[string]string
+
+	// skip is the reason the candidate can't be migrated, or "" if it
+	// can.
+	skip string
+
+	// decl is replaced by the go:generate directive, and (for maps)
+	// refs are the index expressions to convert to function calls.
+	decl ast.Node
+	refs []*ast.IndexExpr
+}
+
+// edit is a change to the contents of a source file.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// migrateFile holds a parsed source file, and the edits made to it.
+type migrateFile struct {
+	name  string
+	src   []byte
+	ast   *ast.File
+	edits []edit
+}
+
+// migrator holds the state of the migrate subcommand.
+type migrator struct {
+	fset  *token.FileSet
+	files map[string]*migrateFile // by path
+
+	// pkgFiles holds every file in the package containing each file
+	// named on the command line, for finding references to maps.
+	pkgFiles map[string][]*migrateFile // by directory
+}
+
+// runMigrate implements the migrate subcommand, returning the exit status.
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fastmatch migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "rewrite the files, instead of listing what would be migrated")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "fastmatch migrate: no files specified")
+		flags.Usage()
+		return exitUsage
+	}
+
+	m := &migrator{
+		fset:     token.NewFileSet(),
+		files:    make(map[string]*migrateFile),
+		pkgFiles: make(map[string][]*migrateFile),
+	}
+	var candidates []*candidate
+	for _, path := range flags.Args() {
+		f, err := m.load(path)
+		if err == nil {
+			err = m.loadPackage(f)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "fastmatch migrate:", err)
+			return exitError
+		}
+		candidates = append(candidates, m.find(f)...)
+	}
+
+	status := exitOK
+	migrated := 0
+	for _, c := range candidates {
+		if c.skip != "" {
+			fmt.Fprintf(stdout, "%s: %s: skipped: %s\n", c.pos, c.name, c.skip)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: %s: %s with %d keys\n", c.pos, c.name, c.kind, len(c.cases))
+		if !*write {
+			continue
+		}
+		if err := m.migrate(c); err != nil {
+			fmt.Fprintf(stderr, "fastmatch migrate: %s: %s\n", c.pos, err)
+			status = exitError
+			continue
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		if err := m.save(); err != nil {
+			fmt.Fprintln(stderr, "fastmatch migrate:", err)
+			return exitError
+		}
+	}
+	return status
+}
+
+// load parses a source file, unless it has already been loaded.
+func (m *migrator) load(path string) (*migrateFile, error) {
+	path = filepath.Clean(path)
+	if f := m.files[path]; f != nil {
+		return f, nil
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Object resolution is needed to tell references to a package-level
+	// map from local variables of the same name.
+	file, err := parser.ParseFile(m.fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	f := &migrateFile{name: path, src: src, ast: file}
+	m.files[path] = f
+	return f, nil
+}
+
+// loadPackage loads the other files in the same package as f.
+func (m *migrator) loadPackage(f *migrateFile) error {
+	dir := filepath.Dir(f.name)
+	if _, found := m.pkgFiles[dir]; found {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	m.pkgFiles[dir] = nil
+	for _, path := range paths {
+		other, err := m.load(path)
+		if err != nil {
+			return err
+		}
+		if other.ast.Name.Name == f.ast.Name.Name {
+			m.pkgFiles[dir] = append(m.pkgFiles[dir], other)
+		}
+	}
+	return nil
+}
+
+// find returns the functions and maps in a file which could be migrated.
+func (m *migrator) find(f *migrateFile) []*candidate {
+	var candidates []*candidate
+	for _, decl := range f.ast.Decls {
+		var c *candidate
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			c = m.findSwitch(f, decl)
+		case *ast.GenDecl:
+			c = m.findMap(f, decl)
+		}
+		if c != nil {
+			c.pkg = f.ast.Name.Name
+			c.pos = m.fset.Position(c.decl.Pos())
+			if c.skip == "" {
+				c.skip = m.checkFiles(f, c)
+			}
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// findSwitch returns a candidate if decl is a function whose body consists
+// of a switch on its only parameter, which must be a string.
+func (m *migrator) findSwitch(f *migrateFile, decl *ast.FuncDecl) *candidate {
+	typ := decl.Type
+	if decl.Recv != nil || decl.Body == nil || len(typ.Params.List) != 1 ||
+		len(typ.Params.List[0].Names) != 1 || !isIdent(typ.Params.List[0].Type, "string") ||
+		typ.Results == nil || len(typ.Results.List) != 1 || len(typ.Results.List[0].Names) > 1 {
+		return nil
+	}
+	stmts := decl.Body.List
+	if len(stmts) == 0 || len(stmts) > 2 {
+		return nil
+	}
+	sw, ok := stmts[0].(*ast.SwitchStmt)
+	param := typ.Params.List[0].Names[0].Name
+	if !ok || sw.Init != nil || !isIdent(sw.Tag, param) {
+		return nil
+	}
+
+	c := &candidate{
+		name:    decl.Name.Name,
+		kind:    "switch",
+		retType: m.text(f, typ.Results.List[0].Type),
+		cases:   make(map[string]string),
+		decl:    decl,
+	}
+	if len(typ.Results.List[0].Names) > 0 {
+		c.skip = "named result"
+		return c
+	}
+	if len(stmts) == 2 {
+		ret, ok := stmts[1].(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			c.skip = "statement following switch is not a return"
+			return c
+		}
+		c.none = m.value(f, c, ret.Results[0], param)
+	}
+	for _, stmt := range sw.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if len(clause.Body) != 1 {
+			c.skip = "case does not consist of a single return"
+			return c
+		}
+		ret, ok := clause.Body[0].(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			c.skip = "case does not consist of a single return"
+			return c
+		}
+		value := m.value(f, c, ret.Results[0], param)
+		if clause.List == nil {
+			c.none = value
+			continue
+		}
+		for _, expr := range clause.List {
+			key, ok := stringLit(expr)
+			if !ok {
+				c.skip = "case is not a string literal"
+				return c
+			}
+			c.cases[key] = value
+		}
+	}
+	if c.none == "" && c.skip == "" {
+		c.skip = "no default"
+	}
+	return c
+}
+
+// findMap returns a candidate if decl declares a single package-level
+// variable, initialized with a map literal with string keys.
+func (m *migrator) findMap(f *migrateFile, decl *ast.GenDecl) *candidate {
+	if decl.Tok != token.VAR || len(decl.Specs) != 1 {
+		return nil
+	}
+	spec := decl.Specs[0].(*ast.ValueSpec)
+	if len(spec.Names) != 1 || len(spec.Values) != 1 {
+		return nil
+	}
+	lit, ok := spec.Values[0].(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	mapType, ok := lit.Type.(*ast.MapType)
+	if !ok || !isIdent(mapType.Key, "string") {
+		return nil
+	}
+
+	c := &candidate{
+		name:    spec.Names[0].Name,
+		kind:    "map",
+		retType: m.text(f, mapType.Value),
+		cases:   make(map[string]string, len(lit.Elts)),
+		decl:    decl,
+	}
+	c.none = zeroValue(c.retType)
+	switch {
+	case decl.Lparen.IsValid():
+		c.skip = "declared in a group"
+	case spec.Type != nil:
+		c.skip = "declared with an explicit type"
+	case ast.IsExported(c.name):
+		c.skip = "exported"
+	}
+	for _, elt := range lit.Elts {
+		kv := elt.(*ast.KeyValueExpr)
+		key, ok := stringLit(kv.Key)
+		if !ok {
+			c.skip = "key is not a string literal"
+			return c
+		}
+		value := kv.Value
+		if cl, ok := value.(*ast.CompositeLit); ok && cl.Type == nil {
+			// The type was elided, but is needed in a return
+			// statement.
+			c.cases[key] = c.retType + m.value(f, c, value, "")
+			continue
+		}
+		c.cases[key] = m.value(f, c, value, "")
+	}
+	return c
+}
+
+// value returns the source of an expression which is to be moved into the
+// generated file.  If the expression can't be moved, c.skip is set.
+func (m *migrator) value(f *migrateFile, c *candidate, expr ast.Expr, param string) string {
+	imports := make(map[string]bool, len(f.ast.Imports))
+	for _, spec := range f.ast.Imports {
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			name = path[strings.LastIndex(path, "/")+1:]
+		}
+		imports[name] = true
+	}
+
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if n.Name == param && c.skip == "" {
+				c.skip = "value depends on the input"
+			}
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && imports[x.Name] && x.Obj == nil && c.skip == "" {
+				c.skip = "value refers to an imported package"
+			}
+		case *ast.CompositeLit:
+			if n.Type == nil && n != expr && c.skip == "" {
+				c.skip = "value contains a composite literal with an elided type"
+			}
+		case *ast.UnaryExpr:
+			if lit, ok := n.X.(*ast.CompositeLit); ok && lit.Type == nil && c.skip == "" {
+				c.skip = "value contains a composite literal with an elided type"
+			}
+		}
+		return true
+	})
+
+	text := m.text(f, expr)
+	if strings.ContainsAny(text, "\r\n") && c.skip == "" {
+		c.skip = "value spans multiple lines"
+	}
+	return text
+}
+
+// checkFiles returns a reason the candidate can't be migrated because of the
+// files it would produce or, for maps, how the map is used.  Otherwise, it
+// records the references which need to be rewritten.
+func (m *migrator) checkFiles(f *migrateFile, c *candidate) string {
+	dir := filepath.Dir(f.name)
+	for _, ext := range []string{".go", ".tsv"} {
+		if _, err := os.Stat(filepath.Join(dir, c.name+migrateSuffix+ext)); err == nil {
+			return c.name + migrateSuffix + ext + " already exists"
+		}
+	}
+	if c.kind != "map" {
+		return ""
+	}
+
+	spec := c.decl.(*ast.GenDecl).Specs[0]
+	for _, other := range m.pkgFiles[dir] {
+		var stack []ast.Node
+		var skip string
+		var skipPos token.Pos
+		ast.Inspect(other.ast, func(n ast.Node) bool {
+			if n == nil {
+				stack = stack[:len(stack)-1]
+				return false
+			}
+			stack = append(stack, n)
+			ident, ok := n.(*ast.Ident)
+			if !ok || ident.Name != c.name || skip != "" || len(stack) < 2 {
+				return true
+			}
+			if ident.Obj != nil && ident.Obj.Decl != spec {
+				// Something else with the same name.
+				return true
+			}
+			if _, ok := stack[len(stack)-2].(*ast.ValueSpec); ok && other == f {
+				// The declaration itself.
+				return true
+			}
+			if reason := mapRefUsage(stack); reason != "" {
+				skip, skipPos = reason, ident.Pos()
+				return true
+			}
+			c.refs = append(c.refs, stack[len(stack)-2].(*ast.IndexExpr))
+			return true
+		})
+		if skip != "" {
+			return fmt.Sprintf("%s at %s", skip, m.fset.Position(skipPos))
+		}
+	}
+	return ""
+}
+
+// mapRefUsage returns why a reference to a map (the last node on the stack)
+// prevents it from being replaced with a function, or "" if the reference
+// is a lookup which can be converted to a function call.
+func mapRefUsage(stack []ast.Node) string {
+	ident := stack[len(stack)-1]
+	index, ok := stack[len(stack)-2].(*ast.IndexExpr)
+	if !ok || index.X != ident {
+		return "map used other than by indexing"
+	}
+	if len(stack) < 3 {
+		return ""
+	}
+	switch parent := stack[len(stack)-3].(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range parent.Lhs {
+			if lhs == index {
+				return "map modified"
+			}
+		}
+		if len(parent.Lhs) == 2 && len(parent.Rhs) == 1 {
+			return "map lookup uses comma-ok form"
+		}
+	case *ast.ValueSpec:
+		if len(parent.Names) == 2 && len(parent.Values) == 1 {
+			return "map lookup uses comma-ok form"
+		}
+	case *ast.IncDecStmt:
+		return "map modified"
+	}
+	return ""
+}
+
+// migrate writes the cases and generated code for a candidate, and records
+// the edits to the source files.
+func (m *migrator) migrate(c *candidate) error {
+	f := m.files[filepath.Clean(c.pos.Filename)]
+	dir := filepath.Dir(f.name)
+	base := c.name + migrateSuffix
+
+	src, err := generate(c.pkg, c.name, c.retType, c.cases, c.none, nil)
+	if err != nil {
+		return err
+	}
+	var spec bytes.Buffer
+	writeCases(&spec, c.cases)
+	if err := ioutil.WriteFile(filepath.Join(dir, base+".tsv"), spec.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, base+".go"), src, 0644); err != nil {
+		return err
+	}
+
+	var directive bytes.Buffer
+	directive.WriteString("//go:generate fastmatch")
+	for _, arg := range []string{
+		"-package", c.pkg,
+		"-func", c.name,
+		"-type", c.retType,
+		"-none", c.none,
+		"-in", base + ".tsv",
+		"-o", base + ".go",
+	} {
+		directive.WriteByte(' ')
+		directive.WriteString(generateArg(arg))
+	}
+	f.edits = append(f.edits, edit{m.offset(c.decl.Pos()), m.offset(c.decl.End()), directive.String()})
+
+	for _, ref := range c.refs {
+		refFile := m.files[filepath.Clean(m.fset.Position(ref.Pos()).Filename)]
+		refFile.edits = append(refFile.edits,
+			edit{m.offset(ref.Lbrack), m.offset(ref.Lbrack) + 1, "("},
+			edit{m.offset(ref.Rbrack), m.offset(ref.Rbrack) + 1, ")"},
+		)
+	}
+	return nil
+}
+
+// save applies the edits to each source file and writes it.
+func (m *migrator) save() error {
+	paths := make([]string, 0, len(m.files))
+	for path := range m.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		f := m.files[path]
+		if len(f.edits) == 0 {
+			continue
+		}
+		sort.Slice(f.edits, func(i, j int) bool {
+			return f.edits[i].start > f.edits[j].start
+		})
+		src := append([]byte(nil), f.src...)
+		for _, e := range f.edits {
+			src = append(src[:e.start], append([]byte(e.text), src[e.end:]...)...)
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			return fmt.Errorf("%s: rewritten file is invalid: %s", path, err)
+		}
+		if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text returns the source of a node.
+func (m *migrator) text(f *migrateFile, n ast.Node) string {
+	return string(f.src[m.offset(n.Pos()):m.offset(n.End())])
+}
+
+// offset returns the byte offset of a position within its file.
+func (m *migrator) offset(pos token.Pos) int {
+	return m.fset.Position(pos).Offset
+}
+
+// isIdent returns true if expr is an identifier with the supplied name.
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// stringLit returns the value of a string literal.
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// zeroValue returns an expression for the zero value of a type, which is
+// what looking up a missing key in a map returns.
+func zeroValue(typ string) string {
+	switch typ {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"byte", "rune", "float32", "float64", "complex64", "complex128":
+		return "0"
+	case "error":
+		return "nil"
+	}
+	for _, prefix := range []string{"*", "[]", "map[", "func(", "chan ", "<-chan ", "interface{"} {
+		if strings.HasPrefix(typ, prefix) {
+			return "nil"
+		}
+	}
+	return "*new(" + typ + ")"
+}
+
+// writeCases outputs cases in the format read by readCases, sorted by key.
+func writeCases(w io.Writer, cases map[string]string) {
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", quoteKey(key), cases[key])
+	}
+}
+
+// quoteKey returns a key as it should appear in the input format, quoting
+// it only if necessary.
+func quoteKey(key string) string {
+	if key == "" || key[0] == '"' || key[0] == '#' || !utf8.ValidString(key) ||
+		strings.IndexFunc(key, func(r rune) bool { return !unicode.IsPrint(r) }) != -1 {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// generateArg quotes an argument to a go:generate directive, if necessary.
+func generateArg(arg string) string {
+	if strings.ContainsAny(arg, " \t\"") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const migrateSource = `package p
+
+import "strings"
+
+type Method int
+
+const (
+	MethodUnknown Method = iota
+	MethodGet
+	MethodPost
+)
+
+// parseMethod converts a string to a Method.
+func parseMethod(s string) Method {
+	switch s {
+	case "GET", "get":
+		return MethodGet
+	case "POST":
+		return MethodPost
+	}
+	return MethodUnknown
+}
+
+var colors = map[string]int{
+	"red":   1,
+	"green": 2,
+}
+
+var shapes = map[string][2]int{
+	"square": {4, 4},
+}
+
+func upper(s string) string {
+	switch s {
+	case "a":
+		return strings.ToUpper("a")
+	default:
+		return s
+	}
+}
+
+func describe(s string) (int, [2]int, bool) {
+	_, ok := shapes[s]
+	return colors[s] + colors["red"], shapes[s], ok
+}
+`
+
+const migrateOther = `package p
+
+func green() int {
+	colors := 2
+	return colors
+}
+
+func red() int {
+	return colors["red"]
+}
+`
+
+// TestMigrate tests finding and rewriting code, and that the result still
+// type-checks.
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fastmatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "p.go")
+	if err := ioutil.WriteFile(path, []byte(migrateSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.go"), []byte(migrateOther), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"migrate", path}, nil, &stdout, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	for _, expect := range []string{
+		"p.go:14:1: parseMethod: switch with 3 keys\n",
+		"p.go:24:1: colors: map with 2 keys\n",
+		"p.go:29:1: shapes: skipped: map lookup uses comma-ok form at " + path + ":43:11\n",
+		"p.go:33:1: upper: skipped: value refers to an imported package\n",
+	} {
+		if !strings.Contains(stdout.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, stdout.String())
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 2 {
+		t.Errorf("files written without -w: %q", files)
+	}
+
+	stdout.Reset()
+	if status := run([]string{"migrate", "-w", path}, nil, &stdout, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"// parseMethod converts a string to a Method.\n//go:generate fastmatch -package p -func parseMethod -type Method -none MethodUnknown -in parseMethod_fastmatch.tsv -o parseMethod_fastmatch.go\n",
+		"//go:generate fastmatch -package p -func colors -type int -none 0 -in colors_fastmatch.tsv -o colors_fastmatch.go\n",
+		"return colors(s) + colors(\"red\"), shapes[s], ok",
+	} {
+		if !strings.Contains(string(src), expect) {
+			t.Errorf("expected %q in rewritten file:\n%s", expect, src)
+		}
+	}
+	spec, err := ioutil.ReadFile(filepath.Join(dir, "parseMethod_fastmatch.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "GET\tMethodGet\nPOST\tMethodPost\nget\tMethodGet\n"; string(spec) != expect {
+		t.Errorf("expected cases %q, got %q", expect, spec)
+	}
+
+	// The package must still type-check with the generated files.
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ast.File
+	for _, file := range pkgs["p"].Files {
+		files = append(files, file)
+	}
+	if len(files) != 4 {
+		t.Errorf("expected 4 files, got %d", len(files))
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("p", fset, files, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Running again finds nothing more to do, and doesn't overwrite the
+	// generated files.
+	stdout.Reset()
+	if status := run([]string{"migrate", "-w", path}, nil, &stdout, &stderr); status != exitOK {
+		t.Fatalf("exit status %d: %s", status, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "keys") {
+		t.Errorf("unexpected candidates on second run:\n%s", stdout.String())
+	}
+}
+
+// TestMigrateErrors tests the exit status for invalid command lines and
+// files.
+func TestMigrateErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"migrate"},
+		{"migrate", "-bogus", "x.go"},
+	} {
+		if status := run(args, nil, ioutil.Discard, ioutil.Discard); status != exitUsage {
+			t.Errorf("%q: expected exit status %d, got %d", args, exitUsage, status)
+		}
+	}
+	if status := run([]string{"migrate", "/nonexistent.go"}, nil, ioutil.Discard, ioutil.Discard); status != exitError {
+		t.Errorf("expected exit status %d, got %d", exitError, status)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// CompositeKey is a key consisting of two strings, such as an HTTP method and
+// path, for use with GenerateComposite.
+type CompositeKey [2]string
+
+// String formats a CompositeKey for use in error messages.
+func (key CompositeKey) String() string {
+	return key[0] + " " + key[1]
+}
+
+// GenerateComposite outputs Go code which compares a pair of input strings
+// against a set of CompositeKeys.  This is intended for tables which are
+// keyed on more than one string, such as HTTP routes keyed on method and
+// path prefix:
+//
+//	fmt.Fprintln(w, "func route(input1, input2 string) http.HandlerFunc {")
+//	fastmatch.GenerateComposite(w, map[fastmatch.CompositeKey]string{
+//		{"GET", "/users/"}:  "listUsers",
+//		{"POST", "/users/"}: "createUser",
+//		{"GET", "/about"}:   "about",
+//	}, "notFound", nil, []*fastmatch.Flag{fastmatch.HasPrefix})
+//
+// As with Generate, the caller is expected to write the method signature
+// before calling this function.  The strings to examine should be in
+// variables named "input1" and "input2".
+//
+// flags1 and flags2 are the flags used to match the first and second string
+// in each key, respectively.  The generated code first matches input1, and
+// then matches input2 against the keys sharing that first string.
+//
+// Ambiguity is checked across both fields: two keys with different values
+// are ambiguous if an input could match both.  Since the first string is
+// matched independently of the second, keys whose first strings are
+// ambiguous with each other (such as "GET" and "get", with Insensitive) are
+// always reported as ambiguous, even if their second strings could not
+// match the same input.  Ambiguous keys are reported using
+// CompositeKey.String.
+//
+// ValueType is checked against the values in cases.  Coverage, TooShort,
+// TooLong, and Preprocess are not supported.
+func GenerateComposite(w io.Writer, cases map[CompositeKey]string, none string, flags1, flags2 []*Flag) error {
+	keys := make([]CompositeKey, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a][0] != keys[b][0] {
+			return keys[a][0] < keys[b][0]
+		}
+		return keys[a][1] < keys[b][1]
+	})
+
+	// Keys are grouped by their first string.  The first matcher returns
+	// the group number, and the matcher for each group returns the index
+	// (plus one) in keys of the first key in the group with the same
+	// value, so that keys in a group which share a value aren't
+	// ambiguous with each other.
+	var groups []map[string]string
+	var groupFirst []string
+	firstCases := make(map[string]string)
+	byFirst := make(map[string][]CompositeKey)
+	used := make([]bool, len(keys))
+	var byValue map[string]int
+	for n, key := range keys {
+		if _, found := firstCases[key[0]]; !found {
+			groups = append(groups, make(map[string]string))
+			groupFirst = append(groupFirst, key[0])
+			firstCases[key[0]] = strconv.Itoa(len(groups))
+			byValue = make(map[string]int)
+		}
+		index, found := byValue[cases[key]]
+		if !found {
+			index = n
+			byValue[cases[key]] = index
+			used[index] = true
+		}
+		groups[len(groups)-1][key[1]] = strconv.Itoa(index + 1)
+		byFirst[key[0]] = append(byFirst[key[0]], key)
+	}
+
+	// If ValueType was specified (for either field), check the values
+	// against it, since the matchers only see our index numbers.
+	for _, flags := range [][]*Flag{flags1, flags2} {
+		fs, err := parseFlags(flags...)
+		if err != nil {
+			return err
+		}
+		for _, flag := range flags {
+			if flag.tooShort != "" || flag.tooLong != "" || flag.preprocess != "" {
+				return &ErrBadFlags{unsupported: []string{flagName(flag)}, unsupportedBy: "GenerateComposite"}
+			}
+		}
+		if fs.valueType != "" {
+			values := make(map[string]string, len(cases))
+			for key, value := range cases {
+				values[key.String()] = value
+			}
+			if err := fs.checkValueType(values, none); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Check for ambiguity before outputting anything, so we can report
+	// the full keys.
+	if err := Generate(ioutil.Discard, firstCases, "0", internalFlags(flags1)...); err != nil {
+		ambiguous, ok := err.(*ErrAmbiguous)
+		if !ok {
+			return err
+		}
+		e := new(ErrAmbiguous)
+		for _, group := range ambiguous.sortedKeys() {
+			var composite []string
+			for _, first := range group {
+				for _, key := range byFirst[first] {
+					composite = append(composite, key.String())
+				}
+			}
+			e.add(nil, composite...)
+		}
+		return e
+	}
+	for n, group := range groups {
+		if err := Generate(ioutil.Discard, group, "0", internalFlags(flags2)...); err != nil {
+			ambiguous, ok := err.(*ErrAmbiguous)
+			if !ok {
+				return err
+			}
+			e := new(ErrAmbiguous)
+			for _, group := range ambiguous.sortedKeys() {
+				var composite []string
+				for _, second := range group {
+					composite = append(composite, CompositeKey{groupFirst[n], second}.String())
+				}
+				e.add(nil, composite...)
+			}
+			return e
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, "\tfastmatchFirst := func(input string) int {"); err != nil {
+		return err
+	}
+	if err := Generate(w, firstCases, "0", internalFlags(flags1)...); err != nil {
+		return err
+	}
+	for n, group := range groups {
+		fmt.Fprintf(w, "\tfastmatchSecond%d := func(input string) int {", n+1)
+		fmt.Fprintln(w)
+		if err := Generate(w, group, "0", internalFlags(flags2)...); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "\tvar matched int")
+	fmt.Fprintln(w, "\tswitch fastmatchFirst(input1) {")
+	for n := range groups {
+		fmt.Fprintf(w, "\tcase %d:", n+1)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\t\tmatched = fastmatchSecond%d(input2)", n+1)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\t}")
+
+	fmt.Fprintln(w, "\tswitch matched {")
+	for n, key := range keys {
+		if !used[n] {
+			continue
+		}
+		fmt.Fprintf(w, "\tcase %d: // %s, %s", n+1, strconv.Quote(key[0]), strconv.Quote(key[1]))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\treturn", cases[key])
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestComposite tests matching a pair of strings.
+func TestComposite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strings"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func route(input1, input2 string) string {")
+		err := GenerateComposite(w, map[CompositeKey]string{
+			{"GET", "/users/"}:  `"listUsers"`,
+			{"POST", "/users/"}: `"createUser"`,
+			{"GET", "/about"}:   `"about"`,
+			{"DELETE", "/u/"}:   `"deleteUser"`,
+
+			// Overlaps "GET /users/", but isn't ambiguous since
+			// the value is the same.
+			{"GET", "/users/all"}: `"listUsers"`,
+		}, `"notFound"`, []*Flag{Insensitive}, []*Flag{HasPrefix})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\targs := strings.SplitN(os.Args[1], \" \", 2)")
+		fmt.Fprintln(w, "\tfmt.Println(route(args[0], args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testCase := range []struct{ method, path, expect string }{
+		{"GET", "/users/123", "listUsers"},
+		{"GET", "/users/all", "listUsers"},
+		{"get", "/users/", "listUsers"},
+		{"GET", "/about/", "about"},
+		{"POST", "/users/", "createUser"},
+		{"POST", "/", "notFound"},
+		{"DELETE", "/u/1", "deleteUser"},
+		{"DELETE", "/users/1", "notFound"},
+		{"PUT", "/users/", "notFound"},
+	} {
+		expectMatch(t, testCase.method+" "+testCase.path, testCase.expect)
+	}
+}
+
+// TestCompositeAmbiguous tests that ambiguity in either field is reported
+// using the full keys.
+func TestCompositeAmbiguous(t *testing.T) {
+	err := GenerateComposite(ioutil.Discard, map[CompositeKey]string{
+		{"GET", "/a"}:  "1",
+		{"GET", "/ab"}: "2",
+		{"PUT", "/a"}:  "3",
+	}, "0", nil, []*Flag{HasPrefix})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if expect := `ambiguous matches: "GET /a", "GET /ab"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+
+	err = GenerateComposite(ioutil.Discard, map[CompositeKey]string{
+		{"GET", "/a"}: "1",
+		{"get", "/b"}: "2",
+	}, "0", []*Flag{Insensitive}, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if expect := `ambiguous matches: "GET /a", "get /b"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+}
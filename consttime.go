@@ -0,0 +1,224 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ConstantTime is a flag, which can be passed to Generate, to specify that
+// the generated code should take the same amount of time to execute
+// regardless of where (or whether) the input differs from each of the cases.
+// This is intended for comparing input against secrets, such as token
+// prefixes, where an early return could leak information via a timing side
+// channel.
+//
+// Every case is compared against the input in its entirety, using
+// arithmetic rather than branches.  The only branches in the generated code
+// depend on the length of the input (which is assumed not to be secret), and
+// on which case matched (which is revealed by the return value anyway).
+//
+// This produces much slower code than Generate normally does.  It may be
+// combined with HasPrefix, HasSuffix, Insensitive, Equivalent, and input
+// normalization flags, but not StopUpon, Ignore, IgnoreExcept, or
+// NoTrailingMark.
+var ConstantTime = new(Flag)
+
+// quoteByte formats a byte for use in generated code.
+func quoteByte(b byte) string {
+	if b < utf8.RuneSelf {
+		return strconv.QuoteRuneToASCII(rune(b))
+	}
+	return fmt.Sprintf("0x%x", b)
+}
+
+// generateConstantTime implements Generate when the ConstantTime flag is
+// specified.  The flags have already been validated by Generate.
+func generateConstantTime(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	equiv, partialMatch, backwards := fs.equiv, fs.partialMatch, fs.backwards
+
+	// Use the regular code generator to check for ambiguity.
+	otherFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range internalFlags(flags) {
+		if flag != ConstantTime {
+			otherFlags = append(otherFlags, flag)
+		}
+	}
+	if err := Generate(ioutil.Discard, cases, none, otherFlags...); err != nil {
+		return err
+	}
+
+	// Keys (prior to normalization) are needed in a stable order if
+	// we're recording coverage.
+	origCases := cases
+	origKeys := make([]string, 0, len(cases))
+	for key := range cases {
+		origKeys = append(origKeys, key)
+	}
+	sort.Strings(origKeys)
+
+	if len(fs.normalize) > 0 {
+		normalized := make(map[string]string, len(cases))
+		for key, value := range cases {
+			normalized[fs.normalizeKey(key)] = value
+		}
+		cases = normalized
+	}
+
+	// Each unique return value is assigned a number, which is what we
+	// accumulate while comparing.  Since we've already checked for
+	// ambiguity, any keys which match the same input will have the same
+	// number.
+	values := make([]string, 0, len(cases))
+	valueNums := make(map[string]int, len(cases))
+	for _, value := range cases {
+		if valueNums[value] == 0 {
+			values = append(values, value)
+			valueNums[value] = -1
+		}
+	}
+	sort.Strings(values)
+	for n, value := range values {
+		valueNums[value] = n + 1
+	}
+
+	// Keys are compared in order of length, so that we can share the
+	// length check.
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if len(keys[a]) != len(keys[b]) {
+			return len(keys[a]) > len(keys[b])
+		}
+		return keys[a] < keys[b]
+	})
+
+	for _, flag := range fs.normalize {
+		if _, err := fmt.Fprintf(w, "\tinput = %s(input)", fs.normalizeCall(flag)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	// The comparison helper is only declared if some key has a byte to
+	// compare (the longest is first), since an unused closure wouldn't
+	// compile.
+	if len(keys) > 0 && len(keys[0]) > 0 {
+		if _, err := fmt.Fprintln(w, "\tfastmatchEq := func(a, b byte) int {"); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "\t\treturn int((uint32(a^b) - 1) >> 31)")
+		fmt.Fprintln(w, "\t}")
+	}
+	if _, err := fmt.Fprintln(w, "\tvar matched int"); err != nil {
+		return err
+	}
+
+	lengthCheck := "=="
+	if partialMatch {
+		lengthCheck = ">="
+	}
+	for n, key := range keys {
+		if err := fs.stopped(); err != nil {
+			return err
+		}
+		if n == 0 || len(keys[n-1]) != len(key) {
+			fmt.Fprintf(w, "\tif len(input) %s %d {", lengthCheck, len(key))
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "\t\tvar ok int")
+		}
+
+		fmt.Fprintf(w, "\t\tok = 1 // %s", strconv.Quote(key))
+		fmt.Fprintln(w)
+		for i := 0; i < len(key); i++ {
+			var at string
+			if backwards {
+				at = fmt.Sprintf("input[len(input)-%d]", len(key)-i)
+			} else {
+				at = fmt.Sprintf("input[%d]", i)
+			}
+
+			fmt.Fprint(w, "\t\tok &= ")
+			if key[i] < utf8.RuneSelf {
+				first := true
+				for _, r := range equiv.lookup(rune(key[i])) {
+					if r >= utf8.RuneSelf {
+						continue // can't compare to a byte
+					}
+					if !first {
+						fmt.Fprint(w, " | ")
+					}
+					fmt.Fprintf(w, "fastmatchEq(%s, %s)", at, quoteByte(byte(r)))
+					first = false
+				}
+			} else {
+				fmt.Fprintf(w, "fastmatchEq(%s, %s)", at, quoteByte(key[i]))
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "\t\tmatched |= %d * ok", valueNums[cases[key]])
+		fmt.Fprintln(w)
+
+		if n == len(keys)-1 || len(keys[n+1]) != len(key) {
+			fmt.Fprintln(w, "\t}")
+		}
+	}
+
+	fmt.Fprintln(w, "\tswitch matched {")
+	for n, value := range values {
+		fmt.Fprintf(w, "\tcase %d:", n+1)
+		fmt.Fprintln(w)
+		if lines != nil {
+			for _, key := range origKeys {
+				if origCases[key] == value {
+					fs.cover(key, lines.lines)
+				}
+			}
+		}
+		fmt.Fprintln(w, "\t\treturn", value)
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
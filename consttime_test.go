@@ -0,0 +1,156 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestConstantTime tests a constant-time matcher.
+func TestConstantTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":    "1",
+		"bar":    "2",
+		"foobar": "3",
+	}, "0", ConstantTime, Insensitive)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "BAR", "2")
+	expectMatch(t, "FooBar", "3")
+	expectMatch(t, "fob", "0")
+	expectMatch(t, "foob", "0")
+}
+
+// TestPrefixConstantTime tests a constant-time prefix matcher.
+func TestPrefixConstantTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"sk_live_": "1",
+		"sk_test_": "2",
+		"pk_":      "3",
+		"pk_x":     "3",
+	}, "0", ConstantTime, HasPrefix)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "sk_live_abc123", "1")
+	expectMatch(t, "sk_test_", "2")
+	expectMatch(t, "pk_xyz", "3")
+	expectMatch(t, "sk_liv", "0")
+	expectMatch(t, "sk_live-abc", "0")
+}
+
+// TestSuffixConstantTime tests a constant-time suffix matcher.
+func TestSuffixConstantTime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"\u00e9t\u00e9": "1",
+		"xyz":           "2",
+	}, "0", ConstantTime, HasSuffix)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "l'\u00e9t\u00e9", "1")
+	expectMatch(t, "wxyz", "2")
+	expectMatch(t, "xy", "0")
+}
+
+// TestConstantTimeNoBytes tests that the generated code compiles when no
+// key has any bytes to compare.
+func TestConstantTimeNoBytes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, cases := range []map[string]string{
+		{},
+		{"": "1"},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", cases, "0", ConstantTime, HasPrefix)
+		defer cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expect := "0"
+		if len(cases) > 0 {
+			expect = "1"
+		}
+		expectMatch(t, "foo", expect)
+	}
+}
+
+// TestConstantTimeNoEarlyReturn tests that the generated code does not
+// contain any return statements prior to comparing every case.
+func TestConstantTimeNoEarlyReturn(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{
+		"foo": "1",
+		"bar": "2",
+	}, "0", ConstantTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip over the fastmatchEq helper:
+	out := strings.Replace(b.String(), "return int(", "", 1)
+	if n := strings.Index(out, "return"); n < strings.LastIndex(out, "fastmatchEq(") {
+		t.Errorf("early return in constant-time code:\n%s", out)
+	}
+}
+
+// TestConstantTimeBadFlags tests that ConstantTime can't be combined with
+// flags which require early returns.
+func TestConstantTimeBadFlags(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{"foo": "1"}, "0", ConstantTime, Ignore('.'))
+	if _, ok := err.(*ErrBadFlags); !ok {
+		t.Errorf("expected *ErrBadFlags, got %v", err)
+	}
+}
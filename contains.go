@@ -0,0 +1,297 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// GenerateContains outputs Go code for a function which reports whether any
+// of the keys in cases occur anywhere within its input, rather than only at
+// the start or end.  The function has the signature:
+//
+//	func fn(input string) (retType, int)
+//
+// It returns the value of the key which matched, and the offset in bytes
+// of the start of the match.  If no key occurs in the input, none is
+// returned, along with -1.  This replaces a loop calling strings.Contains
+// for each key, or a runtime Aho-Corasick library.
+//
+// The generated code examines each byte of the input at most once, using a
+// state machine built with the Aho-Corasick algorithm.  It returns the
+// first match to end, and of the matches which end at the same byte, the
+// longest.  For example, with the keys "he", "she", and "hers", the input
+// "ushers" matches "she" at offset 1.
+//
+// Insensitive and Equivalent (of single-byte runes) are supported, as are
+// Params and ValueType.  Other flags are not, nor are empty keys.
+func GenerateContains(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag != Insensitive && flag != NoImports && len(flag.equivalent) == 0 &&
+			flag.params == "" && flag.valueType == "" && flag.goVersion == "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateContains"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(cases, none); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		if key == "" {
+			return errors.New("GenerateContains does not support empty keys")
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Keys which are the same once equivalent bytes are replaced share a
+	// state, so must have the same value.
+	canonical := make(map[string]string, len(keys))
+	ambiguous := new(ErrAmbiguous)
+	for _, key := range keys {
+		c := fs.containsKey(key)
+		if other, found := canonical[c]; found && cases[other] != cases[key] {
+			ambiguous.add(nil, other, key)
+		} else if !found {
+			canonical[c] = key
+		}
+	}
+	if len(ambiguous.keys) > 0 {
+		return ambiguous
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) (%s, int) {", fn, fs.extraParams(), retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	if len(keys) > 0 {
+		if err := fs.writeContains(w, cases, canonical); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "\treturn %s, -1", none)
+	fmt.Fprintln(w)
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// containsKey returns key with each byte replaced by the first of its
+// equivalents.  Only single-byte runes are replaced, since the generated code
+// examines the input one byte at a time.
+func (fs *flagSet) containsKey(key string) string {
+	b := []byte(key)
+	for i := range b {
+		if b[i] < utf8.RuneSelf {
+			if to := fs.equiv.lookup(rune(b[i]))[0]; to < utf8.RuneSelf {
+				b[i] = byte(to)
+			}
+		}
+	}
+	return string(b)
+}
+
+// containsState is a node in the trie of keys built by writeContains.
+type containsState struct {
+	next  map[byte]int
+	depth int
+
+	// key is the original key which this state matches, if any.  After
+	// the failure links are computed, it is instead the longest key
+	// which ends at this state.
+	key string
+}
+
+// writeContains outputs the state machine for GenerateContains.  canonical
+// maps each key, with equivalent bytes replaced, to the original key.
+func (fs *flagSet) writeContains(w io.Writer, cases map[string]string, canonical map[string]string) error {
+	// Build the trie, then number its states breadth-first, so the
+	// output is the same each time.
+	trie := []*containsState{{next: make(map[byte]int)}}
+	alphabet := make(map[byte]bool)
+	for c, key := range canonical {
+		s := 0
+		for i := 0; i < len(c); i++ {
+			alphabet[c[i]] = true
+			n, found := trie[s].next[c[i]]
+			if !found {
+				n = len(trie)
+				trie = append(trie, &containsState{next: make(map[byte]int), depth: trie[s].depth + 1})
+				trie[s].next[c[i]] = n
+			}
+			s = n
+		}
+		trie[s].key = key
+	}
+	bytes := make([]byte, 0, len(alphabet))
+	for b := range alphabet {
+		bytes = append(bytes, b)
+	}
+	sort.Slice(bytes, func(a, b int) bool { return bytes[a] < bytes[b] })
+
+	order := []int{0}
+	for n := 0; n < len(order); n++ {
+		for _, b := range bytes {
+			if next, found := trie[order[n]].next[b]; found {
+				order = append(order, next)
+			}
+		}
+	}
+	states := make([]*containsState, len(order))
+	renumber := make([]int, len(order))
+	for n, s := range order {
+		renumber[s] = n
+		states[n] = trie[s]
+	}
+	for _, state := range states {
+		for b, next := range state.next {
+			state.next[b] = renumber[next]
+		}
+	}
+
+	// Fill in the transitions which aren't in the trie by following the
+	// failure links.  Since states are in breadth-first order, the state
+	// a failure link points to has already been filled in.
+	fail := make([]int, len(states))
+	goTo := make([][]int, len(states))
+	for s, state := range states {
+		goTo[s] = make([]int, len(bytes))
+		for i, b := range bytes {
+			if next, found := state.next[b]; found {
+				goTo[s][i] = next
+				if s != 0 {
+					fail[next] = goTo[fail[s]][i]
+				}
+			} else if s != 0 {
+				goTo[s][i] = goTo[fail[s]][i]
+			}
+		}
+		if state.key == "" && s != 0 {
+			state.key = states[fail[s]].key
+		}
+	}
+
+	// The generated code returns as soon as it reaches a state which
+	// matches a key, so only the other states which can be reached
+	// without passing through one need any code.
+	reachable := make([]bool, len(states))
+	reachable[0] = true
+	for queue := []int{0}; len(queue) > 0; queue = queue[1:] {
+		if s := queue[0]; states[s].key == "" {
+			for _, next := range goTo[s] {
+				if !reachable[next] {
+					reachable[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	// In the initial state, bytes which can't begin a key are skipped
+	// without going through the switch.
+	first := fs.firstBytes(cases)
+	if len(first) > 1 && len(first) < 256 {
+		table := make([]byte, 256)
+		for _, b := range first {
+			table[b] = 1
+		}
+		writeLookupTable(w, "\t", "fastmatchFirst", table)
+	}
+
+	fmt.Fprintln(w, "\tstate := 0")
+	fmt.Fprintln(w, "\tfor i := 0; i < len(input); i++ {")
+	if len(first) < 256 {
+		fmt.Fprintln(w, "\t\tif state == 0 {")
+		writeSkipAhead(w, "\t\t\t", first)
+		fmt.Fprintln(w, "\t\t}")
+	}
+	fmt.Fprintln(w, "\t\tswitch state {")
+	for s, state := range states {
+		if state.key != "" || !reachable[s] {
+			continue
+		}
+		fmt.Fprintf(w, "\t\tcase %d:", s)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\tswitch input[i] {")
+		var targets []int
+		byTarget := make(map[int][]byte)
+		for i, b := range bytes {
+			next := goTo[s][i]
+			if next == 0 {
+				continue
+			}
+			if _, found := byTarget[next]; !found {
+				targets = append(targets, next)
+			}
+			byTarget[next] = append(byTarget[next], fs.equiv.byteEquivalents(b)...)
+		}
+		sort.Ints(targets)
+		for _, next := range targets {
+			quoted := make([]string, len(byTarget[next]))
+			for n, b := range byTarget[next] {
+				quoted[n] = quoteByte(b)
+			}
+			fmt.Fprintf(w, "\t\t\tcase %s:", strings.Join(quoted, ", "))
+			fmt.Fprintln(w)
+			if key := states[next].key; key != "" {
+				start := "i"
+				if len(key) > 1 {
+					start = fmt.Sprintf("i-%d", len(key)-1)
+				}
+				fmt.Fprintf(w, "\t\t\t\treturn %s, %s", cases[key], start)
+			} else {
+				fmt.Fprintf(w, "\t\t\t\tstate = %d", next)
+			}
+			fmt.Fprintln(w)
+		}
+		if s != 0 {
+			fmt.Fprintln(w, "\t\t\tdefault:")
+			fmt.Fprintln(w, "\t\t\t\tstate = 0")
+		}
+		fmt.Fprintln(w, "\t\t\t}")
+	}
+	fmt.Fprintln(w, "\t\t}")
+	_, err := fmt.Fprintln(w, "\t}")
+	return err
+}
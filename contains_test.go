@@ -0,0 +1,152 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestGenerateContains tests finding keys anywhere in the input.
+func TestGenerateContains(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		flags   []*Flag
+		matches map[string]string
+	}{
+		{nil, map[string]string{
+			"ushers":    "2 1",
+			"hers":      "1 0",
+			"ahishers":  "3 1",
+			"xhxexrxs":  "0 -1",
+			"h":         "0 -1",
+			"":          "0 -1",
+			"abc日本語":    "5 3",
+			"USHERS":    "0 -1",
+			"sh he":     "1 3",
+			"shhhhhis":  "3 5",
+			"hershey":   "1 0",
+			"xxxxxxshe": "2 6",
+		}},
+		{[]*Flag{Insensitive}, map[string]string{
+			"USHERS": "2 1",
+			"aHiS":   "3 1",
+			"xyz日本語": "5 3",
+			"CAFÉ":   "0 -1",
+		}},
+		{[]*Flag{Equivalent('a', 'Ã')}, map[string]string{
+			"un café":  "6 3",
+			"caf\xc3":  "0 -1",
+			"cafa\xa9": "0 -1",
+			"cafÃ":     "0 -1",
+		}},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+			err := GenerateContains(w, "find", "int", map[string]string{
+				"he":   "1",
+				"she":  "2",
+				"his":  "3",
+				"hers": "4",
+				"日本":   "5",
+				"café": "6",
+			}, "0", testCase.flags...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tfmt.Println(find(os.Args[1]))")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+
+		for input, expect := range testCase.matches {
+			expectMatch(t, input, expect)
+		}
+		cleanup()
+	}
+}
+
+// TestGenerateContainsSkipAhead tests that the input is scanned for the bytes
+// which can begin a key, rather than run through the state machine.
+func TestGenerateContainsSkipAhead(t *testing.T) {
+	for _, testCase := range []struct {
+		cases  map[string]string
+		flags  []*Flag
+		expect string
+	}{
+		{map[string]string{"foo": "1", "far": "2"}, nil, "for i < len(input) && input[i] != 'f' {"},
+		{map[string]string{"foo": "1", "bar": "2"}, nil, "for i < len(input) && fastmatchFirst[input[i]] == 0 {"},
+		{map[string]string{"foo": "1"}, []*Flag{Insensitive}, "for i < len(input) && fastmatchFirst[input[i]] == 0 {"},
+	} {
+		var b strings.Builder
+		if err := GenerateContains(&b, "find", "int", testCase.cases, "0", testCase.flags...); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), testCase.expect) {
+			t.Errorf("%v: expected %q in:\n%s", testCase.cases, testCase.expect, b.String())
+		}
+	}
+}
+
+// TestGenerateContainsErrors tests unsupported flags, empty keys, and keys
+// which are the same once flags are applied.
+func TestGenerateContainsErrors(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{HasPrefix},
+		{Ignore('-')},
+		{TooShort("0")},
+	} {
+		err := GenerateContains(ioutil.Discard, "find", "int", map[string]string{"foo": "1"}, "0", flags...)
+		if err == nil || !strings.Contains(err.Error(), "GenerateContains does not support flags") {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+	if err := GenerateContains(ioutil.Discard, "find", "int", map[string]string{"": "1"}, "0"); err == nil {
+		t.Error("expected error for empty key")
+	}
+	err := GenerateContains(ioutil.Discard, "find", "int", map[string]string{"foo": "1", "FOO": "2"}, "0", Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+	err = GenerateContains(ioutil.Discard, "find", "int", map[string]string{"foo": "1", "FOO": "1"}, "0", Insensitive)
+	if err != nil {
+		t.Errorf("unexpected error for keys with the same value: %s", err)
+	}
+}
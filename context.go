@@ -0,0 +1,51 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"context"
+	"io"
+)
+
+// GenerateContext is like Generate, but stops early and returns ctx.Err() if
+// ctx is canceled or its deadline passes.  Generating code for very large
+// numbers of keys can take a long time, most of which is spent indexing the
+// keys and checking them for ambiguity; this allows build tools to enforce
+// a timeout, or to cleanly abandon generation when a build is aborted.
+//
+// Cancellation is checked periodically while indexing keys, checking them for
+// ambiguity, and writing code for each strategy, so GenerateContext may not
+// return immediately.  Output already written to w when it does is
+// incomplete, and should be discarded (see Render).
+func GenerateContext(ctx context.Context, w io.Writer, cases map[string]string, none string, flags ...*Flag) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return Generate(w, cases, none, append(flags[:len(flags):len(flags)], &Flag{ctx: ctx})...)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+// countdownContext is a context which is canceled after its Err method has
+// been called a certain number of times.
+type countdownContext struct {
+	context.Context
+	remaining int
+}
+
+// Err implements context.Context.
+func (ctx *countdownContext) Err() error {
+	if ctx.remaining--; ctx.remaining < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+// TestGenerateContext tests that generation stops once the context is
+// canceled, and otherwise produces the same code as Generate.
+func TestGenerateContext(t *testing.T) {
+	cases := make(map[string]string, 1000)
+	for n := 0; n < 1000; n++ {
+		cases[fmt.Sprintf("key%d", n)] = fmt.Sprint(n)
+	}
+
+	var expect, actual bytes.Buffer
+	if err := Generate(&expect, cases, "-1", Insensitive); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateContext(context.Background(), &actual, cases, "-1", Insensitive); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Len() != expect.Len() {
+		t.Errorf("expected %d bytes of output, got %d", expect.Len(), actual.Len())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := GenerateContext(ctx, ioutil.Discard, cases, "-1"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	// Cancellation should be noticed partway through, both while
+	// indexing and while writing the state machine.
+	for _, n := range []int{1, 2, 5, 10} {
+		ctx := &countdownContext{Context: context.Background(), remaining: n}
+		if err := GenerateContext(ctx, ioutil.Discard, cases, "-1"); err != context.Canceled {
+			t.Errorf("expected context.Canceled after %d checks, got %v", n, err)
+		}
+	}
+
+	ctx = &countdownContext{Context: context.Background(), remaining: 1}
+	if err := GenerateContext(ctx, ioutil.Discard, map[string]string{"1x": "1"}, "-1", AnyDigit('x')); err != context.Canceled {
+		t.Errorf("expected context.Canceled from AnyDigit, got %v", err)
+	}
+}
+
+// TestContextAmbiguity tests that cancellation is noticed while checking for
+// ambiguity, and not only while indexing.
+func TestContextAmbiguity(t *testing.T) {
+	cases := make(map[string]string, 1000)
+	keys := make([]string, 0, 1000)
+	for n := 0; n < 1000; n++ {
+		key := fmt.Sprintf("key%d", n)
+		cases[key] = fmt.Sprint(n)
+		keys = append(keys, key)
+	}
+
+	for _, n := range []int{0, 1, 10, 100} {
+		state := newStateMachine(keys)
+		if err := state.indexKeys(context.Background(), makeEquivalents(), true); err != nil {
+			t.Fatal(err)
+		}
+		ctx := &countdownContext{Context: context.Background(), remaining: n}
+		if err := state.checkAmbiguity(ctx, cases, cases, nil, false); err != context.Canceled {
+			t.Errorf("expected context.Canceled after %d checks, got %v", n, err)
+		}
+	}
+}
+
+// TestContextStrategies tests that each strategy stops writing code once the
+// context is canceled.
+func TestContextStrategies(t *testing.T) {
+	cases := map[string]string{
+		"foo": "1",
+		"bar": "2",
+		"baz": "3",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for name, gen := range map[string]func(fs *flagSet) error{
+		"TrieStrategy": func(fs *flagSet) error {
+			return generateTrie(ioutil.Discard, cases, "0", fs, nil)
+		},
+		"LinearStrategy": func(fs *flagSet) error {
+			return generateLinear(ioutil.Discard, cases, "0", fs, nil)
+		},
+		"ConstantTime": func(fs *flagSet) error {
+			return generateConstantTime(ioutil.Discard, cases, "0", fs, nil)
+		},
+		"lookup table": func(fs *flagSet) error {
+			return generateLookupTable(ioutil.Discard, map[string]string{"a": "1", "b": "2"}, "0", fs)
+		},
+	} {
+		fs, err := parseFlags()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs.ctx = ctx
+		if err := gen(fs); err != context.Canceled {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoverageManifest maps lines in generated code back to the keys which cause
+// them to be executed.  Combined with the output of "go test -coverprofile",
+// it can be used to determine which keys were never exercised by the tests
+// of the package containing the generated code.  (The coverage percentage
+// of generated code is otherwise not very meaningful, since most of the
+// generated statements are shared by many keys.)
+//
+// A CoverageManifest is populated by passing the Coverage flag to Generate.
+// It can be serialized as JSON, so that it can be saved alongside the
+// generated code and used later.
+type CoverageManifest struct {
+	// File is the name of the generated file.  It is compared against
+	// the end of the filenames in the coverage profile, so it does not
+	// need to include the full package path.
+	File string `json:"file"`
+
+	// Line is the line number in File at which the output of Generate
+	// begins.  It should be set by the caller before calling Generate.
+	// Zero is treated as 1.
+	Line int `json:"line"`
+
+	// Keys lists the lines corresponding to each key, in the order in
+	// which they were generated.  A key may appear more than once.  Keys
+	// which are redundant (such as a key with the same value as one of
+	// its prefixes, when HasPrefix is specified) do not appear at all.
+	Keys []CoverageRange `json:"keys"`
+}
+
+// CoverageRange is a range of lines (inclusive) in a CoverageManifest which
+// are only executed when the input matches Key.
+type CoverageRange struct {
+	Key       string `json:"key"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// Coverage is a flag, which can be passed to Generate, to record which lines
+// of the generated code correspond to each key in the supplied
+// CoverageManifest.  The caller should set the File and Line fields of the
+// manifest before calling Generate.  For example:
+//
+//	m := &fastmatch.CoverageManifest{
+//		File: "keywords.go",
+//		Line: bytes.Count(buf.Bytes(), []byte("\n")) + 1,
+//	}
+//	err := fastmatch.Generate(&buf, cases, "-1", fastmatch.Coverage(m))
+//
+// When used with ConstantTime, every key sharing the same value maps to the
+// same line, since the generated code does not otherwise distinguish between
+// them.
+func Coverage(m *CoverageManifest) *Flag {
+	return &Flag{coverage: m}
+}
+
+// add records that line n (counting from zero at the start of Generate's
+// output) corresponds to key.
+func (m *CoverageManifest) add(key string, n int) {
+	base := m.Line
+	if base == 0 {
+		base = 1
+	}
+	m.Keys = append(m.Keys, CoverageRange{
+		Key:       key,
+		StartLine: base + n,
+		EndLine:   base + n,
+	})
+}
+
+// cover records that line n of the output corresponds to key.  Keys which
+// were expanded from an AnyDigit pattern are recorded under the pattern, and
+// keys changed by TransformKeys under the original, so that the manifest
+// refers to the keys the caller supplied.
+func (fs *flagSet) cover(key string, n int) {
+	m := fs.coverage
+	n += fs.coverOffset
+	pattern, isPattern := fs.digitPatterns[key]
+	if isPattern {
+		key = pattern
+	}
+	if orig, found := fs.keyOrigins[key]; found {
+		key = orig
+	}
+	if isPattern {
+		// Every expansion of the pattern usually shares the same
+		// line, which only needs to be recorded once.
+		if last := len(m.Keys) - 1; last >= 0 && m.Keys[last].Key == key {
+			base := m.Line
+			if base == 0 {
+				base = 1
+			}
+			if m.Keys[last].StartLine == base+n {
+				return
+			}
+		}
+	}
+	m.add(key, n)
+}
+
+// Write outputs the manifest as JSON.
+func (m *CoverageManifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(m)
+}
+
+// ReadCoverageManifest parses a CoverageManifest previously output by
+// CoverageManifest.Write.
+func ReadCoverageManifest(r io.Reader) (*CoverageManifest, error) {
+	m := new(CoverageManifest)
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UncoveredKeys reads a coverage profile, as output by "go test
+// -coverprofile", and returns a sorted list of keys in the manifest whose
+// lines were never executed.  An error is returned if the profile cannot be
+// parsed.
+func UncoveredKeys(m *CoverageManifest, profile io.Reader) ([]string, error) {
+	type block struct {
+		startLine, endLine int
+	}
+	var executed []block
+
+	s := bufio.NewScanner(profile)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// Each line is of the form:
+		// name.go:line.column,line.column numberOfStatements count
+		badLine := fmt.Errorf("malformed coverage profile at line %d: %q", n, line)
+		colon := strings.LastIndexByte(line, ':')
+		if colon < 0 {
+			return nil, badLine
+		}
+		file := line[:colon]
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) != 3 {
+			return nil, badLine
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, badLine
+		}
+		var b block
+		var startCol, endCol int
+		if _, err := fmt.Sscanf(fields[0], "%d.%d,%d.%d", &b.startLine, &startCol, &b.endLine, &endCol); err != nil {
+			return nil, badLine
+		}
+
+		if count == 0 || (file != m.File && !strings.HasSuffix(file, "/"+m.File)) {
+			continue
+		}
+		executed = append(executed, b)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	covered := make(map[string]bool, len(m.Keys))
+	for _, r := range m.Keys {
+		for _, b := range executed {
+			if b.startLine <= r.EndLine && b.endLine >= r.StartLine {
+				covered[r.Key] = true
+				break
+			}
+		}
+	}
+
+	var uncovered []string
+	seen := make(map[string]bool, len(m.Keys))
+	for _, r := range m.Keys {
+		if !covered[r.Key] && !seen[r.Key] {
+			uncovered = append(uncovered, r.Key)
+			seen[r.Key] = true
+		}
+	}
+	sort.Strings(uncovered)
+	return uncovered, nil
+}
+
+// lineCounter wraps an io.Writer, counting the number of lines written.
+type lineCounter struct {
+	w     io.Writer
+	lines int
+}
+
+// Write implements io.Writer.
+func (lc *lineCounter) Write(p []byte) (int, error) {
+	n, err := lc.w.Write(p)
+	lc.lines += bytes.Count(p[:n], []byte{'\n'})
+	return n, err
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var coverageTests = []struct {
+	name  string
+	flags []*Flag
+}{
+	{"none", nil},
+	{"Insensitive", []*Flag{Insensitive}},
+	{"HasPrefix", []*Flag{HasPrefix}},
+	{"HasSuffix", []*Flag{HasSuffix}},
+	{"StopUpon", []*Flag{StopUpon('.')}},
+	{"Ignore", []*Flag{Ignore('-')}},
+	{"NoTrailingMark", []*Flag{HasPrefix, NoTrailingMark}},
+	{"ConstantTime", []*Flag{ConstantTime}},
+	{"ASCIIOnly", []*Flag{ASCIIOnly, HasPrefix}},
+	{"Preprocess", []*Flag{Preprocess("input = input[0:]\n_ = input")}},
+}
+
+// TestCoverage checks that each key in the manifest points at a line which
+// returns the corresponding value.
+func TestCoverage(t *testing.T) {
+	cases := map[string]string{
+		"foo":    "1",
+		"bar":    "2",
+		"baz":    "3",
+		"quux.x": "4",
+		"qux":    "1",
+	}
+
+	for _, testCase := range coverageTests {
+		m := &CoverageManifest{File: "generated.go", Line: 10}
+		var b bytes.Buffer
+		if err := Generate(&b, cases, "0", append(testCase.flags, Coverage(m))...); err != nil {
+			t.Errorf("%s: %s", testCase.name, err)
+			continue
+		}
+		lines := strings.Split(b.String(), "\n")
+
+		seen := make(map[string]bool, len(cases))
+		for _, r := range m.Keys {
+			seen[r.Key] = true
+			if r.StartLine != r.EndLine {
+				t.Errorf("%s: expected single line for %q, got %d-%d", testCase.name, r.Key, r.StartLine, r.EndLine)
+				continue
+			}
+			actual := strings.TrimSpace(lines[r.StartLine-m.Line])
+			if expect := "return " + cases[r.Key]; actual != expect {
+				t.Errorf("%s: expected %q at line %d for %q, got %q", testCase.name, expect, r.StartLine, r.Key, actual)
+			}
+		}
+		for key := range cases {
+			if !seen[key] {
+				t.Errorf("%s: %q missing from manifest", testCase.name, key)
+			}
+		}
+	}
+}
+
+// TestCoverageNotRecordedOnValidation checks that internal calls to Generate
+// (used to validate flags) don't add bogus entries to the manifest.
+func TestCoverageNotRecordedOnValidation(t *testing.T) {
+	m := new(CoverageManifest)
+	cases := map[string]string{"foo": "1", "bar": "2"}
+	if err := Generate(new(bytes.Buffer), cases, "0", ConstantTime, Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Keys) != len(cases) {
+		t.Errorf("expected %d entries, got %+v", len(cases), m.Keys)
+	}
+}
+
+// TestCoverageManifestJSON tests serializing and parsing a CoverageManifest.
+func TestCoverageManifestJSON(t *testing.T) {
+	m := &CoverageManifest{
+		File: "generated.go",
+		Line: 3,
+		Keys: []CoverageRange{{"foo", 12, 12}, {"bar", 15, 15}},
+	}
+	var b bytes.Buffer
+	if err := m.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := ReadCoverageManifest(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, m2) {
+		t.Errorf("expected %+v, got %+v", m, m2)
+	}
+}
+
+const testCoverProfile = `mode: set
+example.com/pkg/generated.go:5.20,6.14 2 1
+example.com/pkg/generated.go:7.20,8.14 2 0
+example.com/pkg/generated.go:9.20,10.14 2 0
+example.com/pkg/other.go:9.20,10.14 2 1
+`
+
+// TestUncoveredKeys tests combining a CoverageManifest with a coverage
+// profile.
+func TestUncoveredKeys(t *testing.T) {
+	m := &CoverageManifest{
+		File: "generated.go",
+		Keys: []CoverageRange{
+			{"foo", 6, 6},
+			{"bar", 8, 8},
+			{"baz", 10, 10},
+			{"qux", 8, 8},
+			{"qux", 6, 6},
+		},
+	}
+
+	uncovered, err := UncoveredKeys(m, strings.NewReader(testCoverProfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"bar", "baz"}; !reflect.DeepEqual(expect, uncovered) {
+		t.Errorf("expected %q, got %q", expect, uncovered)
+	}
+
+	if _, err := UncoveredKeys(m, strings.NewReader("mode: set\nbogus\n")); err == nil {
+		t.Error("expected error parsing malformed profile")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+// DenseStates is a flag, which can be passed to Generate, to specify that
+// the final state of each key should be renumbered to a small integer
+// before it is compared.  The state accumulated while examining the input
+// is a sum of sparse values, so the switch statement comparing it to the
+// state expected for each key can't be compiled into a jump table.  With
+// DenseStates, the sum is instead used to index a lookup table, which
+// contains the numbers 1, 2, 3, and so on for each key (in sorted order),
+// and the switch statement compares the result of the lookup.  This also
+// makes the generated code easier to follow in a debugger.
+//
+// The lookup table needs an entry for every state up to the largest final
+// state, so this is only done for keys of the same length whose largest
+// final state is less than 4096.  It has no effect on strategies other than
+// the state machine, or on keys matched by HasPrefix or HasSuffix, whose
+// final states are compared as each key ends.
+var DenseStates = new(Flag)
+
+// maxDenseTable is the largest lookup table output by DenseStates.
+const maxDenseTable = 4096
+
+// denseTable returns the lookup table output by DenseStates for keys, or
+// nil if one can't be used.  Each key's final state maps to its position in
+// keys, starting at one.
+func (state *stateMachine) denseTable(keys []string) []byte {
+	if len(keys) > 255 {
+		return nil
+	}
+	var largest uint64
+	for _, key := range keys {
+		if s := state.finalState(key); s > largest {
+			largest = s
+		}
+	}
+	if largest >= maxDenseTable {
+		return nil
+	}
+
+	// writeLookupTable outputs 16 bytes per line.
+	table := make([]byte, (largest/16+1)*16)
+	for n, key := range keys {
+		table[state.finalState(key)] = byte(n + 1)
+	}
+	return table
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDenseStates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{DenseStates},
+		{DenseStates, Insensitive},
+		{DenseStates, TrustLength},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"foo":    "1",
+			"bar":    "2",
+			"baz":    "3",
+			"qux":    "4",
+			"foobar": "5",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "foo", "1")
+		expectMatch(t, "bar", "2")
+		expectMatch(t, "baz", "3")
+		expectMatch(t, "qux", "4")
+		expectMatch(t, "foobar", "5")
+		expectMatch(t, "fob", "0")
+		expectMatch(t, "quz", "0")
+		expectMatch(t, "foobaz", "0")
+		cleanup()
+	}
+}
+
+func TestDenseStatesOutput(t *testing.T) {
+	cases := map[string]string{"bar": "1", "baz": "2", "foo": "3"}
+	var b bytes.Buffer
+	if err := Generate(&b, cases, "0", DenseStates); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"_l3_dense = \"\" +\n",
+		"[state] {\n\t\t\tcase 1: //",
+		"\t\t\tcase 3: //",
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected output to contain %q:\n%s", expect, b.String())
+		}
+	}
+
+	// Without the flag, the sums are compared directly.
+	b.Reset()
+	if err := Generate(&b, cases, "0"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "_dense") {
+		t.Errorf("unexpected lookup table:\n%s", b.String())
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxDepth returns a flag, which can be passed to Generate along with
+// HasPrefix, to examine no more than the first n runes of the input.  This
+// bounds the work done for adversarial input, such as a long run of runes
+// which are skipped because of Ignore, when routing on a bounded prefix
+// (such as the first segment of a path).
+//
+// Runes which are ignored count towards the limit, so a key can only match
+// if it (including any ignored runes in the input) begins within the first
+// n runes.  Generate returns an error if any key is longer than n runes,
+// since it could never match.  The limit applies to the input after any
+// Preprocess code, but before any NormalizeInput functions, and before the
+// check for ASCIIOnly, which thus only examines the first n runes.
+func MaxDepth(n int) *Flag {
+	return &Flag{maxDepth: n}
+}
+
+// depthGuardCode is emitted at the beginning of the generated code when
+// MaxDepth is specified.  It truncates the input after the rune limit,
+// which is substituted for each %[1]d.  Continuation bytes are not counted;
+// if the input is no more than n bytes long, it can't be more than n runes.
+const depthGuardCode = `	if len(input) > %[1]d {
+		for i, n := 0, 0; i < len(input); i++ {
+			if input[i]&0xc0 != 0x80 {
+				if n == %[1]d {
+					input = input[:i]
+					break
+				}
+				n++
+			}
+		}
+	}
+`
+
+// writeDepthGuard checks that MaxDepth can be used with the other flags and
+// the keys in cases, then outputs depthGuardCode.  Any lines of the
+// generated code recorded for coverage thereafter are offset by the lines
+// in the guard.
+func writeDepthGuard(w io.Writer, cases map[string]string, fs *flagSet) error {
+	if !fs.partialMatch || fs.backwards {
+		return &ErrBadFlags{requires: [2]string{"MaxDepth", "HasPrefix"}}
+	}
+
+	keys, _ := splitShapes(cases)
+	var tooLong []string
+	for key := range keys {
+		if utf8.RuneCountInString(fs.mangle(fs.normalizeKey(key))) > fs.maxDepth {
+			tooLong = append(tooLong, strconv.Quote(key))
+		}
+	}
+	if len(tooLong) > 0 {
+		sort.Strings(tooLong)
+		return fmt.Errorf("keys cannot match with MaxDepth(%d): %s", fs.maxDepth, strings.Join(tooLong, ", "))
+	}
+
+	guard := fmt.Sprintf(depthGuardCode, fs.maxDepth)
+	fs.coverOffset += strings.Count(guard, "\n")
+	_, err := io.WriteString(w, guard)
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestMaxDepth tests that keys only match if they begin within the limit.
+func TestMaxDepth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		flags  []*Flag
+		expect map[string]string
+	}{
+		{[]*Flag{HasPrefix, Ignore('/'), MaxDepth(8)}, map[string]string{
+			"api/v1":             "1",
+			"/////api":           "1",
+			"//////api":          "0",
+			"/css":               "2",
+			"//////\u00e9t":      "3",
+			"///////\u00e9t":     "0",
+			"///////\u00e9t/css": "0",
+		}},
+		{[]*Flag{HasPrefix, MaxDepth(4), Strategy(TrieStrategy)}, map[string]string{
+			"api/v1":  "1",
+			"\u00e9t": "3",
+			"/api":    "0",
+		}},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"api":     "1",
+			"css":     "2",
+			"\u00e9t": "3",
+		}, "0", testCase.flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+
+		for input, expect := range testCase.expect {
+			expectMatch(t, input, expect)
+		}
+		cleanup()
+	}
+}
+
+// TestMaxDepthErrors tests the flags and keys which can't be used with
+// MaxDepth.
+func TestMaxDepthErrors(t *testing.T) {
+	cases := map[string]string{"api": "1", "static": "2"}
+	for _, flags := range [][]*Flag{
+		{MaxDepth(8)},
+		{HasSuffix, MaxDepth(8)},
+	} {
+		err := Generate(ioutil.Discard, cases, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok || err.Error() != `flag "MaxDepth" requires "HasPrefix"` {
+			t.Errorf("%s: unexpected error: %v", flagNames(flags), err)
+		}
+	}
+
+	err := Generate(ioutil.Discard, cases, "0", HasPrefix, Ignore('-'), MaxDepth(5))
+	if err == nil || err.Error() != `keys cannot match with MaxDepth(5): "static"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Generate(ioutil.Discard, map[string]string{"s-t-a-t-i-c": "1"}, "0", HasPrefix, Ignore('-'), MaxDepth(6)); err != nil {
+		t.Errorf("ignored runes in keys should not count towards MaxDepth: %s", err)
+	}
+}
+
+// TestMaxDepthSelfTest tests that GenerateTest doesn't output inputs which
+// exceed the limit.
+func TestMaxDepthSelfTest(t *testing.T) {
+	var b bytes.Buffer
+	if err := GenerateTest(&b, "match(%q)", "", map[string]string{"ab": "1", "abcd": "2"}, HasPrefix, Ignore('-'), MaxDepth(5)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `match("-a-b-")`) {
+		t.Errorf("expected padded input within the limit:\n%s", b.String())
+	}
+	if strings.Contains(b.String(), `match("-a-b-c-d-")`) {
+		t.Errorf("unexpected padded input beyond the limit:\n%s", b.String())
+	}
+}
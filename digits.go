@@ -0,0 +1,129 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"sort"
+	"strings"
+)
+
+// AnyDigit returns a flag, which can be passed to Generate, to specify that
+// placeholder matches any ASCII digit at the position(s) where it appears in
+// a key.  This is useful for matching classes of numeric codes, such as HTTP
+// status codes:
+//
+//	fastmatch.Generate(w, map[string]string{
+//		"2xx": "Success",
+//		"404": "NotFound",
+//		"4xx": "ClientError",
+//		"5xx": "ServerError",
+//	}, "Unknown", fastmatch.AnyDigit('x'))
+//
+// Each key containing the placeholder is expanded into every key it could
+// match.  (A key containing the placeholder n times thus becomes 10^n keys,
+// so this is best reserved for short codes.)  Keys without the placeholder
+// take precedence over those with it, so in the example above, "404" returns
+// NotFound, and other 4xx codes return ClientError.  Two keys which both
+// contain the placeholder and which can match the same input are reported
+// as ambiguous if their values differ.
+//
+// The placeholder is compared as written, without regard to Insensitive or
+// Equivalent, and may not itself be a digit.
+func AnyDigit(placeholder rune) *Flag {
+	return &Flag{anyDigit: placeholder}
+}
+
+// expandDigits returns a new cases map, in which each key containing the
+// AnyDigit placeholder has been replaced by the keys it matches.  The
+// original keys are recorded in fs.digitPatterns.
+func (fs *flagSet) expandDigits(cases map[string]string) (map[string]string, error) {
+	placeholder := string(fs.anyDigit)
+
+	var patterns []string
+	expanded := make(map[string]string, len(cases))
+	for key, value := range cases {
+		if strings.Contains(key, placeholder) {
+			patterns = append(patterns, key)
+		} else {
+			expanded[key] = value
+		}
+	}
+	if len(patterns) == 0 {
+		return cases, nil
+	}
+	sort.Strings(patterns)
+
+	fs.digitPatterns = make(map[string]string)
+	e := new(ErrAmbiguous)
+	for _, pattern := range patterns {
+		if err := fs.ctx.Err(); err != nil {
+			return nil, err
+		}
+		value := cases[pattern]
+		parts := strings.Split(pattern, placeholder)
+		digits := make([]byte, len(parts)-1)
+		for {
+			var b strings.Builder
+			for n, part := range parts {
+				b.WriteString(part)
+				if n < len(digits) {
+					b.WriteByte('0' + digits[n])
+				}
+			}
+			key := b.String()
+
+			if other, found := fs.digitPatterns[key]; found {
+				if cases[other] != value {
+					e.add(nil, other, pattern)
+				}
+			} else if _, found := expanded[key]; !found {
+				expanded[key] = value
+				fs.digitPatterns[key] = pattern
+			}
+
+			// Advance to the next combination of digits, like an
+			// odometer.
+			n := len(digits) - 1
+			for ; n >= 0; n-- {
+				if digits[n]++; digits[n] < 10 {
+					break
+				}
+				digits[n] = 0
+			}
+			if n < 0 {
+				break
+			}
+		}
+	}
+	if len(e.keys) > 0 {
+		return nil, e
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExpandDigits tests the expansion of AnyDigit patterns into keys.
+func TestExpandDigits(t *testing.T) {
+	fs, err := parseFlags(AnyDigit('x'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := fs.expandDigits(map[string]string{
+		"1x":  "A",
+		"12":  "B",
+		"x0x": "C",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cases) != 10+100 {
+		t.Errorf("expected 110 keys, got %d", len(cases))
+	}
+	for key, expect := range map[string]string{
+		"10":  "A",
+		"12":  "B",
+		"19":  "A",
+		"000": "C",
+		"909": "C",
+	} {
+		if actual := cases[key]; actual != expect {
+			t.Errorf("expected %q for %q, got %q", expect, key, actual)
+		}
+	}
+	if _, found := cases["1x"]; found {
+		t.Error("pattern was not removed")
+	}
+	if pattern := fs.digitPatterns["19"]; pattern != "1x" {
+		t.Errorf("expected \"19\" to map back to \"1x\", got %q", pattern)
+	}
+	if _, found := fs.digitPatterns["12"]; found {
+		t.Error("exact key should not map back to a pattern")
+	}
+}
+
+// TestAnyDigitAmbiguous tests that overlapping patterns are reported using
+// the keys as supplied.
+func TestAnyDigitAmbiguous(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{
+		"4x4": "1",
+		"40x": "2",
+		"5xx": "3",
+	}, "0", AnyDigit('x'))
+	e, ok := err.(*ErrAmbiguous)
+	if !ok {
+		t.Fatalf("expected *ErrAmbiguous, got %v", err)
+	}
+	if expect := [][]string{{"40x", "4x4"}}; !reflect.DeepEqual(e.sortedKeys(), expect) {
+		t.Errorf("expected %q, got %q", expect, e.sortedKeys())
+	}
+
+	// Ambiguity detected after expansion should also refer to the
+	// patterns.
+	err = Generate(ioutil.Discard, map[string]string{
+		"4x": "1",
+		"4":  "2",
+	}, "0", AnyDigit('x'), HasPrefix)
+	e, ok = err.(*ErrAmbiguous)
+	if !ok {
+		t.Fatalf("expected *ErrAmbiguous, got %v", err)
+	}
+	if expect := [][]string{{"4", "4x"}}; !reflect.DeepEqual(e.sortedKeys(), expect) {
+		t.Errorf("expected %q, got %q", expect, e.sortedKeys())
+	}
+
+	if _, ok := Generate(ioutil.Discard, map[string]string{"1": "1"}, "0", AnyDigit('1')).(*ErrBadFlags); !ok {
+		t.Error("expected *ErrBadFlags for digit placeholder")
+	}
+}
+
+// TestAnyDigitCoverage tests that the coverage manifest refers to patterns,
+// rather than the keys they were expanded into.
+func TestAnyDigitCoverage(t *testing.T) {
+	cases := map[string]string{
+		"404": "1",
+		"4xx": "2",
+		"5xx": "3",
+	}
+	m := new(CoverageManifest)
+	var b bytes.Buffer
+	if err := Generate(&b, cases, "0", AnyDigit('x'), Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+
+	seen := make(map[string]int)
+	for _, r := range m.Keys {
+		seen[r.Key]++
+		actual := strings.TrimSpace(lines[r.StartLine-1])
+		if expect := "return " + cases[r.Key]; actual != expect {
+			t.Errorf("expected %q at line %d for %q, got %q", expect, r.StartLine, r.Key, actual)
+		}
+	}
+	for key := range cases {
+		if seen[key] == 0 {
+			t.Errorf("%q missing from manifest", key)
+		}
+	}
+}
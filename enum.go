@@ -0,0 +1,232 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EnumValue describes one value of an enum type, for GenerateEnum.
+type EnumValue struct {
+	// Const is the name of the Go constant, such as "ColorRed".
+	Const string
+
+	// Name is the string form of the value, such as "red".
+	Name string
+
+	// Aliases are additional strings which are parsed as this value.
+	Aliases []string
+}
+
+// EnumConstants is a flag, which can be passed to GenerateEnum, to also
+// output a typed constant for each value.  The constants are numbered
+// starting at one, in the order supplied, so that the zero value of the
+// type is not a valid enum value.
+var EnumConstants = new(Flag)
+
+// EnumSentinel returns a flag, which can be passed to GenerateEnum, to also
+// output a compile-time check (see GenerateExhaustiveCheck) that the
+// constants haven't changed since the code was generated.  sentinel is the
+// name of a constant one greater than the last value, which is typically
+// declared using iota:
+//
+//	const (
+//		ColorRed Color = iota
+//		ColorGreen
+//		numColors
+//	)
+//
+// If EnumConstants is also passed, the sentinel is declared along with the
+// other constants.
+func EnumSentinel(sentinel string) *Flag {
+	return &Flag{sentinel: sentinel}
+}
+
+// GenerateExhaustiveCheck outputs a function which fails to compile unless
+// consts are consecutive, in the order supplied, and sentinel is one greater
+// than the last of them.  This is intended to accompany code generated from
+// a list of enum values, such as a matcher whose values are the constants,
+// so that adding a new value without regenerating the code breaks the
+// build:
+//
+//	fastmatch.GenerateExhaustiveCheck(w, []string{"ColorRed", "ColorGreen"}, "numColors")
+//
+// The generated function is named "_", so it can be output at the top level
+// any number of times, and is never called.  Unlike the other functions in
+// this package, the caller does not need to write the signature first.
+func GenerateExhaustiveCheck(w io.Writer, consts []string, sentinel string) error {
+	if len(consts) == 0 {
+		return errors.New("no constants to check")
+	}
+
+	if _, err := fmt.Fprintln(w, "// This fails to compile if the constants have changed since this file was"); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "// generated.  Regenerate it if so.")
+	fmt.Fprintln(w, "func _() {")
+	fmt.Fprintln(w, "	var x [1]struct{}")
+	for n, c := range append(consts[1:len(consts):len(consts)], sentinel) {
+		fmt.Fprintf(w, "	_ = x[%s-%s-%d]", c, consts[0], n+1)
+		fmt.Fprintln(w)
+	}
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// GenerateEnum outputs everything needed to convert between strings and an
+// enum type, which would otherwise require several coordinated calls to
+// Generate and GenerateReverse.  For an enum type named Color, the following
+// are output:
+//
+//	func ParseColor(input string) (Color, bool)
+//	func ColorString(input Color) string
+//	func ColorValues() []Color
+//
+// ParseColor returns false if the input does not match the Name (or any of
+// the Aliases) of any value.  ColorString returns "" for unknown values.
+// ColorValues returns all of the values, in the order supplied.  An error is
+// returned if the same string is used more than once, as a Name or an
+// alias, before anything is output.
+//
+// The caller is responsible for declaring the type.  The constants are also
+// expected to already exist, unless the EnumConstants flag is passed.  If
+// they do, the EnumSentinel flag can be used to check that they haven't
+// changed since the code was generated.  Other flags are passed to Generate
+// when building the parser:
+//
+//	fmt.Fprintln(w, "type Color int")
+//	fastmatch.GenerateEnum(w, "Color", []fastmatch.EnumValue{
+//		{Const: "ColorRed", Name: "red"},
+//		{Const: "ColorGreen", Name: "green"},
+//	}, fastmatch.EnumConstants, fastmatch.Insensitive)
+func GenerateEnum(w io.Writer, enum string, values []EnumValue, flags ...*Flag) error {
+	// The enum may be qualified with a package name, which needs to be
+	// stripped to build the function names.
+	base := enum
+	if n := strings.LastIndex(base, "."); n != -1 {
+		base = base[n+1:]
+	}
+
+	constants := false
+	var sentinel string
+	matchFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag == EnumConstants {
+			constants = true
+		} else if flag.sentinel != "" {
+			sentinel = flag.sentinel
+		} else {
+			matchFlags = append(matchFlags, flag)
+		}
+	}
+
+	cases := make(map[string]string, len(values))
+	reverseCases := make(map[string]string, len(values))
+	consts := make(map[string]string, len(values))
+	for _, value := range values {
+		for _, name := range append([]string{value.Name}, value.Aliases...) {
+			if other, found := consts[name]; found {
+				return fmt.Errorf("duplicate enum name %s: %s and %s", strconv.Quote(name), other, value.Const)
+			}
+			consts[name] = value.Const
+			cases[name] = value.Const + ", true"
+		}
+		reverseCases[value.Name] = value.Const
+	}
+
+	if constants {
+		if _, err := fmt.Fprintln(w, "const ("); err != nil {
+			return err
+		}
+		for n, value := range values {
+			if n == 0 {
+				fmt.Fprintf(w, "\t%s %s = iota + 1", value.Const, enum)
+			} else {
+				fmt.Fprintf(w, "\t%s", value.Const)
+			}
+			fmt.Fprintln(w)
+		}
+		if sentinel != "" {
+			fmt.Fprintf(w, "\t%s", sentinel)
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, ")")
+		fmt.Fprintln(w)
+	}
+
+	if _, err := fmt.Fprintf(w, "// Parse%s returns the %s with the supplied name.  The second return", base, enum); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// value is false if the name is not recognized.")
+	fmt.Fprintf(w, "func Parse%s(input string) (%s, bool) {", base, enum)
+	fmt.Fprintln(w)
+	if err := Generate(w, cases, fmt.Sprintf("*new(%s), false", enum), matchFlags...); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %sString returns the name of a %s value, or \"\" if the value is", base, enum)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// not recognized.")
+	fmt.Fprintf(w, "func %sString(input %s) string {", base, enum)
+	fmt.Fprintln(w)
+	if err := GenerateReverse(w, reverseCases, strconv.Quote("")); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %sValues returns every %s value.", base, enum)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %sValues() []%s {", base, enum)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\treturn []%s{", enum)
+	fmt.Fprintln(w)
+	for _, value := range values {
+		fmt.Fprintf(w, "\t\t%s,", value.Const)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\t}")
+	_, err := fmt.Fprintln(w, "}") // end of func
+
+	if sentinel != "" && len(values) > 0 && err == nil {
+		fmt.Fprintln(w)
+		consts := make([]string, len(values))
+		for n, value := range values {
+			consts[n] = value.Const
+		}
+		err = GenerateExhaustiveCheck(w, consts, sentinel)
+	}
+	return err
+}
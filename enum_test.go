@@ -0,0 +1,177 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestEnum tests generating constants, a parser, a reverse function, and a
+// list of values for an enum.
+func TestEnum(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type Color int")
+		fmt.Fprintln(w)
+		err := GenerateEnum(w, "Color", []EnumValue{
+			{Const: "ColorRed", Name: "red", Aliases: []string{"crimson"}},
+			{Const: "ColorGreen", Name: "green"},
+			{Const: "ColorBlue", Name: "blue"},
+		}, EnumConstants, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tc, ok := ParseColor(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(int(c), ok, ColorString(c), ColorValues())")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "red", "1 true red [1 2 3]")
+	expectMatch(t, "Crimson", "1 true red [1 2 3]")
+	expectMatch(t, "BLUE", "3 true blue [1 2 3]")
+	expectMatch(t, "purple", "0 false  [1 2 3]")
+}
+
+// TestEnumDuplicate tests that a string used by more than one value, or
+// more than once by the same value, is reported as an error.
+func TestEnumDuplicate(t *testing.T) {
+	for expect, values := range map[string][]EnumValue{
+		`duplicate enum name "red": ColorRed and ColorCrimson`: {
+			{Const: "ColorRed", Name: "red"},
+			{Const: "ColorCrimson", Name: "crimson", Aliases: []string{"red"}},
+		},
+		`duplicate enum name "red": ColorRed and ColorScarlet`: {
+			{Const: "ColorRed", Name: "red"},
+			{Const: "ColorScarlet", Name: "red"},
+		},
+		`duplicate enum name "red": ColorRed and ColorRed`: {
+			{Const: "ColorRed", Name: "red", Aliases: []string{"red"}},
+		},
+	} {
+		var buf bytes.Buffer
+		err := GenerateEnum(&buf, "Color", values, EnumConstants)
+		if err == nil || err.Error() != expect {
+			t.Errorf("expected %q, got %v", expect, err)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("unexpected output before %q", expect)
+		}
+	}
+}
+
+// TestEnumSentinel tests that the output of GenerateExhaustiveCheck compiles
+// when the constants are unchanged, and fails to compile otherwise.
+func TestEnumSentinel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		consts []string
+		ok     bool
+	}{
+		{[]string{"ColorRed", "ColorGreen", "ColorBlue"}, true},
+		{[]string{"ColorRed", "ColorGreen", "ColorPurple", "ColorBlue"}, false},
+		{[]string{"ColorRed", "ColorBlue", "ColorGreen"}, false},
+		{[]string{"ColorRed", "ColorGreen", "ColorBlue", "ColorPurple"}, false},
+	} {
+		cleanup, err := generateProgram([]string{"fmt"}, func(w io.Writer) error {
+			fmt.Fprintln(w, "type Color int")
+			fmt.Fprintln(w, "const (")
+			for n, c := range testCase.consts {
+				if n == 0 {
+					fmt.Fprintln(w, "\tColorRed Color = iota")
+				} else {
+					fmt.Fprintln(w, "\t"+c)
+				}
+			}
+			fmt.Fprintln(w, "\tnumColors")
+			fmt.Fprintln(w, ")")
+			fmt.Fprintln(w)
+			err := GenerateEnum(w, "Color", []EnumValue{
+				{Const: "ColorRed", Name: "red"},
+				{Const: "ColorGreen", Name: "green"},
+				{Const: "ColorBlue", Name: "blue"},
+			}, EnumSentinel("numColors"))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tfmt.Println(ColorValues())")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command("go", "build", "-o", os.DevNull, "generated.go").CombinedOutput()
+		if testCase.ok && err != nil {
+			t.Errorf("%q: %s: %s", testCase.consts, err, out)
+		} else if !testCase.ok && !bytes.Contains(out, []byte("out of bounds")) {
+			t.Errorf("%q: expected index out of bounds, got: %s", testCase.consts, out)
+		}
+		cleanup()
+	}
+}
+
+// TestGenerateExhaustiveCheck tests the output of GenerateExhaustiveCheck.
+func TestGenerateExhaustiveCheck(t *testing.T) {
+	var b bytes.Buffer
+	if err := GenerateExhaustiveCheck(&b, []string{"A", "B", "C"}, "numABC"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"_ = x[B-A-1]", "_ = x[C-A-2]", "_ = x[numABC-A-3]"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	if err := GenerateExhaustiveCheck(&b, nil, "numABC"); err == nil {
+		t.Error("expected error with no constants")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GenerateEnviron outputs Go code for a function named fn, which matches
+// environment variables of the form "PREFIX_KEY=value", such as the entries
+// returned by os.Environ, as used by configuration loaders.  The generated
+// function has the signature:
+//
+//	func fn(input string) (typ, int)
+//
+// The keys in cases are the variable names without prefix, which is
+// prepended to each of them.  If the name in input matches, the generated
+// function returns the corresponding value from cases, and the offset in
+// input of the variable's value, i.e. the byte following the '='.  If input
+// has no '=', the offset is len(input), so input[offset:] is always the
+// value.  If the name doesn't match, none and -1 are returned.  For example:
+//
+//	fastmatch.GenerateEnviron(w, "matchEnv", "Setting", "APP_",
+//		map[string]string{
+//			"PORT": "SettingPort",
+//			"HOST": "SettingHost",
+//		}, "SettingNone")
+//
+// generates matchEnv, for which matchEnv("APP_PORT=8080") returns
+// SettingPort and 9.
+//
+// Names are matched by a closure output by Generate, with StopUpon('='), so
+// the flags supported by Generate also apply.  Matching is case-sensitive
+// (as it is on Unix) unless Insensitive is specified.  HasPrefix,
+// HasSuffix, ValueType, TooShort, TooLong, and Coverage are not supported.
+// An error is returned if prefix or any of the keys contains '='.
+func GenerateEnviron(w io.Writer, fn, typ, prefix string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag == HasPrefix || flag == HasSuffix || flag.valueType != "" ||
+			flag.tooShort != "" || flag.tooLong != "" || flag.coverage != nil {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateEnviron"}
+	}
+	if strings.IndexByte(prefix, '=') != -1 {
+		return fmt.Errorf("prefix %s contains '='", strconv.Quote(prefix))
+	}
+
+	// The closure returns the value, and the offset just past the '=' if
+	// the name in the input is the same length as the key.  Otherwise
+	// (such as when there is no '=', or the input contains ignored
+	// runes), the generated code has to look for it.
+	names := make(map[string]string, len(cases))
+	for key, value := range cases {
+		if key == "" {
+			return ErrEmptyKey
+		}
+		if strings.IndexByte(key, '=') != -1 {
+			return fmt.Errorf("key %s contains '='", strconv.Quote(key))
+		}
+		names[prefix+key] = value + ", " + strconv.Itoa(len(prefix)+len(key)+1)
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) (%s, int) {", fn, fs.extraParams(), typ); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tfastmatchName := func(input string) (%s, int) {", typ)
+	fmt.Fprintln(w)
+	if err := Generate(w, names, none+", -1", append(internalFlags(flags), StopUpon('='))...); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\tvalue, offset := fastmatchName(input)")
+	fmt.Fprintln(w, "\tif offset > 0 && (offset > len(input) || input[offset-1] != '=') {")
+	fmt.Fprintln(w, "\t\toffset = len(input)")
+	fmt.Fprintln(w, "\t\t"+fs.forEachIndex("input"))
+	fmt.Fprintln(w, "\t\t\tif input[i] == '=' {")
+	fmt.Fprintln(w, "\t\t\t\toffset = i + 1")
+	fmt.Fprintln(w, "\t\t\t\tbreak")
+	fmt.Fprintln(w, "\t\t\t}")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn value, offset")
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
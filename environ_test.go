@@ -0,0 +1,116 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestEnviron tests matching environment variables and finding their
+// values.
+func TestEnviron(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		flags  []*Flag
+		expect map[string]string
+	}{
+		{nil, map[string]string{
+			"APP_PORT=8080":    `1 "8080"`,
+			"APP_HOST=a=b":     `2 "a=b"`,
+			"APP_HOST=":        `2 ""`,
+			"APP_HOST":         `2 ""`,
+			"app_port=8080":    "0 -1",
+			"APP_PORTS=8080":   "0 -1",
+			"OTHER_PORT=8080":  "0 -1",
+			"APP_=x":           "0 -1",
+			"APP_HOSTNAME=foo": "0 -1",
+		}},
+		{[]*Flag{Insensitive, Ignore('-')}, map[string]string{
+			"app_port=8080":  `1 "8080"`,
+			"APP_HO-ST=a":    `2 "a"`,
+			"APP-_PORT=8080": `1 "8080"`,
+		}},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+			err := GenerateEnviron(w, "matchEnv", "int", "APP_", map[string]string{
+				"PORT": "1",
+				"HOST": "2",
+			}, "0", testCase.flags...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tkey, offset := matchEnv(os.Args[1])")
+			fmt.Fprintln(w, "\tif offset < 0 {")
+			fmt.Fprintln(w, "\t\tfmt.Print(key, \" \", offset)")
+			fmt.Fprintln(w, "\t\treturn")
+			fmt.Fprintln(w, "\t}")
+			fmt.Fprintln(w, "\tfmt.Printf(\"%d %q\", key, os.Args[1][offset:])")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+
+		for input, expect := range testCase.expect {
+			expectMatch(t, input, expect)
+		}
+		cleanup()
+	}
+}
+
+// TestEnvironErrors tests invalid names and unsupported flags.
+func TestEnvironErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		prefix string
+		cases  map[string]string
+		flags  []*Flag
+		expect string
+	}{
+		{"APP=", map[string]string{"PORT": "1"}, nil, `prefix "APP=" contains '='`},
+		{"APP_", map[string]string{"PORT=": "1"}, nil, `key "PORT=" contains '='`},
+		{"APP_", map[string]string{"": "1"}, nil, ErrEmptyKey.Error()},
+		{"APP_", map[string]string{"PORT": "1"}, []*Flag{HasPrefix}, `GenerateEnviron does not support flags: "HasPrefix"`},
+	} {
+		err := GenerateEnviron(ioutil.Discard, "matchEnv", "int", testCase.prefix, testCase.cases, "0", testCase.flags...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+}
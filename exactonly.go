@@ -0,0 +1,126 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ExactOnly is used with HasPrefix to mark keys which only match if they are
+// the entire input, rather than a prefix of it.  This allows a vocabulary
+// which mixes commands (such as "quit") with prefixes (such as "set ") to be
+// matched by one function.
+//
+// The generated code checks the exact-only keys first, so an exact match
+// takes priority over a shorter key which is a prefix of it.  Each key must
+// also be present in the cases passed to Generate.  ExactOnly cannot be
+// combined with Coverage.
+func ExactOnly(keys ...string) *Flag {
+	return &Flag{exactOnly: keys}
+}
+
+// generateExactOnly implements generateCases when ExactOnly is specified.  It
+// outputs a closure which matches the exact-only keys, followed by a prefix
+// matcher for the rest.
+func generateExactOnly(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	exactCases := make(map[string]string, len(fs.exactOnly))
+	for _, key := range fs.exactOnly {
+		value, ok := cases[key]
+		if !ok {
+			return fmt.Errorf("ExactOnly key %s is not in cases", strconv.Quote(key))
+		}
+		exactCases[key] = value
+	}
+	prefixCases := make(map[string]string, len(cases)-len(exactCases))
+	for key, value := range cases {
+		if _, ok := exactCases[key]; !ok {
+			prefixCases[key] = value
+		}
+	}
+
+	// Keys with the same value share a number, which is what the
+	// closure returns.
+	var values []string
+	valueNums := make(map[string]int, len(exactCases))
+	for _, value := range exactCases {
+		if valueNums[value] == 0 {
+			values = append(values, value)
+			valueNums[value] = -1
+		}
+	}
+	sort.Strings(values)
+	for n, value := range values {
+		valueNums[value] = n + 1
+	}
+	numCases := make(map[string]string, len(exactCases))
+	for key, value := range exactCases {
+		numCases[key] = strconv.Itoa(valueNums[value])
+	}
+
+	// The closure returns 0 rather than a TooShort or TooLong value; an
+	// input which is neither still has to be tried against the prefixes.
+	exactFlags := make([]*Flag, 0, len(flags))
+	prefixFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range internalFlags(flags) {
+		if len(flag.exactOnly) > 0 {
+			continue
+		}
+		prefixFlags = append(prefixFlags, flag)
+		if flag != HasPrefix && flag.tooShort == "" && flag.tooLong == "" {
+			exactFlags = append(exactFlags, flag)
+		}
+	}
+	if fs.result != nil {
+		// Both matchers are part of our output, so are reported.
+		exactFlags = append(exactFlags, Report(fs.result))
+		prefixFlags = append(prefixFlags, Report(fs.result))
+	}
+
+	if _, err := fmt.Fprintln(w, "\tfastmatchExactOnly := func(input string) int {"); err != nil {
+		return err
+	}
+	if err := Generate(w, numCases, "0", exactFlags...); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\tswitch fastmatchExactOnly(input) {")
+	for n, value := range values {
+		fmt.Fprintf(w, "\tcase %d:", n+1)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\t\treturn %s", value)
+		fmt.Fprintln(w)
+	}
+	if _, err := fmt.Fprintln(w, "\t}"); err != nil {
+		return err
+	}
+
+	return Generate(w, prefixCases, none, prefixFlags...)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// TestExactOnly tests mixing exact-only keys with prefixes in HasPrefix mode.
+func TestExactOnly(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"quit": "1",
+		"q":    "2",
+		"set ": "3",
+		"help": "4",
+	}, "0", HasPrefix, Insensitive, ExactOnly("quit", "help"))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "quit", "1")
+	expectMatch(t, "QUIT", "1")
+	expectMatch(t, "quitter", "2")
+	expectMatch(t, "q", "2")
+	expectMatch(t, "set x=1", "3")
+	expectMatch(t, "help", "4")
+	expectMatch(t, "help me", "0")
+	expectMatch(t, "", "0")
+}
+
+// TestExactOnlyErrors tests that ExactOnly requires HasPrefix, and that its
+// keys must be in the cases.
+func TestExactOnlyErrors(t *testing.T) {
+	cases := map[string]string{"a": "1", "b": "2"}
+	for _, flags := range [][]*Flag{
+		{ExactOnly("a")},
+		{HasSuffix, ExactOnly("a")},
+		{HasPrefix, ExactOnly("a"), Coverage(&CoverageManifest{})},
+	} {
+		err := Generate(ioutil.Discard, cases, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+	if err := Generate(ioutil.Discard, cases, "0", HasPrefix, ExactOnly("c")); err == nil {
+		t.Error("expected error for ExactOnly key not in cases")
+	}
+	if err := Generate(ioutil.Discard, cases, "0", HasPrefix, ExactOnly("a", "b")); err != nil {
+		t.Errorf("unexpected error with no prefix keys: %s", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateExactOrPrefix outputs Go code which prefers an exact match of the
+// input against the keys in cases, but failing that, returns the value of
+// the longest key which is a prefix of the input.  This is common when
+// looking up configuration, where a setting for "/static/favicon.ico"
+// should take precedence over one for "/static/", which in turn should
+// apply to any other path beginning with it.  (Passing HasPrefix to
+// Generate instead would report those keys as ambiguous.)
+//
+// The generated code returns two values: the value of the matching key, and
+// a bool which is true if the match was exact.  The function signature
+// written by the caller must thus have two return values:
+//
+//	fmt.Fprintln(w, "func lookup(input string) (Handler, bool) {")
+//	fastmatch.GenerateExactOrPrefix(w, cases, "nil")
+//
+// If no key matches, none is returned along with false.  Keys are compared
+// against prefixes of the input which are the same length, so flags which
+// change the length of what's matched (such as Ignore, or Equivalent runes
+// which are encoded with different numbers of bytes) are not supported.
+// Neither is Preprocess, since the generated code calls the matcher for each
+// length.
+func GenerateExactOrPrefix(w io.Writer, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag == HasPrefix || flag == HasSuffix || flag == NoTrailingMark || flag == ConstantTime ||
+			flag == InsensitiveUnicode || flag == InsensitiveTurkish ||
+			len(flag.stop) > 0 || len(flag.ignore) > 0 || len(flag.ignoreExcept) > 0 ||
+			flag.normalizeFunc != nil || flag.tooShort != "" || flag.tooLong != "" ||
+			flag.preprocess != "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	for r, rs := range fs.equiv {
+		if !sameRuneLen(r, rs) {
+			unsupported = append(unsupported, "Equivalent")
+			break
+		}
+	}
+	if _, shapes := splitShapes(cases); len(shapes) > 0 {
+		unsupported = append(unsupported, "token shapes")
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateExactOrPrefix"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(cases, none); err != nil {
+			return err
+		}
+	}
+
+	// Keys with the same value share a number, which is what the
+	// closure returns.
+	var values []string
+	valueNums := make(map[string]int, len(cases))
+	for _, value := range cases {
+		if valueNums[value] == 0 {
+			values = append(values, value)
+			valueNums[value] = -1
+		}
+	}
+	sort.Strings(values)
+	for n, value := range values {
+		valueNums[value] = n + 1
+	}
+	numCases := make(map[string]string, len(cases))
+	keys := make([]string, 0, len(cases))
+	lengthSet := make(map[int]bool)
+	for key, value := range cases {
+		numCases[key] = strconv.Itoa(valueNums[value])
+		keys = append(keys, key)
+		if fs.anyDigit != 0 {
+			lengthSet[len(strings.Replace(key, string(fs.anyDigit), "0", -1))] = true
+		} else {
+			lengthSet[len(key)] = true
+		}
+	}
+	sort.Strings(keys)
+	lengths := make([]int, 0, len(lengthSet))
+	for l := range lengthSet {
+		lengths = append(lengths, l)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintln(w, "\tfastmatchExact := func(input string) int {"); err != nil {
+		return err
+	}
+	if err := Generate(w, numCases, "0", internalFlags(flags)...); err != nil {
+		return err
+	}
+
+	// Since the closure only matches input of the same length as a key,
+	// there's no point trying prefixes of any other length.
+	fmt.Fprintln(w, "\tmatch, exact := fastmatchExact(input), true")
+	fmt.Fprintln(w, "\tif match == 0 {")
+	fmt.Fprintln(w, "\t\texact = false")
+	fmt.Fprintf(w, "\t\tfor _, n := range [...]int{%s} {", joinInts(lengths))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\tif n < len(input) {")
+	fmt.Fprintln(w, "\t\t\t\tif match = fastmatchExact(input[:n]); match != 0 {")
+	fmt.Fprintln(w, "\t\t\t\t\tbreak")
+	fmt.Fprintln(w, "\t\t\t\t}")
+	fmt.Fprintln(w, "\t\t\t}")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tswitch match {")
+	for n, value := range values {
+		fmt.Fprintf(w, "\tcase %d:", n+1)
+		fmt.Fprintln(w)
+		if lines != nil {
+			for _, key := range keys {
+				if cases[key] == value {
+					fs.cover(key, lines.lines)
+				}
+			}
+		}
+		fmt.Fprintf(w, "\t\treturn %s, exact", value)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintf(w, "\treturn %s, false", none)
+	fmt.Fprintln(w)
+
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// joinInts formats a list of integers separated by commas.
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for n, i := range ns {
+		s[n] = strconv.Itoa(i)
+	}
+	return strings.Join(s, ", ")
+}
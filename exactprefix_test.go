@@ -0,0 +1,119 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestExactOrPrefix tests that an exact match takes precedence over the
+// longest prefix match.
+func TestExactOrPrefix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func lookup(input string) (string, bool) {")
+		err := GenerateExactOrPrefix(w, map[string]string{
+			"/":                   `"root"`,
+			"/static/":            `"static"`,
+			"/static/favicon.ico": `"favicon"`,
+			"/static/js/":         `"static"`,
+			"/API/":               `"api"`,
+		}, `""`, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(lookup(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "/static/favicon.ico", "favicon true")
+	expectMatch(t, "/static/favicon.icon", "favicon false")
+	expectMatch(t, "/static/favicon.ic", "static false")
+	expectMatch(t, "/static/", "static true")
+	expectMatch(t, "/static/js/app.js", "static false")
+	expectMatch(t, "/api/v1", "api false")
+	expectMatch(t, "/", "root true")
+	expectMatch(t, "/other", "root false")
+	expectMatch(t, "other", "false")
+	expectMatch(t, "", "false")
+}
+
+// TestExactOrPrefixOutput tests coverage of the generated code, and that
+// flags which change the length of the input are rejected.
+func TestExactOrPrefixOutput(t *testing.T) {
+	m := &CoverageManifest{Line: 5}
+	var b bytes.Buffer
+	cases := map[string]string{"a": "1", "ab": "2", "b": "1"}
+	if err := GenerateExactOrPrefix(&b, cases, "0", Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "range [...]int{2, 1}") {
+		t.Errorf("expected key lengths in output:\n%s", b.String())
+	}
+	lines := strings.Split(b.String(), "\n")
+	if len(m.Keys) != len(cases) {
+		t.Fatalf("expected %d coverage entries, got %d", len(cases), len(m.Keys))
+	}
+	for _, r := range m.Keys {
+		expect := "return " + cases[r.Key] + ", exact"
+		if n := r.StartLine - m.Line; n < 0 || n >= len(lines) || strings.TrimSpace(lines[n]) != expect {
+			t.Errorf("coverage line %d for %q is not %q:\n%s", r.StartLine, r.Key, expect, b.String())
+		}
+	}
+
+	for _, flags := range [][]*Flag{
+		{HasPrefix},
+		{Ignore('-')},
+		{IgnorePlural},
+		{Equivalent('o', '\u00f6')},
+	} {
+		err := GenerateExactOrPrefix(ioutil.Discard, cases, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+	if err := GenerateExactOrPrefix(ioutil.Discard, cases, "0", Equivalent('o', '0')); err != nil {
+		t.Errorf("unexpected error from single-byte Equivalent: %s", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// exportValue converts a Go value expression from a cases map to the literal
+// value it represents, for use outside of Go code.  String literals are
+// unquoted and integers are converted to decimal; anything else (such as
+// identifiers) is returned as-is.  The second return value is true if the
+// expression was numeric.
+func exportValue(expr string) (string, bool) {
+	if s, err := strconv.Unquote(expr); err == nil {
+		return s, false
+	}
+	if n, err := strconv.ParseInt(expr, 0, 64); err == nil {
+		// Go allows hex, octal, and binary integer literals, which
+		// aren't universally understood elsewhere.
+		return strconv.FormatInt(n, 10), true
+	}
+	if isDecimalFloat(expr) {
+		return expr, true
+	}
+	return expr, false
+}
+
+// isDecimalFloat returns true if s is a decimal floating-point literal, with
+// an optional sign and exponent.  Unlike strconv.ParseFloat, it rejects
+// "Inf", "NaN", and hexadecimal floats, which in a Go expression are more
+// likely to be identifiers, and aren't numbers in SQL.
+func isDecimalFloat(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	digits := 0
+	for s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = s[1:]
+		digits++
+	}
+	if s != "" && s[0] == '.' {
+		s = s[1:]
+		for s != "" && s[0] >= '0' && s[0] <= '9' {
+			s = s[1:]
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '-' || s[0] == '+') {
+			s = s[1:]
+		}
+		if s == "" {
+			return false
+		}
+		for s != "" && s[0] >= '0' && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+	return s == ""
+}
+
+// checkExportFlags returns an ErrBadFlags if any flags are passed to one of
+// the export functions, which write the keys exactly as supplied, and so
+// can't reflect flags such as Insensitive which change what they match.
+func checkExportFlags(by string, flags []*Flag) error {
+	if len(flags) == 0 {
+		return nil
+	}
+	unsupported := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		unsupported = append(unsupported, flagName(flag))
+	}
+	return &ErrBadFlags{unsupported: unsupported, unsupportedBy: by}
+}
+
+// quoteSQL formats a string as a SQL string literal.
+func quoteSQL(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// ExportSQL outputs SQL INSERT statements which populate a lookup table with
+// the contents of a cases map, so that a database can mirror the set of
+// strings matched by code from Generate.
+//
+// table, keyColumn, and valueColumn are written to the output verbatim, and
+// thus must already be quoted if the target database requires it.  Keys are
+// written as SQL string literals.  Values which are Go string literals are
+// unquoted and written as SQL string literals.  Integer literals are written
+// as decimal numbers, as are decimal floating-point literals; any other
+// expression (such as a constant name, or a hexadecimal float) is written as
+// a string containing the expression itself.
+//
+// Statements are written in alphabetic order by key.  This function accepts
+// flags (in order to match Generate's function signature), but none are
+// currently supported, since the table can't represent flags such as
+// Insensitive which change how keys are matched.  Passing any results in an
+// ErrBadFlags.
+func ExportSQL(w io.Writer, table, keyColumn, valueColumn string, cases map[string]string, flags ...*Flag) error {
+	if err := checkExportFlags("ExportSQL", flags); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, numeric := exportValue(cases[key])
+		if !numeric {
+			value = quoteSQL(value)
+		}
+		_, err := fmt.Fprintf(w, "INSERT INTO %s (%s, %s) VALUES (%s, %s);", table, keyColumn, valueColumn, quoteSQL(key), value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// ExportCSV outputs the contents of a cases map as comma-separated values,
+// suitable for loading into a database lookup table.  The first row is a
+// header containing keyColumn and valueColumn.
+//
+// Values are converted in the same manner as ExportSQL, and rows are written
+// in alphabetic order by key.  As with ExportSQL, passing any flags results
+// in an ErrBadFlags.
+func ExportCSV(w io.Writer, keyColumn, valueColumn string, cases map[string]string, flags ...*Flag) error {
+	if err := checkExportFlags("ExportCSV", flags); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// csv.Writer buffers its output and only reports errors upon Flush(),
+	// so we render to memory and check the final write.
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	cw.Write([]string{keyColumn, valueColumn})
+	for _, key := range keys {
+		value, _ := exportValue(cases[key])
+		cw.Write([]string{key, value})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	_, err := b.WriteTo(w)
+	return err
+}
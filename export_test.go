@@ -0,0 +1,134 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+var exportCases = map[string]string{
+	"foo":   "1",
+	"bar":   `"two"`,
+	"it's":  "Apostrophe",
+	"quux":  "0x10",
+	"e":     "2.718e0",
+	"inf":   "Inf",
+	"nan":   "NaN",
+	"pi":    "-3.14",
+	"hex":   "0x1p-2",
+	"a,b,c": `"x\"y"`,
+}
+
+// TestExportSQL tests exporting a cases map as SQL INSERT statements.
+func TestExportSQL(t *testing.T) {
+	var b bytes.Buffer
+	if err := ExportSQL(&b, "keywords", "keyword", "token", exportCases); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `INSERT INTO keywords (keyword, token) VALUES ('a,b,c', 'x"y');
+INSERT INTO keywords (keyword, token) VALUES ('bar', 'two');
+INSERT INTO keywords (keyword, token) VALUES ('e', 2.718e0);
+INSERT INTO keywords (keyword, token) VALUES ('foo', 1);
+INSERT INTO keywords (keyword, token) VALUES ('hex', '0x1p-2');
+INSERT INTO keywords (keyword, token) VALUES ('inf', 'Inf');
+INSERT INTO keywords (keyword, token) VALUES ('it''s', 'Apostrophe');
+INSERT INTO keywords (keyword, token) VALUES ('nan', 'NaN');
+INSERT INTO keywords (keyword, token) VALUES ('pi', -3.14);
+INSERT INTO keywords (keyword, token) VALUES ('quux', 16);
+`
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+// TestExportCSV tests exporting a cases map as CSV.
+func TestExportCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := ExportCSV(&b, "keyword", "token", exportCases); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `keyword,token
+"a,b,c","x""y"
+bar,two
+e,2.718e0
+foo,1
+hex,0x1p-2
+inf,Inf
+it's,Apostrophe
+nan,NaN
+pi,-3.14
+quux,16
+`
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+// TestIsDecimalFloat tests which value expressions are exported as numbers.
+func TestIsDecimalFloat(t *testing.T) {
+	for s, expect := range map[string]bool{
+		"1.5":      true,
+		"-1.5":     true,
+		".5":       true,
+		"5.":       true,
+		"1e10":     true,
+		"1.5E-3":   true,
+		"":         false,
+		".":        false,
+		"-":        false,
+		"1e":       false,
+		"e10":      false,
+		"Inf":      false,
+		"infinity": false,
+		"NaN":      false,
+		"0x1p-2":   false,
+		"1.5x":     false,
+	} {
+		if got := isDecimalFloat(s); got != expect {
+			t.Errorf("expected %v for %q, got %v", expect, s, got)
+		}
+	}
+}
+
+// TestExportFlags tests that the export functions reject flags, since they
+// can't represent them.
+func TestExportFlags(t *testing.T) {
+	err := ExportSQL(ioutil.Discard, "keywords", "keyword", "token", exportCases, Insensitive)
+	if expect := `ExportSQL does not support flags: "Insensitive"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+	err = ExportCSV(ioutil.Discard, "keyword", "token", exportCases, HasPrefix, Ignore('-'))
+	if expect := `ExportCSV does not support flags: "HasPrefix" and "Ignore"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+}
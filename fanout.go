@@ -0,0 +1,157 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strconv"
+)
+
+// MaxFanOut returns a flag, which can be passed to Generate, to limit the
+// number of values in the case clauses of each switch statement which
+// examines a byte of the input.  (The Go compiler handles very large, sparse
+// switch statements poorly.)  Where the limit would be exceeded, such as when
+// many runes are Equivalent, the generated code instead looks up which case
+// to take in a 256-byte table.
+//
+// This only affects the switch statements which examine the input.  Use
+// AnalyzeFanOut to see how large the generated switch statements are.
+func MaxFanOut(n int) *Flag {
+	return &Flag{maxFanOut: n}
+}
+
+// fanOut returns the number of case values needed to list classes in a
+// switch statement, and the number of (non-empty) classes.
+func fanOut(classes [][]rune) (values, nonEmpty int) {
+	for _, class := range classes {
+		values += len(class)
+		if len(class) > 0 {
+			nonEmpty++
+		}
+	}
+	return
+}
+
+// useClassTable returns true if classes should be looked up using a table
+// (see writeClassTable), rather than listed in the case clauses, in order to
+// stay within limit.
+func useClassTable(classes [][]rune, limit int) bool {
+	values, nonEmpty := fanOut(classes)
+	return limit > 0 && values > limit && nonEmpty < values && nonEmpty < 256
+}
+
+// writeClassTable outputs a string constant mapping each byte to the number
+// (starting at 1) of the class containing it, or zero if it isn't in any of
+// the classes.  The returned function formats the case value to use for a
+// class.  Runes which aren't representable as a byte are omitted, since
+// they can't match a byte of the input anyway.
+func writeClassTable(w io.Writer, indent, name string, classes [][]rune) func([]rune) string {
+	table := make([]byte, 256)
+	nums := make(map[rune]string, len(classes))
+	n := 0
+	for _, class := range classes {
+		if len(class) == 0 {
+			continue
+		}
+		n++
+		nums[class[0]] = strconv.Itoa(n)
+		for _, r := range class {
+			if r >= 0 && r < 256 {
+				table[r] = byte(n)
+			}
+		}
+	}
+	writeLookupTable(w, indent, name, table)
+
+	return func(class []rune) string {
+		return nums[class[0]]
+	}
+}
+
+// FanOutReport describes the largest switch statements in the code output
+// by Generate.  It is returned by AnalyzeFanOut.
+type FanOutReport struct {
+	// Input is the largest number of case values in any switch statement
+	// which examines a byte of the input.  This is what MaxFanOut
+	// limits.
+	Input int
+
+	// State is the largest number of case values in any switch statement
+	// which examines the state accumulated while matching.  This is
+	// typically the number of keys of the same length.
+	State int
+}
+
+// String formats a FanOutReport.
+func (r *FanOutReport) String() string {
+	return fmt.Sprintf("largest switch on input: %d values; largest switch on state: %d values", r.Input, r.State)
+}
+
+// AnalyzeFanOut reports the size of the largest switch statements which
+// Generate would output for the same arguments.
+func AnalyzeFanOut(cases map[string]string, none string, flags ...*Flag) (*FanOutReport, error) {
+	var b bytes.Buffer
+	b.WriteString("package fastmatch\nfunc fastmatch(input string) {\n")
+	if err := Generate(&b, cases, none, internalFlags(flags)...); err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", b.Bytes(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(FanOutReport)
+	ast.Inspect(f, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.SwitchStmt)
+		if !ok {
+			return true
+		}
+		values := 0
+		for _, clause := range stmt.Body.List {
+			values += len(clause.(*ast.CaseClause).List)
+		}
+		switch tag := stmt.Tag.(type) {
+		case *ast.IndexExpr:
+			if values > r.Input {
+				r.Input = values
+			}
+		case *ast.Ident:
+			if tag.Name == "state" && values > r.State {
+				r.State = values
+			}
+		}
+		return true
+	})
+	return r, nil
+}
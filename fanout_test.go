@@ -0,0 +1,85 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"strings"
+	"testing"
+)
+
+// fanOutCases returns a key for each ASCII letter and digit.
+func fanOutCases() map[string]string {
+	cases := make(map[string]string)
+	for _, r := range Range('a', 'z', '0', '9') {
+		cases[string(r)+"00"] = "1"
+	}
+	return cases
+}
+
+// TestAnalyzeFanOut tests that AnalyzeFanOut reports the size of the
+// generated switch statements, and that MaxFanOut reduces it.
+func TestAnalyzeFanOut(t *testing.T) {
+	r, err := AnalyzeFanOut(fanOutCases(), "0", Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Input != 62 || r.State != 36 {
+		t.Errorf("unexpected report without MaxFanOut: %s", r)
+	}
+
+	r, err = AnalyzeFanOut(fanOutCases(), "0", Insensitive, MaxFanOut(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Input != 36 || r.State != 36 {
+		t.Errorf("unexpected report with MaxFanOut: %s", r)
+	}
+
+	// No table is needed if the limit isn't exceeded.
+	r, err = AnalyzeFanOut(fanOutCases(), "0", MaxFanOut(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Input != 36 {
+		t.Errorf("unexpected report when under MaxFanOut: %s", r)
+	}
+}
+
+// TestClassTable tests the table output by writeClassTable.
+func TestClassTable(t *testing.T) {
+	var b strings.Builder
+	caseRunes := writeClassTable(&b, "\t", "table", [][]rune{nil, {'a', 'A'}, {'b', 0x100}})
+	if caseRunes([]rune{'a', 'A'}) != "1" || caseRunes([]rune{'b', 0x100}) != "2" {
+		t.Error("wrong class numbers")
+	}
+	expect := `"\x00\x01\x02\x00`
+	if !strings.Contains(b.String(), expect) {
+		t.Errorf("expected table containing %s, got:\n%s", expect, b.String())
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package fastmatchtest provides helpers for testing code generated by the
+// pifke.org/fastmatch package.  These are intended for packages which wrap
+// the code generator (for example, to generate matchers from their own input
+// formats), so that their test suites can compile and run the generated code,
+// rather than only comparing it against expected output.
+//
+// A typical test looks like:
+//
+//	p, err := fastmatchtest.Runnable(t, fastmatchtest.Forward, "int", cases, "0", flags...)
+//	if p != nil {
+//		defer p.Close()
+//	}
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	p.Expect(t, "foo", "1")
+//
+// Each helper invokes the go command, so tests using them are slow, and
+// should generally be skipped when testing.Short reports true.  The helpers
+// don't change the working directory or environment of the calling process,
+// so tests using them can run in parallel.
+package fastmatchtest
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pifke.org/fastmatch"
+)
+
+// Direction is passed to Runnable to specify whether fastmatch.Generate or
+// fastmatch.GenerateReverse should be used to generate the matcher.
+type Direction bool
+
+const (
+	Forward Direction = true  // use Generate
+	Reverse Direction = false // use GenerateReverse
+)
+
+// Program is a runnable Go program, written to a temporary directory by
+// Runnable or NewProgram.  Close should be called when the caller is done
+// with it.
+type Program struct {
+	dir string
+}
+
+// NewProgram creates a temporary module directory, and writes a runnable
+// program named generated.go therein.  The package clause and import block
+// are written by this function; body is responsible for outputting
+// everything else, including func main().  The program should print its
+// result to standard output, which is what Expect compares.  Only the
+// standard library can be imported.
+func NewProgram(imports []string, body func(io.Writer) error) (*Program, error) {
+	dir, err := ioutil.TempDir("", "fastmatchtest")
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{dir: dir}
+
+	err = ioutil.WriteFile(p.path("go.mod"), goMod(), 0644)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	out, err := os.Create(p.path("generated.go"))
+	if err == nil {
+		err = writeProgram(out, imports, body)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// goMod returns the contents of the go.mod file for a temporary module.  The
+// go directive names the release of the toolchain running the test, so that
+// generated code can use any language feature it supports.
+func goMod() []byte {
+	mod := "module fastmatchtest\n"
+	if tags := build.Default.ReleaseTags; len(tags) > 0 {
+		mod += fmt.Sprintf("\ngo %s\n", strings.TrimPrefix(tags[len(tags)-1], "go"))
+	}
+	return []byte(mod)
+}
+
+// path returns the absolute path to the named file in the program's
+// directory.
+func (p *Program) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+// command returns a go command, with the supplied arguments, which runs in
+// the program's directory.
+func (p *Program) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = p.dir
+	return cmd
+}
+
+// writeProgram implements NewProgram.
+func writeProgram(w io.Writer, imports []string, body func(io.Writer) error) error {
+	if _, err := fmt.Fprintln(w, "package main"); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "import (")
+	for _, imp := range imports {
+		fmt.Fprintf(w, "\t%q\n", imp)
+	}
+	fmt.Fprintln(w, ")")
+	fmt.Fprintln(w)
+	return body(w)
+}
+
+// Runnable uses Generate or GenerateReverse to create a program containing a
+// function named match, which takes a string and returns retType.  The
+// program calls match with its first argument, and prints the result.
+//
+// fastmatch.GenerateTest is also used to generate the automated self-test,
+// which is run with go test.  Failures are reported to t, however are
+// non-fatal.
+//
+// If an error is returned, the Program has already been closed, and nil is
+// returned in its place.
+func Runnable(t testing.TB, which Direction, retType string, cases map[string]string, none string, flags ...*fastmatch.Flag) (*Program, error) {
+	p, err := NewProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		if _, err := fmt.Fprintln(w, "func match(input string)", retType, "{"); err != nil {
+			return err
+		}
+		var err error
+		if which == Forward {
+			err = fastmatch.Generate(w, cases, none, flags...)
+		} else {
+			err = fastmatch.GenerateReverse(w, cases, none, flags...)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors generating or running the automated tests are recorded, but
+	// are not fatal.
+	if err := p.selfTest(which, cases, flags); err != nil {
+		t.Errorf("self-test: %s", err)
+	}
+	return p, nil
+}
+
+// selfTest writes the output of GenerateTest to generated_test.go, and runs
+// it.
+func (p *Program) selfTest(which Direction, cases map[string]string, flags []*fastmatch.Flag) error {
+	fwd, rev := "match(%q)", ""
+	if which == Reverse {
+		fwd, rev = "", "match(%s)"
+	}
+
+	out, err := os.Create(p.path("generated_test.go"))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, "package main")
+	if err == nil {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "import \"testing\"")
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "func TestMatch(t *testing.T) {")
+		err = fastmatch.GenerateTest(out, fwd, rev, cases, flags...)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if result, err := p.command("test").CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(result)))
+	}
+	return nil
+}
+
+// Dir returns the temporary directory containing the program.
+func (p *Program) Dir() string {
+	return p.dir
+}
+
+// Run uses go run to execute the program, passing input as its argument.
+// The output is returned with leading and trailing whitespace removed.
+func (p *Program) Run(input string) (string, error) {
+	out, err := p.command("run", p.path("generated.go"), input).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Expect runs the program with the provided input, and reports an error to
+// t if the output differs from expect.  Failing to run the program is fatal.
+func (p *Program) Expect(t testing.TB, input, expect string) {
+	out, err := p.Run(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != expect {
+		t.Errorf("expected %q, got %q for input %q", expect, out, input)
+	}
+}
+
+// Close removes the temporary directory.
+func (p *Program) Close() error {
+	return os.RemoveAll(p.dir)
+}
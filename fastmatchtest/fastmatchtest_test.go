@@ -0,0 +1,149 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatchtest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pifke.org/fastmatch"
+)
+
+// TestRunnable tests generating and running a matcher.
+func TestRunnable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	t.Parallel()
+
+	p, err := Runnable(t, Forward, "int", map[string]string{
+		"foo": "1",
+		"bar": "2",
+	}, "0", fastmatch.Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	p.Expect(t, "FOO", "1")
+	p.Expect(t, "bar", "2")
+	p.Expect(t, "baz", "0")
+}
+
+// TestRunnableReverse tests generating and running a reverse matcher.
+func TestRunnableReverse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	t.Parallel()
+
+	p, err := Runnable(t, Reverse, "string", map[string]string{
+		"foo": `"1"`,
+		"bar": `"2"`,
+	}, `"baz"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	p.Expect(t, "1", "foo")
+	p.Expect(t, "2", "bar")
+	p.Expect(t, "0", "baz")
+}
+
+// TestRunnableError tests that generation errors are returned.
+func TestRunnableError(t *testing.T) {
+	p, err := Runnable(t, Forward, "int", map[string]string{"a": "1", "A": "2"}, "0", fastmatch.Insensitive)
+	if err == nil {
+		p.Close()
+		t.Fatal("expected error for ambiguous cases")
+	}
+	if p != nil {
+		t.Error("expected nil Program on error")
+	}
+}
+
+// TestHermetic tests that the working directory and GOPATH are not changed,
+// so that tests can run in parallel.
+func TestHermetic(t *testing.T) {
+	wd, _ := os.Getwd()
+	gopath := os.Getenv("GOPATH")
+
+	p, err := NewProgram(nil, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "func main() {}")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("working directory changed from %q to %q", wd, now)
+	}
+	if now := os.Getenv("GOPATH"); now != gopath {
+		t.Errorf("GOPATH changed from %q to %q", gopath, now)
+	}
+	if !filepath.IsAbs(p.Dir()) {
+		t.Errorf("expected absolute path, got %q", p.Dir())
+	}
+	if _, err := os.Stat(filepath.Join(p.Dir(), "go.mod")); err != nil {
+		t.Error(err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p.Dir()); !os.IsNotExist(err) {
+		t.Errorf("expected %q to be removed", p.Dir())
+	}
+}
+
+// TestNewProgram tests a program with a caller-supplied body.
+func TestNewProgram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	t.Parallel()
+
+	p, err := NewProgram([]string{"fmt", "os", "strings"}, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "func main() { fmt.Println(strings.ToUpper(os.Args[1])) }")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err := os.Stat(p.Dir()); err != nil {
+		t.Fatal(err)
+	}
+	p.Expect(t, "abc", "ABC")
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldTag is the struct tag key read by GenerateFieldSetter.
+const FieldTag = "fastmatch"
+
+// GenerateFieldSetter outputs Go code for a function named fn, which assigns
+// a value to the field of a struct named by a key, such as when decoding
+// HTTP headers or configuration files into a struct.  The keys are taken
+// from the fastmatch tags on the fields of v, which must be a struct or a
+// pointer to one:
+//
+//	type Request struct {
+//		ContentLength int64  `fastmatch:"content-length"`
+//		Host          string `fastmatch:"host"`
+//		Accept        string `fastmatch:"accept,accept-encoding"`
+//	}
+//
+//	fastmatch.GenerateFieldSetter(w, "setRequestField", "", Request{},
+//		fastmatch.Insensitive)
+//
+// A tag may list several keys, separated by commas, which all set the same
+// field.  Fields without a tag, or with a tag of "-", are skipped.  The
+// generated function has the signature:
+//
+//	func fn(dst *typ, key, value string) (bool, error)
+//
+// It returns false if key doesn't name a field, in which case dst is
+// unchanged.  typ is the Go expression for the struct's type in the
+// generated code; if it is "", the name of v's type is used, unqualified by
+// its package name.
+//
+// Fields must be exported, and their underlying type must be a boolean,
+// numeric, or string type.  Values for fields which are not strings are
+// parsed with the strconv package, which the caller is responsible for
+// importing, and any error from doing so is returned.
+//
+// Keys are matched by a closure output by Generate, so the flags supported
+// by Generate, such as Insensitive, apply to the keys.  Flags which refer to
+// the values or the signature, such as ValueType, TooShort, TooLong, and
+// Params, are not supported, nor is Coverage.
+func GenerateFieldSetter(w io.Writer, fn, typ string, v interface{}, flags ...*Flag) error {
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.valueType != "" || flag.tooShort != "" || flag.tooLong != "" || flag.params != "" || flag.coverage != nil {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateFieldSetter"}
+	}
+
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot generate field setter for %v, which is not a struct", t)
+	}
+	if typ == "" {
+		typ = t.Name()
+		if typ == "" {
+			return fmt.Errorf("cannot derive type name for unnamed type %s", t)
+		}
+	}
+
+	// The closure matching keys returns the index (starting at one) of
+	// the field to set.
+	var fields []reflect.StructField
+	cases := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(FieldTag)
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf("field %s is not exported", field.Name)
+		}
+		if _, err := parseFieldExpr(field.Type); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err.Error())
+		}
+		fields = append(fields, field)
+		for _, key := range strings.Split(tag, ",") {
+			if key == "" {
+				return ErrEmptyKey
+			}
+			if other, found := cases[key]; found {
+				n, _ := strconv.Atoi(other)
+				return fmt.Errorf("key %s is used by fields %s and %s",
+					strconv.Quote(key), fields[n-1].Name, field.Name)
+			}
+			cases[key] = strconv.Itoa(len(fields))
+		}
+	}
+	if len(fields) == 0 {
+		return errors.New("no fields have a " + FieldTag + " tag")
+	}
+
+	keys := make([][]string, len(fields))
+	for key, value := range cases {
+		n, _ := strconv.Atoi(value)
+		keys[n-1] = append(keys[n-1], key)
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(dst *%s, key, value string) (bool, error) {", fn, typ); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tfastmatchField := func(input string) int {")
+	if err := Generate(w, cases, "0", internalFlags(flags)...); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\tswitch fastmatchField(key) {")
+	for n, field := range fields {
+		sort.Strings(keys[n])
+		quoted := make([]string, len(keys[n]))
+		for i, key := range keys[n] {
+			quoted[i] = strconv.Quote(key)
+		}
+		fmt.Fprintf(w, "\tcase %d: // %s", n+1, strings.Join(quoted, ", "))
+		fmt.Fprintln(w)
+		writeFieldAssignment(w, field, t.PkgPath())
+	}
+	fmt.Fprintln(w, "\tdefault:")
+	fmt.Fprintln(w, "\t\treturn false, nil")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn true, nil")
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// parseFieldExpr returns the strconv call which parses value into a field of
+// type t, or "" if t is a string type and no parsing is needed.
+func parseFieldExpr(t reflect.Type) (string, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return "", nil
+	case reflect.Bool:
+		return "strconv.ParseBool(value)", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("strconv.ParseInt(value, 10, %d)", intBits(t)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprintf("strconv.ParseUint(value, 10, %d)", intBits(t)), nil
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("strconv.ParseFloat(value, %d)", t.Bits()), nil
+	}
+	return "", fmt.Errorf("cannot set %s, which is not a boolean, numeric, or string type", t)
+}
+
+// intBits returns the bitSize argument to strconv.ParseInt or ParseUint for
+// an integer type.  Types whose size depends on the platform use 0, meaning
+// the size of int.
+func intBits(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint, reflect.Uintptr:
+		return 0
+	}
+	return t.Bits()
+}
+
+// fieldTypeExpr returns the name of type t in generated code belonging to
+// package pkgPath.
+func fieldTypeExpr(t reflect.Type, pkgPath string) string {
+	if t.Name() != "" && t.PkgPath() == pkgPath {
+		return t.Name()
+	}
+	return t.String()
+}
+
+// writeFieldAssignment outputs the body of the case which sets field.
+func writeFieldAssignment(w io.Writer, field reflect.StructField, pkgPath string) {
+	parse, _ := parseFieldExpr(field.Type)
+	typ := fieldTypeExpr(field.Type, pkgPath)
+	if parse == "" {
+		if typ == "string" {
+			fmt.Fprintf(w, "\t\tdst.%s = value", field.Name)
+		} else {
+			fmt.Fprintf(w, "\t\tdst.%s = %s(value)", field.Name, typ)
+		}
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintf(w, "\t\tv, err := %s", parse)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\tif err != nil {")
+	fmt.Fprintln(w, "\t\t\treturn true, err")
+	fmt.Fprintln(w, "\t\t}")
+	if typ == field.Type.Kind().String() && (field.Type.Kind() == reflect.Bool ||
+		field.Type.Kind() == reflect.Int64 || field.Type.Kind() == reflect.Uint64 ||
+		field.Type.Kind() == reflect.Float64) {
+		fmt.Fprintf(w, "\t\tdst.%s = v", field.Name)
+	} else {
+		fmt.Fprintf(w, "\t\tdst.%s = %s(v)", field.Name, typ)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// fieldsTestStruct is the struct whose tags TestFieldSetter generates a
+// setter for.  Its declaration is repeated in the generated program.
+type fieldsTestStruct struct {
+	Host    string  `fastmatch:"host"`
+	Length  int64   `fastmatch:"content-length,length"`
+	Retries uint8   `fastmatch:"retries"`
+	Ratio   float32 `fastmatch:"ratio"`
+	Debug   bool    `fastmatch:"debug"`
+	Other   string  `fastmatch:"-"`
+	Ignored string
+}
+
+// TestFieldSetter tests setting fields of a struct by their tags.
+func TestFieldSetter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strconv", "strings"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type fieldsTestStruct struct {")
+		fmt.Fprintln(w, "\tHost    string")
+		fmt.Fprintln(w, "\tLength  int64")
+		fmt.Fprintln(w, "\tRetries uint8")
+		fmt.Fprintln(w, "\tRatio   float32")
+		fmt.Fprintln(w, "\tDebug   bool")
+		fmt.Fprintln(w, "\tOther   string")
+		fmt.Fprintln(w, "\tIgnored string")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w)
+		if err := GenerateFieldSetter(w, "set", "", fieldsTestStruct{}, Insensitive); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tvar dst fieldsTestStruct")
+		fmt.Fprintln(w, "\tkv := strings.SplitN(os.Args[1], \"=\", 2)")
+		fmt.Fprintln(w, "\tfound, err := set(&dst, kv[0], kv[1])")
+		fmt.Fprintln(w, "\tfmt.Printf(\"%t %v %+v\", found, err != nil, dst)")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for input, expect := range map[string]string{
+		"host=example.com": "true false {Host:example.com Length:0 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+		"Content-Length=5": "true false {Host: Length:5 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+		"length=-7":        "true false {Host: Length:-7 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+		"retries=3":        "true false {Host: Length:0 Retries:3 Ratio:0 Debug:false Other: Ignored:}",
+		"retries=300":      "true true {Host: Length:0 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+		"ratio=0.5":        "true false {Host: Length:0 Retries:0 Ratio:0.5 Debug:false Other: Ignored:}",
+		"DEBUG=true":       "true false {Host: Length:0 Retries:0 Ratio:0 Debug:true Other: Ignored:}",
+		"other=x":          "false false {Host: Length:0 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+		"ignored=x":        "false false {Host: Length:0 Retries:0 Ratio:0 Debug:false Other: Ignored:}",
+	} {
+		expectMatch(t, input, expect)
+	}
+}
+
+// TestFieldSetterErrors tests invalid structs and unsupported flags.
+func TestFieldSetterErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		v      interface{}
+		flags  []*Flag
+		expect string
+	}{
+		{"host", nil, "not a struct"},
+		{struct {
+			Host string `fastmatch:"host"`
+		}{}, nil, "unnamed type"},
+		{fieldsTestStruct{}, []*Flag{Params("x int")}, `GenerateFieldSetter does not support flags: "Params"`},
+	} {
+		err := GenerateFieldSetter(ioutil.Discard, "set", "", testCase.v, testCase.flags...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+
+	type unexported struct {
+		host string `fastmatch:"host"`
+	}
+	type badKind struct {
+		Hosts []string `fastmatch:"host"`
+	}
+	type untagged struct {
+		Host string
+	}
+	type duplicate struct {
+		Host  string `fastmatch:"host"`
+		Other string `fastmatch:"other,host"`
+	}
+	for v, expect := range map[interface{}]string{
+		unexported{}: "field host is not exported",
+		&badKind{}:   "field Hosts: cannot set []string",
+		untagged{}:   "no fields have a fastmatch tag",
+		duplicate{}:  `key "host" is used by fields Host and Other`,
+	} {
+		err := GenerateFieldSetter(ioutil.Discard, "set", "T", v)
+		if err == nil || !strings.Contains(err.Error(), expect) {
+			t.Errorf("expected error containing %q, got %v", expect, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+)
+
+// FileSpec describes a Go source file containing a single matcher, for
+// GenerateFile.
+type FileSpec struct {
+	// Package is the name of the package in the package clause.
+	Package string
+
+	// FuncName is the name of the generated function.
+	FuncName string
+
+	// InputType is the type of the function's input parameter: "string"
+	// (the default, used if this is empty), or "[]byte" (see
+	// GenerateBytes).
+	InputType string
+
+	// ReturnType is the type of the function's return value.
+	ReturnType string
+
+	// Cases, None, and Flags are passed to Generate.
+	Cases map[string]string
+	None  string
+	Flags []*Flag
+
+	// Imports lists any packages referenced by the values in Cases, by
+	// None, or by Params.  Those required by Flags are imported
+	// automatically (see Imports).
+	Imports []string
+}
+
+// GenerateFile outputs a complete Go source file, consisting of a package
+// clause, imports, and a function generated as described by spec.  Unlike
+// Generate, the caller does not write anything themselves.  The file is
+// formatted with go/format, and nothing is written to w unless it is
+// syntactically valid.
+//
+// The file begins with a comment marking it as generated, so that it is
+// ignored by tools such as golint:
+//
+//	// Code generated by fastmatch. DO NOT EDIT.
+func GenerateFile(w io.Writer, spec FileSpec) error {
+	if spec.Package == "" || spec.FuncName == "" || spec.ReturnType == "" {
+		return errors.New("FileSpec requires Package, FuncName, and ReturnType")
+	}
+
+	seen := make(map[string]bool)
+	var imports []string
+	for _, path := range append(Imports(spec.Flags...), spec.Imports...) {
+		if !seen[path] {
+			imports = append(imports, path)
+			seen[path] = true
+		}
+	}
+	sort.Strings(imports)
+
+	var b bytes.Buffer
+	writeFileHeader(&b, spec.Package, imports)
+	fmt.Fprintln(&b)
+
+	switch spec.InputType {
+	case "", "string":
+		fs, err := parseFlags(spec.Flags...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "func %s(input string%s) %s {\n", spec.FuncName, fs.extraParams(), spec.ReturnType)
+		if err := Generate(&b, spec.Cases, spec.None, spec.Flags...); err != nil {
+			return err
+		}
+	case "[]byte":
+		if err := GenerateBytes(&b, spec.FuncName, spec.ReturnType, spec.Cases, spec.None, spec.Flags...); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported InputType %q", spec.InputType)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code is invalid: %s", err.Error())
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writeFileHeader outputs the start of a generated file, up to and including
+// the imports.
+func writeFileHeader(w io.Writer, pkg string, imports []string) {
+	fmt.Fprintln(w, "// Code generated by fastmatch. DO NOT EDIT.")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "package %s\n", pkg)
+	if len(imports) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "import (")
+		for _, path := range imports {
+			fmt.Fprintf(w, "\t%q\n", path)
+		}
+		fmt.Fprintln(w, ")")
+	}
+}
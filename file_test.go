@@ -0,0 +1,115 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"testing"
+)
+
+// TestGenerateFile tests that GenerateFile outputs a complete, formatted
+// source file.
+func TestGenerateFile(t *testing.T) {
+	for _, spec := range []FileSpec{
+		{
+			Package:    "lookup",
+			FuncName:   "matchColor",
+			ReturnType: "color.Color",
+			Cases:      map[string]string{"red": "color.RGBA{R: 255}", "black": "color.Black"},
+			None:       "nil",
+			Flags:      []*Flag{Insensitive, Params("def color.Color")},
+			Imports:    []string{"image/color"},
+		},
+		{
+			Package:    "lookup",
+			FuncName:   "matchBytes",
+			InputType:  "[]byte",
+			ReturnType: "int",
+			Cases:      map[string]string{"foo": "1", "bar": "2"},
+			None:       "0",
+		},
+	} {
+		var b bytes.Buffer
+		if err := GenerateFile(&b, spec); err != nil {
+			t.Fatalf("%s: %s", spec.FuncName, err)
+		}
+		src := b.String()
+		if formatted, err := format.Source(b.Bytes()); err != nil || string(formatted) != src {
+			t.Errorf("%s: output is not formatted (%v):\n%s", spec.FuncName, err, src)
+		}
+		inputType := spec.InputType
+		if inputType == "" {
+			inputType = "string"
+		}
+		for _, expect := range []string{
+			"// Code generated by fastmatch. DO NOT EDIT.\n",
+			"\npackage lookup\n",
+			"func " + spec.FuncName + "(input " + inputType,
+		} {
+			if !strings.Contains(src, expect) {
+				t.Errorf("%s: expected %q in output:\n%s", spec.FuncName, expect, src)
+			}
+		}
+		for _, path := range spec.Imports {
+			if !strings.Contains(src, `"`+path+`"`) {
+				t.Errorf("%s: expected import of %s:\n%s", spec.FuncName, path, src)
+			}
+		}
+	}
+}
+
+// TestGenerateFileErrors tests that nothing is written if the file can't be
+// generated.
+func TestGenerateFileErrors(t *testing.T) {
+	good := FileSpec{
+		Package:    "lookup",
+		FuncName:   "match",
+		ReturnType: "int",
+		Cases:      map[string]string{"foo": "1", "FOO": "2"},
+		None:       "0",
+	}
+	for name, modify := range map[string]func(*FileSpec){
+		"no package":     func(spec *FileSpec) { spec.Package = "" },
+		"bad input type": func(spec *FileSpec) { spec.InputType = "rune" },
+		"invalid syntax": func(spec *FileSpec) { spec.None = "0 +" },
+		"ambiguous":      func(spec *FileSpec) { spec.Flags = []*Flag{Insensitive} },
+	} {
+		spec := good
+		modify(&spec)
+		var b bytes.Buffer
+		if err := GenerateFile(&b, spec); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if b.Len() > 0 {
+			t.Errorf("%s: expected no output, got:\n%s", name, b.String())
+		}
+	}
+}
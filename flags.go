@@ -30,15 +30,38 @@ package fastmatch
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"sort"
 	"strconv"
+	"unicode"
 )
 
 // ErrBadFlags is returned when nonsensical flags are passed to Generate.
 type ErrBadFlags struct {
 	cannotCombine    []string
 	cannotStopIgnore sortableRunes
+	badGoVersion     string
+	badPlaceholder   rune
+	badPreprocess    string
+
+	// tooManyEquivalents is an equivalence set larger than
+	// maxEquivalents.
+	tooManyEquivalents sortableRunes
+
+	// unsupported lists flags which are not supported by the function
+	// named in unsupportedBy.
+	unsupported   []string
+	unsupportedBy string
+
+	// requires is a flag, and another flag which it can only be used
+	// with.
+	requires [2]string
+
+	// badMaxState is true if MaxState was passed a value less than
+	// MinMaxState.
+	badMaxState bool
 }
 
 // writeListSeparator outputs a list separator between items in a list.
@@ -85,16 +108,669 @@ func (e *ErrBadFlags) Error() string {
 		b.WriteString(strconv.QuoteRune(r))
 	}
 
+	if e.badGoVersion != "" {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString("invalid TargetGoVersion: ")
+		b.WriteString(strconv.Quote(e.badGoVersion))
+	}
+
+	if e.badPlaceholder != 0 {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString("invalid AnyDigit placeholder: ")
+		b.WriteString(strconv.QuoteRune(e.badPlaceholder))
+	}
+
+	if e.badPreprocess != "" {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString("invalid Preprocess code: ")
+		b.WriteString(e.badPreprocess)
+	}
+
+	if len(e.tooManyEquivalents) > 0 {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(b, "Equivalent set of %d runes (starting with %s) exceeds the maximum of %d; use NormalizeInput to fold large character classes instead",
+			len(e.tooManyEquivalents), strconv.QuoteRune(e.tooManyEquivalents[0]), maxEquivalents)
+	}
+
+	if e.badMaxState {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(b, "MaxState must be at least %d", MinMaxState)
+	}
+
+	if e.requires[0] != "" {
+		if b.Len() != 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(b, "flag %s requires %s", strconv.Quote(e.requires[0]), strconv.Quote(e.requires[1]))
+	}
+
+	sort.Strings(e.unsupported)
+	for n, key := range e.unsupported {
+		if n == 0 {
+			if b.Len() != 0 {
+				b.WriteString("; ")
+			}
+			b.WriteString(e.unsupportedBy)
+			b.WriteString(" does not support flags: ")
+		} else {
+			writeListSeparator(b, n, len(e.unsupported)-1)
+		}
+		b.WriteString(strconv.Quote(key))
+	}
+
 	return b.String()
 }
 
+// flagName returns the name of a Flag, for use in error messages.
+func flagName(flag *Flag) string {
+	switch flag {
+	case Insensitive:
+		return "Insensitive"
+	case InsensitiveUnicode:
+		return "InsensitiveUnicode"
+	case InsensitiveTurkish:
+		return "InsensitiveTurkish"
+	case Normalize:
+		return "Normalize"
+	case HasPrefix:
+		return "HasPrefix"
+	case HasSuffix:
+		return "HasSuffix"
+	case NoTrailingMark:
+		return "NoTrailingMark"
+	case ConstantTime:
+		return "ConstantTime"
+	case LongestMatch:
+		return "LongestMatch"
+	case LargeLookupTable:
+		return "LargeLookupTable"
+	case ZeroAllocs:
+		return "ZeroAllocs"
+	case Inline:
+		return "Inline"
+	case EnumConstants:
+		return "EnumConstants"
+	case IgnorePlural:
+		return "IgnorePlural"
+	case ChompLine:
+		return "ChompLine"
+	case FoldAccents:
+		return "FoldAccents"
+	case IdentifierCase:
+		return "IdentifierCase"
+	case ReversePrefix:
+		return "ReversePrefix"
+	case NoImports:
+		return "NoImports"
+	case ASCIIOnly:
+		return "ASCIIOnly"
+	case TrustLength:
+		return "TrustLength"
+	case DenseStates:
+		return "DenseStates"
+	case BufferOutput:
+		return "BufferOutput"
+	case FoldEquivalents:
+		return "FoldEquivalents"
+	case NULTerminated:
+		return "NULTerminated"
+	}
+	switch {
+	case len(flag.equivalent) > 0:
+		return "Equivalent"
+	case len(flag.stop) > 0:
+		return "StopUpon"
+	case len(flag.ignore) > 0:
+		return "Ignore"
+	case len(flag.ignoreExcept) > 0:
+		return "IgnoreExcept"
+	case flag.normalizeImport != "":
+		return "NFC"
+	case flag.normalizeFunc != nil:
+		return "NormalizeInput"
+	case flag.coverage != nil:
+		return "Coverage"
+	case flag.goVersion != "":
+		return "TargetGoVersion"
+	case flag.strategy != AutoStrategy:
+		return "Strategy"
+	case flag.anyDigit != 0:
+		return "AnyDigit"
+	case flag.maxFanOut != 0:
+		return "MaxFanOut"
+	case flag.maxStateSet:
+		return "MaxState"
+	case flag.maxDepth != 0:
+		return "MaxDepth"
+	case flag.valueType != "":
+		return "ValueType"
+	case flag.sentinel != "":
+		return "EnumSentinel"
+	case flag.tooShort != "":
+		return "TooShort"
+	case flag.tooLong != "":
+		return "TooLong"
+	case flag.params != "":
+		return "Params"
+	case flag.preprocess != "":
+		return "Preprocess"
+	case flag.ctx != nil:
+		return "GenerateContext"
+	case flag.streamSize != 0:
+		return "StreamOutput"
+	case flag.onProgress != nil:
+		return "OnProgress"
+	case flag.cacheDir != "":
+		return "CacheDir"
+	case flag.comparer != "":
+		return "Comparer"
+	case flag.chain != "":
+		return "Chain"
+	case len(flag.exactOnly) > 0:
+		return "ExactOnly"
+	case flag.result != nil:
+		return "Report"
+	case flag.maxOutput != 0:
+		return "MaxOutputBytes"
+	case flag.transform != nil:
+		return "TransformKeys"
+	case flag.helpers != nil:
+		return "Generator"
+	}
+	return "unknown"
+}
+
 // Flag can be passed to Generate and GenerateReverse to modify the functions'
 // behavior.  Users of this package should not instantiate their own Flags.
-// Rather, they should use one of HasPrefix, HasSuffix, Insensitive,
-// Normalize, or the return value from Equivalent(), StopUpon(), Ignore(), or
-// IgnoreExcept().  Unknown Flags are silently discarded.
+// Rather, they should use one of the predefined Flags (such as HasPrefix or
+// Insensitive), or the return value from one of the functions which returns
+// a Flag (such as Equivalent or StopUpon).  Unknown Flags are silently
+// discarded.
 type Flag struct {
 	equivalent, stop, ignore, ignoreExcept []rune
+
+	// normalizeExpr is the function applied to the input by the
+	// generated code; normalizeFunc is applied to each key at generation
+	// time.  normalizeImport is the package (if any) which needs to be
+	// imported by the generated code.
+	normalizeExpr, normalizeImport string
+	normalizeFunc                  func(string) string
+
+	// coverage is where the Coverage flag records line numbers.
+	coverage *CoverageManifest
+
+	goVersion string
+	strategy  MatchStrategy
+	anyDigit  rune
+	maxFanOut int
+	maxDepth  int
+
+	// maxState is from MaxState.  maxStateSet distinguishes MaxState(0)
+	// from other flags, so that it can be rejected.
+	maxState    uint64
+	maxStateSet bool
+
+	// valueType and valueDecls are from ValueType.
+	valueType  string
+	valueDecls []string
+
+	sentinel string
+	params   string
+
+	// tooShort and tooLong are from TooShort and TooLong.
+	tooShort, tooLong string
+
+	preprocess string
+
+	// ctx is from GenerateContext.
+	ctx context.Context
+
+	// streamSize and progress are from StreamOutput.
+	streamSize int
+	progress   func(int64)
+
+	onProgress func(Progress)
+	cacheDir   string
+	comparer   string
+	chain      string
+	exactOnly  []string
+	result     *Result
+	maxOutput  int64
+	transform  func(string) string
+
+	// helpers is set by Generator.
+	helpers *helperSet
+}
+
+// flagSet is the parsed representation of a list of Flags.
+type flagSet struct {
+	equiv                      runeEquivalents
+	stop, ignore, ignoreExcept []rune // expanded to include equivalents
+	normalize                  []*Flag
+
+	partialMatch, backwards      bool // HasPrefix or HasSuffix
+	noTrailingMark, constantTime bool
+	largeLookupTable, inline     bool
+	strategy                     MatchStrategy
+
+	coverage  *CoverageManifest
+	goMinor   int    // from TargetGoVersion; 0 if not specified
+	maxFanOut int    // from MaxFanOut; 0 if not specified
+	maxDepth  int    // from MaxDepth; 0 if not specified
+	maxState  uint64 // from MaxState; 0 if not specified
+
+	valueType  string
+	valueDecls []string
+	params     string // from Params
+	ctx        context.Context
+	streamSize int // from StreamOutput; 0 if not specified
+	progress   func(int64)
+	onProgress func(Progress)
+	cacheDir   string // from CacheDir
+	comparer   string // from Comparer
+	noImports  bool
+	asciiOnly  bool
+	trustLen   bool // from TrustLength
+
+	denseStates     bool
+	foldEquivalents bool
+	buffered        bool // from BufferOutput
+
+	// caseFold is set by InsensitiveUnicode and InsensitiveTurkish, until
+	// foldCase has been called.  turkish is set by the latter.
+	caseFold, turkish bool
+
+	// exactOnly is the keys from ExactOnly.
+	exactOnly []string
+
+	// result is from Report.
+	result *Result
+
+	// maxOutput is from MaxOutputBytes, or 0 if there is no limit.
+	// capped is the writer which enforces it, set by Generate.
+	maxOutput int64
+	capped    *SizeCappedWriter
+
+	// transforms is the function from each TransformKeys flag, in order.
+	// Once applied, the original keys are recorded in keyOrigins.
+	transforms []func(string) string
+	keyOrigins map[string]string
+
+	helpers *helperSet // from Generator
+
+	// chain is the function from Chain, and chainCheck is the call to
+	// it checked by ValueType.
+	chain, chainCheck string
+
+	// tooShort and tooLong are the expressions from TooShort and
+	// TooLong, or "" if not specified.
+	tooShort, tooLong string
+
+	// preprocess is the code from each Preprocess flag, in order.
+	preprocess []string
+
+	// coverOffset is the number of lines output by generateCases (such
+	// as for ASCIIOnly) before the code which records coverage.
+	coverOffset int
+
+	// anyDigit is the placeholder rune from AnyDigit, and digitPatterns
+	// maps each key it was expanded into back to the original key.
+	anyDigit      rune
+	digitPatterns map[string]string
+}
+
+// parseFlags validates a list of Flags and converts them to a flagSet.
+func parseFlags(flags ...*Flag) (*flagSet, error) {
+	// Check the size of each Equivalent set before building the
+	// equivalence map, since doing so is quadratic.  The combined sets
+	// are checked again afterwards.
+	for _, flag := range flags {
+		if len(flag.equivalent) > maxEquivalents {
+			rs := append(sortableRunes(nil), flag.equivalent...)
+			sort.Sort(rs)
+			return nil, &ErrBadFlags{tooManyEquivalents: rs}
+		}
+	}
+	fs := &flagSet{equiv: makeEquivalents(flags...), ctx: context.Background()}
+	for _, rs := range fs.equiv {
+		if len(rs) > maxEquivalents {
+			return nil, &ErrBadFlags{tooManyEquivalents: rs}
+		}
+	}
+
+	for _, flag := range flags {
+		if flag == NoTrailingMark {
+			fs.noTrailingMark = true
+		} else if flag == ConstantTime {
+			fs.constantTime = true
+		} else if flag == LargeLookupTable {
+			fs.largeLookupTable = true
+		} else if flag == Inline {
+			fs.inline = true
+		} else if flag == NoImports {
+			fs.noImports = true
+		} else if flag == ASCIIOnly {
+			fs.asciiOnly = true
+		} else if flag == TrustLength {
+			fs.trustLen = true
+		} else if flag == DenseStates {
+			fs.denseStates = true
+		} else if flag == FoldEquivalents {
+			fs.foldEquivalents = true
+		} else if flag == BufferOutput {
+			fs.buffered = true
+		} else if flag == InsensitiveUnicode {
+			fs.caseFold = true
+		} else if flag == InsensitiveTurkish {
+			fs.caseFold, fs.turkish = true, true
+		}
+		if flag.normalizeFunc != nil {
+			fs.normalize = append(fs.normalize, flag)
+		}
+		if flag.coverage != nil {
+			fs.coverage = flag.coverage
+		}
+		if flag.strategy != AutoStrategy {
+			fs.strategy = flag.strategy
+		}
+		if flag.goVersion != "" {
+			minor, ok := parseGoVersion(flag.goVersion)
+			if !ok {
+				return nil, &ErrBadFlags{badGoVersion: flag.goVersion}
+			}
+			fs.goMinor = minor
+		}
+		if flag.params != "" {
+			fs.params = flag.params
+		}
+		if flag.tooShort != "" {
+			fs.tooShort = flag.tooShort
+		}
+		if flag.tooLong != "" {
+			fs.tooLong = flag.tooLong
+		}
+		if flag.preprocess != "" {
+			if err := checkPreprocess(flag.preprocess); err != nil {
+				return nil, err
+			}
+			fs.preprocess = append(fs.preprocess, flag.preprocess)
+		}
+		if flag.ctx != nil {
+			fs.ctx = flag.ctx
+		}
+		if flag.streamSize != 0 {
+			fs.streamSize, fs.progress = flag.streamSize, flag.progress
+		}
+		if flag.onProgress != nil {
+			fs.onProgress = flag.onProgress
+		}
+		if flag.cacheDir != "" {
+			fs.cacheDir = flag.cacheDir
+		}
+		if flag.comparer != "" {
+			fs.comparer = flag.comparer
+		}
+		if flag.chain != "" {
+			fs.chain = flag.chain
+		}
+		if len(flag.exactOnly) > 0 {
+			fs.exactOnly = append(fs.exactOnly, flag.exactOnly...)
+		}
+		if flag.result != nil {
+			fs.result = flag.result
+		}
+		if flag.maxOutput != 0 {
+			fs.maxOutput = flag.maxOutput
+		}
+		if flag.transform != nil {
+			fs.transforms = append(fs.transforms, flag.transform)
+		}
+		if flag.helpers != nil {
+			fs.helpers = flag.helpers
+		}
+		if flag.valueType != "" {
+			fs.valueType = flag.valueType
+			fs.valueDecls = flag.valueDecls
+		}
+		if flag.maxFanOut > 0 {
+			fs.maxFanOut = flag.maxFanOut
+		}
+		if flag.maxDepth > 0 {
+			fs.maxDepth = flag.maxDepth
+		}
+		if flag.maxStateSet {
+			if flag.maxState < MinMaxState {
+				return nil, &ErrBadFlags{badMaxState: true}
+			}
+			fs.maxState = flag.maxState
+		}
+		if flag.anyDigit != 0 {
+			if flag.anyDigit >= '0' && flag.anyDigit <= '9' {
+				return nil, &ErrBadFlags{badPlaceholder: flag.anyDigit}
+			}
+			fs.anyDigit = flag.anyDigit
+		}
+		if flag == HasPrefix {
+			if fs.backwards {
+				return nil, &ErrBadFlags{cannotCombine: []string{"HasPrefix", "HasSuffix"}}
+			}
+			fs.partialMatch = true
+		} else if flag == HasSuffix {
+			if fs.partialMatch && !fs.backwards {
+				return nil, &ErrBadFlags{cannotCombine: []string{"HasPrefix", "HasSuffix"}}
+			}
+			fs.partialMatch = true
+			fs.backwards = true
+		}
+		if len(flag.stop) > 0 {
+			fs.stop = append(fs.stop, flag.stop...)
+		}
+		if len(flag.ignore) > 0 {
+			if len(fs.ignoreExcept) > 0 {
+				return nil, &ErrBadFlags{cannotCombine: []string{"Ignore", "IgnoreExcept"}}
+			}
+			fs.ignore = append(fs.ignore, flag.ignore...)
+		}
+		if len(flag.ignoreExcept) > 0 {
+			if len(fs.ignore) > 0 {
+				return nil, &ErrBadFlags{cannotCombine: []string{"Ignore", "IgnoreExcept"}}
+			}
+			fs.ignoreExcept = append(fs.ignoreExcept, flag.ignoreExcept...)
+		}
+	}
+
+	if fs.noTrailingMark && fs.backwards {
+		return nil, &ErrBadFlags{cannotCombine: []string{"NoTrailingMark", "HasSuffix"}}
+	} else if fs.noTrailingMark && !fs.partialMatch {
+		return nil, &ErrBadFlags{requires: [2]string{"NoTrailingMark", "HasPrefix"}}
+	}
+	if fs.buffered && fs.streamSize != 0 {
+		return nil, &ErrBadFlags{cannotCombine: []string{"BufferOutput", "StreamOutput"}}
+	}
+
+	if len(fs.exactOnly) > 0 {
+		if !fs.partialMatch || fs.backwards {
+			return nil, &ErrBadFlags{requires: [2]string{"ExactOnly", "HasPrefix"}}
+		}
+		if fs.coverage != nil {
+			return nil, &ErrBadFlags{cannotCombine: []string{"Coverage", "ExactOnly"}}
+		}
+	}
+
+	if fs.trustLen {
+		// The input must be the length of a key, so it can't
+		// contain anything other than a key.
+		var cannotCombine []string
+		for _, flag := range flags {
+			if flag == HasPrefix || flag == HasSuffix || len(flag.stop) > 0 ||
+				len(flag.ignore) > 0 || len(flag.ignoreExcept) > 0 ||
+				flag.tooShort != "" || flag.tooLong != "" {
+				cannotCombine = append(cannotCombine, flagName(flag))
+			}
+		}
+		if len(cannotCombine) > 0 {
+			return nil, &ErrBadFlags{cannotCombine: append(cannotCombine, "TrustLength")}
+		}
+	}
+
+	if fs.noImports {
+		var unsupported []string
+		for _, flag := range flags {
+			if flag.normalizeImport != "" {
+				unsupported = append(unsupported, flagName(flag))
+			}
+		}
+		if fs.noTrailingMark && fs.partialMatch {
+			// Needs the unicode package to find cluster
+			// boundaries.
+			unsupported = append(unsupported, "NoTrailingMark")
+		}
+		if len(unsupported) > 0 {
+			return nil, &ErrBadFlags{unsupported: unsupported, unsupportedBy: "NoImports"}
+		}
+	}
+
+	if fs.caseFold {
+		var cannotCombine []string
+		for _, flag := range flags {
+			if flag == ASCIIOnly || (fs.turkish && (flag == Insensitive || flag == IdentifierCase || flag == InsensitiveUnicode)) {
+				cannotCombine = append(cannotCombine, flagName(flag))
+			}
+		}
+		if len(cannotCombine) > 0 {
+			name := "InsensitiveUnicode"
+			if fs.turkish {
+				name = "InsensitiveTurkish"
+			}
+			return nil, &ErrBadFlags{cannotCombine: append(cannotCombine, name)}
+		}
+	}
+
+	// Multi-byte equivalents are replaced in the input up front, so the
+	// byte-at-a-time matching code only needs to know about the rest.
+	if fs.foldEquivalents {
+		if fs.asciiOnly {
+			return nil, &ErrBadFlags{cannotCombine: []string{"ASCIIOnly", "FoldEquivalents"}}
+		}
+		folds := unicodeFolds(fs.equiv)
+		if flag := unicodeFlag(folds); flag != nil {
+			fs.normalize = append(fs.normalize, flag)
+			fs.stop = foldRunes(fs.stop, folds)
+			fs.ignore = foldRunes(fs.ignore, folds)
+			fs.ignoreExcept = foldRunes(fs.ignoreExcept, folds)
+		}
+		fs.equiv = asciiEquivalents(fs.equiv)
+	}
+
+	// Check that stop and ignore runes are never equivalent.
+	var stopIgnore sortableRunes
+	for _, r1 := range fs.stop {
+		for _, r2 := range fs.ignore {
+			if fs.equiv.isEquiv(r1, r2) {
+				stopIgnore = append(stopIgnore, r1)
+			}
+		}
+	}
+	if len(stopIgnore) > 0 {
+		return nil, &ErrBadFlags{cannotStopIgnore: stopIgnore}
+	}
+
+	fs.stop = fs.equiv.expand(fs.stop)
+	fs.ignore = fs.equiv.expand(fs.ignore)
+	fs.ignoreExcept = fs.equiv.expand(fs.ignoreExcept)
+
+	return fs, nil
+}
+
+// internalFlags returns flags minus any Coverage, ValueType, StreamOutput,
+// OnProgress, CacheDir, Report, or MaxOutputBytes flags.  This is used when
+// we call Generate internally, either to check for errors or with values of
+// our own, and don't want it to record coverage, check the values, buffer the
+// output separately from ours, report progress on a subset of the work, cache
+// the result, reset the Result, or limit the size of output we discard.
+func internalFlags(flags []*Flag) []*Flag {
+	newFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag.coverage == nil && flag.valueType == "" && flag.streamSize == 0 &&
+			flag.onProgress == nil && flag.cacheDir == "" && flag.result == nil &&
+			flag.maxOutput == 0 {
+			newFlags = append(newFlags, flag)
+		}
+	}
+	return newFlags
+}
+
+// normalizeKey applies any NormalizeInput flags to a key.
+func (fs *flagSet) normalizeKey(key string) string {
+	for _, flag := range fs.normalize {
+		key = flag.normalizeFunc(key)
+	}
+	return key
+}
+
+// mangle truncates a key at the first stop rune (or the last, if suffix
+// matching), and removes any ignored runes.  The result is what the
+// generated code actually compares against the input.
+func (fs *flagSet) mangle(key string) string {
+	if len(fs.stop) == 0 && len(fs.ignore) == 0 && len(fs.ignoreExcept) == 0 {
+		return key
+	}
+
+	// When suffix matching, we walk the key from the end, so that
+	// truncation happens at the last stop rune.
+	runes := []rune(key)
+	if fs.backwards {
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+	}
+
+	newKey := make([]rune, 0, len(runes))
+mangleKey:
+	for _, r1 := range runes {
+		for _, r2 := range fs.stop {
+			if r1 == r2 {
+				break mangleKey
+			}
+		}
+		if len(fs.ignoreExcept) > 0 {
+			notIgnored := false
+			for _, r2 := range fs.ignoreExcept {
+				if r1 == r2 {
+					notIgnored = true
+					break
+				}
+			}
+			if !notIgnored {
+				continue mangleKey
+			}
+		} else {
+			for _, r2 := range fs.ignore {
+				if r1 == r2 {
+					continue mangleKey
+				}
+			}
+		}
+		newKey = append(newKey, r1)
+	}
+
+	if fs.backwards {
+		// Put the runes back in their original order.
+		for i, j := 0, len(newKey)-1; i < j; i, j = i+1, j-1 {
+			newKey[i], newKey[j] = newKey[j], newKey[i]
+		}
+	}
+	return string(newKey)
 }
 
 // Insensitive is a flag, which can be passed to Generate, to specify that
@@ -108,12 +784,31 @@ var Insensitive = new(Flag)
 // generated code.
 var Normalize = new(Flag)
 
+// maxEquivalents is the largest number of runes which can be equivalent to
+// each other.  Each one becomes a case in the generated code, wherever any of
+// them appears in a key, so large sets quickly produce enormous output.
+// (Since the input is examined one byte at a time, no more than 256 of them
+// could ever match anyway.)
+const maxEquivalents = 256
+
 // Equivalent is a flag, which can be passed to Generate, to specify
 // runes that should be treated identically when matching.
+//
+// Sets of more than 256 runes (including those built up from several
+// overlapping Equivalent flags) are rejected with ErrBadFlags.  To match
+// large classes of characters, such as all of CJK, use NormalizeInput to map
+// them to a single representative instead.
 func Equivalent(runes ...rune) *Flag {
 	return &Flag{equivalent: runes}
 }
 
+// EquivalentString is like Equivalent, but takes the runes as a string, so
+// that a group such as EquivalentString("aàáâä") can be written compactly
+// or read from a configuration file.
+func EquivalentString(runes string) *Flag {
+	return Equivalent([]rune(runes)...)
+}
+
 // HasPrefix is a flag, which can be passed to Generate, to specify that
 // runes proceeding a match should be ignored.
 //
@@ -170,12 +865,22 @@ func StopUpon(runes ...rune) *Flag {
 	return &Flag{stop: runes}
 }
 
+// StopUponString is like StopUpon, but takes the runes as a string.
+func StopUponString(runes string) *Flag {
+	return StopUpon([]rune(runes)...)
+}
+
 // Ignore is a flag, which can be passed to Generate, to specify runes
 // (including equivalents) which should be ignored for matching purposes.
 func Ignore(runes ...rune) *Flag {
 	return &Flag{ignore: runes}
 }
 
+// IgnoreString is like Ignore, but takes the runes as a string.
+func IgnoreString(runes string) *Flag {
+	return Ignore([]rune(runes)...)
+}
+
 // IgnoreExcept is a flag, which can be passed to Generate, to specify which
 // runes (including equivalents) should be examined when matching.  This is
 // similar to Ignore, except that when this flag is present, any runes not
@@ -186,6 +891,90 @@ func IgnoreExcept(runes ...rune) *Flag {
 	return &Flag{ignoreExcept: runes}
 }
 
+// IgnoreExceptString is like IgnoreExcept, but takes the runes as a string.
+func IgnoreExceptString(runes string) *Flag {
+	return IgnoreExcept([]rune(runes)...)
+}
+
+// NormalizeInput is a flag, which can be passed to Generate, to specify a
+// function to be applied to the input before matching.  expr is the function
+// as it should appear in the generated code, and fn is the same function,
+// which is applied to each key at generation time.
+//
+// For example, the following emits a matcher which trims whitespace from the
+// input:
+//
+//	fastmatch.Generate(w, cases, "nil",
+//		fastmatch.NormalizeInput("strings.TrimSpace", strings.TrimSpace))
+//
+// The caller is responsible for importing any packages referenced by expr.
+// If more than one NormalizeInput flag is specified, they are applied in
+// order.
+func NormalizeInput(expr string, fn func(string) string) *Flag {
+	return &Flag{normalizeExpr: expr, normalizeFunc: fn}
+}
+
+// NFC is a flag, which can be passed to Generate, to specify that the input
+// should be converted to Unicode Normalization Form C (using
+// golang.org/x/text/unicode/norm) before matching.  Keys are normalized the
+// same way at generation time, so composed and decomposed forms of the same
+// text will match.
+//
+// To avoid depending on x/text, this package requires the caller to supply
+// the normalization function, which should be norm.NFC.String:
+//
+//	fastmatch.Generate(w, cases, "nil", fastmatch.NFC(norm.NFC.String))
+//
+// The generated code needs to import x/text; see Imports.
+func NFC(fn func(string) string) *Flag {
+	return &Flag{
+		normalizeExpr:   "norm.NFC.String",
+		normalizeImport: "golang.org/x/text/unicode/norm",
+		normalizeFunc:   fn,
+	}
+}
+
+// NoImports is a flag, which can be passed to Generate, to guarantee that the
+// generated code does not need to import any packages, so that it can be
+// pasted into (or generated within) a package with a strict import policy.
+// Generate returns ErrBadFlags if it is combined with any flags which would
+// require an import (see Imports).
+//
+// The caller remains responsible for any packages referenced by the value
+// expressions or by NormalizeInput.
+var NoImports = new(Flag)
+
+// Imports returns a sorted list of import paths required by code generated
+// with the supplied flags.  The caller should include these in the import
+// block of the file they're generating.
+func Imports(flags ...*Flag) []string {
+	seen := make(map[string]bool, len(flags))
+	var imports []string
+	add := func(path string) {
+		if !seen[path] {
+			imports = append(imports, path)
+			seen[path] = true
+		}
+	}
+
+	noTrailingMark, partialMatch := false, false
+	for _, flag := range flags {
+		if flag.normalizeImport != "" {
+			add(flag.normalizeImport)
+		}
+		if flag == NoTrailingMark {
+			noTrailingMark = true
+		} else if flag == HasPrefix {
+			partialMatch = true
+		}
+	}
+	if noTrailingMark && partialMatch {
+		add("unicode")
+	}
+	sort.Strings(imports)
+	return imports
+}
+
 // Range accepts zero or more pairs of runes, and returns a slice covering all
 // runes between the even and odd arguments, inclusive.  It can be used with
 // flags which take a list of runes as arguments, such as Equivalent,
@@ -196,28 +985,133 @@ func IgnoreExcept(runes ...rune) *Flag {
 //	fastmatch.Generate(w, cases, "nil",
 //		fastmatch.IgnoreExcept(Range('0', '9', 'a', 'z', 'A', 'Z')...))
 //
-// If passed an odd number of arguments, this function will panic.
+// If passed an odd number of arguments, a pair whose end precedes its start,
+// or a pair including invalid runes, this function will panic.  Pairs which
+// overlap are allowed, but each rune is only included once.  Use RangeErr for
+// ranges which come from configuration files or other input.
 //
 // See also the predefined ranges: Numbers, Lowercase, Uppercase, Letters,
 // and Alphanumeric.
 func Range(args ...rune) []rune {
+	rs, err := RangeErr(args...)
+	if err != nil && !err.(*ErrBadRange).Warning() {
+		panic(err.Error())
+	}
+	return rs
+}
+
+// ErrBadRange is returned by RangeErr if its arguments are invalid or
+// suspicious.  Each pair is listed as its start and end.
+type ErrBadRange struct {
+	// Odd is true if an odd number of arguments was passed.
+	Odd bool
+
+	// Reversed lists pairs whose end precedes their start, such as
+	// ('z', 'a').
+	Reversed [][2]rune
+
+	// Invalid lists pairs including runes which can't appear in valid
+	// UTF-8: surrogate halves (U+D800 through U+DFFF), and values which
+	// are negative or greater than unicode.MaxRune.
+	Invalid [][2]rune
+
+	// Overlapping lists pairs which overlap an earlier pair.
+	Overlapping [][2]rune
+}
+
+// Warning returns true if the only problem found was overlapping pairs, in
+// which case the slice returned by RangeErr is still usable.
+func (e *ErrBadRange) Warning() bool {
+	return !e.Odd && len(e.Reversed) == 0 && len(e.Invalid) == 0
+}
+
+func (e *ErrBadRange) Error() string {
+	b := new(bytes.Buffer)
+	if e.Odd {
+		b.WriteString("odd number of arguments")
+	}
+	for _, problem := range []struct {
+		descr string
+		pairs [][2]rune
+	}{
+		{"reversed", e.Reversed},
+		{"invalid runes in", e.Invalid},
+		{"overlapping", e.Overlapping},
+	} {
+		for n, pair := range problem.pairs {
+			if n == 0 {
+				if b.Len() != 0 {
+					b.WriteString("; ")
+				}
+				b.WriteString(problem.descr)
+				b.WriteString(" pairs: ")
+			} else {
+				writeListSeparator(b, n, len(problem.pairs)-1)
+			}
+			fmt.Fprintf(b, "%s-%s", strconv.QuoteRune(pair[0]), strconv.QuoteRune(pair[1]))
+		}
+	}
+	return "bad arguments to Range: " + b.String()
+}
+
+// RangeErr is like Range, but returns *ErrBadRange instead of panicking if
+// its arguments are invalid.  If pairs overlap, the runes are returned along
+// with an error whose Warning method returns true.
+func RangeErr(args ...rune) ([]rune, error) {
+	e := new(ErrBadRange)
 	if len(args)%2 != 0 {
-		panic("wrong number of arguments to Range")
+		e.Odd = true
+		return nil, e
 	}
 
-	l := 0
+	pairs := make([][2]rune, 0, len(args)/2)
 	for i := 0; i < len(args); i += 2 {
-		l += int(args[i+1]-args[i]) + 1
+		pair := [2]rune{args[i], args[i+1]}
+		if pair[1] < pair[0] {
+			e.Reversed = append(e.Reversed, pair)
+		} else if pair[0] < 0 || pair[1] > unicode.MaxRune ||
+			(pair[0] <= 0xdfff && pair[1] >= 0xd800) {
+			e.Invalid = append(e.Invalid, pair)
+		} else {
+			pairs = append(pairs, pair)
+		}
+	}
+	if !e.Warning() {
+		return nil, e
+	}
+
+	l := 0
+	for n, pair := range pairs {
+		l += int(pair[1]-pair[0]) + 1
+		for _, earlier := range pairs[:n] {
+			if pair[0] <= earlier[1] && pair[1] >= earlier[0] {
+				e.Overlapping = append(e.Overlapping, pair)
+				break
+			}
+		}
 	}
 	rs := make([]rune, 0, l)
 
-	for i := 0; i < len(args); i += 2 {
-		for j := 0; j <= int(args[i+1]-args[i]); j++ {
-			rs = append(rs, args[i]+rune(j))
+	var seen map[rune]bool
+	if len(e.Overlapping) > 0 {
+		seen = make(map[rune]bool, l)
+	}
+	for _, pair := range pairs {
+		for r := pair[0]; r <= pair[1]; r++ {
+			if seen != nil {
+				if seen[r] {
+					continue
+				}
+				seen[r] = true
+			}
+			rs = append(rs, r)
 		}
 	}
 
-	return rs
+	if len(e.Overlapping) > 0 {
+		return rs, e
+	}
+	return rs, nil
 }
 
 // Numbers is a predefined Range covering the ASCII digits from 0 through 9.
@@ -235,3 +1129,92 @@ var Uppercase = Range('A', 'Z')
 // Alphanumeric is a predefined Range covering ASCII numeric digits and
 // upper- and lower-case letters.
 var Alphanumeric = Range('0', '9', 'a', 'z', 'A', 'Z')
+
+// Invisible is a predefined list of invisible formatting characters which
+// frequently appear in copy-pasted text: the byte order mark (U+FEFF),
+// zero-width space (U+200B), zero-width non-joiner (U+200C), zero-width
+// joiner (U+200D), word joiner (U+2060), and soft hyphen (U+00AD).
+var Invisible = []rune{'\u00ad', '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff'}
+
+// IgnoreInvisible is a flag, which can be passed to Generate, to specify that
+// the runes in Invisible should be ignored for matching purposes.  This is
+// equivalent to Ignore(Invisible...), and likewise may not be combined with
+// IgnoreExcept.
+var IgnoreInvisible = Ignore(Invisible...)
+
+// Separators is a predefined list of the runes commonly used to separate
+// words in identifiers and configuration keys: hyphen, underscore, and space.
+var Separators = []rune{'-', '_', ' '}
+
+// IgnoreSeparators is a flag, which can be passed to Generate, to specify
+// that the runes in Separators should be ignored for matching purposes, so
+// that (for instance) "max-age", "max_age", and "max age" are all
+// considered the same.  This is equivalent to Ignore(Separators...), and
+// likewise may not be combined with IgnoreExcept.
+var IgnoreSeparators = Ignore(Separators...)
+
+// IdentifierCase is a flag, which can be passed to Generate, to specify that
+// keys should match irrespective of whether they are written in camelCase,
+// PascalCase, snake_case, or kebab-case.  This combines Insensitive and
+// IgnoreSeparators, so "maxAge", "MaxAge", "max_age", and "MAX-AGE" are all
+// considered the same.  It is intended for parsing field names in JSON or
+// YAML configuration files.
+//
+// Keys which are distinct as written, but the same once case and separators
+// are disregarded (such as "re_sign" and "resign"), are reported by Generate
+// as an ErrAmbiguous if their values differ.  Like IgnoreSeparators, this
+// may not be combined with IgnoreExcept.
+var IdentifierCase = &Flag{ignore: Separators}
+
+// trimPlural removes a single trailing 's' or 'S' from a string.
+func trimPlural(s string) string {
+	if n := len(s) - 1; n >= 0 && (s[n] == 's' || s[n] == 'S') {
+		return s[:n]
+	}
+	return s
+}
+
+// trimPluralExpr is the equivalent of trimPlural in the generated code.
+const trimPluralExpr = `func(s string) string {
+		if n := len(s) - 1; n >= 0 && (s[n] == 's' || s[n] == 'S') {
+			return s[:n]
+		}
+		return s
+	}`
+
+// IgnorePlural is a flag, which can be passed to Generate, to specify that a
+// single trailing 's' (or 'S') should be removed from both the input and
+// the keys before matching, so that "header" and "headers" are considered
+// the same.  This is a crude heuristic, which only handles regular English
+// plurals.  Keys which differ only by a trailing 's' (such as "new" and
+// "news") are reported as ambiguous if their values differ.
+var IgnorePlural = NormalizeInput(trimPluralExpr, trimPlural)
+
+// chompLine removes a single trailing "\n" or "\r\n" from a string.
+func chompLine(s string) string {
+	if n := len(s) - 1; n >= 0 && s[n] == '\n' {
+		if n > 0 && s[n-1] == '\r' {
+			return s[:n-1]
+		}
+		return s[:n]
+	}
+	return s
+}
+
+// chompLineExpr is the equivalent of chompLine in the generated code.
+const chompLineExpr = `func(s string) string {
+		if n := len(s) - 1; n >= 0 && s[n] == '\n' {
+			if n > 0 && s[n-1] == '\r' {
+				return s[:n-1]
+			}
+			return s[:n]
+		}
+		return s
+	}`
+
+// ChompLine is a flag, which can be passed to Generate, to specify that a
+// single trailing "\n" or "\r\n" should be removed from the input before
+// matching, so that lines read with (for instance) bufio.Reader.ReadString
+// match without the caller having to trim them first.  Like IgnorePlural,
+// this does not allocate memory.
+var ChompLine = NormalizeInput(chompLineExpr, chompLine)
@@ -33,7 +33,11 @@ import (
 	"io/ioutil"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"unicode"
+	"unicode/utf8"
 )
 
 // typeOf returns the type name of a value, including pointer dereferences.
@@ -83,6 +87,12 @@ var badFlagsTests = []struct {
 		expect: &ErrBadFlags{
 			cannotStopIgnore: []rune{'a', 'b', 'c'},
 		},
+	}, {
+		flags:     []*Flag{Equivalent(append([]rune{'a'}, Range('\u4e00', '\u9fff')...)...)},
+		expectStr: "Equivalent set of 20993 runes (starting with 'a') exceeds the maximum of 256; use NormalizeInput to fold large character classes instead",
+	}, {
+		flags:     []*Flag{Equivalent(Range('\u0100', '\u0180')...), Equivalent(Range('\u0180', '\u0200')...)},
+		expectStr: "Equivalent set of 257 runes (starting with '\u0100') exceeds the maximum of 256; use NormalizeInput to fold large character classes instead",
 	},
 }
 
@@ -171,3 +181,176 @@ func TestRange(t *testing.T) {
 		}
 	}
 }
+
+// TestRangeErr tests that RangeErr returns an error, and Range panics, when
+// passed invalid arguments.
+func TestRangeErr(t *testing.T) {
+	if rs, err := RangeErr('a', 'c'); err != nil {
+		t.Errorf("unexpected error from RangeErr: %s", err)
+	} else if string(rs) != "abc" {
+		t.Errorf("expected \"abc\" from RangeErr, got %q", string(rs))
+	}
+
+	for _, testCase := range []struct {
+		args   []rune
+		expect ErrBadRange
+	}{
+		{[]rune{'a'}, ErrBadRange{Odd: true}},
+		{[]rune{'a', 'c', 'x'}, ErrBadRange{Odd: true}},
+		{[]rune{'a', 'c', 'z', 'x'}, ErrBadRange{Reversed: [][2]rune{{'z', 'x'}}}},
+		{[]rune{'\ud7ff', '\ue000'}, ErrBadRange{Invalid: [][2]rune{{0xd7ff, 0xe000}}}},
+		{[]rune{0xdc00, 0xdc00}, ErrBadRange{Invalid: [][2]rune{{0xdc00, 0xdc00}}}},
+		{[]rune{-1, 'a', 'a', unicode.MaxRune + 1}, ErrBadRange{Invalid: [][2]rune{{-1, 'a'}, {'a', unicode.MaxRune + 1}}}},
+	} {
+		if _, err := RangeErr(testCase.args...); err == nil {
+			t.Errorf("no error from RangeErr(%s)", quoteRunes(testCase.args))
+		} else if e, ok := err.(*ErrBadRange); !ok {
+			t.Errorf("expected *ErrBadRange from RangeErr(%s), got %s", quoteRunes(testCase.args), typeOf(err))
+		} else if !reflect.DeepEqual(*e, testCase.expect) {
+			t.Errorf("expected %+v from RangeErr(%s), got %+v", testCase.expect, quoteRunes(testCase.args), *e)
+		} else if e.Warning() {
+			t.Errorf("RangeErr(%s): %s is only a warning", quoteRunes(testCase.args), err)
+		}
+	}
+
+	// Overlapping pairs are only a warning:
+	rs, err := RangeErr('a', 'c', 'x', 'z', 'b', 'd', 'c', 'c')
+	if e, ok := err.(*ErrBadRange); !ok || !e.Warning() {
+		t.Errorf("expected warning from overlapping pairs, got %v", err)
+	} else if !reflect.DeepEqual(e.Overlapping, [][2]rune{{'b', 'd'}, {'c', 'c'}}) {
+		t.Errorf("incorrect overlapping pairs: %+v", e.Overlapping)
+	} else if err.Error() != "bad arguments to Range: overlapping pairs: 'b'-'d' and 'c'-'c'" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+	if string(rs) != "abcxyzd" {
+		t.Errorf("expected \"abcxyzd\" from overlapping pairs, got %q", string(rs))
+	}
+	if string(Range('a', 'c', 'b', 'd')) != "abcd" {
+		t.Error("Range did not remove overlap")
+	}
+
+	// The predefined ranges should not contain duplicates:
+	for _, rs := range [][]rune{Numbers, Letters, Lowercase, Uppercase, Alphanumeric, Invisible} {
+		seen := make(map[rune]bool, len(rs))
+		for _, r := range rs {
+			if seen[r] || !utf8.ValidRune(r) {
+				t.Errorf("predefined range %s contains duplicate or invalid rune %s", quoteRunes(rs), strconv.QuoteRune(r))
+			}
+			seen[r] = true
+		}
+	}
+
+	for _, args := range [][]rune{{'a'}, {'z', 'a'}, {0xd800, 0xdfff}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Range(%s) did not panic", quoteRunes(args))
+				}
+			}()
+			Range(args...)
+		}()
+	}
+}
+
+// TestStringFlags tests the flags which take runes as a string.
+func TestStringFlags(t *testing.T) {
+	for _, testCase := range []struct {
+		flag, expect *Flag
+	}{
+		{EquivalentString("aàáâä"), Equivalent('a', 'à', 'á', 'â', 'ä')},
+		{StopUponString(".;"), StopUpon('.', ';')},
+		{IgnoreString("-\u2010"), Ignore('-', '\u2010')},
+		{IgnoreExceptString("09"), IgnoreExcept('0', '9')},
+	} {
+		if !reflect.DeepEqual(testCase.flag, testCase.expect) {
+			t.Errorf("expected %s flag %+v, got %+v", flagName(testCase.expect), testCase.expect, testCase.flag)
+		}
+	}
+}
+
+// TestNFC tests that the NFC flag emits the normalization call, and that
+// Imports reports the package it needs.
+func TestNFC(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{"a": "1"}, "0", NFC(func(s string) string { return s }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "\tinput = norm.NFC.String(input)\n") {
+		t.Errorf("normalization call missing from output: %q", b.String())
+	}
+
+	imports := Imports(Insensitive, NFC(nil), NFC(nil))
+	if !reflect.DeepEqual(imports, []string{"golang.org/x/text/unicode/norm"}) {
+		t.Errorf("unexpected imports %q", imports)
+	}
+}
+
+// TestNormalizeInputAmbiguous tests that keys which normalize to the same
+// string are reported as ambiguous.
+func TestNormalizeInputAmbiguous(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{
+		"Foo": "1",
+		"foo": "2",
+	}, "0", NormalizeInput("strings.ToLower", strings.ToLower))
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
+
+// TestIgnorePluralAmbiguous tests that keys which differ only by a trailing
+// 's' are reported as ambiguous.
+func TestIgnorePluralAmbiguous(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{"new": "1", "news": "2"}, "0", IgnorePlural)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+	if trimPlural("") != "" || trimPlural("s") != "" || trimPlural("ABCS") != "ABC" {
+		t.Error("trimPlural returned wrong result")
+	}
+}
+
+// TestIdentifierCaseAmbiguous tests that keys which collide once case and
+// separators are disregarded are reported as ambiguous.
+func TestIdentifierCaseAmbiguous(t *testing.T) {
+	if err := Generate(ioutil.Discard, map[string]string{"re_sign": "1", "resign": "2"}, "0", IdentifierCase); err == nil {
+		t.Error("expected error from colliding keys")
+	} else if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+
+	if err := Generate(ioutil.Discard, map[string]string{"userID": "1", "user_id": "1"}, "0", IdentifierCase); err != nil {
+		t.Errorf("unexpected error from redundant keys: %v", err)
+	}
+
+	if err := Generate(ioutil.Discard, map[string]string{"a": "1"}, "0", IdentifierCase, IgnoreExcept('a')); err == nil {
+		t.Error("expected error combining IdentifierCase and IgnoreExcept")
+	}
+}
+
+// TestNoImports tests that flags requiring imports are rejected when
+// NoImports is given.
+func TestNoImports(t *testing.T) {
+	cases := map[string]string{"a": "1"}
+	for _, flags := range [][]*Flag{
+		{NFC(nil)},
+		{NoTrailingMark, HasPrefix},
+	} {
+		err := Generate(ioutil.Discard, cases, "0", append(flags, NoImports)...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+
+	flags := []*Flag{Insensitive, Normalize, NoImports}
+	if err := Generate(ioutil.Discard, cases, "0", flags...); err != nil {
+		t.Error(err)
+	}
+	if imports := Imports(flags...); len(imports) > 0 {
+		t.Errorf("unexpected imports %q", imports)
+	}
+
+	if err := GenerateOverridable(ioutil.Discard, "f", "int", cases, "0", NoImports); err == nil {
+		t.Error("expected error from GenerateOverridable")
+	}
+}
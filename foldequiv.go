@@ -0,0 +1,187 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// FoldEquivalents is a flag, which can be passed to Generate, to specify
+// that Equivalent sets containing runes which encode to more than one byte
+// of UTF-8 should be honored.  The generated code otherwise examines the
+// input one byte at a time, so only single-byte (ASCII) runes can be
+// equivalent to each other, and multi-byte runes in Equivalent are ignored.
+//
+// With this flag, each multi-byte rune in an Equivalent set is replaced in
+// the input by the first rune of that set (in sorted order, so a single-byte
+// rune is chosen if there is one) before matching.  Keys, and the runes
+// passed to StopUpon, Ignore, and IgnoreExcept, are replaced the same way at
+// generation time.  For example, with FoldEquivalents and Equivalent('i',
+// 'ï', 'Ï'), "naïve" and "naÏve" both match the key "naive".
+//
+// The replacement is done by a NormalizeInput function, which scans the
+// input for such runes, and allocates a new string whenever it finds one.
+// Inputs without any are matched without allocating.
+//
+// This is only a folding step in front of the usual matcher; the generated
+// code still does not decode runes.  Keys containing multi-byte runes which
+// are not in any Equivalent set match exactly, with or without this flag,
+// and Insensitive only folds the ASCII letters, so "Да" does not match "да"
+// unless they are made Equivalent.  Use InsensitiveUnicode to fold the case
+// of non-ASCII letters.
+var FoldEquivalents = new(Flag)
+
+// unicodeFolds returns a map from each multi-byte rune in equiv to the rune
+// which replaces it when FoldEquivalents is set.
+func unicodeFolds(equiv runeEquivalents) map[rune]rune {
+	folds := make(map[rune]rune)
+	for r, rs := range equiv {
+		if r >= utf8.RuneSelf && rs[0] != r {
+			folds[r] = rs[0]
+		}
+	}
+	return folds
+}
+
+// asciiEquivalents returns a copy of equiv without any multi-byte runes,
+// which unicodeFolds has made redundant.
+func asciiEquivalents(equiv runeEquivalents) runeEquivalents {
+	newEquiv := make(runeEquivalents, len(equiv))
+	for r, rs := range equiv {
+		if r >= utf8.RuneSelf {
+			continue
+		}
+		ascii := make(sortableRunes, 0, len(rs))
+		for _, r2 := range rs {
+			if r2 < utf8.RuneSelf {
+				ascii = append(ascii, r2)
+			}
+		}
+		if len(ascii) > 1 {
+			newEquiv[r] = ascii
+		}
+	}
+	return newEquiv
+}
+
+// foldRunes applies folds to a list of runes.
+func foldRunes(rs []rune, folds map[rune]rune) []rune {
+	if len(rs) == 0 {
+		return rs
+	}
+	newRs := make([]rune, len(rs))
+	for n, r := range rs {
+		if to, found := folds[r]; found {
+			r = to
+		}
+		newRs[n] = r
+	}
+	return newRs
+}
+
+// unicodeFlag returns a NormalizeInput flag which applies folds, or nil if
+// folds is empty.
+func unicodeFlag(folds map[rune]rune) *Flag {
+	if len(folds) == 0 {
+		return nil
+	}
+	fn := func(s string) string {
+		var b []byte
+		last := 0
+		for i, r := range s {
+			if to, found := folds[r]; found {
+				b = append(b, s[last:i]...)
+				b = append(b, string(to)...)
+				last = i + utf8.RuneLen(r)
+			}
+		}
+		if b == nil {
+			return s
+		}
+		return string(append(b, s[last:]...))
+	}
+	return NormalizeInput(unicodeExpr(folds), fn)
+}
+
+// unicodeExpr outputs the equivalent of the function returned by unicodeFlag
+// in the generated code.  Runes are grouped into case clauses by their
+// replacement and their encoded length, which is needed to find the end of
+// the rune without importing unicode/utf8.
+func unicodeExpr(folds map[rune]rune) string {
+	type clause struct {
+		to  rune
+		len int
+	}
+	groups := make(map[clause][]rune)
+	var clauses []clause
+	for r, to := range folds {
+		c := clause{to, utf8.RuneLen(r)}
+		if _, found := groups[c]; !found {
+			clauses = append(clauses, c)
+		}
+		groups[c] = append(groups[c], r)
+	}
+	sort.Slice(clauses, func(a, b int) bool {
+		if clauses[a].to != clauses[b].to {
+			return clauses[a].to < clauses[b].to
+		}
+		return clauses[a].len < clauses[b].len
+	})
+
+	var b bytes.Buffer
+	b.WriteString("func(s string) string {\n")
+	b.WriteString("\t\tvar b []byte\n")
+	b.WriteString("\t\tlast := 0\n")
+	b.WriteString("\t\tfor i, r := range s {\n")
+	b.WriteString("\t\t\tvar to string\n")
+	b.WriteString("\t\t\tvar n int\n")
+	b.WriteString("\t\t\tswitch r {\n")
+	for _, c := range clauses {
+		rs := sortableRunes(groups[c])
+		sort.Sort(rs)
+		fmt.Fprintf(&b, "\t\t\tcase %s:\n", quoteRunes(rs))
+		fmt.Fprintf(&b, "\t\t\t\tto, n = %s, %d\n", strconv.QuoteToASCII(string(c.to)), c.len)
+	}
+	b.WriteString("\t\t\tdefault:\n")
+	b.WriteString("\t\t\t\tcontinue\n")
+	b.WriteString("\t\t\t}\n")
+	b.WriteString("\t\t\tb = append(append(b, s[last:i]...), to...)\n")
+	b.WriteString("\t\t\tlast = i + n\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\tif b == nil {\n")
+	b.WriteString("\t\t\treturn s\n")
+	b.WriteString("\t\t}\n")
+	b.WriteString("\t\treturn string(append(b, s[last:]...))\n")
+	b.WriteString("\t}")
+	return b.String()
+}
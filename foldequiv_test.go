@@ -0,0 +1,143 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// TestFoldEquivalents tests matching with Equivalent sets containing multi-byte
+// runes.
+func TestFoldEquivalents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		{FoldEquivalents, Equivalent('i', 'ï', 'Ï'), Equivalent('Д', 'д')},
+		{FoldEquivalents, Equivalent('i', 'ï', 'Ï'), Equivalent('Д', 'д'), Strategy(LinearStrategy)},
+		{FoldEquivalents, Equivalent('i', 'ï', 'Ï'), Equivalent('Д', 'д'), HasPrefix},
+		{FoldEquivalents, Equivalent('i', 'ï', 'Ï'), Equivalent('Д', 'д'), Insensitive},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"naïve": "1",
+			"да":    "2",
+			"foo":   "3",
+		}, "0", flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		expectMatch(t, "naïve", "1")
+		expectMatch(t, "naÏve", "1")
+		expectMatch(t, "naive", "1")
+		expectMatch(t, "да", "2")
+		expectMatch(t, "Да", "2")
+		expectMatch(t, "foo", "3")
+		expectMatch(t, "na\xefve", "0")
+		expectMatch(t, "naïv", "0")
+		expectMatch(t, "дa", "0") // Latin a
+		cleanup()
+	}
+}
+
+// TestFoldEquivalentsByteWise tests that, apart from the folds, FoldEquivalents
+// leaves matching byte-wise, and that inputs without a folded rune aren't
+// copied.
+func TestFoldEquivalentsByteWise(t *testing.T) {
+	fn := unicodeFlag(map[rune]rune{'ï': 'i'}).normalizeFunc
+	if got := fn("naïve"); got != "naive" {
+		t.Errorf("expected naive, got %q", got)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { fn("да naive") }); allocs != 0 {
+		t.Errorf("expected no allocations without a folded rune, got %v", allocs)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"да":    "1",
+		"naïve": "2",
+	}, "0", FoldEquivalents, Insensitive, Equivalent('i', 'ï'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "да", "1")
+	expectMatch(t, "ДА", "0")
+	expectMatch(t, "NAÏVE", "0")
+	expectMatch(t, "NAIVE", "2")
+}
+
+// TestFoldEquivalentsIgnore tests that runes passed to Ignore are replaced like the
+// keys are.
+func TestFoldEquivalentsIgnore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"ab": "1",
+	}, "0", FoldEquivalents, Equivalent('-', '‐', '‑'), Ignore('‐'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "ab", "1")
+	expectMatch(t, "a-b", "1")
+	expectMatch(t, "a‐b", "1")
+	expectMatch(t, "a‑b", "1")
+	expectMatch(t, "a_b", "0")
+}
+
+// TestFoldEquivalentsFlags tests flags which can't be combined with
+// FoldEquivalents, and that it has no effect without multi-byte equivalents.
+func TestFoldEquivalentsFlags(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", FoldEquivalents, ASCIIOnly)
+	if _, ok := err.(*ErrBadFlags); !ok {
+		t.Errorf("expected *ErrBadFlags, got %v", err)
+	}
+
+	fs, err := parseFlags(FoldEquivalents, Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.normalize) != 0 {
+		t.Error("FoldEquivalents normalized the input without multi-byte equivalents")
+	}
+	if !fs.equiv.isEquiv('a', 'A') {
+		t.Error("FoldEquivalents removed ASCII equivalents")
+	}
+}
@@ -29,21 +29,29 @@
 package fastmatch
 
 import (
+	"bytes"
 	"fmt"
 	"hash/fnv"
 	"io"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
-// reverseString returns a string in reverse order.  I'm shocked this isn't
-// part of the standard library.
-func reverseString(s string) string {
-	r := []rune(s)
-	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
-		r[i], r[j] = r[j], r[i]
+// reverseBytes returns a string with its bytes in reverse order.  This is
+// used to construct the keys for suffix matching, since the generated code
+// examines the input one byte at a time, starting from the end.
+//
+// The result is usually not valid UTF-8.  We don't reverse runes instead,
+// since doing so would mean multi-byte runes (and thus combining sequences,
+// bidirectional text, etc.) wouldn't match.
+func reverseBytes(s string) string {
+	b := make([]byte, len(s))
+	for i := range b {
+		b[i] = s[len(s)-1-i]
 	}
-	return string(r)
+	return string(b)
 }
 
 // Generate outputs Go code to compare a string to a set of possible matches
@@ -63,10 +71,16 @@ func reverseString(s string) string {
 // different return values.  This function attempts to detect this and will
 // return an error if ambiguity is detected.
 //
+// The generated code does not allocate memory, unless the input is
+// normalized (see NormalizeInput) or the return values do so.  The same
+// cases and flags always produce the same output, so generated files can be
+// checked for staleness by regenerating and comparing them.
+//
 // The output is not buffered, and will be incomplete if an error is
 // returned.  If the caller cares about this, they should have a way to
-// discard the written output on error.  Errors writing to the supplied
-// io.Writer will be passed back to the caller.
+// discard the written output on error, such as by using Render, or pass
+// BufferOutput.  Errors writing to the supplied io.Writer will be passed
+// back to the caller.
 //
 // Example usage:
 //
@@ -76,58 +90,236 @@ func reverseString(s string) string {
 //		"bar": "2",
 //		"baz": "3",
 //	}, "-1", fastmatch.Insensitive)
-func Generate(w io.Writer, origCases map[string]string, none string, flags ...*Flag) error {
-	equiv := makeEquivalents(flags...)
-	var stop, ignore, ignoreExcept []rune
+func Generate(w io.Writer, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var capped *SizeCappedWriter
+	if fs.maxOutput > 0 {
+		capped = NewSizeCappedWriter(w, fs.maxOutput)
+		fs.capped = capped
+		w = capped
+	}
+	if r := fs.result; r != nil {
+		if r.depth == 0 {
+			*r = Result{Warnings: fs.warnings(flags)}
+			pw := &progressWriter{w: w}
+			w = pw
+			defer func() { r.Bytes = pw.written }()
+		}
+		r.depth++
+		defer func() { r.depth-- }()
+	}
+	if fs.chain != "" {
+		if none, fs.chainCheck, flags, err = fs.resolveChain(none, flags); err != nil {
+			return err
+		}
+	}
+	gen := generateCases
+	if fs.cacheDir != "" && fs.coverage == nil && fs.helpers == nil {
+		gen = generateCached
+	}
+	if capped != nil {
+		// Not every write is checked for errors, so make sure the
+		// limit wasn't exceeded along the way.
+		inner := gen
+		gen = func(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+			if err := inner(w, cases, none, fs, flags); err != nil {
+				return err
+			}
+			return capped.err
+		}
+	}
+	if fs.buffered {
+		var b bytes.Buffer
+		if err := gen(&b, cases, none, fs, flags); err != nil {
+			return err
+		}
+		_, err := b.WriteTo(w)
+		return err
+	}
+	if fs.streamSize == 0 {
+		return gen(w, cases, none, fs, flags)
+	}
 
-	partialMatch := false
-	backwards := false
-	for _, flag := range flags {
-		if flag == HasPrefix {
-			if backwards {
-				return &ErrBadFlags{cannotCombine: []string{"HasPrefix", "HasSuffix"}}
+	bw := fs.streamWriter(w)
+	if err := gen(bw, cases, none, fs, flags); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// generateCases implements Generate, once the flags have been parsed and the
+// output wrapped as needed.
+func generateCases(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	if len(fs.transforms) > 0 {
+		return generateTransformed(w, cases, none, fs, flags)
+	}
+	if fs.valueType != "" {
+		noneCheck := none
+		if fs.chainCheck != "" {
+			noneCheck = fs.chainCheck
+		}
+		if err := fs.checkValueType(cases, noneCheck); err != nil {
+			return err
+		}
+		for _, value := range []string{fs.tooShort, fs.tooLong} {
+			if value == "" {
+				continue
 			}
-			partialMatch = true
-		} else if flag == HasSuffix {
-			if partialMatch && !backwards {
-				return &ErrBadFlags{cannotCombine: []string{"HasPrefix", "HasSuffix"}}
+			if err := fs.checkValueType(nil, value); err != nil {
+				return err
 			}
-			partialMatch = true
-			backwards = true
 		}
-		if len(flag.stop) > 0 {
-			stop = append(stop, flag.stop...)
+	}
+
+	if fs.chain != "" && fs.modifiesInput() {
+		if _, err := fmt.Fprintf(w, "\t%s := input\n", chainInput); err != nil {
+			return err
+		}
+		fs.coverOffset++
+	}
+	if len(fs.exactOnly) > 0 {
+		return generateExactOnly(w, cases, none, fs, flags)
+	}
+	fs.foldCase(cases, flags)
+	if err := writePreprocess(w, fs); err != nil {
+		return err
+	}
+	if fs.maxDepth > 0 {
+		if err := writeDepthGuard(w, cases, fs); err != nil {
+			return err
 		}
-		if len(flag.ignore) > 0 {
-			if len(ignoreExcept) > 0 {
-				return &ErrBadFlags{cannotCombine: []string{"Ignore", "IgnoreExcept"}}
-			}
-			ignore = append(ignore, flag.ignore...)
+	}
+	if fs.asciiOnly {
+		if err := writeASCIIGuard(w, cases, none, fs); err != nil {
+			return err
+		}
+	}
+	if fs.tooShort != "" || fs.tooLong != "" {
+		if err := writeLengthGuard(w, cases, fs, flags); err != nil {
+			return err
 		}
-		if len(flag.ignoreExcept) > 0 {
-			if len(ignore) > 0 {
-				return &ErrBadFlags{cannotCombine: []string{"Ignore", "IgnoreExcept"}}
+	}
+
+	if _, shapes := splitShapes(cases); len(shapes) > 0 {
+		return generateShapes(w, cases, none, fs, flags)
+	}
+	if fs.anyDigit == 0 {
+		return generate(w, cases, none, fs, flags)
+	}
+	cases, err := fs.expandDigits(cases)
+	if err != nil {
+		return err
+	}
+	err = generate(w, cases, none, fs, flags)
+	if e, ok := err.(*ErrAmbiguous); ok {
+		e.rename(fs.digitPatterns)
+	}
+	return err
+}
+
+// generate implements Generate, once any AnyDigit patterns and token shapes
+// have been handled.
+func generate(w io.Writer, origCases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	var err error
+	equiv := fs.equiv
+	stop, ignore, ignoreExcept := fs.stop, fs.ignore, fs.ignoreExcept
+	partialMatch, backwards, noTrailingMark := fs.partialMatch, fs.backwards, fs.noTrailingMark
+	normalize := fs.normalize
+
+	if fs.comparer != "" && fs.strategy != LinearStrategy {
+		return &ErrBadFlags{requires: [2]string{"Comparer", "LinearStrategy"}}
+	}
+
+	if fs.strategy == LinearStrategy {
+		for name, used := range map[string]bool{
+			"ConstantTime": fs.constantTime,
+			"Inline":       fs.inline,
+		} {
+			if used {
+				return &ErrBadFlags{unsupported: []string{name}, unsupportedBy: "LinearStrategy"}
 			}
-			ignoreExcept = append(ignoreExcept, flag.ignoreExcept...)
 		}
+		fs.reportStrategy("linear", len(origCases))
+		return fs.reportWhole(len(origCases), func() error {
+			return generateLinear(w, origCases, none, fs, flags)
+		})
+	}
+
+	if fs.inline {
+		fs.reportStrategy("inline", len(origCases))
+		return fs.reportWhole(len(origCases), func() error {
+			return generateInline(w, origCases, none, fs, flags)
+		})
 	}
 
-	// Check that stop and ignore runes are never equivalent.
-	var stopIgnore sortableRunes
-	for _, r1 := range stop {
-		for _, r2 := range ignore {
-			if equiv.isEquiv(r1, r2) {
-				stopIgnore = append(stopIgnore, r1)
+	if fs.strategy == TrieStrategy {
+		fs.reportStrategy("trie", len(origCases))
+		return fs.reportWhole(len(origCases), func() error {
+			return generateTrie(w, origCases, none, fs, flags)
+		})
+	}
+
+	if fs.constantTime {
+		for name, used := range map[string]bool{
+			"StopUpon":       len(stop) > 0,
+			"Ignore":         len(ignore) > 0,
+			"IgnoreExcept":   len(ignoreExcept) > 0,
+			"NoTrailingMark": noTrailingMark,
+		} {
+			if used {
+				return &ErrBadFlags{cannotCombine: []string{"ConstantTime", name}}
 			}
 		}
+		fs.reportStrategy("constant time", len(origCases))
+		return fs.reportWhole(len(origCases), func() error {
+			return generateConstantTime(w, origCases, none, fs, flags)
+		})
 	}
-	if len(stopIgnore) > 0 {
-		return &ErrBadFlags{cannotStopIgnore: stopIgnore}
+
+	if fs.strategy == AutoStrategy && fs.canUseLookupTable(origCases) {
+		fs.reportStrategy("lookup table", len(origCases))
+		return fs.reportWhole(len(origCases), func() error {
+			return generateLookupTable(w, origCases, none, fs)
+		})
 	}
 
-	stop = equiv.expand(stop)
-	ignore = equiv.expand(ignore)
-	ignoreExcept = equiv.expand(ignoreExcept)
+	// The generated code examines the input one byte at a time, so
+	// ignored runes which encode to more than one byte need to be checked
+	// separately.
+	var ignoreBytes []rune
+	var ignoreSeqs []string
+	for _, r := range ignore {
+		if r < utf8.RuneSelf {
+			ignoreBytes = append(ignoreBytes, r)
+		} else {
+			ignoreSeqs = append(ignoreSeqs, string(r))
+		}
+	}
+
+	// If the input is going to be normalized before matching, the keys
+	// need to be normalized the same way.  Different keys which normalize
+	// to the same string are ambiguous if their values differ.
+	var normToOrig map[string][]string
+	if len(normalize) > 0 {
+		normalized := make(map[string]string, len(origCases))
+		normToOrig = make(map[string][]string, len(origCases))
+		e := new(ErrAmbiguous)
+		for key, value := range origCases {
+			newKey := fs.normalizeKey(key)
+			if others := normToOrig[newKey]; len(others) > 0 && origCases[others[0]] != value {
+				e.add(nil, others[0], key)
+			}
+			normalized[newKey] = value
+			normToOrig[newKey] = append(normToOrig[newKey], key)
+		}
+		if len(e.keys) > 0 {
+			return e
+		}
+		origCases = normalized
+	}
 
 	// Create a new map with the actual keys being searched for.  If stop
 	// runes were specified, the keys will be truncated if they contain
@@ -142,47 +334,19 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 		backToOrig = make(map[string][]string, len(origCases))
 
 		for key, value := range origCases {
+			mangled := fs.mangle(key)
 			if backwards {
-				key = reverseString(key)
+				mangled = reverseBytes(mangled)
 			}
-
-			newKey := make([]rune, 0, len(key))
-		mangleKey:
-			for _, r1 := range key {
-				for _, r2 := range stop {
-					if r1 == r2 {
-						break mangleKey
-					}
-				}
-				if len(ignoreExcept) > 0 {
-					notIgnored := false
-					for _, r2 := range ignoreExcept {
-						if r1 == r2 {
-							notIgnored = true
-							break
-						}
-					}
-					if !notIgnored {
-						continue mangleKey
-					}
-				} else {
-					for _, r2 := range ignore {
-						if r1 == r2 {
-							continue mangleKey
-						}
-					}
-				}
-				newKey = append(newKey, r1)
-			}
-			cases[string(newKey)] = value
-			backToOrig[string(newKey)] = append(backToOrig[string(newKey)], key)
+			cases[mangled] = value
+			backToOrig[mangled] = append(backToOrig[mangled], key)
 		}
 	} else if backwards {
 		cases = make(map[string]string, len(origCases))
 		backToOrig = make(map[string][]string, len(origCases))
 
 		for key, value := range origCases {
-			newKey := reverseString(key)
+			newKey := reverseBytes(key)
 			cases[newKey] = value
 			backToOrig[newKey] = append(backToOrig[newKey], key)
 		}
@@ -190,13 +354,48 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 		cases = origCases
 	}
 
+	// If a coverage manifest was requested, we need to keep track of the
+	// line number of each return statement, and the original key(s) it
+	// corresponds to.
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+	cover := func(key string) {
+		if lines == nil {
+			return
+		}
+		origKeys := []string{key}
+		if backToOrig != nil {
+			origKeys = backToOrig[key]
+		}
+		for _, key := range origKeys {
+			if normToOrig != nil {
+				for _, key := range normToOrig[key] {
+					fs.cover(key, lines.lines)
+				}
+			} else {
+				fs.cover(key, lines.lines)
+			}
+		}
+	}
+
+	// The keys are processed in sorted order, so that the output is the
+	// same every time for the same input.
+	sortedKeys := make([]string, 0, len(cases))
+	for key := range cases {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
 	// In order to generate (hopefully) unique labels, we hash the keys.
 	h := fnv.New32a()
 
 	// Search is partitioned based on the length of the input.  Split
 	// cases into each possible search space:
 	keys := make(map[int][]string)
-	for key := range cases {
+	for _, key := range sortedKeys {
 		keys[len(key)] = append(keys[len(key)], key)
 		h.Write([]byte(key))
 	}
@@ -205,6 +404,13 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 		lengths = append(lengths, len)
 	}
 	sort.Sort(sort.Reverse(lengths))
+	fs.reportStrategy("state machine", len(cases))
+	progress := Progress{Keys: len(cases), Buckets: len(lengths)}
+	bucketKeys := make(map[int]int, len(lengths))
+	for l := range keys {
+		bucketKeys[l] = len(keys[l])
+	}
+	fs.reportProgress(progress)
 
 	// For partial matching, include shorter cases in the search space for
 	// longer ones.  (Reminder: lengths array is sorted in descending
@@ -230,14 +436,116 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 		return fmt.Sprintf("input[%d+ignored]", off)
 	}
 
+	for _, flag := range normalize {
+		if _, err := fmt.Fprintf(w, "\tinput = %s(input)", fs.normalizeCall(flag)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	if noTrailingMark && partialMatch {
+		if _, err := fmt.Fprint(w, extendsClusterCode); err != nil {
+			return err
+		}
+	}
+
+	// writeIgnoreSeqs outputs a check for each multi-byte ignored rune at
+	// a given offset, which jumps to label if found.  before is output
+	// between the check and the jump.
+	writeIgnoreSeqs := func(w io.Writer, indent string, off int, label string, before func(w io.Writer, n int)) {
+		for _, seq := range ignoreSeqs {
+			var b bytes.Buffer
+			fmt.Fprintf(&b, "len(input) >= %d+ignored", off+len(seq))
+			for j := 0; j < len(seq); j++ {
+				if backwards {
+					fmt.Fprintf(&b, " && input[len(input)-%d-ignored] == 0x%x", off+len(seq)-j, seq[j])
+				} else {
+					fmt.Fprintf(&b, " && input[%d+ignored] == 0x%x", off+j, seq[j])
+				}
+			}
+			fmt.Fprintf(w, "%sif %s { // %s", indent, b.String(), strconv.QuoteToASCII(seq))
+			fmt.Fprintln(w)
+			if before != nil {
+				before(w, len(seq))
+			}
+			fmt.Fprintf(w, "%s\tignored += %d", indent, len(seq))
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "%s\tgoto %s", indent, label)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, indent+"}")
+		}
+	}
+
+	// inputAfterOffset returns the remainder of the input following a
+	// given offset.  (This is only used for forward matching.)
+	inputAfterOffset := func(off int) string {
+		if len(ignore) == 0 && len(ignoreExcept) == 0 {
+			return fmt.Sprintf("input[%d:]", off+1)
+		}
+		return fmt.Sprintf("input[%d+ignored:]", off+1)
+	}
+
+	// When matching the entire input, and the only flag which relaxes
+	// the length check is Ignore (with runes that encode to a single
+	// byte), a mismatch after skipping ignored runes counts the bytes
+	// which aren't ignored, rather than falling through to each shorter
+	// length of keys in turn (which would skip the same ignored runes
+	// again every time).  The count tells us exactly which length of keys
+	// to examine.  We don't count up front, since most input contains no
+	// ignored runes, and falling through is cheap if none were skipped.
+	countIgnored := len(ignore) > 0 && len(ignoreSeqs) == 0 && len(stop) == 0 && !partialMatch &&
+		len(lengths) > 0 && lengths[0] > 0
+	countLabel := fmt.Sprintf("fastmatch_%x_count", h.Sum32())
+	if countIgnored {
+		fmt.Fprintln(w, "\tfastmatchCounted := false")
+		fmt.Fprintln(w, "\tvar fastmatchLen, fastmatchAt int")
+	}
+
 	wroteSwitch := false
-	for _, l := range lengths {
+	jumpedToNext := false
+	for n, l := range lengths {
 		state := newStateMachine(keys[l])
-		state.indexKeys(equiv, partialMatch)
-		if err := state.checkAmbiguity(cases, origCases, backToOrig); err != nil {
+		if fs.maxState != 0 {
+			state.maxState = fs.maxState
+		}
+		if err := state.indexKeys(fs.ctx, equiv, partialMatch); err != nil {
+			return err
+		}
+		if err := state.checkAmbiguity(fs.ctx, cases, origCases, backToOrig, noTrailingMark); err != nil {
 			return err
 		}
 
+		// When StopUpon, Ignore, or IgnoreExcept are in effect, the
+		// length of the input doesn't tell us which length to examine.
+		// A mismatch thus needs to fall through to the next
+		// (shorter) set of cases, rather than returning immediately.
+		if jumpedToNext || countIgnored {
+			fmt.Fprintf(w, "\tfastmatch_%x_l%d:", h.Sum32(), l)
+			fmt.Fprintln(w)
+			jumpedToNext = false
+		}
+		mismatch := "return " + none
+		fallThrough := !partialMatch && n < len(lengths)-1 &&
+			(len(stop) > 0 || len(ignore) > 0 || len(ignoreExcept) > 0)
+		if fallThrough {
+			mismatch = fmt.Sprintf("goto fastmatch_%x_l%d", h.Sum32(), lengths[n+1])
+		}
+		writeMismatch := func(w io.Writer, indent string, off int) {
+			if countIgnored {
+				// The off bytes before the mismatch which
+				// weren't ignored don't need to be counted.
+				fmt.Fprintln(w, indent+"if ignored > 0 || fastmatchCounted {")
+				fmt.Fprintf(w, "%s\tfastmatchLen, fastmatchAt = %d, %d+ignored", indent, off, off)
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, indent+"\tgoto", countLabel)
+				fmt.Fprintln(w, indent+"}")
+			}
+			fmt.Fprintln(w, indent+mismatch)
+			if fallThrough {
+				jumpedToNext = true
+			}
+		}
+
 		// We don't bother checking the fmt.Fprint return value
 		// everywhere, but we do want to do so once early on, so we
 		// can bail if our effort is going to waste.  We also check it
@@ -249,10 +557,17 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 			}
 		} else {
 			if !wroteSwitch {
+				if fs.trustLen {
+					fmt.Fprint(w, trustLengthComment(lengths))
+				}
 				fmt.Fprintln(w, "\tswitch len(input) {")
 				wroteSwitch = true
 			}
-			if _, err := fmt.Fprintf(w, "\tcase %d:", l); err != nil {
+			if fs.trustLen && n == len(lengths)-1 {
+				if _, err := fmt.Fprintf(w, "\tdefault: // %d", l); err != nil {
+					return err
+				}
+			} else if _, err := fmt.Fprintf(w, "\tcase %d:", l); err != nil {
 				return err
 			}
 		}
@@ -260,18 +575,33 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 		fmt.Fprintln(w, "\t\tvar state uint64")
 		if len(ignore) > 0 || len(ignoreExcept) > 0 {
 			fmt.Fprintln(w, "\t\tvar ignored int")
+		} else if !backwards && l > 1 {
+			// Indexing the last byte first proves to the compiler
+			// that the input is long enough for every offset we
+			// examine below, eliminating their bounds checks.
+			fmt.Fprintf(w, "\t\t_ = input[%d] // bounds check hint", l-1)
+			fmt.Fprintln(w)
 		}
 
 		for realOffset := 0; realOffset < l; realOffset++ {
+			if err := fs.stopped(); err != nil {
+				return err
+			}
 			if state.continued != nil && state.continued.offset == realOffset {
 				fmt.Fprintln(w, "\t\tswitch state {")
-				for before, after := range state.continued.collapsed {
+				befores := make([]string, 0, len(state.continued.collapsed))
+				for before := range state.continued.collapsed {
+					befores = append(befores, before)
+				}
+				sort.Strings(befores)
+				for _, before := range befores {
 					fmt.Fprintf(w, "\t\tcase %s:", before)
 					fmt.Fprintln(w)
-					fmt.Fprintf(w, "\t\t\tstate = 0x%x", after)
+					fmt.Fprintf(w, "\t\t\tstate = 0x%x", state.continued.collapsed[before])
 					fmt.Fprintln(w)
 				}
 				fmt.Fprintln(w, "\t\t}")
+				fs.reportStates(state)
 				state = state.continued
 			}
 
@@ -281,7 +611,7 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 			writeIgnore := func(w io.Writer) {
 				fmt.Fprintf(w, "\t\t\tif len(input) <= ignored+%d {", l)
 				fmt.Fprintln(w)
-				fmt.Fprintln(w, "\t\t\t\treturn", none)
+				writeMismatch(w, "\t\t\t\t", realOffset)
 				fmt.Fprintln(w, "\t\t\t}")
 				fmt.Fprintln(w, "\t\t\tignored++")
 				fmt.Fprintln(w, "\t\t\tgoto", label)
@@ -290,16 +620,50 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 				fmt.Fprintln(w, "\t"+label+":")
 			}
 
-			fmt.Fprintln(w, "\t\tswitch", inputAtOffset(realOffset), "{")
+			writeIgnoreSeqs(w, "\t\t", realOffset, label, func(w io.Writer, n int) {
+				fmt.Fprintf(w, "\t\t\tif len(input) < ignored+%d {", l+n)
+				fmt.Fprintln(w)
+				writeMismatch(w, "\t\t\t\t", realOffset)
+				fmt.Fprintln(w, "\t\t\t}")
+			})
 
-			if len(ignore) > 0 {
-				fmt.Fprintf(w, "\t\tcase %s:", quoteRunes(ignore))
+			// If MaxFanOut was specified, and there are too many
+			// runes to list in the case clauses, we instead look
+			// up which case to take in a table.
+			var notInInput []rune
+			if len(ignoreExcept) > 0 {
+				// If a non-ignored rune is not present in any
+				// of the matches at this position, finding it
+				// in the input causes matching to cease.  Stop
+				// runes are never ignored, and keys are
+				// truncated at them, so the same goes for them:
+				notInInput = equiv.expand(append(append([]rune(nil), ignoreExcept...), stop...), state.possible[offset])
+			}
+			caseRunes := quoteRunes
+			switchOn := inputAtOffset(realOffset)
+			if fs.maxFanOut > 0 {
+				classes := make([][]rune, 0, len(state.possible[offset])+2)
+				classes = append(classes, ignoreBytes, notInInput)
+				for _, r := range state.possible[offset] {
+					classes = append(classes, equiv.lookup(r))
+				}
+				if useClassTable(classes, fs.maxFanOut) {
+					table := fmt.Sprintf("fastmatch_%x_l%d_o%d_class", h.Sum32(), l, realOffset)
+					caseRunes = writeClassTable(w, "\t\t", table, classes)
+					switchOn = table + "[" + switchOn + "]"
+				}
+			}
+
+			fmt.Fprintln(w, "\t\tswitch", switchOn, "{")
+
+			if len(ignoreBytes) > 0 {
+				fmt.Fprintf(w, "\t\tcase %s:", caseRunes(ignoreBytes))
 				fmt.Fprintln(w)
 				writeIgnore(w)
 			}
 
 			for _, r := range state.possible[offset] {
-				fmt.Fprintf(w, "\t\tcase %s:", quoteRunes(equiv.lookup(r)))
+				fmt.Fprintf(w, "\t\tcase %s:", caseRunes(equiv.lookup(r)))
 				fmt.Fprintln(w)
 
 				if len(state.noMore[offset][r]) > 0 {
@@ -307,7 +671,16 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 					for _, key := range state.noMore[offset][r] {
 						fmt.Fprintf(w, "\t\t\tcase %s:", state.finalString(key))
 						fmt.Fprintln(w)
-						fmt.Fprintln(w, "\t\t\t\treturn", cases[key])
+						if noTrailingMark {
+							fmt.Fprintf(w, "\t\t\t\tif !%s(%s) {", extendsClusterFunc, inputAfterOffset(realOffset))
+							fmt.Fprintln(w)
+							cover(key)
+							fmt.Fprintln(w, "\t\t\t\t\treturn", cases[key])
+							fmt.Fprintln(w, "\t\t\t\t}")
+						} else {
+							cover(key)
+							fmt.Fprintln(w, "\t\t\t\treturn", cases[key])
+						}
 					}
 					fmt.Fprintln(w, "\t\t\t}")
 				}
@@ -318,14 +691,10 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 				}
 			}
 			if len(ignoreExcept) > 0 {
-				// If a non-ignored rune is not present in any
-				// of the matches at this position, finding it
-				// in the input causes matching to cease:
-				notInInput := equiv.expand(ignoreExcept, state.possible[offset], stop)
 				if len(notInInput) > 0 {
-					fmt.Fprintf(w, "\t\tcase %s:", quoteRunes(notInInput))
+					fmt.Fprintf(w, "\t\tcase %s:", caseRunes(notInInput))
 					fmt.Fprintln(w)
-					fmt.Fprintln(w, "\t\t\treturn", none)
+					writeMismatch(w, "\t\t\t", realOffset)
 				}
 
 				// Ignore all other runes:
@@ -340,7 +709,7 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 				// omitted our final switch block and the next
 				// statement will be a return none.)
 				fmt.Fprintln(w, "\t\tdefault:")
-				fmt.Fprintln(w, "\t\t\treturn", none)
+				writeMismatch(w, "\t\t\t", realOffset)
 			}
 			fmt.Fprintln(w, "\t\t}") // end of "switch input[offset]"
 		}
@@ -374,15 +743,16 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 				fmt.Fprintln(w)
 			}
 			if len(ignore) > 0 || len(ignoreExcept) > 0 || len(stop) > 0 {
+				writeIgnoreSeqs(w, "\t\t\t", l, label, nil)
 				fmt.Fprintln(w, "\t\t\tswitch", inputAtOffset(l), "{")
 				if len(stop) > 0 {
 					fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(stop))
 					fmt.Fprintln(w)
 					// empty case
 				}
-				if len(ignore) > 0 || len(ignoreExcept) > 0 {
-					if len(ignore) > 0 {
-						fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(ignore))
+				if len(ignoreBytes) > 0 || len(ignoreExcept) > 0 {
+					if len(ignoreBytes) > 0 {
+						fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(ignoreBytes))
 						fmt.Fprintln(w)
 					} else {
 						fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(equiv.expand(ignoreExcept, stop)))
@@ -402,30 +772,95 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 			}
 
 			// Compare actual state to possible final values:
-			if len(state.final) == 1 && state.next == 1 {
-				for key := range state.final {
+			finalKeys := make([]string, 0, len(state.final))
+			for key := range state.final {
+				finalKeys = append(finalKeys, key)
+			}
+			sort.Strings(finalKeys)
+			var dense []byte
+			if fs.denseStates {
+				dense = state.denseTable(finalKeys)
+			}
+			unconditional := len(state.final) == 1 && state.next == 1
+			if unconditional {
+				for _, key := range finalKeys {
+					cover(key)
 					fmt.Fprintln(w, "\t\treturn", cases[key])
 				}
+			} else if dense != nil {
+				// The sum may exceed the largest final state if
+				// the input isn't one of the keys.
+				table := fmt.Sprintf("fastmatch_%x_l%d_dense", h.Sum32(), l)
+				writeLookupTable(w, "\t\t", table, dense)
+				fmt.Fprintf(w, "\t\tif state < %d {", len(dense))
+				fmt.Fprintln(w)
+				fmt.Fprintf(w, "\t\t\tswitch %s[state] {", table)
+				fmt.Fprintln(w)
+				for n, key := range finalKeys {
+					fmt.Fprintf(w, "\t\t\tcase %d: // %s", n+1, state.finalString(key))
+					fmt.Fprintln(w)
+					cover(key)
+					fmt.Fprintln(w, "\t\t\t\treturn", cases[key])
+				}
+				fmt.Fprintln(w, "\t\t\t}")
+				fmt.Fprintln(w, "\t\t}")
 			} else {
 				fmt.Fprintln(w, "\t\tswitch state {")
-				for key := range state.final {
+				for _, key := range finalKeys {
 					fmt.Fprintf(w, "\t\tcase %s:", state.finalString(key))
 					fmt.Fprintln(w)
+					cover(key)
 					fmt.Fprintln(w, "\t\t\treturn", cases[key])
 				}
 				fmt.Fprintln(w, "\t\t}")
 			}
+			if countIgnored && !unconditional {
+				// All of the input has been examined, so no
+				// shorter keys can match either.
+				fmt.Fprintln(w, "\t\treturn", none)
+			}
 			if len(stop) > 0 || len(ignore) > 0 || len(ignoreExcept) > 0 {
 				fmt.Fprintln(w, "\t}") // end of "if len(input)"
 			}
 		}
+
+		fs.reportStates(state)
+		progress.KeysDone += bucketKeys[l]
+		progress.BucketsDone++
+		fs.reportProgress(progress)
 	}
 	if wroteSwitch {
 		fmt.Fprintln(w, "\t}") // end of "switch len(input)"
 	}
+	if countIgnored {
+		// Count the bytes which aren't ignored, and jump straight to
+		// the keys of that length.  If we've already done so, there's
+		// no match.
+		fmt.Fprintln(w, "\treturn", none)
+		fmt.Fprintln(w, "\t"+countLabel+":")
+		fmt.Fprintln(w, "\tif !fastmatchCounted {")
+		fmt.Fprintln(w, "\t\tfastmatchCounted = true")
+		fmt.Fprintln(w, "\t\tfor ; fastmatchAt < len(input); fastmatchAt++ {")
+		fmt.Fprintln(w, "\t\t\tswitch input[fastmatchAt] {")
+		fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(ignoreBytes))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\tdefault:")
+		fmt.Fprintln(w, "\t\t\t\tfastmatchLen++")
+		fmt.Fprintln(w, "\t\t\t}")
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t\tswitch fastmatchLen {")
+		for _, l := range lengths {
+			fmt.Fprintf(w, "\t\tcase %d:", l)
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "\t\t\tgoto fastmatch_%x_l%d", h.Sum32(), l)
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t}")
+	}
 	fmt.Fprintln(w, "\treturn", none)
 
-	_, err := fmt.Fprintln(w, "}") // end of func
+	_, err = fmt.Fprintln(w, "}") // end of func
 	return err
 }
 
@@ -436,12 +871,19 @@ func Generate(w io.Writer, origCases map[string]string, none string, flags ...*F
 // If the supplied io.Writer is not valid, or if more than one string maps to
 // the same value, an error is returned.
 //
-// This function accepts flags (in order to match Generate's function
-// signature), but they are currently ignored.
-func GenerateReverse(w io.Writer, cases map[string]string, none string, _ ...*Flag) error {
+// Flags are ignored, unless ReversePrefix is specified, in which case the
+// generated function looks up keys by the text of their value expressions.
+// (The flags are accepted regardless, in order to match Generate's function
+// signature.)
+func GenerateReverse(w io.Writer, cases map[string]string, none string, flags ...*Flag) error {
 	if err := checkReverseAmbiguity(cases); err != nil {
 		return err
 	}
+	for _, flag := range flags {
+		if flag == ReversePrefix {
+			return generateReversePrefix(w, cases, none, flags)
+		}
+	}
 
 	// Case statements are written in alphabetic order by key
 	keys := make([]string, 0, len(cases))
@@ -466,6 +908,17 @@ func GenerateReverse(w io.Writer, cases map[string]string, none string, _ ...*Fl
 	return err
 }
 
+// ZeroAllocs is a flag, which can be passed to GenerateTest, to also output
+// a check (using testing.AllocsPerRun) that the generated functions do not
+// allocate memory.
+//
+// The code output by Generate and GenerateReverse never allocates by
+// itself.  However, input normalization flags (such as NFC) call external
+// functions which may allocate, as may the return value expressions passed
+// in by the caller.  This flag is intended to catch these sorts of
+// regressions.
+var ZeroAllocs = new(Flag)
+
 // GenerateTest outputs a simple unit test which exercises the generated code.
 //
 // An error is returned if the supplied io.Writer is not valid.  As with
@@ -479,33 +932,117 @@ func GenerateReverse(w io.Writer, cases map[string]string, none string, _ ...*Fl
 // the matcher and "%s.String()" for the reverse matcher.  Passing "" causes
 // the respective function to not be tested.
 //
-// Flags should match what was passed to Generate, but are currently ignored.
-// Future versions of this routine may output more sophisticated tests which
-// take flags into account.
-func GenerateTest(w io.Writer, fn, reverseFn string, cases map[string]string, _ ...*Flag) error {
+// Each key is tested in a subtest named for the key (or, for token shapes
+// such as IntegerToken, the name of the shape), so that a failure identifies
+// the key, and individual keys can be tested using "go test -run".  Keys
+// containing an AnyDigit placeholder, and token shapes, are tested using an
+// example of the input they match.
+//
+// Flags should match what was passed to Generate, although only a few of
+// them affect the test.  If StopUpon, Ignore, or IgnoreExcept are specified,
+// each key is also tested with a stop rune following it (or preceding it,
+// with HasSuffix), and with ignored runes surrounding each of its runes.
+// These extra inputs are omitted with input normalization flags, and when
+// they exceed MaxDepth.  TransformKeys is applied to each key to get the
+// input to test, and AnyDigit and token shapes are handled as described
+// above.
+//
+// A final subtest passes the empty string, and inputs one byte shorter and
+// longer than each key, to check that the length checks don't panic.  (What
+// these return isn't checked, since GenerateTest doesn't know the none
+// value.)  This subtest is omitted if TrustLength is specified, since such
+// inputs violate its contract.  ZeroAllocs adds a check that the generated
+// functions don't allocate.  Other flags are ignored.
+func GenerateTest(w io.Writer, fn, reverseFn string, cases map[string]string, flags ...*Flag) error {
 	keys := make([]string, 0, len(cases))
 	for key := range cases {
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
 
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	zeroAllocs := false
+	for _, flag := range flags {
+		if flag == ZeroAllocs {
+			zeroAllocs = true
+		}
+	}
+
+	// Keys containing an AnyDigit placeholder are tested using the first
+	// input they match (if any; they may be entirely overridden by other
+	// keys).
+	inputs := make(map[string]string, len(cases))
 	for _, key := range keys {
-		if fn != "" {
-			_, err := fmt.Fprintf(w, "\tif %s != %s {", fmt.Sprintf(fn, key), cases[key])
-			if err != nil {
-				return err
+		inputs[key] = transformKey(key, fs.transforms)
+	}
+	if fs.anyDigit != 0 {
+		if _, err := fs.expandDigits(cases); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if strings.ContainsRune(key, fs.anyDigit) {
+				delete(inputs, key)
 			}
-			fmt.Fprintln(w)
-			fmt.Fprintf(w, "\t\tt.Errorf(\"wrong answer for %%q\", %q)", key)
-			fmt.Fprintln(w)
-			fmt.Fprintln(w, "\t}") // endif
 		}
+		for input, key := range fs.digitPatterns {
+			if other, found := inputs[key]; !found || input < other {
+				inputs[key] = input
+			}
+		}
+	}
 
-		if reverseFn != "" {
-			_, err := fmt.Fprintf(w, "\tif %s != %q {", fmt.Sprintf(reverseFn, cases[key]), key)
-			if err != nil {
-				return err
+	// Token shapes are tested using an example input, unless that input
+	// would match a regular key instead.
+	for _, shape := range tokenShapes {
+		if _, found := inputs[shape.key]; found {
+			delete(inputs, shape.key)
+			if _, found := cases[shape.example]; !found {
+				inputs[shape.key] = shape.example
+			}
+		}
+	}
+
+	// Each key is tested in its own subtest, so that failures identify
+	// it, and so it can be selected using "go test -run".
+	shapeNames := make(map[string]string, len(tokenShapes))
+	for _, shape := range tokenShapes {
+		shapeNames[shape.key] = shape.name
+	}
+	for _, key := range keys {
+		input, found := inputs[key]
+		testFwd := fn != "" && found
+		if !testFwd && reverseFn == "" {
+			continue
+		}
+
+		name := key
+		if shapeName, found := shapeNames[key]; found {
+			name = shapeName
+		}
+		if _, err := fmt.Fprintf(w, "\tt.Run(%q, func(t *testing.T) {", name); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+
+		if testFwd {
+			testInputs := []string{input}
+			if _, found := shapeNames[key]; !found {
+				testInputs = append(testInputs, fs.boundaryInputs(input)...)
+			}
+			for _, input := range testInputs {
+				fmt.Fprintf(w, "\t\tif %s != %s {", fmt.Sprintf(fn, input), cases[key])
+				fmt.Fprintln(w)
+				fmt.Fprintf(w, "\t\t\tt.Errorf(\"wrong answer for %%q\", %q)", input)
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "\t\t}") // endif
 			}
+		}
+
+		if reverseFn != "" {
+			fmt.Fprintf(w, "\t\tif %s != %q {", fmt.Sprintf(reverseFn, cases[key]), key)
 			fmt.Fprintln(w)
 
 			// Escape opening and closing quotes if needed:
@@ -514,11 +1051,141 @@ func GenerateTest(w io.Writer, fn, reverseFn string, cases map[string]string, _
 				s = "\\" + s[:len(s)-1] + `\"`
 			}
 
-			fmt.Fprintf(w, "\t\tt.Errorf(\"wrong reverse answer for %s\")", s)
+			fmt.Fprintf(w, "\t\t\tt.Errorf(\"wrong reverse answer for %s\")", s)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "\t\t}") // endif
+		}
+
+		if _, err := fmt.Fprintln(w, "\t})"); err != nil {
+			return err
+		}
+	}
+
+	// Inputs one byte either side of each key, and the empty string,
+	// exercise the length checks.  We don't know what they should
+	// return, but they shouldn't panic.
+	if fn != "" && !fs.trustLen {
+		seen := map[string]bool{"": true}
+		probes := []string{""}
+		for _, key := range keys {
+			input, found := inputs[key]
+			if _, shape := shapeNames[key]; !found || shape || input == "" {
+				continue
+			}
+			for _, probe := range []string{input[:len(input)-1], input + "x"} {
+				if !seen[probe] {
+					seen[probe] = true
+					probes = append(probes, probe)
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(w, "\tt.Run(\"boundaries\", func(t *testing.T) {"); err != nil {
+			return err
+		}
+		for _, probe := range probes {
+			fmt.Fprintf(w, "\t\t_ = %s", fmt.Sprintf(fn, probe))
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "\t})")
+	}
+
+	if zeroAllocs {
+		// The calls are wrapped in a single closure, since
+		// AllocsPerRun reports the average number of allocations.
+		for _, f := range []struct {
+			format string
+			arg    func(key string) string
+		}{
+			{fn, func(key string) string { return inputs[key] }},
+			{reverseFn, func(key string) string { return cases[key] }},
+		} {
+			if f.format == "" {
+				continue
+			}
+			if _, err := fmt.Fprintln(w, "\tif allocs := testing.AllocsPerRun(10, func() {"); err != nil {
+				return err
+			}
+			for _, key := range keys {
+				fmt.Fprintf(w, "\t\t_ = %s", fmt.Sprintf(f.format, f.arg(key)))
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "\t}); allocs != 0 {")
+			fmt.Fprintf(w, "\t\tt.Errorf(\"%%v allocations calling %%s\", allocs, %q)", f.format)
 			fmt.Fprintln(w)
 			fmt.Fprintln(w, "\t}") // endif
 		}
 	}
-	_, err := fmt.Fprintln(w, "}") // end of func
+	_, err = fmt.Fprintln(w, "}") // end of func
 	return err
 }
+
+// boundaryInputs returns inputs derived from one which matches a key, which
+// should match the same key given the StopUpon, Ignore, and IgnoreExcept
+// flags: the input followed by a stop rune (or preceded by one, if matching
+// a suffix), and the input padded with ignored runes before, between, and
+// after each of its runes.
+func (fs *flagSet) boundaryInputs(input string) []string {
+	if len(fs.normalize) > 0 {
+		// We don't know what normalization will do to the runes we
+		// add.
+		return nil
+	}
+
+	var result []string
+	if len(fs.stop) > 0 {
+		if fs.backwards {
+			result = append(result, string(fs.stop[0])+input)
+		} else {
+			result = append(result, input+string(fs.stop[0]))
+		}
+	}
+
+	// When NoTrailingMark is specified, an ignored rune which extends a
+	// grapheme cluster would prevent a match after the key.
+	var ignored rune
+	for _, r := range fs.ignore {
+		if !extendsCluster(r) {
+			ignored = r
+			break
+		}
+	}
+	if len(fs.ignoreExcept) > 0 {
+	nextCandidate:
+		for _, r := range []rune{' ', '-', '_', '.', '~', '!'} {
+			for _, other := range fs.ignoreExcept {
+				if r == other {
+					continue nextCandidate
+				}
+			}
+			for _, other := range fs.stop {
+				if r == other {
+					continue nextCandidate
+				}
+			}
+			ignored = r
+			break
+		}
+	}
+	if ignored != 0 {
+		var b strings.Builder
+		b.WriteRune(ignored)
+		for _, r := range input {
+			b.WriteRune(r)
+			b.WriteRune(ignored)
+		}
+		result = append(result, b.String())
+	}
+
+	// Inputs which exceed MaxDepth wouldn't match.
+	if fs.maxDepth > 0 {
+		n := 0
+		for _, s := range result {
+			if utf8.RuneCountInString(s) <= fs.maxDepth {
+				result[n] = s
+				n++
+			}
+		}
+		result = result[:n]
+	}
+	return result
+}
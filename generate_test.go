@@ -29,6 +29,7 @@
 package fastmatch
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -138,6 +139,52 @@ func generateRunnable(t *testing.T, which testDirection, retType string, cases m
 	return cleanup, err
 }
 
+// generateProgram creates a temporary directory, adds it to GOPATH, and
+// writes a runnable program named generated.go therein.  The package clause
+// and import block are written by this function; body is responsible for
+// outputting everything else, including func main().
+//
+// As with generateRunnable, a cleanup function is returned, which should be
+// executed by the caller at the completion of the test.
+func generateProgram(imports []string, body func(io.Writer) error) (func(), error) {
+	cleanup := func() {}
+
+	var out io.Writer
+	dir, err := ioutil.TempDir("", "fastmatch_test")
+	if err == nil {
+		savedWd, _ := os.Getwd()
+		err = os.Chdir(dir)
+		if err == nil {
+			savedGopath := os.Getenv("GOPATH")
+			os.Setenv("GOPATH", fmt.Sprintf("%s:%s", dir, savedGopath))
+			cleanup = func() {
+				os.Setenv("GOPATH", savedGopath)
+				os.Chdir(savedWd)
+				os.RemoveAll(dir)
+			}
+			out, err = os.Create("generated.go")
+		}
+	}
+	if err != nil {
+		return cleanup, err
+	}
+
+	_, err = fmt.Fprintln(out, "package main")
+	if err != nil {
+		return cleanup, err
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "import (")
+	for _, imp := range imports {
+		fmt.Fprintf(out, "\t%q", imp)
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out, ")")
+	fmt.Fprintln(out)
+
+	return cleanup, body(out)
+}
+
 // expectMatch uses `go run` to execute our generated test.go file.  It passes
 // the provided input and compares the output to what the test expects.
 func expectMatch(t *testing.T, input, expect string) {
@@ -286,6 +333,60 @@ func TestHasSuffix(t *testing.T) {
 	expectMatch(t, "baz", "0")
 }
 
+// TestHasSuffixUnicode tests suffix matching of keys containing multi-byte
+// runes, including right-to-left text, combining characters, and
+// bidirectional control characters.
+func TestHasSuffixUnicode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"\u05e9\u05dc\u05d5\u05dd":              "1", // Hebrew "shalom"
+		"\u0633\u0644\u0627\u0645":              "2", // Arabic "salaam"
+		"e\u0301":                               "3", // decomposed "é"
+		"\u05d0\u05d1\u200f":                    "4", // Hebrew + RLM
+		"\u05d2.\u0627\u0644\u0639\u0631\u0628": "5",
+	}, "0", HasSuffix)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "\u05e9\u05dc\u05d5\u05dd", "1")
+	expectMatch(t, "\u05d0\u05de\u05e8 \u05e9\u05dc\u05d5\u05dd", "1")
+	expectMatch(t, "\u0642\u0627\u0644 \u0633\u0644\u0627\u0645", "2")
+	expectMatch(t, "cafe\u0301", "3")
+	expectMatch(t, "caf\u00e9", "0")
+	expectMatch(t, "x\u05d0\u05d1\u200f", "4")
+	expectMatch(t, "\u05d0\u05d1", "0")
+	expectMatch(t, "\u05d2.\u0627\u0644\u0639\u0631\u0628", "5")
+	expectMatch(t, "\u05dd\u05d5\u05dc\u05e9", "0")
+}
+
+// TestSuffixStopUponUnicode tests combining StopUpon and HasSuffix with
+// multi-byte keys.
+func TestSuffixStopUponUnicode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"\u05e7\u05d5\u05d1\u05e5.\u05d8\u05e7\u05e1\u05d8": "1",
+		"\u0645\u0644\u0641.\u0646\u0635":                   "2",
+	}, "0", HasSuffix, StopUpon('.'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "\u05d8\u05e7\u05e1\u05d8", "1")
+	expectMatch(t, "\u05d0.\u05d8\u05e7\u05e1\u05d8", "1")
+	expectMatch(t, "\u0646\u0635", "2")
+	expectMatch(t, "\u0645.\u0646\u0635", "2")
+	expectMatch(t, "\u0635\u0646", "0")
+}
+
 // TestStopUpon tests a matcher that's been directed to stop when a certain
 // rune is encountered.
 func TestStopUpon(t *testing.T) {
@@ -429,6 +530,100 @@ func TestIgnore(t *testing.T) {
 	expectMatch(t, "...", "0")
 }
 
+// TestIgnoreLengths tests that a mismatch against longer keys falls through
+// to shorter keys when Ignore is specified, since the input length includes
+// ignored runes.
+func TestIgnoreLengths(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo_bar":  "1",
+		"quux_baz": "2",
+	}, "0", Ignore('_'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo_bar", "1")
+	expectMatch(t, "foo__bar", "1")
+	expectMatch(t, "quuxbaz", "2")
+	expectMatch(t, "foobarx", "0")
+}
+
+// TestIgnoreCounted tests that, when matching the entire input, a mismatch
+// after skipping ignored bytes counts the remaining bytes, instead of falling
+// through to each shorter length of keys.
+func TestIgnoreCounted(t *testing.T) {
+	cases := map[string]string{"foo": "1", "quux": "2", "abcdefg": "3"}
+	var b bytes.Buffer
+	if err := Generate(&b, cases, "0", Ignore('-', '_')); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"\t\tswitch fastmatchLen {\n", "\t\t\t\tfastmatchLen++\n", "\t\tif ignored > 0 || fastmatchCounted {\n"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+	for _, flags := range [][]*Flag{{Ignore('-'), HasPrefix}, {Ignore('-'), StopUpon(' ')}, {Ignore('\u00ad')}} {
+		b.Reset()
+		if err := Generate(&b, cases, "0", flags...); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(b.String(), "fastmatchLen") {
+			t.Errorf("%s: unexpected count of ignored bytes:\n%s", flagNames(flags), b.String())
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", cases, "0", Ignore('-', '_'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "abcdefg", "3")
+	expectMatch(t, "----------foo", "1")
+	expectMatch(t, "foo__________", "1")
+	expectMatch(t, "q-u-u-x", "2")
+	expectMatch(t, "quux---", "2")
+	expectMatch(t, "a-b-c-d-e-f-g", "3")
+	expectMatch(t, "abc-defg_", "3")
+	expectMatch(t, "f-o-o", "1")
+	expectMatch(t, "foo--------x", "0")
+	expectMatch(t, "abc-defgh", "0")
+	expectMatch(t, "fooquux", "0")
+	expectMatch(t, "-_-_-", "0")
+	expectMatch(t, "", "0")
+}
+
+// TestStopUponLengths tests that a mismatch against longer keys falls through
+// to shorter keys when StopUpon is specified.
+func TestStopUponLengths(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":  "1",
+		"quux": "2",
+	}, "0", StopUpon('.'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo.bar", "1")
+	expectMatch(t, "quux.bar", "2")
+	expectMatch(t, "fooo.bar", "0")
+}
+
 // TestMultipleIgnore tests that multiple Ignore runes can be specified.
 func TestMultipleIgnore(t *testing.T) {
 	if testing.Short() {
@@ -512,6 +707,220 @@ func TestIgnoreEquivalent(t *testing.T) {
 	expectMatch(t, "bar", "0")
 }
 
+// TestIgnoreInvisible tests ignoring invisible multi-byte runes.
+func TestIgnoreInvisible(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":      "1",
+		"b\u00e9r": "2",
+	}, "0", IgnoreInvisible, Ignore('-'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "\ufefffoo", "1")
+	expectMatch(t, "f\u200bo-o\u00ad", "1")
+	expectMatch(t, "b\u2060\u00e9r\u200d", "2")
+	expectMatch(t, "fo\u200b", "0")
+	expectMatch(t, "foo\u200bo", "0")
+}
+
+// TestSuffixIgnoreInvisible tests ignoring invisible multi-byte runes when
+// suffix matching.
+func TestSuffixIgnoreInvisible(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		".txt":  "1",
+		".html": "2",
+	}, "0", IgnoreInvisible, HasSuffix)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo.txt\ufeff", "1")
+	expectMatch(t, "foo.t\u200bxt", "1")
+	expectMatch(t, "\u200c.ht\u00adml", "2")
+	expectMatch(t, "foo.tx\u200b", "0")
+}
+
+// TestIgnoreSeparatorsPlural tests the IgnoreSeparators and IgnorePlural
+// flags, which are typically used together for matching configuration keys.
+func TestIgnoreSeparatorsPlural(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"max_age":     "1",
+		"header":      "2",
+		"allow-hosts": "3",
+	}, "0", Insensitive, IgnoreSeparators, IgnorePlural)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "max_age", "1")
+	expectMatch(t, "Max-Age", "1")
+	expectMatch(t, "max age", "1")
+	expectMatch(t, "maxAges", "1")
+	expectMatch(t, "headers", "2")
+	expectMatch(t, "HEADERS", "2")
+	expectMatch(t, "allow_host", "3")
+	expectMatch(t, "allowhostss", "0")
+}
+
+// TestChompLine tests that a single trailing newline is disregarded.
+func TestChompLine(t *testing.T) {
+	if chompLine("") != "" || chompLine("\r\n") != "" || chompLine("a\r") != "a\r" || chompLine("a\n\n") != "a\n" {
+		t.Error("chompLine returned wrong result")
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo": "1",
+		"bar": "2",
+	}, "0", ChompLine)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "foo\n", "1")
+	expectMatch(t, "bar\r\n", "2")
+	expectMatch(t, "bar\r", "0")
+	expectMatch(t, "foo\n\n", "0")
+	expectMatch(t, "foo\r\r\n", "0")
+	expectMatch(t, "\n", "0")
+}
+
+// TestIdentifierCase tests the IdentifierCase flag.
+func TestIdentifierCase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"maxAge":        "1",
+		"allow_origin":  "2",
+		"Cache-Control": "3",
+	}, "0", IdentifierCase)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "maxAge", "1")
+	expectMatch(t, "max_age", "1")
+	expectMatch(t, "MAX-AGE", "1")
+	expectMatch(t, "AllowOrigin", "2")
+	expectMatch(t, "allow-origin", "2")
+	expectMatch(t, "cache_control", "3")
+	expectMatch(t, "cachecontrol", "3")
+	expectMatch(t, "cache.control", "0")
+}
+
+// TestAnyDigit tests matching classes of numeric codes.
+func TestAnyDigit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"2xx": "2",
+		"404": "44",
+		"4xx": "4",
+		"5xx": "5",
+	}, "0", AnyDigit('x'))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "200", "2")
+	expectMatch(t, "299", "2")
+	expectMatch(t, "404", "44")
+	expectMatch(t, "400", "4")
+	expectMatch(t, "503", "5")
+	expectMatch(t, "301", "0")
+	expectMatch(t, "4xx", "0")
+	expectMatch(t, "4000", "0")
+}
+
+// TestTokenShapes tests matching keywords along with token shapes.
+func TestTokenShapes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"if":         "1",
+		"for":        "2",
+		"0xdead":     "3",
+		IntegerToken: "4",
+		HexToken:     "5",
+		DateToken:    "6",
+	}, "0", Insensitive)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "IF", "1")
+	expectMatch(t, "0xDEAD", "3")
+	expectMatch(t, "0123", "4")
+	expectMatch(t, "0xBEEF", "5")
+	expectMatch(t, "0x", "0")
+	expectMatch(t, "0xg", "0")
+	expectMatch(t, "2024-02-29", "6")
+	expectMatch(t, "2024-13-01", "0")
+	expectMatch(t, "2024-1-01", "0")
+	expectMatch(t, "-1", "0")
+	expectMatch(t, "", "0")
+}
+
+// TestMaxFanOut tests matching using class tables instead of large case
+// clauses.
+func TestMaxFanOut(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":   "1",
+		"bar":   "2",
+		"baz":   "3",
+		"fizz":  "4",
+		"buzz":  "5",
+		"quuux": "6",
+	}, "0", Insensitive, IgnoreExcept(Letters...), MaxFanOut(4))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "FOO", "1")
+	expectMatch(t, "b-a-r", "2")
+	expectMatch(t, "BaZ", "3")
+	expectMatch(t, "fi.zz", "4")
+	expectMatch(t, "buz", "0")
+	expectMatch(t, "quUux!", "6")
+	expectMatch(t, "bax", "0")
+}
+
 // TestIgnoreExcept tests matching where all but a subset of runes are
 // ignored.
 func TestIgnoreExcept(t *testing.T) {
@@ -637,6 +1046,49 @@ func TestSuffixIgnoreExcept(t *testing.T) {
 	expectMatch(t, "222", "0")
 }
 
+// TestNormalizeInput tests a matcher which normalizes its input prior to
+// matching.
+func TestNormalizeInput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	// Stand-in for norm.NFC.String, which only knows about "é":
+	compose := func(s string) string {
+		return strings.Replace(s, "e\u0301", "\u00e9", -1)
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strings"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func compose(s string) string {")
+		fmt.Fprintln(w, "\treturn strings.Replace(s, \"e\\u0301\", \"\\u00e9\", -1)")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func match(input string) int {")
+		err := Generate(w, map[string]string{
+			"caf\u00e9":   "1",
+			"cre\u0301me": "2",
+		}, "0", NormalizeInput("compose", compose))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "caf\u00e9", "1")
+	expectMatch(t, "cafe\u0301", "1")
+	expectMatch(t, "cr\u00e9me", "2")
+	expectMatch(t, "cre\u0301me", "2")
+	expectMatch(t, "cafe", "0")
+}
+
 // TestChained tests chaining multiple state machines together, to match
 // longer strings.
 func TestChained(t *testing.T) {
@@ -644,14 +1096,10 @@ func TestChained(t *testing.T) {
 		t.Skip("skipping compiled tests in short mode")
 	}
 
-	oldMaxState := maxState
-	defer func() { maxState = oldMaxState }()
-	maxState = 16
-
 	cleanup, err := generateRunnable(t, match, "int", map[string]string{
 		"abcdef": "1",
 		"ghijkl": "2",
-	}, "0")
+	}, "0", MaxState(16))
 	defer cleanup()
 	if err != nil {
 		t.Fatalf(err.Error())
@@ -662,6 +1110,27 @@ func TestChained(t *testing.T) {
 	expectMatch(t, "123456", "0")
 }
 
+// TestMaxState tests that MaxState rejects values which are too small, and
+// that an error is returned if the states at one offset don't fit.
+func TestMaxState(t *testing.T) {
+	cases := map[string]string{"abcdef": "1", "ghijkl": "2"}
+	for _, n := range []uint64{0, MinMaxState - 1} {
+		err := Generate(ioutil.Discard, cases, "0", MaxState(n))
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("MaxState(%d): expected *ErrBadFlags, got %v", n, err)
+		}
+	}
+
+	wide := make(map[string]string, 26)
+	for r := 'a'; r <= 'z'; r++ {
+		wide[string(r)+"x"] = fmt.Sprint(r)
+	}
+	err := Generate(ioutil.Discard, wide, "0", MaxState(MinMaxState))
+	if err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Errorf("expected error from MaxState(%d) with %d keys, got %v", MinMaxState, len(wide), err)
+	}
+}
+
 // TestReverse tests a simple reverse matcher.
 func TestReverse(t *testing.T) {
 	if testing.Short() {
@@ -682,6 +1151,102 @@ func TestReverse(t *testing.T) {
 	expectMatch(t, "0", "baz")
 }
 
+// TestZeroAllocs tests that the self-test output with the ZeroAllocs flag
+// passes for both the forward and reverse matchers.
+func TestZeroAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":    "1",
+		"bar":    "2",
+		"foobar": "3",
+	}, "0", ZeroAllocs)
+	cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup, err = generateRunnable(t, reverseMatch, "string", map[string]string{
+		"foo": `"1"`,
+		"bar": `"2"`,
+	}, `""`, ZeroAllocs)
+	cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestZeroAllocsOutput tests that ZeroAllocs adds an allocation check to
+// the self-test, covering every key.
+func TestZeroAllocsOutput(t *testing.T) {
+	var b bytes.Buffer
+	if err := GenerateTest(&b, "Match(%q)", "", map[string]string{"a": "1", "b": "2"}, ZeroAllocs); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"testing.AllocsPerRun", `_ = Match("a")`, `_ = Match("b")`} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := GenerateTest(&b, "Match(%q)", "", map[string]string{"a": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "AllocsPerRun") {
+		t.Error("did not expect allocation check without ZeroAllocs")
+	}
+}
+
+// TestGenerateTestSubtests tests that each key is tested in a subtest named
+// for the key.
+func TestGenerateTestSubtests(t *testing.T) {
+	var b bytes.Buffer
+	cases := map[string]string{"a": "1", "b c": "2", IntegerToken: "3"}
+	if err := GenerateTest(&b, "Match(%q)", "", cases); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"\tt.Run(\"a\", func(t *testing.T) {\n\t\tif Match(\"a\") != 1 {\n\t\t\tt.Errorf(\"wrong answer for %q\", \"a\")\n\t\t}\n\t})\n",
+		"\tt.Run(\"b c\", func(t *testing.T) {\n",
+		"\tt.Run(\"IntegerToken\", func(t *testing.T) {\n\t\tif Match(\"42\") != 3 {\n",
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+}
+
+// TestGenerateTestBoundaries tests that inputs exercising StopUpon and Ignore
+// are derived from each key.
+func TestGenerateTestBoundaries(t *testing.T) {
+	var b bytes.Buffer
+	if err := GenerateTest(&b, "Match(%q)", "", map[string]string{"ab": "1"}, StopUpon('.'), Ignore('-')); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"\t\tif Match(\"ab.\") != 1 {\n",
+		"\t\tif Match(\"-a-b-\") != 1 {\n",
+		"\tt.Run(\"boundaries\", func(t *testing.T) {\n\t\t_ = Match(\"\")\n\t\t_ = Match(\"a\")\n\t\t_ = Match(\"abx\")\n\t})\n",
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := GenerateTest(&b, "Match(%q)", "", map[string]string{"ab": "1"}, StopUpon('.'), HasSuffix, IgnoreExcept('a', 'b')); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"Match(\".ab\") != 1", "Match(\" a b \") != 1"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+}
+
 // TestBadWriter tests that Generate and GenerateReverse return an error
 // if passed an unusable io.Writer.
 func TestBadWriter(t *testing.T) {
@@ -705,3 +1270,199 @@ func TestBadWriter(t *testing.T) {
 		t.Errorf("no error from GenerateTest (reverse matcher) on closed io.Writer")
 	}
 }
+
+// TestDeterministic tests that Generate produces the same output every time
+// for the same input, regardless of map iteration order.
+func TestDeterministic(t *testing.T) {
+	cases := make(map[string]string, 300)
+	for n := 0; n < 300; n++ {
+		cases[fmt.Sprintf("k%dx%d", n%17, n)] = fmt.Sprint(n % 5)
+	}
+	for _, flags := range [][]*Flag{
+		nil,
+		{Insensitive},
+		{HasSuffix},
+		{StopUpon(' ')},
+		{Ignore('-')},
+		{MaxFanOut(2)},
+	} {
+		var expect bytes.Buffer
+		if err := Generate(&expect, cases, "-1", flags...); err != nil {
+			t.Fatal(err)
+		}
+		for n := 0; n < 5; n++ {
+			var b bytes.Buffer
+			if err := Generate(&b, cases, "-1", flags...); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != expect.String() {
+				t.Errorf("%s: output differs between calls", flagNames(flags))
+				break
+			}
+		}
+	}
+}
+
+// TestDeterministicChained tests that state values collapsed when chaining
+// state machines are numbered the same way every time.
+func TestDeterministicChained(t *testing.T) {
+	cases := map[string]string{
+		"abcdef": "1",
+		"abcxyz": "2",
+		"ghijkl": "3",
+		"ghixyz": "4",
+		"mnopqr": "5",
+		"stuvwx": "6",
+	}
+	var expect bytes.Buffer
+	if err := Generate(&expect, cases, "0", MaxState(64)); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 20; n++ {
+		var b bytes.Buffer
+		if err := Generate(&b, cases, "0", MaxState(64)); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != expect.String() {
+			t.Fatalf("output differs between calls:\n%s\n\n%s", expect.String(), b.String())
+		}
+	}
+}
+
+// TestBoundsCheckHint tests that each length bucket begins with a bounds
+// check hint, and that the compiler eliminates the bounds checks in the
+// generated code.
+func TestBoundsCheckHint(t *testing.T) {
+	cases := map[string]string{"foo": "1", "quux": "2", "a": "3"}
+	var b bytes.Buffer
+	if err := Generate(&b, cases, "0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{"\t\t_ = input[2] // bounds check hint\n", "\t\t_ = input[3] // bounds check hint\n"} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "input[0] // bounds check hint") {
+		t.Errorf("unexpected hint for single-byte key:\n%s", b.String())
+	}
+	for _, flags := range [][]*Flag{{HasSuffix}, {Ignore('-')}} {
+		b.Reset()
+		if err := Generate(&b, cases, "0", flags...); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(b.String(), "bounds check hint") {
+			t.Errorf("%s: unexpected hint:\n%s", flagNames(flags), b.String())
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+	cleanup, err := generateProgram([]string{"os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func exact(input string) int {")
+		if err := Generate(w, cases, "0"); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func prefix(input string) int {")
+		if err := Generate(w, cases, "0", HasPrefix, StopUpon(' ')); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfor _, arg := range os.Args {")
+		fmt.Fprintln(w, "\t\t_, _ = exact(arg), prefix(arg)")
+		fmt.Fprintln(w, "\t}")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("go", "build", "-gcflags=-d=ssa/check_bce/debug=1", "generated.go").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	if strings.Contains(string(out), "IsInBounds") {
+		t.Errorf("bounds checks remain in generated code:\n%s", out)
+	}
+}
+
+// BenchmarkIgnore measures the generated code's performance on inputs
+// containing ignored runes.  Since the generated code has to be compiled,
+// the timing is done by a separate program, and reported as the ns/match
+// metric.
+func BenchmarkIgnore(b *testing.B) {
+	cases := map[string]string{
+		"foo":        "1",
+		"quux":       "2",
+		"xyzzy":      "3",
+		"frobzz":     "4",
+		"abcdefg":    "5",
+		"abcdefgh":   "6",
+		"abcdefghi":  "7",
+		"abcdefghij": "8",
+		"foobar":     "9",
+	}
+	inputs := []struct{ name, input string }{
+		{"None", "foobar"},
+		{"NoneLong", "abcdefghij"},
+		{"Sparse", "foo-bar"},
+		{"Dense", "a-b-c-d-e-f-g-h-i-j"},
+		{"Leading", "----------foo"},
+		{"Trailing", "abcdefghi-"},
+		{"Mismatch", "foo--------------------x"},
+		{"Short", "x"},
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "testing"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) int {")
+		if err := Generate(w, cases, "0", Ignore('-', '_')); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "var sink int")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfor _, input := range []string{")
+		for _, in := range inputs {
+			fmt.Fprintf(w, "\t\t%q,", in.input)
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "\t} {")
+		fmt.Fprintln(w, "\t\tr := testing.Benchmark(func(b *testing.B) {")
+		fmt.Fprintln(w, "\t\t\tfor n := 0; n < b.N; n++ {")
+		fmt.Fprintln(w, "\t\t\t\tsink = match(input)")
+		fmt.Fprintln(w, "\t\t\t}")
+		fmt.Fprintln(w, "\t\t})")
+		fmt.Fprintln(w, "\t\tfmt.Println(float64(r.T.Nanoseconds()) / float64(r.N))")
+		fmt.Fprintln(w, "\t}")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	out, err := exec.Command("go", "run", "generated.go").CombinedOutput()
+	if err != nil {
+		b.Fatalf("%s: %s", err, out)
+	}
+	results := strings.Fields(string(out))
+	if len(results) != len(inputs) {
+		b.Fatalf("unexpected output: %s", out)
+	}
+	for n, in := range inputs {
+		var ns float64
+		if _, err := fmt.Sscan(results[n], &ns); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportMetric(ns, "ns/match")
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Generator assembles a Go source file containing more than one matcher
+// function.  Each call to Add generates a function in memory; Flush then
+// writes the whole file, with a package clause and the imports needed by
+// every function, formatted with go/format:
+//
+//	g := fastmatch.NewGenerator(w, "lookup")
+//	if err := g.Add("matchColor", "Color", colors, "0", fastmatch.Insensitive); err != nil {
+//		return err
+//	}
+//	if err := g.Add("matchShape", "Shape", shapes, "0"); err != nil {
+//		return err
+//	}
+//	return g.Flush()
+//
+// Function names must be unique.  Labels and lookup tables in the generated
+// code are local to the function which uses them, so they never collide
+// with those of another function.  Helper functions which would otherwise
+// be repeated in each function which uses them, such as those output for
+// FoldEquivalents, are declared once, and named after the first function
+// which used them.
+//
+// Nothing is written to w until Flush, so a failed Add leaves no trace in
+// the output.
+type Generator struct {
+	w       io.Writer
+	pkg     string
+	imports map[string]bool
+	funcs   map[string]bool
+	body    bytes.Buffer
+	helpers *helperSet
+}
+
+// NewGenerator returns a Generator which writes a file in package pkg to w.
+func NewGenerator(w io.Writer, pkg string) *Generator {
+	return &Generator{
+		w:       w,
+		pkg:     pkg,
+		imports: make(map[string]bool),
+		funcs:   make(map[string]bool),
+		helpers: &helperSet{names: make(map[string]string)},
+	}
+}
+
+// Import adds packages referenced by the values or none expressions passed
+// to Add, or by Params, to the file's imports.  Those required by flags are
+// imported automatically (see Imports).
+func (g *Generator) Import(paths ...string) {
+	for _, path := range paths {
+		g.imports[path] = true
+	}
+}
+
+// Add generates a function named fn, which matches its input against cases
+// as described by Generate, and returns a value of type retType.  If an
+// error is returned, nothing is added to the file.
+func (g *Generator) Add(fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	if g.funcs[fn] {
+		return fmt.Errorf("function %s was already added", fn)
+	}
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "\nfunc %s(input string%s) %s {\n", fn, fs.extraParams(), retType)
+	g.helpers.fn = fn
+	numHelpers := len(g.helpers.exprs)
+	genFlags := append(append([]*Flag(nil), flags...), &Flag{helpers: g.helpers})
+	if err := Generate(&b, cases, none, genFlags...); err != nil {
+		for _, expr := range g.helpers.exprs[numHelpers:] {
+			delete(g.helpers.names, expr)
+		}
+		g.helpers.exprs = g.helpers.exprs[:numHelpers]
+		return err
+	}
+
+	g.body.Write(b.Bytes())
+	g.funcs[fn] = true
+	g.Import(Imports(flags...)...)
+	return nil
+}
+
+// Flush formats the file and writes it.  An error is returned if the file is
+// not valid Go, such as if one of the values was not a valid expression.
+func (g *Generator) Flush() error {
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+
+	var b bytes.Buffer
+	writeFileHeader(&b, g.pkg, imports)
+	for n, expr := range g.helpers.exprs {
+		if n == 0 {
+			fmt.Fprintln(&b)
+		}
+		fmt.Fprintf(&b, "var %s = %s\n", g.helpers.names[expr], expr)
+	}
+	b.Write(g.body.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code is invalid: %s", err.Error())
+	}
+	_, err = g.w.Write(src)
+	return err
+}
+
+// helperSet records the helper functions declared by a Generator.
+type helperSet struct {
+	// fn is the function being generated, which new helpers are named
+	// after.
+	fn string
+
+	// names maps each helper's expression to its name, and exprs lists
+	// the expressions in the order they were first used.
+	names map[string]string
+	exprs []string
+}
+
+// normalizeCall returns the expression which calls a NormalizeInput flag's
+// function in the generated code.  Function literals are declared once by
+// the Generator, if there is one.
+func (fs *flagSet) normalizeCall(flag *Flag) string {
+	expr := flag.normalizeExpr
+	if fs.helpers == nil || !strings.HasPrefix(expr, "func(") {
+		return expr
+	}
+	if name, found := fs.helpers.names[expr]; found {
+		return name
+	}
+	name := fmt.Sprintf("fastmatch_%s_%d", fs.helpers.fn, len(fs.helpers.exprs)+1)
+	fs.helpers.names[expr] = name
+	fs.helpers.exprs = append(fs.helpers.exprs, expr)
+	return name
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerator tests generating a file containing more than one matcher.
+func TestGenerator(t *testing.T) {
+	var b bytes.Buffer
+	g := NewGenerator(&b, "main")
+	g.Import("strconv")
+	unicode := []*Flag{FoldEquivalents, Equivalent('i', 'ï')}
+	if err := g.Add("matchA", "int", map[string]string{"naïve": "1", "foo": "2"}, "0", unicode...); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Add("matchB", "string", map[string]string{"naïve": `strconv.Itoa(3)`, "bar": `"4"`}, `""`, unicode...); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Add("matchA", "int", map[string]string{"foo": "1"}, "0"); err == nil {
+		t.Error("expected error adding matchA twice")
+	}
+	if err := g.Add("matchC", "int", map[string]string{"foo": "1", "FOO": "2"}, "0", Insensitive); err == nil {
+		t.Error("expected error for ambiguous keys")
+	}
+	if err := g.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	src := b.String()
+	if n := strings.Count(src, "func(s string) string {"); n != 1 {
+		t.Errorf("expected FoldEquivalents helper to be declared once, found %d times:\n%s", n, src)
+	}
+	if strings.Contains(src, "matchC") {
+		t.Errorf("unexpected output from failed Add:\n%s", src)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+	dir, err := ioutil.TempDir("", "fastmatch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "generated.go"), b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\n" +
+		"func main() {\n\tfmt.Println(matchA(os.Args[1]), matchB(os.Args[1]))\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for input, expect := range map[string]string{
+		"naive": "1 3",
+		"naïve": "1 3",
+		"foo":   "2 ",
+		"bar":   "0 4",
+	} {
+		cmd := exec.Command("go", "run", "generated.go", "main.go", input)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %s", err.Error(), strings.TrimSpace(string(out)))
+		}
+		if got := strings.TrimRight(string(out), "\n"); got != expect {
+			t.Errorf("expected %q, got %q for input %q", expect, got, input)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// CommonHeaders lists frequently-used HTTP header names, for use with
+// GenerateCanonicalHeaderKey.
+var CommonHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Accept-Ranges",
+	"Age",
+	"Authorization",
+	"Cache-Control",
+	"Connection",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"Cookie",
+	"Date",
+	"Etag",
+	"Expect",
+	"Expires",
+	"Forwarded",
+	"Host",
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"If-Unmodified-Since",
+	"Keep-Alive",
+	"Last-Modified",
+	"Link",
+	"Location",
+	"Origin",
+	"Pragma",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Range",
+	"Referer",
+	"Retry-After",
+	"Server",
+	"Set-Cookie",
+	"Strict-Transport-Security",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"User-Agent",
+	"Vary",
+	"Via",
+	"Www-Authenticate",
+	"X-Forwarded-For",
+	"X-Forwarded-Host",
+	"X-Forwarded-Proto",
+	"X-Request-Id",
+}
+
+// isTokenByte reports whether c may appear in an HTTP header name, per the
+// definition of tchar in RFC 7230.
+func isTokenByte(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '^', '_', '`', '|', '~':
+		return true
+	}
+	return false
+}
+
+// GenerateCanonicalHeaderKey outputs Go code for a function named fn, which
+// returns the canonical form of an HTTP header name, in the same manner as
+// textproto.CanonicalMIMEHeaderKey (and http.CanonicalHeaderKey).  The
+// generated function has the signature:
+//
+//	func fn(input string) string
+//
+// Input which matches one of headers, irrespective of case, is converted
+// without allocating memory, by returning a string constant.  Other input is
+// passed to textproto.CanonicalMIMEHeaderKey, so the generated code must
+// import net/textproto.  This is useful in proxies and servers, which
+// canonicalize every header they receive, the vast majority of which are
+// drawn from a small set of well-known names, such as CommonHeaders:
+//
+//	fastmatch.GenerateCanonicalHeaderKey(w, "canonicalHeaderKey",
+//		fastmatch.CommonHeaders)
+//
+// The headers may be supplied in any case.  An error is returned if any of
+// them are empty, or contain bytes which are not valid in a header name.
+func GenerateCanonicalHeaderKey(w io.Writer, fn string, headers []string) error {
+	cases := make(map[string]string, len(headers))
+	for _, header := range headers {
+		if header == "" {
+			return ErrEmptyKey
+		}
+		for i := 0; i < len(header); i++ {
+			if !isTokenByte(header[i]) {
+				return fmt.Errorf("invalid header name: %s", strconv.Quote(header))
+			}
+		}
+		cases[header] = strconv.Quote(textproto.CanonicalMIMEHeaderKey(header))
+	}
+
+	if _, err := fmt.Fprintf(w, "// %s returns the canonical format of the MIME header key input, like", fn); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// textproto.CanonicalMIMEHeaderKey, without allocating if it is a known header.")
+	fmt.Fprintf(w, "func %s(input string) string {", fn)
+	fmt.Fprintln(w)
+	return Generate(w, cases, "textproto.CanonicalMIMEHeaderKey(input)", Insensitive, ASCIIOnly)
+}
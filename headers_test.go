@@ -0,0 +1,88 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestCanonicalHeaderKey tests that canonicalizing known headers doesn't
+// allocate, and that other input is canonicalized by textproto.
+func TestCanonicalHeaderKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "net/textproto", "os", "testing"}, func(w io.Writer) error {
+		if err := GenerateCanonicalHeaderKey(w, "canonicalHeaderKey", CommonHeaders); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tinput := os.Args[1]")
+		fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+		fmt.Fprintln(w, "\t\tcanonicalHeaderKey(input)")
+		fmt.Fprintln(w, "\t})")
+		fmt.Fprintln(w, "\tfmt.Printf(\"%q %t %v\", canonicalHeaderKey(input), canonicalHeaderKey(input) == textproto.CanonicalMIMEHeaderKey(input), allocs)")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "Content-Type", `"Content-Type" true 0`)
+	expectMatch(t, "content-type", `"Content-Type" true 0`)
+	expectMatch(t, "WWW-AUTHENTICATE", `"Www-Authenticate" true 0`)
+	expectMatch(t, "x-custom-header", `"X-Custom-Header" true 1`)
+	expectMatch(t, "Content-Typo", `"Content-Typo" true 0`)
+	expectMatch(t, "Keep-alive", `"Keep-alive" true 0`)
+}
+
+// TestCanonicalHeaderKeyErrors tests invalid header names.
+func TestCanonicalHeaderKeyErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		headers []string
+		expect  string
+	}{
+		{[]string{"Host", ""}, ErrEmptyKey.Error()},
+		{[]string{"Content Type"}, `invalid header name: "Content Type"`},
+		{[]string{"X-Café"}, `invalid header name: "X-Café"`},
+	} {
+		err := GenerateCanonicalHeaderKey(ioutil.Discard, "canonicalHeaderKey", testCase.headers)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package iana downloads and parses registries maintained by the Internet
+// Assigned Numbers Authority, such as URI schemes and media types, into
+// cases for fastmatch.Generate.  This allows matchers for these to be kept
+// current by regenerating them, rather than by editing lists of keys by hand.
+//
+// The registries are fetched from the CSV files published by IANA.  Since
+// these change over time (which is the point), a Fetcher can cache the files
+// it downloads and verify them against pinned checksums, so that the
+// generated code is reproducible until the caller chooses to update it.
+package iana
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pifke.org/fastmatch"
+)
+
+// Registry describes an IANA registry, for use with a Fetcher.
+type Registry struct {
+	// Name is a human-readable name for the registry, such as "URI
+	// Schemes".
+	Name string
+
+	// Source is the URL of the registry's web page.
+	Source string
+
+	// URLs are the CSV files which make up the registry.  The first line
+	// of each is expected to name the columns.
+	URLs []string
+
+	// KeyColumn is the name of the column containing the keys.
+	KeyColumn string
+}
+
+// URISchemes is the registry of URI schemes, such as "http" and "mailto".
+var URISchemes = &Registry{
+	Name:      "URI Schemes",
+	Source:    "https://www.iana.org/assignments/uri-schemes/",
+	URLs:      []string{"https://www.iana.org/assignments/uri-schemes/uri-schemes-1.csv"},
+	KeyColumn: "URI Scheme",
+}
+
+// MediaTypes is the registry of media types, such as "text/plain".
+var MediaTypes = &Registry{
+	Name:   "Media Types",
+	Source: "https://www.iana.org/assignments/media-types/",
+	URLs: []string{
+		"https://www.iana.org/assignments/media-types/application.csv",
+		"https://www.iana.org/assignments/media-types/audio.csv",
+		"https://www.iana.org/assignments/media-types/font.csv",
+		"https://www.iana.org/assignments/media-types/image.csv",
+		"https://www.iana.org/assignments/media-types/message.csv",
+		"https://www.iana.org/assignments/media-types/model.csv",
+		"https://www.iana.org/assignments/media-types/multipart.csv",
+		"https://www.iana.org/assignments/media-types/text.csv",
+		"https://www.iana.org/assignments/media-types/video.csv",
+	},
+	KeyColumn: "Template",
+}
+
+// HTTPMethods is the registry of HTTP methods, such as "GET".
+var HTTPMethods = &Registry{
+	Name:      "HTTP Methods",
+	Source:    "https://www.iana.org/assignments/http-methods/",
+	URLs:      []string{"https://www.iana.org/assignments/http-methods/methods.csv"},
+	KeyColumn: "Method Name",
+}
+
+// Provenance returns a description of the registry, for use with
+// fastmatch.GenerateProvenance.  The License field is left blank, since
+// IANA's terms of use are for the caller to interpret.
+func (r *Registry) Provenance() fastmatch.Provenance {
+	return fastmatch.Provenance{
+		Name:   "IANA " + r.Name,
+		Source: r.Source,
+	}
+}
+
+// ErrChecksum is returned when a downloaded or cached file does not match
+// its pinned checksum.
+type ErrChecksum struct {
+	URL              string
+	Expected, Actual string
+}
+
+// Error implements the error interface.
+func (e *ErrChecksum) Error() string {
+	return fmt.Sprintf("%s: expected SHA-256 %s, got %s", e.URL, e.Expected, e.Actual)
+}
+
+// Fetcher downloads registries.  The zero value downloads every file each
+// time it is used, without verifying it.
+type Fetcher struct {
+	// Client is used to download files.  If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// CacheDir, if not empty, is a directory in which downloaded files
+	// are stored.  Files found there are used instead of downloading
+	// them again; to update a registry, remove its files.
+	CacheDir string
+
+	// Pins maps URLs to the expected SHA-256 checksum (in hexadecimal)
+	// of their contents.  Files with a pinned checksum which don't match
+	// cause ErrChecksum to be returned.  Files without a pinned checksum
+	// are not verified.
+	Pins map[string]string
+}
+
+// Cases returns the cases for a registry.  value is called with each key
+// and the corresponding row of the registry (mapping column names to their
+// contents), and returns the value expression for the key, or "" to omit the
+// key:
+//
+//	cases, err := new(iana.Fetcher).Cases(iana.HTTPMethods, func(key string, row map[string]string) string {
+//		if row["Safe"] != "yes" {
+//			return ""
+//		}
+//		return strconv.Quote(key)
+//	})
+//
+// Rows with an empty key are skipped.  If a key appears more than once, the
+// last row wins.
+func (f *Fetcher) Cases(r *Registry, value func(key string, row map[string]string) string) (map[string]string, error) {
+	cases := make(map[string]string)
+	for _, u := range r.URLs {
+		b, err := f.fetch(u)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := parseCSV(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", u, err)
+		}
+		for _, row := range rows {
+			key, ok := row[r.KeyColumn]
+			if !ok {
+				return nil, fmt.Errorf("%s: no %q column", u, r.KeyColumn)
+			}
+			if key = strings.TrimSpace(key); key == "" {
+				continue
+			}
+			if v := value(key, row); v != "" {
+				cases[key] = v
+			}
+		}
+	}
+	return cases, nil
+}
+
+// fetch returns the contents of the file at u, from the cache if possible.
+func (f *Fetcher) fetch(u string) ([]byte, error) {
+	var cached string
+	if f.CacheDir != "" {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		cached = filepath.Join(f.CacheDir, parsed.Host, filepath.FromSlash(parsed.Path))
+		if b, err := ioutil.ReadFile(cached); err == nil {
+			return b, f.verify(u, b)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.verify(u, b); err != nil {
+		return nil, err
+	}
+
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(cached, b, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// verify checks b against the pinned checksum (if any) for u.
+func (f *Fetcher) verify(u string, b []byte) error {
+	expected, ok := f.Pins[u]
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(b)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return &ErrChecksum{URL: u, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// parseCSV parses a CSV file whose first line names the columns.
+func parseCSV(b []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for n := range header {
+		header[n] = strings.TrimSpace(strings.TrimPrefix(header[n], "\ufeff"))
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for n, column := range header {
+			if n < len(record) {
+				row[column] = record[n]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
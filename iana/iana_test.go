@@ -0,0 +1,115 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package iana
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const methodsCSV = "\ufeffMethod Name,Safe,Idempotent,Reference\n" +
+	"GET,yes,yes,\"[RFC9110, Section 9.3.1]\"\n" +
+	"POST,no,no,\"[RFC9110,\n Section 9.3.3]\"\n" +
+	"PUT,no,yes,[RFC9110]\n" +
+	",,,\n"
+
+// TestCases tests fetching, parsing, caching, and pinning a registry.
+func TestCases(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/methods.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(methodsCSV))
+	}))
+	defer server.Close()
+
+	cacheDir, err := ioutil.TempDir("", "iana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	registry := &Registry{
+		Name:      "HTTP Methods",
+		URLs:      []string{server.URL + "/methods.csv"},
+		KeyColumn: "Method Name",
+	}
+	sum := sha256.Sum256([]byte(methodsCSV))
+	f := &Fetcher{
+		CacheDir: cacheDir,
+		Pins:     map[string]string{registry.URLs[0]: hex.EncodeToString(sum[:])},
+	}
+	value := func(key string, row map[string]string) string {
+		if row["Idempotent"] != "yes" {
+			return ""
+		}
+		return strconv.Quote(key)
+	}
+	expect := map[string]string{"GET": `"GET"`, "PUT": `"PUT"`}
+
+	for n := 0; n < 2; n++ {
+		cases, err := f.Cases(registry, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cases, expect) {
+			t.Errorf("expected %q, got %q", expect, cases)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	f.Pins[registry.URLs[0]] = "0000"
+	if _, err := f.Cases(registry, value); err == nil {
+		t.Error("expected checksum error")
+	} else if _, ok := err.(*ErrChecksum); !ok {
+		t.Errorf("expected *ErrChecksum, got %v", err)
+	}
+
+	registry.KeyColumn = "Nonexistent"
+	if _, err := new(Fetcher).Cases(registry, value); err == nil {
+		t.Error("expected error from missing column")
+	}
+
+	registry.URLs[0] = server.URL + "/missing.csv"
+	if _, err := new(Fetcher).Cases(registry, value); err == nil {
+		t.Error("expected error from missing file")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Inline is a flag, which can be passed to Generate, to output a plain
+// switch statement instead of a state machine.  For a small number of keys,
+// this is simple enough for the Go compiler to inline the generated function
+// into its callers, which can matter more than how quickly the function
+// itself executes.  Use Inlinable to check whether this is likely to be the
+// case.
+//
+// Inline cannot be combined with flags which change how the input is
+// compared, such as Insensitive or HasPrefix, or which add code before the
+// switch statement, such as TooShort or ASCIIOnly.
+var Inline = new(Flag)
+
+// inlineBudget is the maximum cost of a function which the Go compiler will
+// inline.  (See inlineMaxBudget in cmd/compile/internal/inline.)
+const inlineBudget = 80
+
+// inlineCallCost is the additional cost the Go compiler assigns to a
+// function call.
+const inlineCallCost = 57
+
+// exprCost estimates the inlining cost of a comma-separated list of Go
+// expressions, by counting syntax nodes.  Function calls are assumed not to
+// be inlined themselves.  (Type conversions can't be distinguished from
+// calls without type information, so they are also counted as calls.)
+func exprCost(exprs string) (int, error) {
+	expr, err := parser.ParseExpr("_(" + exprs + ")")
+	if err != nil {
+		return 0, err
+	}
+
+	cost := 0
+	for _, arg := range expr.(*ast.CallExpr).Args {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			switch node.(type) {
+			case nil, *ast.ParenExpr:
+			case *ast.CallExpr:
+				cost += inlineCallCost + 1
+			default:
+				cost++
+			}
+			return true
+		})
+	}
+	return cost, nil
+}
+
+// Inlinable reports whether the function output by Generate with the Inline
+// flag is likely to be inlined by the Go compiler, and the estimated cost
+// used to determine this.  The estimate is based on the compiler's current
+// heuristics, and may not be accurate for all versions of Go.
+//
+// An error is returned if the flags cannot be used with Inline, or if the
+// values are not valid Go expressions.
+func Inlinable(cases map[string]string, none string, flags ...*Flag) (bool, int, error) {
+	if err := checkInlineFlags(flags); err != nil {
+		return false, 0, err
+	}
+
+	// The switch statement and final return:
+	cost, err := exprCost(none)
+	if err != nil {
+		return false, 0, err
+	}
+	cost += 3
+
+	for _, value := range cases {
+		valueCost, err := exprCost(value)
+		if err != nil {
+			return false, 0, err
+		}
+		cost += valueCost + 3 // case, key, and return
+	}
+
+	return cost <= inlineBudget, cost, nil
+}
+
+// checkInlineFlags returns an error if any of the flags cannot be combined
+// with Inline: those which change how the input is compared, or which add
+// code before the switch statement.  Flags which only affect how the output
+// is written or checked, such as BufferOutput, Report, or ValueType, are
+// accepted.
+func checkInlineFlags(flags []*Flag) error {
+	var unsupported []string
+	for _, flag := range flags {
+		switch flag {
+		case Insensitive, InsensitiveUnicode, InsensitiveTurkish, Normalize,
+			HasPrefix, HasSuffix, LongestMatch, ReversePrefix, NoTrailingMark,
+			ConstantTime, ASCIIOnly, FoldEquivalents:
+			unsupported = append(unsupported, flagName(flag))
+			continue
+		}
+		if len(flag.equivalent) > 0 || len(flag.stop) > 0 || len(flag.ignore) > 0 || len(flag.ignoreExcept) > 0 ||
+			flag.normalizeFunc != nil || flag.preprocess != "" || flag.chain != "" || flag.transform != nil ||
+			flag.strategy != AutoStrategy || flag.anyDigit != 0 || flag.comparer != "" || len(flag.exactOnly) > 0 ||
+			flag.maxDepth != 0 || flag.tooShort != "" || flag.tooLong != "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "Inline"}
+	}
+	return nil
+}
+
+// generateInline implements Generate when the Inline flag is specified.
+func generateInline(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	if err := checkInlineFlags(flags); err != nil {
+		return err
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if _, err := fmt.Fprintln(w, "\tswitch input {"); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Fprintf(w, "\tcase %s:", strconv.Quote(key))
+		fmt.Fprintln(w)
+		if lines != nil {
+			fs.cover(key, lines.lines)
+		}
+		fmt.Fprintln(w, "\t\treturn", cases[key])
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
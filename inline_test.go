@@ -0,0 +1,200 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// makeInlineCases returns n cases with integer values.
+func makeInlineCases(n int) map[string]string {
+	cases := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		cases[fmt.Sprintf("key%d", i)] = strconv.Itoa(i + 1)
+	}
+	return cases
+}
+
+var inlinableTests = []struct {
+	cases     map[string]string
+	none      string
+	inlinable bool
+	cost      int
+}{
+	{makeInlineCases(3), "0", true, 16},
+	{makeInlineCases(18), "0", true, 76},
+	{makeInlineCases(20), "0", false, 84},
+	{map[string]string{"a": "foo(1)"}, "0", true, 67},
+	{map[string]string{"a": "foo(1)", "b": "foo(2)"}, "0", false, 130},
+	{map[string]string{"a": `"a", true`}, `"", false`, true, 10},
+}
+
+// TestInlinable tests estimating the inlining cost of the output with the
+// Inline flag.
+func TestInlinable(t *testing.T) {
+	for _, testCase := range inlinableTests {
+		inlinable, cost, err := Inlinable(testCase.cases, testCase.none, Inline)
+		if err != nil {
+			t.Errorf("%q: %s", testCase.cases, err)
+			continue
+		}
+		if inlinable != testCase.inlinable || cost != testCase.cost {
+			t.Errorf("expected %v, %d, got %v, %d for %q", testCase.inlinable, testCase.cost, inlinable, cost, testCase.cases)
+		}
+	}
+
+	if _, _, err := Inlinable(map[string]string{"a": "1 +"}, "0"); err == nil {
+		t.Error("expected error for invalid expression")
+	}
+}
+
+// TestInlineBadFlags tests that flags which change how input is compared
+// cannot be combined with Inline.
+func TestInlineBadFlags(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{"a": "1"}, "0", Inline, Insensitive, HasPrefix)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if expect := `Inline does not support flags: "HasPrefix" and "Insensitive"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+
+	for _, flag := range []*Flag{Ignore('-'), StopUpon('.'), IdentifierCase, TooShort("-1"), ASCIIOnly, Strategy(TrieStrategy), ExactOnly("a")} {
+		err := Generate(ioutil.Discard, map[string]string{"a": "1"}, "0", Inline, flag)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagName(flag), err)
+		}
+	}
+}
+
+// TestInlineOutputFlags tests that flags which only affect how the output is
+// written or checked can be combined with Inline.
+func TestInlineOutputFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fastmatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{"foo": "1", "bar": "2"}
+	var expect bytes.Buffer
+	if err := Generate(&expect, cases, "0", Inline); err != nil {
+		t.Fatal(err)
+	}
+
+	var r Result
+	for _, flag := range []*Flag{
+		BufferOutput,
+		Report(&r),
+		MaxOutputBytes(1 << 20),
+		ValueType("int"),
+		OnProgress(func(Progress) {}),
+		StreamOutput(16, nil),
+		CacheDir(dir),
+		Params("ok bool"),
+		TargetGoVersion("1.21"),
+	} {
+		var b bytes.Buffer
+		if err := Generate(&b, cases, "0", Inline, flag); err != nil {
+			t.Errorf("%s: %s", flagName(flag), err)
+		} else if b.String() != expect.String() {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", flagName(flag), expect.String(), b.String())
+		}
+	}
+
+	if err := GenerateContext(context.Background(), ioutil.Discard, cases, "0", Inline); err != nil {
+		t.Errorf("GenerateContext: %s", err)
+	}
+	g := NewGenerator(ioutil.Discard, "foo")
+	if err := g.Add("match", "int", cases, "0", Inline); err != nil {
+		t.Errorf("Generator: %s", err)
+	}
+}
+
+// TestInline checks that the Go compiler agrees with Inlinable, and that
+// the generated code works.
+func TestInline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cases := map[string]string{
+		"foo": "1",
+		"bar": "2",
+		"baz": "3",
+	}
+	inlinable, cost, err := Inlinable(cases, "0", Inline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inlinable {
+		t.Fatalf("expected %q to be inlinable", cases)
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) int {")
+		if err := Generate(w, cases, "0", Inline); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "baz", "3")
+	expectMatch(t, "qux", "0")
+
+	out, err := exec.Command("go", "build", "-gcflags=-m=2", "-o", "/dev/null", "generated.go").CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, out)
+	}
+	m := regexp.MustCompile(`can inline match with cost (\d+)`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("compiler did not inline match: %s", out)
+	}
+	if actual, _ := strconv.Atoi(string(m[1])); actual != cost {
+		t.Errorf("estimated cost %d, compiler reported %d", cost, actual)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// GenerateInterned outputs Go code for a function named fn, which behaves as
+// if generated by Generate, except that it returns a pointer to a shared
+// value instead of evaluating a value expression on each call.  retType is
+// the type of the values.  The generated function has the signature:
+//
+//	func fn(input string) *retType
+//
+// This is intended for values which are large structs, such as token
+// metadata returned by a parser, which would otherwise be constructed from a
+// composite literal every time fn is called.  The values are stored in a
+// package-level array named fnValues.  Callers must not modify them.
+//
+// If none is "nil", fn returns nil when the input is not matched.  Otherwise,
+// none is stored along with the other values.
+//
+// The flags supported by Generate are also supported here, except for
+// Params.  If ValueType is specified, it is checked against the original
+// value expressions.
+func GenerateInterned(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	if fs.params != "" {
+		return &ErrBadFlags{unsupported: []string{"Params"}, unsupportedBy: "GenerateInterned"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(cases, none); err != nil {
+			return err
+		}
+	}
+
+	// Each unique value is stored once.
+	values := make([]string, 0, len(cases)+1)
+	seen := make(map[string]bool, len(cases)+1)
+	for _, value := range cases {
+		if !seen[value] {
+			values = append(values, value)
+			seen[value] = true
+		}
+	}
+	sort.Strings(values)
+	if none != "nil" && !seen[none] {
+		values = append(values, none)
+	}
+	pointers := make(map[string]string, len(values))
+	for n, value := range values {
+		pointers[value] = fmt.Sprintf("&%sValues[%d]", fn, n)
+	}
+	internedCases := make(map[string]string, len(cases))
+	for key, value := range cases {
+		internedCases[key] = pointers[value]
+	}
+	internedNone := "nil"
+	if none != "nil" {
+		internedNone = pointers[none]
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintf(w, "var %sValues = [...]%s{", fn, retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	for _, value := range values {
+		fmt.Fprintf(w, "\t%s,", value)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(input string) *%s {", fn, retType)
+	fmt.Fprintln(w)
+
+	return fs.generateNested(w, lines, internedCases, internedNone, internalFlags(flags))
+}
+
+// generateNested calls Generate on behalf of a generator which has already
+// written part of its own output to w, passing along flags (which should
+// already have been filtered through internalFlags).  If recording coverage,
+// Generate needs its own manifest, since its output doesn't begin where ours
+// does; lines counts what we've written so far, and the keys Generate
+// records are merged into fs.coverage.
+func (fs *flagSet) generateNested(w io.Writer, lines *lineCounter, cases map[string]string, none string, flags []*Flag) error {
+	var m *CoverageManifest
+	if lines != nil {
+		base := fs.coverage.Line
+		if base == 0 {
+			base = 1
+		}
+		m = &CoverageManifest{Line: base + lines.lines}
+		flags = append(flags, Coverage(m))
+	}
+	if err := Generate(w, cases, none, flags...); err != nil {
+		return err
+	}
+	if m != nil {
+		fs.coverage.Keys = append(fs.coverage.Keys, m.Keys...)
+	}
+	return nil
+}
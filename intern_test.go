@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestInterned tests returning pointers to shared values.
+func TestInterned(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type Token struct {")
+		fmt.Fprintln(w, "\tKind int")
+		fmt.Fprintln(w, "\tName string")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w)
+		err := GenerateInterned(w, "lookup", "Token", map[string]string{
+			"if":   `Token{1, "if"}`,
+			"for":  `Token{2, "for"}`,
+			"FOR":  `Token{2, "for"}`,
+			"else": `Token{3, "else"}`,
+		}, `Token{Name: "ident"}`)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\ttok := lookup(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(tok.Kind, tok.Name, tok == lookup(\"for\"))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "if", "1 if false")
+	expectMatch(t, "for", "2 for true")
+	expectMatch(t, "FOR", "2 for true")
+	expectMatch(t, "x", "0 ident false")
+}
+
+// TestInternedNil tests that a none of "nil" is not stored, and that coverage
+// refers to the lines of the generated function.
+func TestInternedNil(t *testing.T) {
+	cases := map[string]string{"a": "1", "b": "2", "c": "1"}
+	m := &CoverageManifest{Line: 3}
+	var b bytes.Buffer
+	if err := GenerateInterned(&b, "f", "int", cases, "nil", Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "var fValues = [...]int{\n\t1,\n\t2,\n}") {
+		t.Errorf("unexpected values in output:\n%s", out)
+	}
+	if !strings.Contains(out, "return nil") {
+		t.Errorf("expected return nil in output:\n%s", out)
+	}
+
+	lines := strings.Split(out, "\n")
+	if len(m.Keys) != len(cases) {
+		t.Errorf("expected %d keys in manifest, got %d", len(cases), len(m.Keys))
+	}
+	for _, r := range m.Keys {
+		actual := strings.TrimSpace(lines[r.StartLine-m.Line])
+		if !strings.HasPrefix(actual, "return &fValues[") {
+			t.Errorf("expected return at line %d for %q, got %q", r.StartLine, r.Key, actual)
+		}
+	}
+}
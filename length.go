@@ -0,0 +1,123 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GenerateWithLength outputs Go code for a function which matches its input
+// against cases, and also returns the number of bytes of the input which
+// were consumed by the match.  The function has the signature:
+//
+//	func fn(input string) (retType, int)
+//
+// This is for lexers and parsers, which need to know where to continue
+// after a match.  With HasPrefix, the length is that of the matching key,
+// plus any runes skipped due to Ignore or IgnoreExcept.  With StopUpon, it
+// excludes the stop rune and anything following it.  With HasSuffix, it is
+// the length of the match at the end of the input.  Otherwise, the entire
+// input is consumed.  If the input doesn't match, none is returned, along
+// with 0.
+//
+// With HasPrefix or HasSuffix, a key which is only reachable via a shorter
+// key with the same value (such as "foobar", if "foo" has the same value)
+// is omitted, since the shorter key always matches first.
+//
+// Flags which modify the input before matching, such as NormalizeInput,
+// FoldEquivalents, Preprocess, or Chain, are not supported, nor are TooShort,
+// TooLong, or ValueType.  If Ignore or IgnoreExcept are specified, the
+// generated code uses StateMachineStrategy.
+func GenerateWithLength(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	ignoring := len(fs.ignore) > 0 || len(fs.ignoreExcept) > 0
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.normalizeFunc != nil || flag.preprocess != "" || flag.chain != "" ||
+			flag == FoldEquivalents || flag == InsensitiveUnicode || flag == InsensitiveTurkish ||
+			flag.tooShort != "" || flag.tooLong != "" || flag.valueType != "" ||
+			(ignoring && flag.strategy != AutoStrategy && flag.strategy != StateMachineStrategy) {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateWithLength"}
+	}
+
+	// Except when the whole input is consumed, each key's length is
+	// known, and the generated code only needs to add the number of
+	// runes it ignored.
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lengthCases := make(map[string]string, len(cases))
+nextKey:
+	for _, key := range keys {
+		if !fs.partialMatch && len(fs.stop) == 0 {
+			lengthCases[key] = cases[key] + ", len(input)"
+			continue
+		}
+
+		mangled := fs.mangle(key)
+		if fs.partialMatch {
+			for _, other := range keys {
+				otherMangled := fs.mangle(other)
+				if len(otherMangled) < len(mangled) && cases[other] == cases[key] &&
+					((!fs.backwards && strings.HasPrefix(mangled, otherMangled)) ||
+						(fs.backwards && strings.HasSuffix(mangled, otherMangled))) {
+					continue nextKey
+				}
+			}
+		}
+		if ignoring {
+			lengthCases[key] = fmt.Sprintf("%s, %d+ignored", cases[key], len(mangled))
+		} else {
+			lengthCases[key] = fmt.Sprintf("%s, %d", cases[key], len(mangled))
+		}
+	}
+
+	matchFlags := flags
+	if ignoring {
+		// The ignored variable only exists in the state machine.
+		matchFlags = append(append([]*Flag(nil), flags...), Strategy(StateMachineStrategy))
+	}
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) (%s, int) {", fn, fs.extraParams(), retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return Generate(w, lengthCases, none+", 0", matchFlags...)
+}
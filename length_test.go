@@ -0,0 +1,98 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestGenerateWithLength tests returning the number of bytes consumed by a
+// match.
+func TestGenerateWithLength(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		flags   []*Flag
+		matches map[string]string
+	}{
+		{nil, map[string]string{"foo": "1 3", "barbaz": "2 6", "fo": "0 0"}},
+		{[]*Flag{Insensitive, HasPrefix}, map[string]string{"FOO": "1 3", "foobar": "1 3", "BARBAZqux": "2 6", "bar": "0 0"}},
+		{[]*Flag{HasPrefix, Ignore('-')}, map[string]string{"f-o-o": "1 5", "-foo-bar": "1 4", "bar--baz": "2 8", "f-o": "0 0"}},
+		{[]*Flag{HasSuffix}, map[string]string{"xfoo": "1 3", "foobarbaz": "2 6", "foox": "0 0"}},
+		{[]*Flag{StopUpon('.')}, map[string]string{"foo.x": "1 3", "barbaz": "2 6", "foo.": "1 3", ".foo": "0 0"}},
+		{[]*Flag{StopUpon('.'), Ignore('-')}, map[string]string{"f-oo.x": "1 4", "foo-": "1 4", "bar-baz": "2 7"}},
+		{[]*Flag{Ignore('-')}, map[string]string{"f-o-o": "1 5", "-foo-": "1 5"}},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+			err := GenerateWithLength(w, "match", "int", map[string]string{
+				"foo":    "1",
+				"foobar": "1",
+				"barbaz": "2",
+			}, "0", testCase.flags...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+
+		for input, expect := range testCase.matches {
+			expectMatch(t, input, expect)
+		}
+		cleanup()
+	}
+}
+
+// TestGenerateWithLengthErrors tests unsupported flags.
+func TestGenerateWithLengthErrors(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{ChompLine},
+		{FoldEquivalents},
+		{TooShort("0")},
+		{Ignore('-'), Strategy(LinearStrategy)},
+	} {
+		err := GenerateWithLength(ioutil.Discard, "match", "int", map[string]string{"foo": "1"}, "0", flags...)
+		if err == nil || !strings.Contains(err.Error(), "GenerateWithLength does not support flags") {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Comparer is a flag, which can be passed to Generate along with
+// Strategy(LinearStrategy), to specify a function which the generated code
+// calls to compare the input to each key, instead of using ==.  This allows
+// the generated code to meet site-specific requirements, such as using an
+// audited constant-time comparison.  fn is the function as it should appear
+// in the generated code, and must have the signature:
+//
+//	func(input, key string) bool
+//
+// input is the portion of the (canonicalized) input being compared, and key
+// is the key after the flags have been applied to it.  The caller is
+// responsible for declaring the function and importing any packages it
+// uses.
+//
+// Comparer is only supported by LinearStrategy, since the other strategies
+// examine the input one byte at a time, and never compare it to a key as a
+// whole.
+func Comparer(fn string) *Flag {
+	return &Flag{comparer: fn}
+}
+
+// linearCanonicalFunc is the name of the closure emitted by LinearStrategy to
+// apply StopUpon, Ignore, IgnoreExcept, and rune equivalence to the input.
+const linearCanonicalFunc = "fastmatchCanonical"
+
+// needsCanonical returns true if LinearStrategy needs to canonicalize the
+// input before comparing it to the keys.
+func (fs *flagSet) needsCanonical() bool {
+	return len(fs.equiv) > 0 || len(fs.stop) > 0 || len(fs.ignore) > 0 || len(fs.ignoreExcept) > 0
+}
+
+// needsEnds returns true if the closure output by writeCanonical also needs
+// to return where each rune of its result ended in the input.  This is the
+// case with NoTrailingMark, since the rune following a match is checked in
+// the input as supplied, which may differ from the canonical form if runes
+// were ignored.
+func (fs *flagSet) needsEnds() bool {
+	return fs.noTrailingMark && fs.partialMatch && fs.needsCanonical()
+}
+
+// writeCanonical outputs a closure which does at runtime what canonicalize
+// does to the keys.  The two must be kept in sync.
+func (fs *flagSet) writeCanonical(w io.Writer) {
+	ret := "string(out)"
+	if fs.needsEnds() {
+		ret = "string(out), ends"
+		fmt.Fprintf(w, "\t%s := func(s string) (string, []int) {", linearCanonicalFunc)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\tout := make([]rune, 0, len(s))")
+		fmt.Fprintln(w, "\t\tends := make([]int, 0, len(s)) // offset in s following each rune of out")
+		fmt.Fprintln(w, "\t\tpending := false")
+		fmt.Fprintln(w, "\t\tfor i, r := range s {")
+		fmt.Fprintln(w, "\t\t\tif pending {")
+		fmt.Fprintln(w, "\t\t\t\tends = append(ends, i)")
+		fmt.Fprintln(w, "\t\t\t\tpending = false")
+		fmt.Fprintln(w, "\t\t\t}")
+	} else {
+		fmt.Fprintf(w, "\t%s := func(s string) string {", linearCanonicalFunc)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\tout := make([]rune, 0, len(s))")
+		fmt.Fprintln(w, "\t\tfor _, r := range s {")
+	}
+	if len(fs.stop) > 0 {
+		fmt.Fprintf(w, "\t\t\tswitch r {\n\t\t\tcase %s: // StopUpon", quoteRunes(fs.stop))
+		fmt.Fprintln(w)
+		if fs.backwards {
+			fmt.Fprintln(w, "\t\t\t\tout = out[:0]")
+			fmt.Fprintln(w, "\t\t\t\tcontinue")
+		} else {
+			fmt.Fprintln(w, "\t\t\t\treturn", ret)
+		}
+		fmt.Fprintln(w, "\t\t\t}")
+	}
+	if len(fs.ignore) > 0 {
+		fmt.Fprintf(w, "\t\t\tswitch r {\n\t\t\tcase %s: // Ignore", quoteRunes(fs.ignore))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\t\tcontinue")
+		fmt.Fprintln(w, "\t\t\t}")
+	}
+	if len(fs.ignoreExcept) > 0 {
+		fmt.Fprintf(w, "\t\t\tswitch r {\n\t\t\tcase %s: // IgnoreExcept", quoteRunes(fs.ignoreExcept))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\tdefault:")
+		fmt.Fprintln(w, "\t\t\t\tcontinue")
+		fmt.Fprintln(w, "\t\t\t}")
+	}
+	if len(fs.equiv) > 0 {
+		// Each set of equivalent runes is replaced by the first.
+		var reps sortableRunes
+		for r, rs := range fs.equiv {
+			if r == rs[0] {
+				reps = append(reps, r)
+			}
+		}
+		sort.Sort(reps)
+		fmt.Fprintln(w, "\t\t\tswitch r { // Equivalent")
+		for _, rep := range reps {
+			fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes(fs.equiv[rep][1:]))
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "\t\t\t\tr = %s", strconv.QuoteRune(rep))
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "\t\t\t}")
+	}
+	fmt.Fprintln(w, "\t\t\tout = append(out, r)")
+	if fs.needsEnds() {
+		fmt.Fprintln(w, "\t\t\tpending = true")
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t\tif pending {")
+		fmt.Fprintln(w, "\t\t\tends = append(ends, len(s))")
+	}
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\treturn", ret)
+	fmt.Fprintln(w, "\t}")
+}
+
+// generateLinear implements Generate when LinearStrategy is specified.
+func generateLinear(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	// The state machine has already solved the problem of detecting
+	// ambiguity, so use it to validate the keys.
+	var validateFlags []*Flag
+	for _, flag := range internalFlags(flags) {
+		if flag.comparer == "" {
+			validateFlags = append(validateFlags, flag)
+		}
+	}
+	validateFlags = append(validateFlags, Strategy(StateMachineStrategy))
+	if err := Generate(ioutil.Discard, cases, none, validateFlags...); err != nil {
+		return err
+	}
+
+	// Keys which are the same once canonicalized are redundant, since
+	// they must have the same value.  The first (in sorted order) is
+	// output, but coverage is recorded for all of them.
+	canonical := make(map[string][]string, len(cases))
+	var canonicalKeys []string
+	origKeys := make([]string, 0, len(cases))
+	for key := range cases {
+		origKeys = append(origKeys, key)
+	}
+	sort.Strings(origKeys)
+	for _, key := range origKeys {
+		c := fs.canonicalize(key)
+		if len(canonical[c]) == 0 {
+			canonicalKeys = append(canonicalKeys, c)
+		}
+		canonical[c] = append(canonical[c], key)
+	}
+	sort.Strings(canonicalKeys)
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	for _, flag := range fs.normalize {
+		if _, err := fmt.Fprintf(w, "\tinput = %s(input)", fs.normalizeCall(flag)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	if fs.noTrailingMark && fs.partialMatch {
+		if _, err := fmt.Fprint(w, extendsClusterCode); err != nil {
+			return err
+		}
+	}
+	in := "input"
+	if fs.needsCanonical() {
+		fs.writeCanonical(w)
+		in = "canonical"
+		if fs.needsEnds() {
+			fmt.Fprintf(w, "\t%s, ends := %s(input)", in, linearCanonicalFunc)
+		} else {
+			fmt.Fprintf(w, "\t%s := %s(input)", in, linearCanonicalFunc)
+		}
+		fmt.Fprintln(w)
+	}
+
+	// equals returns the comparison of a and b, using the function from
+	// Comparer if specified.
+	equals := func(a, b string) string {
+		if fs.comparer != "" {
+			return fmt.Sprintf("%s(%s, %s)", fs.comparer, a, b)
+		}
+		return a + " == " + b
+	}
+
+	for _, c := range canonicalKeys {
+		if err := fs.stopped(); err != nil {
+			return err
+		}
+		quoted := strconv.Quote(c)
+		switch {
+		case !fs.partialMatch:
+			fmt.Fprintf(w, "\tif %s {", equals(in, quoted))
+		case fs.backwards:
+			fmt.Fprintf(w, "\tif len(%s) >= %d && %s {", in, len(c), equals(fmt.Sprintf("%s[len(%s)-%d:]", in, in, len(c)), quoted))
+		case fs.noTrailingMark:
+			// The rune following the match is checked in the
+			// original input.
+			rest := fmt.Sprintf("%s[%d:]", in, len(c))
+			if fs.needsEnds() {
+				rest = "input"
+				if n := utf8.RuneCountInString(c); n > 0 {
+					rest = fmt.Sprintf("input[ends[%d]:]", n-1)
+				}
+			}
+			fmt.Fprintf(w, "\tif len(%s) >= %d && %s && !%s(%s) {", in, len(c), equals(fmt.Sprintf("%s[:%d]", in, len(c)), quoted), extendsClusterFunc, rest)
+		default:
+			fmt.Fprintf(w, "\tif len(%s) >= %d && %s {", in, len(c), equals(fmt.Sprintf("%s[:%d]", in, len(c)), quoted))
+		}
+		fmt.Fprintln(w)
+		if lines != nil {
+			for _, key := range canonical[c] {
+				fs.cover(key, lines.lines)
+			}
+		}
+		fmt.Fprintln(w, "\t\treturn", cases[canonical[c][0]])
+		fmt.Fprintln(w, "\t}")
+	}
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
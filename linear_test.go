@@ -0,0 +1,245 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestLinearStrategy tests that the linear scan matches the same inputs as
+// the state machine, for a variety of flags.
+func TestLinearStrategy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cases := map[string]string{
+		"foo":     "1",
+		"foobar":  "1",
+		"baz":     "3",
+		"qu-ux":   "4",
+		"b\u00e9": "5",
+	}
+	var inputs []string
+	for key := range cases {
+		for _, input := range []string{
+			key,
+			strings.ToUpper(key),
+			key[:len(key)-1],
+			key + "x",
+			"x" + key,
+			key + " x",
+			"x " + key,
+			strings.Replace(key, "o", "-o", 1),
+			strings.Replace(key, "-", "", 1),
+			key + "\u0301",
+			key + "-\u0301",
+		} {
+			inputs = append(inputs, input)
+		}
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{Insensitive},
+		{HasPrefix},
+		{HasSuffix, Insensitive},
+		{StopUpon(' ')},
+		{StopUpon(' '), HasSuffix},
+		{Ignore('-'), Insensitive},
+		{IgnoreExcept(append(Letters, ' ')...)},
+		{Equivalent('o', '0', 'O')},
+		{HasPrefix, NoTrailingMark},
+		{HasPrefix, NoTrailingMark, StopUpon(' ')},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "unicode"}, func(w io.Writer) error {
+			fmt.Fprintln(w, "var _ = unicode.M")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func stateMachine(input string) int {")
+			if err := Generate(w, cases, "0", append(flags, Strategy(StateMachineStrategy))...); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func linear(input string) int {")
+			if err := Generate(w, cases, "0", append(flags, Strategy(LinearStrategy))...); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tok := true")
+			fmt.Fprintln(w, "\tfor _, input := range []string{")
+			for _, input := range inputs {
+				fmt.Fprintf(w, "\t\t%s,", strconv.Quote(input))
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "\t} {")
+			fmt.Fprintln(w, "\t\tif a, b := stateMachine(input), linear(input); a != b {")
+			fmt.Fprintln(w, "\t\t\tfmt.Printf(\"%q: %d != %d\\n\", input, a, b)")
+			fmt.Fprintln(w, "\t\t\tok = false")
+			fmt.Fprintln(w, "\t\t}")
+			fmt.Fprintln(w, "\t}")
+			fmt.Fprintln(w, "\tif ok {")
+			fmt.Fprintln(w, "\t\tfmt.Println(\"ok\")")
+			fmt.Fprintln(w, "\t}")
+			_, err := fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+		expectMatch(t, "", "ok")
+		cleanup()
+	}
+}
+
+// TestLinearStrategyNoTrailingMark tests that, with NoTrailingMark, the rune
+// following a match is checked in the input as supplied, rather than after
+// ignored runes have been removed.
+func TestLinearStrategyNoTrailingMark(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "unicode"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "var _ = unicode.M")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func linear(input string) int {")
+		err := Generate(w, map[string]string{"e": "1", "ab": "2"}, "0", HasPrefix, NoTrailingMark, Ignore('-'), Strategy(LinearStrategy))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(linear(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "e", "1")
+	expectMatch(t, "ex", "1")
+	expectMatch(t, "e\u0301", "0")
+	expectMatch(t, "e-\u0301", "1")
+	expectMatch(t, "-a-b-\u0301", "2")
+	expectMatch(t, "a-b\u0301", "0")
+}
+
+// TestLinearStrategyOutput tests that the linear scan is readable, and that
+// unsupported flags and ambiguous keys are rejected.
+func TestLinearStrategyOutput(t *testing.T) {
+	var b bytes.Buffer
+	cases := map[string]string{"foo": "1", "Foo": "1", "bar": "2"}
+	if err := Generate(&b, cases, "0", Insensitive, Strategy(LinearStrategy)); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"\tcase 'a':\n\t\t\t\tr = 'A'\n",
+		"\tcanonical := fastmatchCanonical(input)\n",
+		"\tif canonical == \"BAR\" {\n\t\treturn 2\n\t}\n\tif canonical == \"FOO\" {\n\t\treturn 1\n\t}\n\treturn 0\n",
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	if err := Generate(ioutil.Discard, cases, "0", ConstantTime, Strategy(LinearStrategy)); err == nil {
+		t.Error("expected error combining ConstantTime and LinearStrategy")
+	}
+	if err := Generate(ioutil.Discard, map[string]string{"a": "1", "A": "2"}, "0", Insensitive, Strategy(LinearStrategy)); err == nil {
+		t.Error("expected error from ambiguous keys")
+	}
+}
+
+// TestComparer tests calling a custom function to compare the input to each
+// key.
+func TestComparer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{HasPrefix},
+		{HasSuffix},
+		{HasPrefix, NoTrailingMark},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os", "strings", "unicode"}, func(w io.Writer) error {
+			fmt.Fprintln(w, "var _ = unicode.M")
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func linear(input string) int {")
+			err := Generate(w, map[string]string{"foo": "1", "bar": "2"}, "0",
+				append(flags, Strategy(LinearStrategy), Comparer("strings.EqualFold"))...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tfmt.Println(linear(os.Args[1]))")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+		expectMatch(t, "FOO", "1")
+		expectMatch(t, "Bar", "2")
+		expectMatch(t, "baz", "0")
+		cleanup()
+	}
+}
+
+// TestComparerOutput tests the emitted comparison, and that Comparer is
+// rejected by other strategies.
+func TestComparerOutput(t *testing.T) {
+	var b bytes.Buffer
+	if err := Generate(&b, map[string]string{"foo": "1"}, "0", HasPrefix, Strategy(LinearStrategy), Comparer("safeEqual")); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "\tif len(input) >= 3 && safeEqual(input[:3], \"foo\") {\n"; !strings.Contains(b.String(), expect) {
+		t.Errorf("expected %q in output:\n%s", expect, b.String())
+	}
+
+	for _, strategy := range []MatchStrategy{AutoStrategy, StateMachineStrategy, TrieStrategy} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", Strategy(strategy), Comparer("safeEqual"))
+		if expect := `flag "Comparer" requires "LinearStrategy"`; err == nil || err.Error() != expect {
+			t.Errorf("%s: expected error %q, got %v", strategy, expect, err)
+		}
+	}
+}
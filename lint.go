@@ -0,0 +1,181 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LintKind describes why LintKeys reported a pair of keys.
+type LintKind int
+
+const (
+	// LintInvisible means the keys differ only by invisible characters,
+	// such as those in Invisible, or other Unicode format characters.
+	LintInvisible LintKind = iota
+
+	// LintCase means the keys differ only by case (and possibly by
+	// invisible characters).
+	LintCase
+
+	// LintConfusable means the keys differ by runes which look alike,
+	// such as Latin "a" and Cyrillic "а" (and possibly by case or
+	// invisible characters).
+	LintConfusable
+)
+
+// String returns a human-readable description of a LintKind.
+func (kind LintKind) String() string {
+	switch kind {
+	case LintInvisible:
+		return "invisible characters"
+	case LintCase:
+		return "case"
+	case LintConfusable:
+		return "confusable characters"
+	}
+	return "unknown"
+}
+
+// Lint is a single pair of keys reported by LintKeys.
+type Lint struct {
+	Key   string
+	Other string
+	Kind  LintKind
+}
+
+// String formats a Lint as a sentence, e.g. `"Foo" differs from "foo" only
+// by case`.
+func (l Lint) String() string {
+	return strconv.Quote(l.Key) + " differs from " + strconv.Quote(l.Other) + " only by " + l.Kind.String()
+}
+
+// confusables maps Cyrillic and Greek letters to the Latin letters they are
+// commonly mistaken for.  It is deliberately short: letters which merely
+// resemble each other in some fonts are not included.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm',
+	'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ζ': 'z', 'η': 'h', 'ι': 'i', 'κ': 'k',
+	'μ': 'm', 'ν': 'n', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'y', 'χ': 'x',
+}
+
+// isInvisible returns true if r is one of the runes LintKeys disregards when
+// looking for keys which differ only by invisible characters.
+func isInvisible(r rune) bool {
+	for _, r2 := range Invisible {
+		if r == r2 {
+			return true
+		}
+	}
+	return unicode.Is(unicode.Cf, r)
+}
+
+// lintForms returns key with invisible characters removed, then also
+// lower-cased, then also with confusables replaced.
+func lintForms(key string) (visible, lower, skeleton string) {
+	visible = strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, key)
+	lower = strings.ToLower(visible)
+	skeleton = strings.Map(func(r rune) rune {
+		if latin, found := confusables[r]; found {
+			return latin
+		}
+		return r
+	}, lower)
+	return
+}
+
+// LintKeys reports pairs of keys in cases which differ only by case, by
+// invisible characters, or by runes which look alike.  Such pairs are
+// almost always mistakes when importing large sets of keys, even though
+// Generate treats them as different keys (unless flags such as Insensitive
+// say otherwise).  Pairs which are already identical once flags are applied
+// are not reported, since Generate reports those itself when they are
+// ambiguous; use AnalyzeOverlap to find them regardless.
+//
+// Only the most specific kind of difference is reported for each pair, with
+// invisible characters taking precedence over case, and case over
+// confusable characters.  Values are not considered.  The result is sorted
+// by Key, then by Other.  An error is returned only if the flags themselves
+// are invalid.
+func LintKeys(cases map[string]string, flags ...*Flag) ([]Lint, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.foldCase(cases, flags)
+
+	type forms struct{ visible, lower string }
+	bySkeleton := make(map[string][]string)
+	keyForms := make(map[string]forms, len(cases))
+	for key := range cases {
+		visible, lower, skeleton := lintForms(key)
+		bySkeleton[skeleton] = append(bySkeleton[skeleton], key)
+		keyForms[key] = forms{visible, lower}
+	}
+
+	var lints []Lint
+	for _, keys := range bySkeleton {
+		if len(keys) < 2 {
+			continue
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			for _, other := range keys[i+1:] {
+				if fs.canonicalize(key) == fs.canonicalize(other) {
+					continue
+				}
+				kind := LintConfusable
+				if keyForms[key].visible == keyForms[other].visible {
+					kind = LintInvisible
+				} else if keyForms[key].lower == keyForms[other].lower {
+					kind = LintCase
+				}
+				lints = append(lints, Lint{key, other, kind})
+			}
+		}
+	}
+	sort.Slice(lints, func(a, b int) bool {
+		if lints[a].Key != lints[b].Key {
+			return lints[a].Key < lints[b].Key
+		}
+		return lints[a].Other < lints[b].Other
+	})
+	return lints, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestLintKeys tests reporting keys which differ only by case, invisible
+// characters, or confusable characters.
+func TestLintKeys(t *testing.T) {
+	lints, err := LintKeys(map[string]string{
+		"foo":            "1",
+		"Foo":            "2",
+		"f\u200boo":      "3",
+		"bar":            "4",
+		"b\u0430r":       "5", // Cyrillic a
+		"B\u0430R\ufeff": "6",
+		"baz":            "7",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Lint{
+		{"B\u0430R\ufeff", "bar", LintConfusable},
+		{"B\u0430R\ufeff", "b\u0430r", LintCase},
+		{"Foo", "foo", LintCase},
+		{"Foo", "f\u200boo", LintCase},
+		{"bar", "b\u0430r", LintConfusable},
+		{"foo", "f\u200boo", LintInvisible},
+	}
+	if !reflect.DeepEqual(expect, lints) {
+		t.Errorf("expected %v, got %v", expect, lints)
+	}
+}
+
+// TestLintKeysFlags tests that pairs which flags already make identical are
+// not reported.
+func TestLintKeysFlags(t *testing.T) {
+	lints, err := LintKeys(map[string]string{
+		"foo":       "1",
+		"Foo":       "1",
+		"f\u00adoo": "2",
+	}, Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Lint{
+		{"Foo", "f\u00adoo", LintCase},
+		{"foo", "f\u00adoo", LintInvisible},
+	}
+	if !reflect.DeepEqual(expect, lints) {
+		t.Errorf("expected %v, got %v", expect, lints)
+	}
+
+	if lints, err = LintKeys(map[string]string{"foo": "1", "f\u00adoo": "2"}, IgnoreInvisible); err != nil {
+		t.Fatal(err)
+	} else if len(lints) > 0 {
+		t.Errorf("unexpected lints with IgnoreInvisible: %v", lints)
+	}
+
+	if _, err := LintKeys(map[string]string{"a": "1"}, HasPrefix, HasSuffix); err == nil {
+		t.Error("expected error combining HasPrefix and HasSuffix")
+	}
+}
+
+// TestLintString tests the human-readable form of a Lint.
+func TestLintString(t *testing.T) {
+	l := Lint{"Foo", "foo", LintCase}
+	if expect := `"Foo" differs from "foo" only by case`; l.String() != expect {
+		t.Errorf("expected %q, got %q", expect, l.String())
+	}
+}
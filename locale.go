@@ -0,0 +1,235 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LocaleMode specifies what GenerateLocales outputs.
+type LocaleMode int
+
+const (
+	// MergedLocales outputs a single function, which matches the keys
+	// from every locale:
+	//
+	//	func fn(input string) retType
+	MergedLocales LocaleMode = iota
+
+	// PerLocale outputs a function for each locale, plus a dispatcher
+	// which calls the function for the locale named by its first
+	// argument:
+	//
+	//	func fnEn(input string) retType
+	//	func fnFr(input string) retType
+	//	func fn(locale, input string) retType
+	PerLocale
+)
+
+// ErrLocaleValues is returned by GenerateLocales when a locale is missing
+// values which other locales have.
+type ErrLocaleValues struct {
+	// missing maps the name of each incomplete locale to the values it
+	// is missing.
+	missing map[string][]string
+}
+
+func (e *ErrLocaleValues) Error() string {
+	locales := make([]string, 0, len(e.missing))
+	for locale := range e.missing {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	var b bytes.Buffer
+	for n, locale := range locales {
+		if n > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "locale %s has no keys for: ", strconv.Quote(locale))
+		b.WriteString(strings.Join(e.missing[locale], ", "))
+	}
+	return b.String()
+}
+
+// GenerateLocales outputs Go code to match the same logical set of cases,
+// translated into several locales.  For example, a configuration parser
+// might accept "true" and "false" in English, and "vrai" and "faux" in
+// French:
+//
+//	fastmatch.GenerateLocales(w, "parseBool", "bool", []fastmatch.CaseSet{
+//		{"en", map[string]string{"true": "true", "false": "false"}},
+//		{"fr", map[string]string{"vrai": "true", "faux": "false"}},
+//	}, "false", fastmatch.PerLocale, fastmatch.Insensitive)
+//
+// Each CaseSet is named for its locale.  Since the cases are translations of
+// each other, every locale must have at least one key for each value which
+// appears in any locale; otherwise, an ErrLocaleValues is returned.
+//
+// With MergedLocales, the keys from every locale are combined (as if by
+// Merge with ConflictError), so that input in any locale is matched.  A key
+// which appears in more than one locale must have the same value in each,
+// and keys from different locales must not be ambiguous with each other
+// when the flags are applied, otherwise an ErrConflict is returned naming
+// the locales.
+//
+// With PerLocale, a separate matcher is output for each locale, named fn
+// followed by the locale name, with its first letter (and the first letter
+// following any punctuation) capitalized and the punctuation removed.  For
+// instance, the matcher for "en-us" is named fnEnUs.  The dispatcher
+// returns none if there is no matcher for the locale; it compares the locale
+// exactly.  Since input is only matched against the keys for one locale,
+// keys from different locales may conflict.  Coverage and Params are not
+// supported with PerLocale.
+func GenerateLocales(w io.Writer, fn, retType string, locales []CaseSet, none string, mode LocaleMode, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	if mode == PerLocale {
+		var unsupported []string
+		for _, flag := range flags {
+			if flag.coverage != nil || flag.params != "" {
+				unsupported = append(unsupported, flagName(flag))
+			}
+		}
+		if len(unsupported) > 0 {
+			return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "PerLocale"}
+		}
+	}
+	if err := checkLocaleValues(locales); err != nil {
+		return err
+	}
+	if fs.valueType != "" {
+		for _, locale := range locales {
+			if err := fs.checkValueType(locale.Cases, none); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mode == PerLocale {
+		return generatePerLocale(w, fn, retType, locales, none, flags)
+	}
+
+	cases, _, err := Merge(ConflictError, locales, internalFlags(flags)...)
+	if err != nil {
+		return err
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) %s {", fn, fs.extraParams(), retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	return fs.generateNested(w, lines, cases, none, internalFlags(flags))
+}
+
+// checkLocaleValues returns an ErrLocaleValues if any locale is missing
+// values which appear in another.
+func checkLocaleValues(locales []CaseSet) error {
+	all := make(map[string]bool)
+	for _, locale := range locales {
+		for _, value := range locale.Cases {
+			all[value] = true
+		}
+	}
+
+	e := &ErrLocaleValues{missing: make(map[string][]string)}
+	for _, locale := range locales {
+		have := make(map[string]bool, len(locale.Cases))
+		for _, value := range locale.Cases {
+			have[value] = true
+		}
+		for value := range all {
+			if !have[value] {
+				e.missing[locale.Name] = append(e.missing[locale.Name], value)
+			}
+		}
+		sort.Strings(e.missing[locale.Name])
+	}
+	if len(e.missing) > 0 {
+		return e
+	}
+	return nil
+}
+
+// generatePerLocale implements GenerateLocales with PerLocale.
+func generatePerLocale(w io.Writer, fn, retType string, locales []CaseSet, none string, flags []*Flag) error {
+	funcs := make([]string, len(locales))
+	seen := make(map[string]string, len(locales))
+	for n, locale := range locales {
+		funcs[n] = appendIdent(fn, locale.Name)
+		if funcs[n] == fn {
+			return fmt.Errorf("invalid locale name: %s", strconv.Quote(locale.Name))
+		}
+		if other, found := seen[funcs[n]]; found {
+			return fmt.Errorf("locales %s and %s would both be matched by %s",
+				strconv.Quote(other), strconv.Quote(locale.Name), funcs[n])
+		}
+		seen[funcs[n]] = locale.Name
+	}
+
+	matchFlags := internalFlags(flags)
+	for n, locale := range locales {
+		if _, err := fmt.Fprintf(w, "func %s(input string) %s {", funcs[n], retType); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		if err := Generate(w, locale.Cases, none, matchFlags...); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "func %s(locale, input string) %s {", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tswitch locale {")
+	for n, locale := range locales {
+		fmt.Fprintf(w, "\tcase %s:", strconv.Quote(locale.Name))
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\t\treturn %s(input)", funcs[n])
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintf(w, "\treturn %s", none)
+	fmt.Fprintln(w)
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
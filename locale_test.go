@@ -0,0 +1,161 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// testLocales are the cases used by the tests in this file.
+var testLocales = []CaseSet{
+	{"en", map[string]string{"true": "1", "yes": "1", "false": "2", "no": "2"}},
+	{"fr", map[string]string{"vrai": "1", "oui": "1", "faux": "2", "non": "2"}},
+	{"pt-BR", map[string]string{"verdadeiro": "1", "sim": "1", "falso": "2", "n\u00e3o": "2"}},
+}
+
+// TestLocalesPerLocale tests the dispatcher and per-locale matchers.
+func TestLocalesPerLocale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strings"}, func(w io.Writer) error {
+		if err := GenerateLocales(w, "parseBool", "int", testLocales, "0", PerLocale, Insensitive); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\targs := strings.SplitN(os.Args[1], \":\", 2)")
+		fmt.Fprintln(w, "\tfmt.Println(parseBool(args[0], args[1]), parseBoolFr(args[1]))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "en:TRUE", "1 0")
+	expectMatch(t, "en:vrai", "0 1")
+	expectMatch(t, "fr:vrai", "1 1")
+	expectMatch(t, "fr:non", "2 2")
+	expectMatch(t, "pt-BR:n\u00e3o", "2 0")
+	expectMatch(t, "de:ja", "0 0")
+}
+
+// TestLocalesMerged tests that a merged matcher accepts input in any
+// locale.
+func TestLocalesMerged(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		if err := GenerateLocales(w, "parseBool", "int", testLocales, "0", MergedLocales, Insensitive); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(parseBool(os.Args[1]))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "Yes", "1")
+	expectMatch(t, "oui", "1")
+	expectMatch(t, "FALSO", "2")
+	expectMatch(t, "ja", "0")
+}
+
+// TestLocalesErrors tests cross-locale conflicts, and locales which are
+// missing values.
+func TestLocalesErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		locales []CaseSet
+		mode    LocaleMode
+		expect  string
+	}{
+		{[]CaseSet{
+			{"en", map[string]string{"no": "2", "yes": "1"}},
+			{"es", map[string]string{"no": "2", "si": "1"}},
+			{"it", map[string]string{"no": "2", "si": "1"}},
+		}, MergedLocales, ""},
+		{[]CaseSet{
+			{"en", map[string]string{"if": "1", "then": "2"}},
+			{"nl", map[string]string{"als": "1", "dan": "2"}},
+			{"fr", map[string]string{"si": "1", "alors": "2"}},
+		}, PerLocale, ""},
+		{[]CaseSet{
+			{"en", map[string]string{"on": "1", "off": "2"}},
+			{"fr", map[string]string{"ON": "1", "on": "2"}},
+		}, PerLocale, "ambiguous matches"},
+		{[]CaseSet{
+			{"en", map[string]string{"fin": "1", "start": "2"}},
+			{"fr", map[string]string{"FIN": "2", "d\u00e9but": "1"}},
+		}, MergedLocales, `ambiguous matches: "FIN" (fr), "fin" (en)`},
+		{[]CaseSet{
+			{"en", map[string]string{"fin": "1", "start": "2"}},
+			{"fr", map[string]string{"fin": "2", "d\u00e9but": "1"}},
+		}, PerLocale, ""},
+		{[]CaseSet{
+			{"en", map[string]string{"true": "1", "false": "2"}},
+			{"fr", map[string]string{"vrai": "1"}},
+			{"de", map[string]string{"wahr": "1", "falsch": "2", "vielleicht": "3"}},
+		}, PerLocale, `locale "en" has no keys for: 3; locale "fr" has no keys for: 2, 3`},
+		{[]CaseSet{
+			{"en-us", map[string]string{"a": "1"}},
+			{"en_us", map[string]string{"b": "1"}},
+		}, PerLocale, `locales "en-us" and "en_us" would both be matched by fEnUs`},
+		{[]CaseSet{
+			{"-", map[string]string{"a": "1"}},
+		}, PerLocale, `invalid locale name: "-"`},
+	} {
+		err := GenerateLocales(ioutil.Discard, "f", "int", testCase.locales, "0", testCase.mode, Insensitive)
+		if testCase.expect == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+
+	err := GenerateLocales(ioutil.Discard, "f", "int", testLocales, "0", PerLocale, Params("ctx int"))
+	if _, ok := err.(*ErrBadFlags); !ok {
+		t.Errorf("expected ErrBadFlags for Params, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// LargeLookupTable is a flag, which can be passed to Generate, to allow a
+// 65536-entry lookup table when all of the keys are one or two bytes long.
+//
+// When every key is a single byte (such as single-character operators in a
+// lexer), Generate emits a 256-byte lookup table indexed by the input,
+// rather than a state machine.  Without this flag, two-byte keys use the
+// state machine as usual, since a table covering every possible pair of
+// bytes adds 64 KiB to the compiled binary (and considerably more to the
+// generated source).
+var LargeLookupTable = new(Flag)
+
+// maxLookupTableValues is the number of unique values which can be
+// represented in a lookup table.  Zero is used to signify no match, so each
+// byte in the table can hold one of 255 other values.
+const maxLookupTableValues = 255
+
+// canUseLookupTable returns true if the cases can be matched with a lookup
+// table.
+func (fs *flagSet) canUseLookupTable(cases map[string]string) bool {
+	if fs.partialMatch || fs.noTrailingMark || fs.constantTime ||
+		len(fs.stop) > 0 || len(fs.ignore) > 0 || len(fs.ignoreExcept) > 0 {
+		return false
+	}
+
+	maxLen := 1
+	if fs.largeLookupTable {
+		maxLen = 2
+	}
+	values := make(map[string]bool, len(cases))
+	for key, value := range cases {
+		if l := len(fs.normalizeKey(key)); l < 1 || l > maxLen {
+			return false
+		}
+		values[value] = true
+	}
+	return len(cases) > 0 && len(values) <= maxLookupTableValues
+}
+
+// byteEquivalents returns the bytes which are equivalent to b.  Equivalent
+// runes which don't fit in a single byte are omitted.
+func (equiv runeEquivalents) byteEquivalents(b byte) []byte {
+	if b >= utf8.RuneSelf {
+		return []byte{b}
+	}
+	var bs []byte
+	for _, r := range equiv.lookup(rune(b)) {
+		if r < utf8.RuneSelf {
+			bs = append(bs, byte(r))
+		}
+	}
+	return bs
+}
+
+// writeLookupTable outputs a string constant containing table.
+func writeLookupTable(w io.Writer, indent, name string, table []byte) {
+	fmt.Fprintf(w, "%sconst %s = \"\" +", indent, name)
+	fmt.Fprintln(w)
+	for n := 0; n < len(table); n += 16 {
+		fmt.Fprint(w, indent+"\t\"")
+		for _, b := range table[n : n+16] {
+			fmt.Fprintf(w, "\\x%02x", b)
+		}
+		if n+16 < len(table) {
+			fmt.Fprintln(w, "\" +")
+		} else {
+			fmt.Fprintln(w, "\"")
+		}
+	}
+}
+
+// generateLookupTable implements Generate when canUseLookupTable returns
+// true.
+func generateLookupTable(w io.Writer, cases map[string]string, none string, fs *flagSet) error {
+	// Each unique return value is assigned a number, which is what gets
+	// stored in the table.
+	values := make([]string, 0, len(cases))
+	valueNums := make(map[string]byte, len(cases))
+	for _, value := range cases {
+		if valueNums[value] == 0 {
+			values = append(values, value)
+			valueNums[value] = 1
+		}
+	}
+	sort.Strings(values)
+	for n, value := range values {
+		valueNums[value] = byte(n + 1)
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var table1, table2 []byte
+	var owner1, owner2 []string
+	e := new(ErrAmbiguous)
+	set := func(table []byte, owner []string, n int, key string) {
+		if table[n] != 0 && table[n] != valueNums[cases[key]] {
+			e.add(nil, owner[n], key)
+		}
+		table[n] = valueNums[cases[key]]
+		owner[n] = key
+	}
+	for _, key := range keys {
+		if err := fs.ctx.Err(); err != nil {
+			return err
+		}
+		normalized := fs.normalizeKey(key)
+		if len(normalized) == 1 {
+			if table1 == nil {
+				table1, owner1 = make([]byte, 1<<8), make([]string, 1<<8)
+			}
+			for _, b := range fs.equiv.byteEquivalents(normalized[0]) {
+				set(table1, owner1, int(b), key)
+			}
+		} else {
+			if table2 == nil {
+				table2, owner2 = make([]byte, 1<<16), make([]string, 1<<16)
+			}
+			for _, b1 := range fs.equiv.byteEquivalents(normalized[0]) {
+				for _, b2 := range fs.equiv.byteEquivalents(normalized[1]) {
+					set(table2, owner2, int(b1)<<8|int(b2), key)
+				}
+			}
+		}
+	}
+	if len(e.keys) > 0 {
+		return e
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	for _, flag := range fs.normalize {
+		if _, err := fmt.Fprintf(w, "\tinput = %s(input)", fs.normalizeCall(flag)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	// writeSwitch outputs a switch statement returning the value
+	// corresponding to the table entry for the input.  keyLen is used to
+	// determine which keys the switch covers, for purposes of the
+	// Coverage flag.
+	writeSwitch := func(indent, index string, keyLen int) {
+		fmt.Fprintf(w, "%sswitch %s {", indent, index)
+		fmt.Fprintln(w)
+		for n, value := range values {
+			fmt.Fprintf(w, "%scase %d:", indent, n+1)
+			fmt.Fprintln(w)
+			if lines != nil {
+				for _, key := range keys {
+					if cases[key] == value && len(fs.normalizeKey(key)) == keyLen {
+						fs.cover(key, lines.lines)
+					}
+				}
+			}
+			fmt.Fprintf(w, "%s\treturn %s", indent, value)
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, indent+"}")
+	}
+
+	if table1 != nil {
+		writeLookupTable(w, "\t", "fastmatchTable1", table1)
+	}
+	if table2 != nil {
+		writeLookupTable(w, "\t", "fastmatchTable2", table2)
+	}
+	if _, err := fmt.Fprintln(w, "\tswitch len(input) {"); err != nil {
+		return err
+	}
+	if table1 != nil {
+		fmt.Fprintln(w, "\tcase 1:")
+		writeSwitch("\t\t", "fastmatchTable1[input[0]]", 1)
+	}
+	if table2 != nil {
+		fmt.Fprintln(w, "\tcase 2:")
+		writeSwitch("\t\t", "fastmatchTable2[int(input[0])<<8|int(input[1])]", 2)
+	}
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var lookupTableTests = []struct {
+	cases  map[string]string
+	flags  []*Flag
+	expect string
+}{
+	{map[string]string{"+": "1", "-": "2"}, nil, "fastmatchTable1"},
+	{map[string]string{"+": "1", "+=": "2"}, nil, ""},
+	{map[string]string{"+": "1", "+=": "2"}, []*Flag{LargeLookupTable}, "fastmatchTable2"},
+	{map[string]string{"+": "1", "-": "2"}, []*Flag{HasPrefix}, ""},
+	{map[string]string{"+": "1", "-": "2"}, []*Flag{StopUpon(' ')}, ""},
+	{map[string]string{"+": "1", "": "2"}, nil, ""},
+}
+
+// TestLookupTableSelection tests when Generate chooses to emit a lookup
+// table instead of a state machine.
+func TestLookupTableSelection(t *testing.T) {
+	for _, testCase := range lookupTableTests {
+		var b bytes.Buffer
+		if err := Generate(&b, testCase.cases, "0", testCase.flags...); err != nil {
+			t.Errorf("%q: %s", testCase.cases, err)
+			continue
+		}
+		usedTable := strings.Contains(b.String(), "fastmatchTable")
+		if testCase.expect == "" && usedTable {
+			t.Errorf("%q: did not expect lookup table", testCase.cases)
+		} else if testCase.expect != "" && !strings.Contains(b.String(), testCase.expect) {
+			t.Errorf("%q: expected %s", testCase.cases, testCase.expect)
+		}
+	}
+}
+
+// TestLookupTableAmbiguous tests that ambiguity is detected when using a
+// lookup table.
+func TestLookupTableAmbiguous(t *testing.T) {
+	err := Generate(new(bytes.Buffer), map[string]string{"a": "1", "A": "2"}, "0", Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
+
+// TestLookupTable tests matching single-byte keys with a lookup table.
+func TestLookupTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"+": "1",
+		"-": "2",
+		"x": "3",
+	}, "0", Insensitive)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "+", "1")
+	expectMatch(t, "-", "2")
+	expectMatch(t, "x", "3")
+	expectMatch(t, "X", "3")
+	expectMatch(t, "\u00e9", "0")
+	expectMatch(t, "y", "0")
+	expectMatch(t, "++", "0")
+}
+
+// TestLargeLookupTable tests matching one- and two-byte keys with the
+// LargeLookupTable flag.
+func TestLargeLookupTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"+":      "1",
+		"+=":     "2",
+		"\u00e9": "3",
+		"Ab":     "4",
+	}, "0", Insensitive, LargeLookupTable)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "+", "1")
+	expectMatch(t, "+=", "2")
+	expectMatch(t, "\u00e9", "3")
+	expectMatch(t, "aB", "4")
+	expectMatch(t, "=+", "0")
+	expectMatch(t, "=", "0")
+	expectMatch(t, "+=+", "0")
+}
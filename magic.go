@@ -0,0 +1,116 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MagicNumbers maps the bytes at the beginning of files in some common
+// formats to the name of the format, for use with GenerateMagic.  Callers
+// may copy it and add formats of their own.
+var MagicNumbers = map[string]string{
+	"\x89PNG\r\n\x1a\n": "PNG",
+	"\x1f\x8b":          "Gzip",
+	"\x7fELF":           "ELF",
+	"%PDF-":             "PDF",
+	"GIF87a":            "GIF",
+	"GIF89a":            "GIF",
+	"\xff\xd8\xff":      "JPEG",
+	"PK\x03\x04":        "Zip",
+}
+
+// GenerateMagic outputs Go code to identify the format of a file (or network
+// payload, etc.) from its first few bytes.  magic maps the bytes identifying
+// each format to the name of the format, such as MagicNumbers.  The
+// following are output, for an enum type named Format:
+//
+//	const (
+//		FormatUnknown Format = iota
+//		FormatELF
+//		...
+//	)
+//	func fn(input []byte) Format
+//
+// The constants are named by appending each format name to enum, and are
+// numbered in order of the format names.  The caller is responsible for
+// declaring the type, which must be an integer type:
+//
+//	fmt.Fprintln(w, "type Format int")
+//	fastmatch.GenerateMagic(w, "sniff", "Format", fastmatch.MagicNumbers)
+//
+// fn returns the format whose magic bytes begin the input, or the Unknown
+// constant if none do.  Input shorter than the magic bytes for a format never
+// matches it, so a truncated buffer can safely be passed.  Flags are passed
+// to Generate, along with HasPrefix; an ErrAmbiguous is returned if the
+// magic bytes for one format begin with those for another.
+func GenerateMagic(w io.Writer, fn, enum string, magic map[string]string, flags ...*Flag) error {
+	unknown := enum + "Unknown"
+	var names []string
+	seen := make(map[string]bool, len(magic))
+	cases := make(map[string]string, len(magic))
+	for prefix, name := range magic {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		cases[prefix] = enum + name
+	}
+	sort.Strings(names)
+
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintln(w, "const ("); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\t%s %s = iota", unknown, enum)
+	fmt.Fprintln(w)
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s%s", enum, name)
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, ")")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// %s identifies the format of input from the bytes at its beginning.", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(input []byte%s) %s {", fn, fs.extraParams(), enum)
+	fmt.Fprintln(w)
+
+	return fs.generateNested(w, lines, cases, unknown, append(internalFlags(flags), HasPrefix))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestMagic tests that the formats in MagicNumbers are identified, and that
+// short buffers don't cause a panic.
+func TestMagic(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"encoding/hex", "fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type Format int")
+		fmt.Fprintln(w)
+		if err := GenerateMagic(w, "sniff", "Format", MagicNumbers); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tinput, _ := hex.DecodeString(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(sniff(input) == FormatPNG, sniff(input) == FormatGIF, sniff(input) == FormatUnknown)")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Arguments can't contain NUL bytes, so the input is hex-encoded.
+	for _, testCase := range []struct{ input, expect string }{
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "true false false"},
+		{"\x89PNG\r\n\x1a", "false false true"},
+		{"GIF89a", "false true false"},
+		{"GIF87a...", "false true false"},
+		{"GIF8", "false false true"},
+		{"", "false false true"},
+	} {
+		expectMatch(t, hex.EncodeToString([]byte(testCase.input)), testCase.expect)
+	}
+}
+
+// TestMagicOutput tests the constants output by GenerateMagic, and that
+// ambiguous magic bytes are rejected.
+func TestMagicOutput(t *testing.T) {
+	var b bytes.Buffer
+	if err := GenerateMagic(&b, "sniff", "Format", MagicNumbers); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"const (\n\tFormatUnknown Format = iota\n\tFormatELF\n\tFormatGIF\n\tFormatGzip\n\tFormatJPEG\n\tFormatPDF\n\tFormatPNG\n\tFormatZip\n)\n",
+		"func sniff(input []byte) Format {\n",
+		"\treturn FormatUnknown\n",
+	} {
+		if !strings.Contains(b.String(), expect) {
+			t.Errorf("expected %q in output:\n%s", expect, b.String())
+		}
+	}
+
+	err := GenerateMagic(ioutil.Discard, "sniff", "Format", map[string]string{"PK": "Zip", "PK\x03\x04": "Jar"})
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"strings"
+)
+
+// KeyMangler applies the same transformations to strings at runtime as
+// Generate applies to keys with a given set of Flags.  This allows callers
+// to combine a generated matcher with a fallback for strings which aren't
+// known at compile time, such as a map populated from a configuration
+// file, and have both treat their input the same way.
+type KeyMangler struct {
+	fs *flagSet
+}
+
+// NewKeyMangler returns a KeyMangler for a set of flags.  ErrBadFlags is
+// returned for any combination of flags Generate would reject.
+func NewKeyMangler(flags ...*Flag) (*KeyMangler, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyMangler{fs: fs}, nil
+}
+
+// Mangle returns what the code output by Generate compares against: s,
+// after applying any NormalizeInput functions, truncating it at the first
+// rune from StopUpon, and removing runes from Ignore (or not in
+// IgnoreExcept).  If HasSuffix was specified, the generated code examines
+// the input from the end, so s is instead truncated at the last stop rune,
+// and only what follows it is kept.
+func (m *KeyMangler) Mangle(s string) string {
+	return m.fs.mangle(m.fs.normalizeKey(s))
+}
+
+// Canonical returns Mangle(s), with each rune also replaced by the first
+// (lowest) rune equivalent to it, such as per Insensitive or Equivalent.
+// Strings which would match the same key have the same Canonical form, so
+// the result is suitable for use as a map key.
+func (m *KeyMangler) Canonical(s string) string {
+	s = m.Mangle(s)
+	if len(m.fs.equiv) == 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		return m.fs.equiv.lookup(r)[0]
+	}, s)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestKeyMangler tests mangling and canonicalizing strings at runtime.
+func TestKeyMangler(t *testing.T) {
+	for _, testCase := range []struct {
+		flags             []*Flag
+		input             string
+		mangle, canonical string
+	}{
+		{nil, "foo.bar", "foo.bar", "foo.bar"},
+		{[]*Flag{StopUpon('.')}, "foo.bar.baz", "foo", "foo"},
+		{[]*Flag{StopUpon('.'), HasSuffix}, "foo.bar.baz", "baz", "baz"},
+		{[]*Flag{Ignore('-'), Insensitive}, "-Foo-Bar", "FooBar", "FOOBAR"},
+		{[]*Flag{IgnoreExcept(Lowercase...)}, "a1b2c3", "abc", "abc"},
+		{[]*Flag{Equivalent('o', '0'), NormalizeInput("strings.TrimSpace", strings.TrimSpace)}, " foo ", "foo", "f00"},
+	} {
+		m, err := NewKeyMangler(testCase.flags...)
+		if err != nil {
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+		if s := m.Mangle(testCase.input); s != testCase.mangle {
+			t.Errorf("%s: expected Mangle(%q) to return %q, got %q", flagNames(testCase.flags), testCase.input, testCase.mangle, s)
+		}
+		if s := m.Canonical(testCase.input); s != testCase.canonical {
+			t.Errorf("%s: expected Canonical(%q) to return %q, got %q", flagNames(testCase.flags), testCase.input, testCase.canonical, s)
+		}
+	}
+
+	if _, err := NewKeyMangler(Ignore('-'), IgnoreExcept('a')); err == nil {
+		t.Error("expected error for invalid flags")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// GenerateMap outputs Go code for a function named fn, which returns a map
+// populated with cases.  The map is built the first time fn is called, and
+// is pre-sized so that it never needs to grow.  retType is the type of the
+// map's values.  The generated function has the signature:
+//
+//	func fn() map[string]retType
+//
+// This is an alternative for callers which need a map (for instance, to
+// iterate over the keys), but want to avoid the cost of building it during
+// package initialization.  The caller must import the sync package.  The
+// returned map is shared between callers, and must not be modified.
+//
+// The only flag supported is TargetGoVersion.  If it is 1.21 or later,
+// sync.OnceValue is used, and fn is declared as a variable rather than a
+// function.  Other flags either change how keys are matched, such as
+// Insensitive, which can't be represented in a map, or have no effect on
+// the map, such as Coverage or Strategy; either way, they result in an
+// ErrBadFlags.  An error is also returned for cases which Generate would
+// reject.
+func GenerateMap(w io.Writer, fn, retType string, cases map[string]string, flags ...*Flag) error {
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.goVersion == "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateMap"}
+	}
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+
+	// The cases are checked the same way as for a matcher, so the same
+	// inputs can be used for both.
+	if err := Generate(ioutil.Discard, cases, "0", flags...); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeBody := func(indent, m string) {
+		fmt.Fprintf(w, "%s%s := make(map[string]%s, %d)", indent, m, retType, len(keys))
+		fmt.Fprintln(w)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s%s[%s] = %s", indent, m, strconv.Quote(key), cases[key])
+			fmt.Fprintln(w)
+		}
+	}
+
+	if fs.atLeastGo(21) {
+		if _, err := fmt.Fprintf(w, "var %s = sync.OnceValue(func() map[string]%s {", fn, retType); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		writeBody("\t", "m")
+		fmt.Fprintln(w, "\treturn m")
+		_, err := fmt.Fprintln(w, "})")
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "var %sOnce sync.Once", fn); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "var %sMap map[string]%s", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s() map[string]%s {", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\t%sOnce.Do(func() {", fn)
+	fmt.Fprintln(w)
+	writeBody("\t\t", "m")
+	fmt.Fprintf(w, "\t\t%sMap = m", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t})")
+	fmt.Fprintf(w, "\treturn %sMap", fn)
+	fmt.Fprintln(w)
+
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestGenerateMap tests the map builder, with and without sync.OnceValue.
+func TestGenerateMap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, version := range []string{"1.0", "1.21"} {
+		cleanup, err := generateProgram([]string{"fmt", "os", "sync"}, func(w io.Writer) error {
+			err := GenerateMap(w, "keywords", "int", map[string]string{
+				"foo": "1",
+				"bar": "2",
+				"baz": "3",
+			}, TargetGoVersion(version))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tm := keywords()")
+			fmt.Fprintln(w, "\tfmt.Println(m[os.Args[1]], len(m), len(keywords()))")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		defer cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectMatch(t, "foo", "1 3 3")
+		expectMatch(t, "baz", "3 3 3")
+		expectMatch(t, "qux", "0 3 3")
+	}
+}
+
+// TestGenerateMapBadFlags tests that flags which can't be represented in a
+// map, or have no effect on it, are rejected.
+func TestGenerateMapBadFlags(t *testing.T) {
+	for expect, flags := range map[string][]*Flag{
+		`GenerateMap does not support flags: "Insensitive"`:             {Insensitive},
+		`GenerateMap does not support flags: "ZeroAllocs"`:              {ZeroAllocs, TargetGoVersion("1.21")},
+		`GenerateMap does not support flags: "Strategy"`:                {Strategy(LinearStrategy)},
+		`GenerateMap does not support flags: "Coverage" and "Strategy"`: {Coverage(new(CoverageManifest)), Strategy(TrieStrategy)},
+	} {
+		err := GenerateMap(ioutil.Discard, "keywords", "int", map[string]string{"a": "1"}, flags...)
+		if err == nil || err.Error() != expect {
+			t.Errorf("expected %q, got %v", expect, err)
+		}
+	}
+
+	err := GenerateMap(ioutil.Discard, "keywords", "int", map[string]string{"a": "1"}, TargetGoVersion("latest"))
+	if expect := `invalid TargetGoVersion: "latest"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+}
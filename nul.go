@@ -0,0 +1,129 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+)
+
+// NULTerminated is a flag, which can be passed to Generate, to specify that
+// matching should stop at the first NUL byte, as if it were the end of the
+// input.  This is intended for input which came from C, such as via cgo,
+// where the string may be followed by a NUL and whatever else was in the
+// buffer.  It is equivalent to StopUpon('\x00').
+//
+// See also GenerateNULTerminated, which additionally outputs a variant of
+// the matcher accepting a []byte, so that a C buffer can be matched without
+// first finding the length of the string within it.
+//
+// ASCIIOnly only examines the input up to the NUL.  TooShort and TooLong,
+// which compare the length of the whole input, are not supported.
+var NULTerminated = StopUpon(0)
+
+// stopsAtNUL returns true if stop, from NULTerminated or StopUpon, contains
+// a NUL.
+func stopsAtNUL(stop []rune) bool {
+	for _, r := range stop {
+		if r == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// nulASCIIGuardCode is the equivalent of asciiGuardCode for NULTerminated,
+// which stops at the first NUL.  The none value is substituted for %s.
+const nulASCIIGuardCode = `	var fastmatchHigh byte
+	for i := 0; i < len(input) && input[i] != 0; i++ {
+		fastmatchHigh |= input[i]
+	}
+	if fastmatchHigh >= 0x80 {
+		return %s
+	}
+`
+
+// GenerateNULTerminated outputs Go code for one or two functions, which
+// match NUL-terminated input from C, as if NULTerminated were specified.
+// The functions have the signatures:
+//
+//	func fn(input string) retType
+//	func bytesFn(input []byte) retType
+//
+// bytesFn accepts a buffer, such as one obtained with C.GoBytes or
+// unsafe.Slice, which only needs to be long enough to contain the NUL, so
+// the caller doesn't have to call strlen first.  It examines the buffer
+// directly, without converting it to a string, and doesn't allocate memory.
+// Either function is omitted if its name is "".
+//
+// The flags are passed to Generate, with NULTerminated added.  Flags which
+// apply functions to the input as a string, such as NormalizeInput,
+// NoTrailingMark, or Preprocess, are not supported, nor are those which
+// depend on the length of the whole input, such as TooShort, TooLong, and
+// MaxDepth.  HasSuffix, ConstantTime,
+// Inline, Coverage, and strategies other than StateMachineStrategy are also
+// not supported.
+func GenerateNULTerminated(w io.Writer, fn, bytesFn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.normalizeFunc != nil || flag.preprocess != "" || flag == NoTrailingMark ||
+			flag.tooShort != "" || flag.tooLong != "" || flag.maxDepth != 0 ||
+			flag == HasSuffix || flag == ConstantTime || flag == Inline || flag.coverage != nil ||
+			(flag.strategy != AutoStrategy && flag.strategy != StateMachineStrategy) {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateNULTerminated"}
+	}
+
+	// The state machine only indexes the input, so the same code works
+	// for a []byte.
+	matchFlags := append(append([]*Flag(nil), flags...), NULTerminated, Strategy(StateMachineStrategy))
+	for n, f := range []struct{ name, typ string }{{fn, "string"}, {bytesFn, "[]byte"}} {
+		if f.name == "" {
+			continue
+		}
+		if n > 0 && fn != "" {
+			fmt.Fprintln(w)
+		}
+		if _, err := fmt.Fprintf(w, "func %s(input %s%s) %s {", f.name, f.typ, fs.extraParams(), retType); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		if err := Generate(w, cases, none, matchFlags...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestNULTerminated tests matching strings and buffers which end at a NUL.
+func TestNULTerminated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{Insensitive, Ignore('-')},
+		{ASCIIOnly, MaxFanOut(1)},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os", "strings", "testing"}, func(w io.Writer) error {
+			err := GenerateNULTerminated(w, "match", "matchBytes", "int", map[string]string{
+				"foo":    "1",
+				"foobar": "2",
+			}, "0", flags...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tinput := strings.Replace(os.Args[1], \"0\", \"\\x00\", -1)")
+			fmt.Fprintln(w, "\tbuf := []byte(input)")
+			fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+			fmt.Fprintln(w, "\t\tmatchBytes(buf)")
+			fmt.Fprintln(w, "\t})")
+			fmt.Fprintln(w, "\tfmt.Println(match(input), matchBytes(buf), allocs)")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+
+		// "0" in the input is replaced with NUL.
+		expectMatch(t, "foo", "1 1 0")
+		expectMatch(t, "foo0", "1 1 0")
+		expectMatch(t, "foo0bar", "1 1 0")
+		expectMatch(t, "foobar0foo", "2 2 0")
+		expectMatch(t, "0foo", "0 0 0")
+		expectMatch(t, "fo0o", "0 0 0")
+
+		// Nothing after the NUL is examined, even by ASCIIOnly.
+		expectMatch(t, "foo0\xff", "1 1 0")
+		expectMatch(t, "foobar0caf\xc3\xa9", "2 2 0")
+		cleanup()
+	}
+}
+
+// TestNULTerminatedErrors tests unsupported flags.
+func TestNULTerminatedErrors(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{HasSuffix},
+		{HasPrefix, NoTrailingMark},
+		{ChompLine},
+		{TooShort("-1")},
+		{TooLong("-1")},
+		{HasPrefix, MaxDepth(8)},
+		{Preprocess("_ = input")},
+		{Strategy(LinearStrategy)},
+	} {
+		err := GenerateNULTerminated(ioutil.Discard, "match", "matchBytes", "int", map[string]string{"foo": "1"}, "0", flags...)
+		if err == nil || !strings.Contains(err.Error(), "GenerateNULTerminated does not support flags") {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+}
+
+// TestNULTerminatedGenerate tests that passing NULTerminated to Generate
+// stops ASCIIOnly's check at the NUL, and rejects TooShort and TooLong.
+func TestNULTerminatedGenerate(t *testing.T) {
+	for _, flags := range [][]*Flag{
+		{NULTerminated, TooShort("-1")},
+		{NULTerminated, TooLong("-1")},
+		{StopUpon(0), TooShort("-1")},
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", flags...)
+		if _, ok := err.(*ErrBadFlags); !ok {
+			t.Errorf("%s: expected *ErrBadFlags, got %v", flagNames(flags), err)
+		}
+	}
+
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strings"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) int {")
+		err := Generate(w, map[string]string{
+			"foo":    "1",
+			"foobar": "2",
+		}, "0", NULTerminated, ASCIIOnly)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(strings.Replace(os.Args[1], \"0\", \"\\x00\", -1)))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "0" in the input is replaced with NUL.
+	expectMatch(t, "foo0caf\xc3\xa9", "1")
+	expectMatch(t, "foobar0\xff", "2")
+	expectMatch(t, "foo\xc3\xa9", "0")
+}
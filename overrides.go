@@ -0,0 +1,112 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+)
+
+// GenerateOverridable outputs Go code for a function named fn, which behaves
+// as if generated by Generate, except that it first consults a map of
+// overrides which can be replaced at runtime.  This allows a few keys to be
+// added (or the values of existing keys changed) without regenerating the
+// code, for instance from a configuration file, while the static set of keys
+// still takes the fast path.  retType is the type of the values.  The
+// following are output:
+//
+//	var fnOverrides atomic.Value
+//	func fnSetOverrides(overrides map[string]retType)
+//	func fn(input string) retType
+//
+// fnSetOverrides may be called concurrently with fn; the map is copied, so
+// the caller is free to modify it afterwards.  Passing nil removes all of the
+// overrides.  When there are no overrides, the cost of the check is a single
+// atomic load.
+//
+// Overrides are matched exactly against the input, without regard to flags
+// such as Insensitive or Ignore.  The flags supported by Generate are
+// otherwise also supported here, except for NoImports, since the generated
+// code needs to import "sync/atomic" (in addition to anything returned by
+// Imports).
+func GenerateOverridable(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	if fs.noImports {
+		return &ErrBadFlags{unsupported: []string{"NoImports"}, unsupportedBy: "GenerateOverridable"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(cases, none); err != nil {
+			return err
+		}
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintf(w, "// %sOverrides holds a map[string]%s, which takes precedence over the", fn, retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// keys matched by %s.", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "var %sOverrides atomic.Value", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// %sSetOverrides replaces the keys which take precedence over the keys", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// matched by %s.  It is safe to call concurrently with %s.", fn, fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %sSetOverrides(overrides map[string]%s) {", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tm := make(map[string]%s, len(overrides))", retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tfor key, value := range overrides {")
+	fmt.Fprintln(w, "\t\tm[key] = value")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintf(w, "\t%sOverrides.Store(m)", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(input string%s) %s {", fn, fs.extraParams(), retType)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tif m, _ := %sOverrides.Load().(map[string]%s); len(m) > 0 {", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\tif value, ok := m[input]; ok {")
+	fmt.Fprintln(w, "\t\t\treturn value")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+
+	return fs.generateNested(w, lines, cases, none, internalFlags(flags))
+}
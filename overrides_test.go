@@ -0,0 +1,100 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestOverridable tests that runtime overrides take precedence over the
+// generated matcher.
+func TestOverridable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "sync/atomic"}, func(w io.Writer) error {
+		err := GenerateOverridable(w, "lookup", "int", map[string]string{
+			"if":  "1",
+			"for": "2",
+		}, "0", Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tbefore := lookup(os.Args[1])")
+		fmt.Fprintln(w, "\tm := map[string]int{\"new\": 3, \"for\": 4}")
+		fmt.Fprintln(w, "\tlookupSetOverrides(m)")
+		fmt.Fprintln(w, "\tdelete(m, \"new\")")
+		fmt.Fprintln(w, "\tafter := lookup(os.Args[1])")
+		fmt.Fprintln(w, "\tlookupSetOverrides(nil)")
+		fmt.Fprintln(w, "\tfmt.Println(before, after, lookup(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "if", "1 1 1")
+	expectMatch(t, "IF", "1 1 1")
+	expectMatch(t, "for", "2 4 2")
+	expectMatch(t, "FOR", "2 2 2")
+	expectMatch(t, "new", "0 3 0")
+	expectMatch(t, "old", "0 0 0")
+}
+
+// TestOverridableCoverage tests that coverage refers to the lines of the
+// generated function, and that errors from Generate are returned.
+func TestOverridableCoverage(t *testing.T) {
+	m := &CoverageManifest{Line: 5}
+	var b bytes.Buffer
+	if err := GenerateOverridable(&b, "f", "int", map[string]string{"a": "1"}, "0", Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if len(m.Keys) != 1 {
+		t.Fatalf("expected 1 coverage entry, got %d", len(m.Keys))
+	}
+	if n := m.Keys[0].StartLine - m.Line; n < 0 || n >= len(lines) || strings.TrimSpace(lines[n]) != "return 1" {
+		t.Errorf("coverage line %d does not refer to return statement:\n%s", m.Keys[0].StartLine, b.String())
+	}
+
+	err := GenerateOverridable(ioutil.Discard, "f", "int", map[string]string{"a": "1", "A": "2"}, "0", Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+// Params returns a flag, which specifies additional parameters (in Go
+// syntax, such as "ctx *ParserState") for generated functions, so that value
+// expressions can refer to caller state, such as "ctx.Intern(...)".
+//
+// Generate and most other functions in this package expect the caller to
+// write the function signature, so value expressions can already refer to
+// any parameters declared there; for these, Params only serves to declare
+// the parameters when checking the values with ValueType.  Functions which
+// write their own signature, such as GenerateReplacer, add the parameters
+// after the input.  Params is not supported by GenerateMap or
+// GenerateInterned, since their values are evaluated only once.
+func Params(params string) *Flag {
+	return &Flag{params: params}
+}
+
+// extraParams returns the parameters from Params, formatted to follow the
+// input parameter in a function signature.
+func (fs *flagSet) extraParams() string {
+	if fs.params == "" {
+		return ""
+	}
+	return ", " + fs.params
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestParams tests passing caller state through to value expressions.
+func TestParams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "strconv"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type State struct{ n int }")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func (s *State) Next() string {")
+		fmt.Fprintln(w, "\ts.n++")
+		fmt.Fprintln(w, "\treturn strconv.Itoa(s.n)")
+		fmt.Fprintln(w, "}")
+		fmt.Fprintln(w)
+		err := GenerateReplacer(w, "replace", map[string]string{
+			"x": "ctx.Next()",
+		}, Params("ctx *State"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(replace(os.Args[1], new(State)))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "axbx", "a1b2")
+	expectMatch(t, "abc", "abc")
+}
+
+// TestParamsValueType tests that value expressions may refer to the
+// parameters when checked against ValueType, and that generators which
+// evaluate values only once reject Params.
+func TestParamsValueType(t *testing.T) {
+	cases := map[string]string{"a": "ctx.Intern(input)", "b": "len(input)"}
+	decls := []string{"type State struct{}", "func (*State) Intern(string) int { return 0 }"}
+	if err := Generate(ioutil.Discard, cases, "0", ValueType("int", decls...), Params("ctx *State")); err != nil {
+		t.Error(err)
+	}
+	if err := Generate(ioutil.Discard, cases, "0", ValueType("int", decls...)); err == nil {
+		t.Error("expected error without Params")
+	} else if _, ok := err.(*ErrValueType); !ok {
+		t.Errorf("expected *ErrValueType, got %v", err)
+	}
+
+	err := Generate(ioutil.Discard, map[string]string{"a": "1", "b": "ctx +"}, "0", ValueType("int"), Params("ctx int"))
+	if err, ok := err.(*ErrValueType); !ok {
+		t.Errorf("expected *ErrValueType, got %v", err)
+	} else if _, ok := err.errs["b"]; !ok {
+		t.Errorf("expected error for key \"b\", got %q", err)
+	}
+
+	if err := GenerateInterned(ioutil.Discard, "f", "int", cases, "0", Params("ctx *State")); err == nil {
+		t.Error("expected error from GenerateInterned")
+	}
+	if err := GenerateMap(ioutil.Discard, "f", "int", cases, Params("ctx *State")); err == nil {
+		t.Error("expected error from GenerateMap")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// Preprocess returns a flag, which can be passed to Generate, to output code
+// (one or more Go statements) at the beginning of the generated code, before
+// anything else examines the input.  This allows small fixups to the input,
+// such as:
+//
+//	fastmatch.Generate(w, cases, "nil",
+//		fastmatch.Preprocess("input = strings.TrimSpace(input)"))
+//
+// This is mostly useful with functions which write the function signature
+// themselves, such as GenerateOverridable, where the caller has no other
+// opportunity to add code to the function.  Unlike NormalizeInput, the keys
+// are not changed to match, so the code should not change what the keys
+// would look like in the input.  Checks on the input performed by flags such
+// as ASCIIOnly and TooShort see the preprocessed input.
+//
+// The code may refer to input, and to any parameters declared by the
+// function signature.  It is checked to be syntactically valid when the flag
+// is passed to Generate, but is otherwise output verbatim, so the caller is
+// responsible for importing any packages it references.  If more than one
+// Preprocess flag is specified, the code is output in order.
+//
+// Preprocess is not supported by GenerateComposite or GenerateExactOrPrefix,
+// which call the generated code more than once.
+func Preprocess(code string) *Flag {
+	return &Flag{preprocess: code}
+}
+
+// checkPreprocess returns an error if code is not a valid sequence of
+// statements.
+func checkPreprocess(code string) error {
+	// Two lines precede the code, which need to be subtracted from line
+	// numbers in errors.
+	src := "package fastmatch\nfunc _(input string) {\n" + code + "\n}\n"
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		return &ErrBadFlags{badPreprocess: fmt.Sprintf("line %d: %s", list[0].Pos.Line-2, list[0].Msg)}
+	} else if err != nil {
+		return err
+	}
+
+	// Unbalanced braces could end the function early, and start another
+	// declaration, which would still parse.
+	if len(f.Decls) != 1 {
+		return &ErrBadFlags{badPreprocess: "code must not end the function"}
+	}
+	return nil
+}
+
+// writePreprocess outputs the code from each Preprocess flag, indented to
+// match the generated code.  Any lines of
+// the generated code recorded for coverage thereafter are offset by the
+// lines output.
+func writePreprocess(w io.Writer, fs *flagSet) error {
+	for _, code := range fs.preprocess {
+		for _, line := range strings.Split(strings.TrimRight(code, "\n"), "\n") {
+			if line != "" {
+				line = "\t" + line
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			fs.coverOffset++
+		}
+	}
+	return nil
+}
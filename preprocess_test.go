@@ -0,0 +1,112 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestPreprocess tests that the code is run before matching, and before
+// the checks for flags such as TooShort.
+func TestPreprocess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	trimPlus := Preprocess("for len(input) > 0 && input[0] == '+' {\n\tinput = input[1:]\n}")
+	for _, testCase := range []struct {
+		flags                []*Flag
+		extraInput, expected string
+	}{
+		{[]*Flag{trimPlus}, "+", "0"},
+		{[]*Flag{trimPlus, TooShort("-1")}, "++fo", "-1"},
+		{[]*Flag{trimPlus, Strategy(TrieStrategy)}, "+fooo", "0"},
+		{[]*Flag{trimPlus, Preprocess(`if input == "bar" { input = "foo" }`)}, "+bar", "1"},
+	} {
+		cleanup, err := generateRunnable(t, match, "int", map[string]string{
+			"foo": "1",
+			"baz": "2",
+		}, "0", testCase.flags...)
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(testCase.flags), err)
+		}
+
+		expectMatch(t, "foo", "1")
+		expectMatch(t, "++foo", "1")
+		expectMatch(t, "+baz", "2")
+		expectMatch(t, "foo+", "0")
+		expectMatch(t, testCase.extraInput, testCase.expected)
+		cleanup()
+	}
+}
+
+// TestPreprocessOutput tests that the code is output at the beginning of
+// the generated code, in the order the flags were specified.
+func TestPreprocessOutput(t *testing.T) {
+	var b bytes.Buffer
+	err := Generate(&b, map[string]string{"foo": "1"}, "0",
+		Preprocess("input = strings.TrimSpace(input)"), ASCIIOnly, Preprocess("input = strings.ToLower(input)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "\tinput = strings.TrimSpace(input)\n\tinput = strings.ToLower(input)\n\tvar fastmatchHigh byte\n") {
+		t.Errorf("expected preprocessing code before ASCIIOnly check:\n%s", b.String())
+	}
+}
+
+// TestPreprocessInvalid tests that code which doesn't parse is rejected.
+func TestPreprocessInvalid(t *testing.T) {
+	for code, expect := range map[string]string{
+		"input = ":                  "invalid Preprocess code: line 2: expected operand",
+		"x := 1\n_ = x)":            "invalid Preprocess code: line 2:",
+		"}\nfunc f(input string) {": "invalid Preprocess code: code must not end the function",
+	} {
+		err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", Preprocess(code))
+		if _, ok := err.(*ErrBadFlags); !ok || !strings.HasPrefix(err.Error(), expect) {
+			t.Errorf("%q: expected error beginning %q, got %v", code, expect, err)
+		}
+	}
+}
+
+// TestPreprocessUnsupported tests the functions which don't support
+// Preprocess.
+func TestPreprocessUnsupported(t *testing.T) {
+	flags := []*Flag{Preprocess("_ = input")}
+	if err := GenerateExactOrPrefix(ioutil.Discard, map[string]string{"foo": "1"}, "0", flags...); err == nil {
+		t.Error("expected error from GenerateExactOrPrefix")
+	}
+	err := GenerateComposite(ioutil.Discard, map[CompositeKey]string{{"a", "b"}: "1"}, "0", flags, nil)
+	if err == nil {
+		t.Error("expected error from GenerateComposite")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+// Progress describes how far along Generate is, for the OnProgress flag.
+type Progress struct {
+	// Keys is the number of distinct keys being matched, and KeysDone
+	// is the number for which code has been output.  Keys may differ
+	// from the number supplied, if AnyDigit patterns were expanded or if
+	// some keys are the same once normalized.
+	Keys, KeysDone int
+
+	// The generated state machine is partitioned by key length.
+	// Buckets is the number of distinct key lengths, and BucketsDone is
+	// the number whose code has been output.  Other strategies, such as
+	// the lookup table, output all of the keys as a single bucket.
+	Buckets, BucketsDone int
+}
+
+// OnProgress returns a flag, which can be passed to Generate, to call fn as
+// code is generated for large numbers of keys.  fn is called once before any
+// code is output, and again after each bucket (see Progress), so that a
+// command-line front end can display a progress bar, or so that a
+// long-running build shows signs of life.
+func OnProgress(fn func(Progress)) *Flag {
+	return &Flag{onProgress: fn}
+}
+
+// reportProgress calls the OnProgress function, if any.
+func (fs *flagSet) reportProgress(p Progress) {
+	if fs.onProgress != nil {
+		fs.onProgress(p)
+	}
+}
+
+// reportWhole reports progress for a strategy which outputs all of the keys
+// at once, using gen.
+func (fs *flagSet) reportWhole(keys int, gen func() error) error {
+	fs.reportProgress(Progress{Keys: keys, Buckets: 1})
+	if err := gen(); err != nil {
+		return err
+	}
+	fs.reportProgress(Progress{Keys: keys, KeysDone: keys, Buckets: 1, BucketsDone: 1})
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// TestOnProgress tests that progress is reported before any output, after
+// each bucket, and once complete.
+func TestOnProgress(t *testing.T) {
+	cases := map[string]string{"a": "1", "bb": "2", "cc": "3", "ddd": "4"}
+	for _, testCase := range []struct {
+		flags  []*Flag
+		expect []Progress
+	}{
+		{
+			expect: []Progress{
+				{Keys: 4, Buckets: 3},
+				{Keys: 4, KeysDone: 1, Buckets: 3, BucketsDone: 1},
+				{Keys: 4, KeysDone: 3, Buckets: 3, BucketsDone: 2},
+				{Keys: 4, KeysDone: 4, Buckets: 3, BucketsDone: 3},
+			},
+		}, {
+			flags: []*Flag{HasPrefix},
+			expect: []Progress{
+				{Keys: 4, Buckets: 3},
+				{Keys: 4, KeysDone: 1, Buckets: 3, BucketsDone: 1},
+				{Keys: 4, KeysDone: 3, Buckets: 3, BucketsDone: 2},
+				{Keys: 4, KeysDone: 4, Buckets: 3, BucketsDone: 3},
+			},
+		}, {
+			flags: []*Flag{Strategy(TrieStrategy)},
+			expect: []Progress{
+				{Keys: 4, Buckets: 1},
+				{Keys: 4, KeysDone: 4, Buckets: 1, BucketsDone: 1},
+			},
+		},
+	} {
+		var reported []Progress
+		flags := append(testCase.flags, OnProgress(func(p Progress) {
+			reported = append(reported, p)
+		}))
+		if err := Generate(ioutil.Discard, cases, "0", flags...); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(reported, testCase.expect) {
+			t.Errorf("%s: expected %v, got %v", flagNames(testCase.flags), testCase.expect, reported)
+		}
+	}
+}
+
+// flagNames returns the names of flags, for error messages.
+func flagNames(flags []*Flag) string {
+	names := make([]string, len(flags))
+	for n, flag := range flags {
+		names[n] = flagName(flag)
+	}
+	return fmt.Sprint(names)
+}
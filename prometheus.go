@@ -0,0 +1,142 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Byte classes used by the code output by GeneratePrometheusNames.  Bytes
+// not listed are invalid.
+const (
+	promValid    = 1 // valid anywhere in a name
+	promNotFirst = 2 // valid except as the first byte
+	promReplaced = 3 // replaced with '_' when canonicalizing
+)
+
+// GeneratePrometheusNames outputs Go code to validate and canonicalize
+// Prometheus-style metric and label names, which must match the regular
+// expression [a-zA-Z_:][a-zA-Z0-9_:]*.  This is a common need in the hot
+// path of observability agents, which receive names from other systems.
+// The following are output:
+//
+//	func validFn(input string) bool
+//	func canonicalFn(input string) (string, bool)
+//
+// validFn reports whether input is a valid name.  canonicalFn replaces each
+// '-' and '.' in the input with '_', and returns the result, or false if the
+// result is still not a valid name.  It only allocates memory if input
+// needed to be changed.  (Prometheus itself further restricts label names,
+// which may not contain ':', but this is not checked.)
+//
+// The functions share a lookup table, named validFnClass.
+func GeneratePrometheusNames(w io.Writer, validFn, canonicalFn string) error {
+	classes := make(map[byte]int, 66)
+	for c := 'a'; c <= 'z'; c++ {
+		classes[byte(c)] = promValid
+		classes[byte(c-'a'+'A')] = promValid
+	}
+	for c := '0'; c <= '9'; c++ {
+		classes[byte(c)] = promNotFirst
+	}
+	classes['_'], classes[':'] = promValid, promValid
+	classes['-'], classes['.'] = promReplaced, promReplaced
+
+	table := validFn + "Class"
+	if _, err := fmt.Fprintf(w, "// %s classifies each byte: 1 if valid anywhere in a name, 2 if valid", table); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// except as the first byte, or 3 if replaced by %s.", canonicalFn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "var %s = [256]uint8{", table)
+	fmt.Fprintln(w)
+	for c := 0; c < 256; c++ {
+		if class := classes[byte(c)]; class != 0 {
+			fmt.Fprintf(w, "\t%s: %d,", strconv.QuoteRune(rune(c)), class)
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %s reports whether input is a valid metric or label name.", validFn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(input string) bool {", validFn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tif input == \"\" || %s[input[0]] != %d {", table, promValid)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\treturn false")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tfor i := 1; i < len(input); i++ {")
+	fmt.Fprintf(w, "\t\tif c := %s[input[i]]; c != %d && c != %d {", table, promValid, promNotFirst)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\treturn false")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn true")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "// %s returns input with '-' and '.' replaced by '_', and whether the", canonicalFn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// result is a valid metric or label name.")
+	fmt.Fprintf(w, "func %s(input string) (string, bool) {", canonicalFn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\tif input == \"\" || %s[input[0]] == %d {", table, promNotFirst)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\treturn \"\", false")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treplace := false")
+	fmt.Fprintln(w, "\tfor i := 0; i < len(input); i++ {")
+	fmt.Fprintf(w, "\t\tswitch %s[input[i]] {", table)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\tcase 0:")
+	fmt.Fprintln(w, "\t\t\treturn \"\", false")
+	fmt.Fprintf(w, "\t\tcase %d:", promReplaced)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\treplace = true")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tif !replace {")
+	fmt.Fprintln(w, "\t\treturn input, true")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tb := []byte(input)")
+	fmt.Fprintln(w, "\tfor i, c := range b {")
+	fmt.Fprintf(w, "\t\tif %s[c] == %d {", table, promReplaced)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\tb[i] = '_'")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn string(b), true")
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
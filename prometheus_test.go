@@ -0,0 +1,72 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestPrometheusNames tests validating and canonicalizing names, and that
+// canonicalizing a valid name doesn't allocate.
+func TestPrometheusNames(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "testing"}, func(w io.Writer) error {
+		if err := GeneratePrometheusNames(w, "validName", "canonicalName"); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tinput := os.Args[1]")
+		fmt.Fprintln(w, "\tcanonical, ok := canonicalName(input)")
+		fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+		fmt.Fprintln(w, "\t\tcanonicalName(input)")
+		fmt.Fprintln(w, "\t})")
+		fmt.Fprintln(w, "\tfmt.Printf(\"%t %q %t %v\", validName(input), canonical, ok, allocs)")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "http_requests_total", `true "http_requests_total" true 0`)
+	expectMatch(t, "job:rate5m", `true "job:rate5m" true 0`)
+	expectMatch(t, "_x9", `true "_x9" true 0`)
+	expectMatch(t, "http.requests-total", `false "http_requests_total" true 1`)
+	expectMatch(t, "-x", `false "_x" true 1`)
+	expectMatch(t, "9x", `false "" false 0`)
+	expectMatch(t, "a b", `false "" false 0`)
+	expectMatch(t, "caf\u00e9", `false "" false 0`)
+}
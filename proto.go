@@ -0,0 +1,100 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// GenerateProtoEnum outputs a pair of functions for converting between
+// strings and a Protocol Buffers enum type, as an alternative to the map
+// lookups performed by the protobuf runtime.
+//
+// enum is the name of the Go type generated by protoc for the enum.  names
+// and values are the corresponding maps generated alongside it, e.g.
+// Color_name and Color_value for an enum named Color.  values is used to
+// build the parser, and thus may contain aliases.  names is used to build the
+// reverse function, and supplies the canonical spelling of each value.
+//
+// Unlike Generate and GenerateReverse, this function writes complete
+// function declarations, including the signatures.  For an enum type named
+// Color, the following are output:
+//
+//	func ParseColor(input string) (Color, bool)
+//	func ColorString(input Color) string
+//
+// (protoc already defines a String method on the type, hence the latter is a
+// function rather than a method.)  ColorString returns "" for values not
+// present in names.
+//
+// Flags are passed through to Generate.  To accept both SCREAMING_SNAKE and
+// CamelCase spellings of each value, pass Insensitive and Ignore('_'):
+//
+//	fastmatch.GenerateProtoEnum(w, "Color", pb.Color_name, pb.Color_value,
+//		fastmatch.Insensitive, fastmatch.Ignore('_'))
+func GenerateProtoEnum(w io.Writer, enum string, names map[int32]string, values map[string]int32, flags ...*Flag) error {
+	// The enum may be qualified with a package name, which needs to be
+	// stripped to build the function names.
+	base := enum
+	if n := strings.LastIndex(base, "."); n != -1 {
+		base = base[n+1:]
+	}
+
+	cases := make(map[string]string, len(values))
+	for key, value := range values {
+		cases[key] = fmt.Sprintf("%s(%d), true", enum, value)
+	}
+
+	if _, err := fmt.Fprintf(w, "// Parse%s returns the %s with the supplied name.  The second return", base, enum); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// value is false if the name is not recognized.")
+	fmt.Fprintf(w, "func Parse%s(input string) (%s, bool) {", base, enum)
+	fmt.Fprintln(w)
+	if err := Generate(w, cases, fmt.Sprintf("%s(0), false", enum), flags...); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	reverseCases := make(map[string]string, len(names))
+	for value, key := range names {
+		reverseCases[key] = fmt.Sprintf("%s(%d)", enum, value)
+	}
+
+	fmt.Fprintf(w, "// %sString returns the canonical name of a %s value, or \"\" if the", base, enum)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// value is not recognized.")
+	fmt.Fprintf(w, "func %sString(input %s) string {", base, enum)
+	fmt.Fprintln(w)
+	return GenerateReverse(w, reverseCases, strconv.Quote(""))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestProtoEnum tests generating a parser and reverse function for a
+// protobuf enum, including aliases and alternate spellings.
+func TestProtoEnum(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type Color int32")
+		fmt.Fprintln(w)
+		err := GenerateProtoEnum(w, "Color", map[int32]string{
+			0: "COLOR_UNSPECIFIED",
+			1: "COLOR_RED",
+			2: "COLOR_DARK_BLUE",
+		}, map[string]int32{
+			"COLOR_UNSPECIFIED": 0,
+			"COLOR_RED":         1,
+			"COLOR_CRIMSON":     1,
+			"COLOR_DARK_BLUE":   2,
+		}, Insensitive, Ignore('_'))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tc, ok := ParseColor(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(int32(c), ok, ColorString(c))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "COLOR_RED", "1 true COLOR_RED")
+	expectMatch(t, "ColorRed", "1 true COLOR_RED")
+	expectMatch(t, "COLOR_CRIMSON", "1 true COLOR_RED")
+	expectMatch(t, "ColorDarkBlue", "2 true COLOR_DARK_BLUE")
+	expectMatch(t, "color_dark_blue", "2 true COLOR_DARK_BLUE")
+	expectMatch(t, "COLOR_GREEN", "0 false COLOR_UNSPECIFIED")
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Provenance describes where case data came from, such as a wordlist or a
+// registry maintained by a third party, for GenerateProvenance.
+type Provenance struct {
+	// Name identifies the data, such as "IANA media types".
+	Name string
+
+	// Source is where the data was obtained, such as a URL or a path
+	// relative to the repository.
+	Source string
+
+	// License is the license under which the data is used, preferably as
+	// an SPDX identifier, such as "CC0-1.0".
+	License string
+
+	// Copyright is any notice which must accompany the data.  It may span
+	// multiple lines.
+	Copyright string
+}
+
+// GenerateProvenance outputs a comment describing where the case data in a
+// generated file came from, followed by a function named fn, which returns
+// the same information at runtime:
+//
+//	func fn() []struct{ Name, Source, License, Copyright string }
+//
+// This is intended for code built from third-party data, so that its
+// origins and license obligations are tracked along with the generated
+// code, and can be reported (for instance, in an "about" screen or a
+// license audit) by the program it is built into.  The caller typically
+// outputs this once per file, alongside the matchers generated from the
+// data:
+//
+//	fastmatch.GenerateProvenance(w, "mediaTypeSources", []fastmatch.Provenance{{
+//		Name:    "IANA media types",
+//		Source:  "https://www.iana.org/assignments/media-types/",
+//		License: "CC0-1.0",
+//	}})
+//
+// Unlike Generate, the caller does not need to write the signature first.
+func GenerateProvenance(w io.Writer, fn string, sources []Provenance) error {
+	if len(sources) == 0 {
+		return errors.New("no provenance to output")
+	}
+
+	if _, err := fmt.Fprintln(w, "// The case data in this file was derived from:"); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		fmt.Fprintln(w, "//")
+		writeProvenanceComment(w, "", source.Name)
+		writeProvenanceComment(w, "Source: ", source.Source)
+		writeProvenanceComment(w, "License: ", source.License)
+		writeProvenanceComment(w, "", source.Copyright)
+	}
+	fmt.Fprintln(w)
+
+	const retType = "[]struct{ Name, Source, License, Copyright string }"
+	fmt.Fprintf(w, "// %s returns the provenance of the case data in this file.", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s() %s {", fn, retType)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\treturn %s{", retType)
+	fmt.Fprintln(w)
+	for _, source := range sources {
+		fmt.Fprintf(w, "\t\t{%s, %s, %s, %s},", strconv.Quote(source.Name),
+			strconv.Quote(source.Source), strconv.Quote(source.License),
+			strconv.Quote(source.Copyright))
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "\t}")
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// writeProvenanceComment outputs an indented comment line for each line of
+// text, with the first line prefixed by label.  Nothing is output if text is
+// empty.
+func writeProvenanceComment(w io.Writer, label, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "//\t%s%s", label, strings.TrimRight(line, " \t\r"))
+		fmt.Fprintln(w)
+		label = strings.Repeat(" ", len(label))
+	}
+}
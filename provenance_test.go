@@ -0,0 +1,80 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestProvenance tests the provenance comment and accessor.
+func TestProvenance(t *testing.T) {
+	var b bytes.Buffer
+	err := GenerateProvenance(&b, "sources", []Provenance{{
+		Name:    "IANA media types",
+		Source:  "https://www.iana.org/assignments/media-types/",
+		License: "CC0-1.0",
+	}, {
+		Name:      "Words",
+		Source:    "testdata/words.txt",
+		Copyright: "Copyright (c) 2016 Someone.\nAll rights reserved.",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	expect := "// The case data in this file was derived from:\n" +
+		"//\n" +
+		"//\tIANA media types\n" +
+		"//\tSource: https://www.iana.org/assignments/media-types/\n" +
+		"//\tLicense: CC0-1.0\n" +
+		"//\n" +
+		"//\tWords\n" +
+		"//\tSource: testdata/words.txt\n" +
+		"//\tCopyright (c) 2016 Someone.\n" +
+		"//\tAll rights reserved.\n"
+	if !strings.HasPrefix(out, expect) {
+		t.Errorf("unexpected comment in output:\n%s", out)
+	}
+	if !strings.Contains(out, "\t\t{\"Words\", \"testdata/words.txt\", \"\", \"Copyright (c) 2016 Someone.\\nAll rights reserved.\"},\n") {
+		t.Errorf("unexpected accessor in output:\n%s", out)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+out, 0); err != nil {
+		t.Errorf("output does not parse: %s\n%s", err, out)
+	}
+
+	if err := GenerateProvenance(ioutil.Discard, "sources", nil); err == nil {
+		t.Error("expected error from empty provenance")
+	}
+}
@@ -0,0 +1,584 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// regexpExtendsCluster is a character class body matching the runes for
+// which extendsCluster returns true.  This must be kept in sync with
+// extendsCluster.
+const regexpExtendsCluster = `\p{M}\x{200d}\x{fe00}-\x{fe0f}\x{1f3fb}-\x{1f3ff}\x{e0020}-\x{e007f}`
+
+// regexpNothing is a regular expression which never matches, used when there
+// are no cases.
+const regexpNothing = `[^\x00-\x{10ffff}]`
+
+// ExportRegexp returns a regular expression, in the syntax accepted by the
+// regexp package, which matches exactly the inputs recognized by the code
+// Generate outputs for the same cases and flags.  This allows the accepted
+// language to be reviewed (or checked against a specification) without
+// reading the generated Go code:
+//
+//	re, err := fastmatch.ExportRegexp(map[string]string{
+//		"GET":  "MethodGet",
+//		"HEAD": "MethodHead",
+//	}, "", fastmatch.Insensitive)
+//	// re == "^(?:[Gg][Ee][Tt]|[Hh][Ee][Aa][Dd])$"
+//
+// Flags are taken into account: equivalent runes become character classes,
+// ignored runes are permitted between (and around) the runes of each key,
+// StopUpon permits anything following a stop rune, and HasPrefix and
+// HasSuffix leave the end (or beginning) of the expression unanchored,
+// except for the keys passed to ExactOnly.  Token shapes are converted to
+// equivalent expressions.  Values are not part of the result, and none is
+// only used to validate the cases.
+//
+// The cases are validated as they would be by Generate, so the same errors
+// are returned.  Flags which transform the input in ways a regular
+// expression can't describe, such as NormalizeInput (and flags built upon
+// it, such as IgnorePlural), NFC, or Preprocess, cause an ErrBadFlags to be
+// returned.  So does ASCIIOnly, since rejecting any input with a non-ASCII
+// byte can't be combined with the rest of the expression without lookahead.
+func ExportRegexp(cases map[string]string, none string, flags ...*Flag) (string, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return "", err
+	}
+	var unsupported []string
+	for _, flag := range fs.normalize {
+		unsupported = append(unsupported, flagName(flag))
+	}
+	if len(fs.preprocess) > 0 {
+		unsupported = append(unsupported, "Preprocess")
+	}
+	if fs.asciiOnly {
+		unsupported = append(unsupported, flagName(ASCIIOnly))
+	}
+	if len(unsupported) > 0 {
+		return "", &ErrBadFlags{unsupported: unsupported, unsupportedBy: "ExportRegexp"}
+	}
+	if err := Generate(ioutil.Discard, cases, none, internalFlags(flags)...); err != nil {
+		return "", err
+	}
+	if len(fs.exactOnly) > 0 {
+		return exportExactOnly(cases, none, fs, flags)
+	}
+
+	keys, shapes := splitShapes(cases)
+
+	// Runes which may appear anywhere (as far as the generated code is
+	// concerned) in the input.
+	var ignored string
+	if len(fs.ignore) > 0 {
+		ignored = regexpClass(fs.ignore, false) + "*"
+	} else if len(fs.ignoreExcept) > 0 {
+		ignored = regexpClass(append(append([]rune(nil), fs.ignoreExcept...), fs.stop...), true) + "*"
+	}
+
+	// When prefix matching, ignored runes following the key don't
+	// matter (except to NoTrailingMark, which checks the rune
+	// immediately following the key).
+	trailing := ignored
+	if fs.partialMatch && !fs.backwards {
+		trailing = ""
+	}
+
+	seen := make(map[string]bool, len(keys))
+	alternatives := make([]string, 0, len(keys))
+	for key := range keys {
+		var b bytes.Buffer
+		for n, r := range []rune(fs.mangle(key)) {
+			if n > 0 {
+				b.WriteString(ignored)
+			}
+			if fs.anyDigit != 0 && r == fs.anyDigit {
+				var digits []rune
+				for d := '0'; d <= '9'; d++ {
+					digits = append(digits, fs.equiv.lookup(d)...)
+				}
+				b.WriteString(regexpClass(digits, false))
+			} else {
+				b.WriteString(regexpClass(fs.equiv.lookup(r), false))
+			}
+		}
+		if b.Len() > 0 {
+			b.WriteString(trailing)
+		}
+		if alt := b.String(); !seen[alt] {
+			seen[alt] = true
+			alternatives = append(alternatives, alt)
+		}
+	}
+	sort.Strings(alternatives)
+
+	var re []string
+	if len(alternatives) > 0 {
+		var b bytes.Buffer
+		if !fs.backwards {
+			b.WriteString("^")
+			b.WriteString(ignored)
+		}
+		b.WriteString("(?:")
+		b.WriteString(strings.Join(alternatives, "|"))
+		b.WriteString(")")
+		stop := ""
+		if len(fs.stop) > 0 {
+			stop = regexpClass(fs.stop, false)
+		}
+		switch {
+		case fs.backwards:
+			// Everything up to the last stop rune is discarded, but
+			// since the keys never contain stop runes, anything can
+			// precede them.
+			b.WriteString("$")
+		case fs.partialMatch && fs.noTrailingMark:
+			// The next rune must not extend the last grapheme
+			// cluster of the key.
+			b.WriteString("(?:$|[^" + regexpExtendsCluster + "])")
+		case fs.partialMatch:
+		case stop != "":
+			b.WriteString("(?:" + stop + "(?s:.*))?$")
+		default:
+			b.WriteString("$")
+		}
+		re = append(re, b.String())
+	}
+	if len(shapes) > 0 {
+		// Flags don't apply to token shapes.
+		shapeAlternatives := make([]string, len(shapes))
+		for n, shape := range shapes {
+			shapeAlternatives[n] = shape.regexp
+		}
+		re = append(re, "^(?:"+strings.Join(shapeAlternatives, "|")+")$")
+	}
+	if len(re) == 0 {
+		return regexpNothing, nil
+	}
+	return strings.Join(re, "|"), nil
+}
+
+// exportExactOnly implements ExportRegexp when ExactOnly is specified.  As in
+// generateExactOnly, the exact-only keys are matched without HasPrefix, so
+// their expression is anchored at the end of the input, and the other keys
+// are matched as usual.
+func exportExactOnly(cases map[string]string, none string, fs *flagSet, flags []*Flag) (string, error) {
+	exactCases := make(map[string]string, len(fs.exactOnly))
+	for _, key := range fs.exactOnly {
+		exactCases[key] = cases[key]
+	}
+	prefixCases := make(map[string]string, len(cases)-len(exactCases))
+	for key, value := range cases {
+		if _, ok := exactCases[key]; !ok {
+			prefixCases[key] = value
+		}
+	}
+
+	exactFlags := make([]*Flag, 0, len(flags))
+	prefixFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if len(flag.exactOnly) > 0 {
+			continue
+		}
+		prefixFlags = append(prefixFlags, flag)
+		if flag != HasPrefix && flag.tooShort == "" && flag.tooLong == "" {
+			exactFlags = append(exactFlags, flag)
+		}
+	}
+
+	var re []string
+	for _, part := range []struct {
+		cases map[string]string
+		flags []*Flag
+	}{
+		{exactCases, exactFlags},
+		{prefixCases, prefixFlags},
+	} {
+		if len(part.cases) == 0 {
+			continue
+		}
+		partRe, err := ExportRegexp(part.cases, none, part.flags...)
+		if err != nil {
+			return "", err
+		}
+		re = append(re, partRe)
+	}
+	if len(re) == 0 {
+		return regexpNothing, nil
+	}
+	return strings.Join(re, "|"), nil
+}
+
+// regexpClass returns a regular expression matching any of the supplied
+// runes (or, if negate is true, any rune except those supplied).  A single
+// rune is returned as a literal rather than a class.  rs need not be sorted
+// or de-duped.
+func regexpClass(rs []rune, negate bool) string {
+	sorted := append(sortableRunes(nil), rs...)
+	sort.Sort(sorted)
+	deduped := sorted[:0]
+	for n, r := range sorted {
+		if n == 0 || r != sorted[n-1] {
+			deduped = append(deduped, r)
+		}
+	}
+
+	if len(deduped) == 1 && !negate {
+		return regexpRune(deduped[0])
+	}
+	var b bytes.Buffer
+	b.WriteString("[")
+	if negate {
+		b.WriteString("^")
+	}
+	for n := 0; n < len(deduped); n++ {
+		// Runs of three or more consecutive runes are written as a
+		// range.
+		end := n
+		for end+1 < len(deduped) && deduped[end+1] == deduped[end]+1 {
+			end++
+		}
+		b.WriteString(regexpRune(deduped[n]))
+		if end-n >= 2 {
+			b.WriteString("-")
+			b.WriteString(regexpRune(deduped[end]))
+			n = end
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// regexpRune returns a rune in a form suitable for use in a regular
+// expression, either on its own or within a character class.  Unlike
+// regexp.QuoteMeta, non-printable runes are escaped, so that the result is
+// readable.
+func regexpRune(r rune) string {
+	switch {
+	case r < utf8.RuneSelf && strings.ContainsRune(`\.+*?()|[]{}^$-`, r):
+		return `\` + string(r)
+	case !unicode.IsPrint(r):
+		return `\x{` + strconv.FormatInt(int64(r), 16) + `}`
+	}
+	return string(r)
+}
+
+// maxImportedKeys is the maximum number of keys ImportRegexp will produce
+// from a single pattern.  Patterns such as `[0-9]{8}` are regular, but are
+// better served by AnyDigit or token shapes than by enumerating every key.
+const maxImportedKeys = 10000
+
+// ErrRegexp is returned by ImportRegexp when a pattern uses a feature which
+// can't be converted to cases and flags.
+type ErrRegexp struct {
+	pattern string
+	reason  string
+}
+
+func (e *ErrRegexp) Error() string {
+	return fmt.Sprintf("cannot import regexp %s: %s", strconv.Quote(e.pattern), e.reason)
+}
+
+// regexpFragment is a string matched by part of a pattern being imported by
+// ImportRegexp, along with whether it was anchored.
+type regexpFragment struct {
+	s          string
+	begin, end bool
+}
+
+// regexpImporter holds the state of ImportRegexp.
+type regexpImporter struct {
+	pattern string
+
+	// fold is true if the pattern is case-insensitive.  Literals are
+	// then converted to foldRep, and equivalents holds the case
+	// orbits which Insensitive doesn't cover.
+	fold        bool
+	insensitive bool
+	equivalents map[string][]rune
+}
+
+// ImportRegexp converts a restricted class of regular expressions into a
+// cases map (with each key mapped to value) and the flags needed to match it
+// with Generate.  This eases migrating existing code which uses regexp to
+// match a set of keywords:
+//
+//	cases, flags, err := fastmatch.ImportRegexp(`^(?i:GET|HEAD|OPTIONS)$`, "true")
+//	if err != nil {
+//		return err
+//	}
+//	fastmatch.Generate(w, cases, "false", flags...)
+//
+// The pattern may contain literals, alternation, character classes, and
+// optional or bounded repetition, all of which are expanded into keys.  It
+// must be anchored: a pattern anchored at both ends matches exact keys, one
+// anchored only with ^ is converted using HasPrefix, and one anchored only
+// with $ is converted using HasSuffix.  A case-insensitive pattern is
+// converted using Insensitive (and Equivalent, for the non-ASCII runes which
+// Unicode case folding also considers equivalent), in which case the keys
+// are lowercase.
+//
+// An ErrRegexp is returned if the pattern contains anything else (such as
+// unbounded repetition, ".", or a mixture of case-sensitive and
+// case-insensitive matching), or if it would expand to more than 10,000
+// keys.  Syntax errors are returned as-is from the regexp/syntax package.
+func ImportRegexp(pattern, value string) (map[string]string, []*Flag, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, nil, err
+	}
+	re = re.Simplify()
+
+	imp := &regexpImporter{pattern: pattern, equivalents: make(map[string][]rune)}
+	imp.fold = foldsCase(re)
+	frags, err := imp.expand(re)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cases := make(map[string]string, len(frags))
+	var flags []*Flag
+	for n, frag := range frags {
+		if n > 0 && (frag.begin != frags[0].begin || frag.end != frags[0].end) {
+			return nil, nil, &ErrRegexp{pattern, "alternatives are not anchored the same way"}
+		}
+		cases[frag.s] = value
+	}
+	if len(frags) > 0 {
+		switch {
+		case frags[0].begin && frags[0].end:
+		case frags[0].begin:
+			flags = append(flags, HasPrefix)
+		case frags[0].end:
+			flags = append(flags, HasSuffix)
+		default:
+			return nil, nil, &ErrRegexp{pattern, "pattern must be anchored with ^, $, or both"}
+		}
+	}
+
+	if imp.insensitive {
+		flags = append(flags, Insensitive)
+	}
+	orbits := make([]string, 0, len(imp.equivalents))
+	for orbit := range imp.equivalents {
+		orbits = append(orbits, orbit)
+	}
+	sort.Strings(orbits)
+	for _, orbit := range orbits {
+		flags = append(flags, Equivalent(imp.equivalents[orbit]...))
+	}
+	return cases, flags, nil
+}
+
+// foldsCase returns true if any literal in a pattern is case-insensitive.
+func foldsCase(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase != 0 {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if foldsCase(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// caseOrbit returns the runes which Unicode case folding considers equivalent
+// to r (including r itself), in ascending order.
+func caseOrbit(r rune) []rune {
+	orbit := sortableRunes{r}
+	for r2 := unicode.SimpleFold(r); r2 != r; r2 = unicode.SimpleFold(r2) {
+		orbit = append(orbit, r2)
+	}
+	sort.Sort(orbit)
+	return orbit
+}
+
+// foldRep returns the rune used in keys to represent r when the pattern is
+// case-insensitive, recording the flags needed to match the rest of its case
+// orbit.
+func (imp *regexpImporter) foldRep(r rune) rune {
+	orbit := caseOrbit(r)
+	if len(orbit) == 1 {
+		return r
+	}
+	rep := unicode.ToLower(orbit[0])
+	for _, r2 := range orbit[1:] {
+		if lower := unicode.ToLower(r2); lower < rep {
+			rep = lower
+		}
+	}
+	if len(orbit) == 2 && orbit[0] < utf8.RuneSelf && orbit[1] < utf8.RuneSelf {
+		imp.insensitive = true
+	} else {
+		if rep < utf8.RuneSelf {
+			imp.insensitive = true
+		}
+		imp.equivalents[string(orbit)] = orbit
+	}
+	return rep
+}
+
+// errorf returns an ErrRegexp describing a problem with part of the pattern.
+func (imp *regexpImporter) errorf(re *syntax.Regexp, format string, args ...interface{}) error {
+	return &ErrRegexp{imp.pattern, fmt.Sprintf(format, args...) + " in " + re.String()}
+}
+
+// expand returns every string matched by part of the pattern.
+func (imp *regexpImporter) expand(re *syntax.Regexp) ([]regexpFragment, error) {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return nil, nil
+
+	case syntax.OpEmptyMatch:
+		return []regexpFragment{{}}, nil
+
+	case syntax.OpBeginText:
+		return []regexpFragment{{begin: true}}, nil
+
+	case syntax.OpEndText:
+		return []regexpFragment{{end: true}}, nil
+
+	case syntax.OpLiteral:
+		runes := make([]rune, len(re.Rune))
+		for n, r := range re.Rune {
+			if imp.fold {
+				if re.Flags&syntax.FoldCase == 0 && len(caseOrbit(r)) > 1 {
+					return nil, imp.errorf(re, "mixed case-sensitive and case-insensitive matching")
+				}
+				r = imp.foldRep(r)
+			}
+			runes[n] = r
+		}
+		return []regexpFragment{{s: string(runes)}}, nil
+
+	case syntax.OpCharClass:
+		var class []rune
+		for n := 0; n+1 < len(re.Rune); n += 2 {
+			if int(re.Rune[n+1]-re.Rune[n])+len(class) >= maxImportedKeys {
+				return nil, imp.errorf(re, "character class too large")
+			}
+			for r := re.Rune[n]; r <= re.Rune[n+1]; r++ {
+				class = append(class, r)
+			}
+		}
+		if imp.fold {
+			inClass := make(map[rune]bool, len(class))
+			for _, r := range class {
+				inClass[r] = true
+			}
+			var reps []rune
+			seen := make(map[rune]bool, len(class))
+			for _, r := range class {
+				for _, r2 := range caseOrbit(r) {
+					if !inClass[r2] {
+						return nil, imp.errorf(re, "mixed case-sensitive and case-insensitive matching")
+					}
+				}
+				if rep := imp.foldRep(r); !seen[rep] {
+					seen[rep] = true
+					reps = append(reps, rep)
+				}
+			}
+			class = reps
+		}
+		frags := make([]regexpFragment, len(class))
+		for n, r := range class {
+			frags[n].s = string(r)
+		}
+		return frags, nil
+
+	case syntax.OpCapture:
+		return imp.expand(re.Sub[0])
+
+	case syntax.OpQuest:
+		frags, err := imp.expand(re.Sub[0])
+		if err != nil {
+			return nil, err
+		}
+		return append([]regexpFragment{{}}, frags...), nil
+
+	case syntax.OpAlternate:
+		var frags []regexpFragment
+		for _, sub := range re.Sub {
+			subFrags, err := imp.expand(sub)
+			if err != nil {
+				return nil, err
+			}
+			frags = append(frags, subFrags...)
+			if len(frags) > maxImportedKeys {
+				return nil, imp.errorf(re, "more than %d keys", maxImportedKeys)
+			}
+		}
+		return frags, nil
+
+	case syntax.OpConcat:
+		frags := []regexpFragment{{}}
+		for _, sub := range re.Sub {
+			subFrags, err := imp.expand(sub)
+			if err != nil {
+				return nil, err
+			}
+			if len(frags)*len(subFrags) > maxImportedKeys {
+				return nil, imp.errorf(re, "more than %d keys", maxImportedKeys)
+			}
+			product := make([]regexpFragment, 0, len(frags)*len(subFrags))
+			for _, a := range frags {
+				for _, b := range subFrags {
+					if a.end && b.s != "" {
+						return nil, imp.errorf(re, "$ not at end of pattern")
+					}
+					if b.begin && a.s != "" {
+						return nil, imp.errorf(re, "^ not at start of pattern")
+					}
+					product = append(product, regexpFragment{a.s + b.s, a.begin || b.begin, a.end || b.end})
+				}
+			}
+			frags = product
+		}
+		return frags, nil
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+		// Simplify has already expanded bounded repetition.
+		return nil, imp.errorf(re, "unbounded repetition")
+
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return nil, imp.errorf(re, "any character")
+	}
+	return nil, imp.errorf(re, "unsupported operator")
+}
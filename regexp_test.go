@@ -0,0 +1,403 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestExportRegexp tests the regular expressions output for various flags,
+// by checking them against inputs which the generated code should and
+// should not match.
+func TestExportRegexp(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		cases    map[string]string
+		flags    []*Flag
+		match    []string
+		notMatch []string
+	}{
+		{
+			name:     "plain",
+			cases:    map[string]string{"foo": "1", "f.o": "2", "": "3"},
+			match:    []string{"foo", "f.o", ""},
+			notMatch: []string{"fo", "FOO", "fxo", "foo ", " foo"},
+		},
+		{
+			name:     "insensitive",
+			cases:    map[string]string{"foo": "1", "bar": "2"},
+			flags:    []*Flag{Insensitive},
+			match:    []string{"foo", "FOO", "bAr"},
+			notMatch: []string{"baz", "foobar"},
+		},
+		{
+			name:     "prefix",
+			cases:    map[string]string{"foo": "1", "bar": "2"},
+			flags:    []*Flag{HasPrefix},
+			match:    []string{"foo", "foobar", "bar\n"},
+			notMatch: []string{"fo", "xfoo"},
+		},
+		{
+			name:     "suffix",
+			cases:    map[string]string{"foo": "1", "bar": "2"},
+			flags:    []*Flag{HasSuffix},
+			match:    []string{"foo", "barfoo", "\nbar"},
+			notMatch: []string{"foox", "ba"},
+		},
+		{
+			name:     "stop",
+			cases:    map[string]string{"foo": "1", "bar": "2"},
+			flags:    []*Flag{StopUpon(' ', '=')},
+			match:    []string{"foo", "foo bar", "bar=\nbaz"},
+			notMatch: []string{"fo o", "foox", " foo"},
+		},
+		{
+			name:     "stop suffix",
+			cases:    map[string]string{"foo": "1"},
+			flags:    []*Flag{StopUpon('/'), HasSuffix},
+			match:    []string{"foo", "x/foo", "/xfoo"},
+			notMatch: []string{"foo/", "fo/o"},
+		},
+		{
+			name:     "ignore",
+			cases:    map[string]string{"foo": "1", "ba-r": "2"},
+			flags:    []*Flag{Ignore('-', '_')},
+			match:    []string{"foo", "-f_o-o-", "bar", "b-a-r"},
+			notMatch: []string{"fo", "f o o"},
+		},
+		{
+			name:     "ignore except",
+			cases:    map[string]string{"foo": "1"},
+			flags:    []*Flag{IgnoreExcept('f', 'o'), StopUpon(';')},
+			match:    []string{"foo", "f.o.o", " foo;f", "xfxoxo"},
+			notMatch: []string{"fooo", "fo;o", "fof"},
+		},
+		{
+			name:     "no trailing mark",
+			cases:    map[string]string{"e": "1"},
+			flags:    []*Flag{HasPrefix, NoTrailingMark, Ignore('-')},
+			match:    []string{"e", "ex", "e-x", "e\u00e9", "e-\u0301"},
+			notMatch: []string{"e\u0301", "-e\u0301", "x"},
+		},
+		{
+			name:     "exact only",
+			cases:    map[string]string{"foo": "1", "bar": "2"},
+			flags:    []*Flag{HasPrefix, ExactOnly("foo")},
+			match:    []string{"foo", "bar", "barx"},
+			notMatch: []string{"foox", "foobar"},
+		},
+		{
+			name:     "any digit",
+			cases:    map[string]string{"v#": "1"},
+			flags:    []*Flag{AnyDigit('#')},
+			match:    []string{"v0", "v9"},
+			notMatch: []string{"v#", "v", "v10"},
+		},
+		{
+			name:     "shapes",
+			cases:    map[string]string{"if": "1", IntegerToken: "2", HexToken: "3", DateToken: "4"},
+			flags:    []*Flag{Insensitive},
+			match:    []string{"IF", "42", "0xBEEF", "2006-01-02"},
+			notMatch: []string{"0x", "2006-13-02", "2006-01-32", "4a"},
+		},
+		{
+			name:     "empty",
+			cases:    map[string]string{},
+			notMatch: []string{"", "foo"},
+		},
+	} {
+		re, err := ExportRegexp(test.cases, "0", test.flags...)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			t.Errorf("%s: %q does not compile: %v", test.name, re, err)
+			continue
+		}
+		for _, input := range test.match {
+			if !compiled.MatchString(input) {
+				t.Errorf("%s: expected %q to match %q", test.name, re, input)
+			}
+		}
+		for _, input := range test.notMatch {
+			if compiled.MatchString(input) {
+				t.Errorf("%s: expected %q not to match %q", test.name, re, input)
+			}
+		}
+	}
+}
+
+// TestExportRegexpDifferential tests that the exported regular expression
+// accepts the same inputs as the code output by Generate, by running both on
+// random inputs built from the runes in the keys and flags.
+func TestExportRegexpDifferential(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cases := map[string]string{"foo": "1", "bar": "2", "ba-z": "3"}
+	alphabet := []rune("fobarzFOx-.é\u0301")
+	rnd := rand.New(rand.NewSource(1))
+	inputs := []string{"", "-foo", "f-o-o", "foo-", "-foo-", "f.oo", "foo.x"}
+	for key := range cases {
+		inputs = append(inputs, key)
+	}
+	for len(inputs) < 10000 {
+		var b strings.Builder
+		if rnd.Intn(2) == 0 {
+			// Mutate a key, since random strings rarely match.
+			keys := []string{"foo", "bar", "baz"}
+			key := []rune(keys[rnd.Intn(len(keys))])
+			for n, r := range key {
+				if rnd.Intn(4) == 0 {
+					b.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+				}
+				if n == 0 || rnd.Intn(8) != 0 {
+					b.WriteRune(r)
+				}
+			}
+			if rnd.Intn(2) == 0 {
+				b.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+			}
+		} else {
+			for n := rnd.Intn(6); n > 0; n-- {
+				b.WriteRune(alphabet[rnd.Intn(len(alphabet))])
+			}
+		}
+		inputs = append(inputs, b.String())
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{Insensitive},
+		{HasPrefix},
+		{HasSuffix},
+		{StopUpon('.')},
+		{StopUpon('.'), HasSuffix},
+		{Ignore('-')},
+		{Ignore('-'), HasPrefix},
+		{Ignore('-'), HasSuffix},
+		{Ignore('-'), StopUpon('.')},
+		{IgnoreExcept('f', 'o', 'b', 'a', 'r', 'z')},
+		{HasPrefix, NoTrailingMark},
+		{HasPrefix, NoTrailingMark, Ignore('-')},
+		{Ignore('-'), HasPrefix, Insensitive},
+		{Ignore('-'), HasPrefix, StopUpon('.')},
+		{IgnoreExcept('f', 'o', 'b', 'a', 'r', 'z'), HasPrefix},
+		{IgnoreExcept('f', 'o', 'b', 'a', 'r', 'z'), HasSuffix},
+		{IgnoreExcept('f', 'o', 'b', 'a', 'r', 'z'), StopUpon('.')},
+		{Ignore('-'), HasPrefix, Strategy(LinearStrategy)},
+		{HasPrefix, ExactOnly("foo")},
+		{HasPrefix, ExactOnly("foo"), Ignore('-')},
+	} {
+		re, err := ExportRegexp(cases, "0", flags...)
+		if err != nil {
+			t.Errorf("%s: %s", flagNames(flags), err)
+			continue
+		}
+		compiled := regexp.MustCompile(re)
+
+		imports := append([]string{"fmt", "io/ioutil", "os", "strings"}, Imports(flags...)...)
+		cleanup, err := generateProgram(imports, func(w io.Writer) error {
+			fmt.Fprintln(w, "func match(input string) int {")
+			if err := Generate(w, cases, "0", flags...); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tin, _ := ioutil.ReadAll(os.Stdin)")
+			fmt.Fprintln(w, "\tfor _, input := range strings.Split(string(in), \"\\x00\") {")
+			fmt.Fprintln(w, "\t\tfmt.Println(match(input) != 0)")
+			fmt.Fprintln(w, "\t}")
+			_, err := fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: %s", flagNames(flags), err)
+		}
+		cmd := exec.Command("go", "run", "generated.go")
+		cmd.Stdin = strings.NewReader(strings.Join(inputs, "\x00"))
+		out, err := cmd.CombinedOutput()
+		cleanup()
+		if err != nil {
+			t.Fatalf("%s: %s: %s", flagNames(flags), err, out)
+		}
+
+		results := strings.Fields(string(out))
+		if len(results) != len(inputs) {
+			t.Fatalf("%s: expected %d results, got %d", flagNames(flags), len(inputs), len(results))
+		}
+		failures := 0
+		for n, input := range inputs {
+			if matched := compiled.MatchString(input); strconv.FormatBool(matched) != results[n] {
+				t.Errorf("%s: %q: generated code returned match=%s, %q returned %v", flagNames(flags), input, results[n], re, matched)
+				if failures++; failures == 5 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestExportRegexpOutput tests the exact form of the regular expression.
+func TestExportRegexpOutput(t *testing.T) {
+	re, err := ExportRegexp(map[string]string{
+		"GET":   "MethodGet",
+		"HEAD":  "MethodHead",
+		"a+b\n": "Other",
+	}, "", Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `^(?:[Aa]\+[Bb]\x{a}|[Gg][Ee][Tt]|[Hh][Ee][Aa][Dd])$`
+	if re != expect {
+		t.Errorf("expected %q, got %q", expect, re)
+	}
+
+	re, err = ExportRegexp(map[string]string{"v#": "1"}, "0", AnyDigit('#'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `^(?:v[0-9])$`; re != expect {
+		t.Errorf("expected %q, got %q", expect, re)
+	}
+}
+
+// TestExportRegexpErrors tests that errors from Generate and unsupported
+// flags are returned.
+func TestExportRegexpErrors(t *testing.T) {
+	_, err := ExportRegexp(map[string]string{"foo": "1", "FOO": "2"}, "0", Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected ErrAmbiguous, got %v", err)
+	}
+
+	for _, flag := range []*Flag{IgnorePlural, ASCIIOnly, Preprocess("input = input[1:]")} {
+		_, err = ExportRegexp(map[string]string{"foo": "1"}, "0", HasPrefix, flag)
+		if _, ok := err.(*ErrBadFlags); !ok || !strings.Contains(err.Error(), flagName(flag)) {
+			t.Errorf("expected ErrBadFlags for %s, got %v", flagName(flag), err)
+		}
+	}
+}
+
+// TestImportRegexp tests that imported patterns match the same inputs as the
+// original regular expression, by exporting them again.
+func TestImportRegexp(t *testing.T) {
+	inputs := []string{
+		"", "get", "GET", "Get", "gets", "xget", "head", "HEAD",
+		"a1", "a2", "a3", "b1", "B1", "a", "color", "colour", "colours",
+		"foo.txt", "foo.TXT", "foo.txt.gz", "k", "K", "\u212a",
+		"\u03c3", "\u03a3", "\u03c2", "x-y", "x_y", "xy",
+	}
+	for _, pattern := range []string{
+		`^(?:GET|HEAD)$`,
+		`^(?i:get|head)$`,
+		`(?i)^k$`,
+		`(?i)^\x{3c3}$`,
+		`^[ab][1-2]$`,
+		`^colou?rs?$`,
+		`^x[-_]?y$`,
+		`^(?:GET|a)`,
+		`(?i)\.txt$`,
+		`^a{1,2}$`,
+		`^[^\x00-\x{10ffff}]$`,
+	} {
+		cases, flags, err := ImportRegexp(pattern, "1")
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+			continue
+		}
+		re, err := ExportRegexp(cases, "0", flags...)
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+			continue
+		}
+		original, exported := regexp.MustCompile(pattern), regexp.MustCompile(re)
+		for _, input := range inputs {
+			if a, b := original.MatchString(input), exported.MatchString(input); a != b {
+				t.Errorf("%s (imported as %s): %q: expected %v, got %v", pattern, re, input, a, b)
+			}
+		}
+	}
+
+	cases, flags, err := ImportRegexp(`^(?i:GET|Head)$`, "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 || cases["get"] != "true" || cases["head"] != "true" {
+		t.Errorf("unexpected cases: %v", cases)
+	}
+	if len(flags) != 1 || flags[0] != Insensitive {
+		t.Errorf("expected Insensitive, got %s", flagNames(flags))
+	}
+}
+
+// TestImportRegexpErrors tests that unsupported patterns are rejected.
+func TestImportRegexpErrors(t *testing.T) {
+	for pattern, reason := range map[string]string{
+		`^a*$`:              "unbounded repetition",
+		`^a.c$`:             "any character",
+		`abc`:               "must be anchored",
+		`^abc$|def$`:        "not anchored the same way",
+		`^a$b`:              "$ not at end",
+		`^(?i:a)b$`:         "mixed case",
+		`^(?i:a)[bB]$`:      "",
+		`^[0-9]{5}$`:        "more than 10000 keys",
+		`^[^a]$`:            "character class too large",
+		`^\bfoo$`:           "unsupported operator",
+		`^(?i:a)[b]$`:       "mixed case",
+		`^[a-c]{2}(?i:x)$`:  "mixed case",
+		`^(?i)[a-c]{2}x$`:   "",
+		`^(?:foo|bar)+baz$`: "unbounded repetition",
+	} {
+		_, _, err := ImportRegexp(pattern, "1")
+		if reason == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", pattern, err)
+			}
+			continue
+		}
+		if _, ok := err.(*ErrRegexp); !ok || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%s: expected ErrRegexp containing %q, got %v", pattern, reason, err)
+		}
+	}
+
+	if _, _, err := ImportRegexp(`^(a$`, "1"); err == nil {
+		t.Error("expected syntax error")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io"
+)
+
+// Render calls a code generator, such as Generate or GenerateReverse, with
+// an in-memory buffer, and returns the buffer if the generator succeeded.
+// The result implements io.WriterTo, so it can be copied to its final
+// destination once complete, and its Len method reports the size of the
+// generated code.
+//
+// This allows the caller to discard incomplete output on error, assemble a
+// file from multiple generated functions, or enforce a size budget and
+// retry with different flags if the output is too large:
+//
+//	b, err := fastmatch.Render(func(w io.Writer) error {
+//		return fastmatch.Generate(w, cases, "-1", fastmatch.Insensitive)
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	if b.Len() > budget {
+//		// try something else
+//	}
+//	_, err = b.WriteTo(out)
+//
+// If gen returns an error, the partial output is discarded and a nil buffer
+// is returned along with the error.
+func Render(gen func(w io.Writer) error) (*bytes.Buffer, error) {
+	b := new(bytes.Buffer)
+	if err := gen(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestRender tests that Render returns the same output as writing directly.
+func TestRender(t *testing.T) {
+	cases := map[string]string{"foo": "1", "bar": "2"}
+
+	var expect bytes.Buffer
+	if err := GenerateReverse(&expect, cases, `""`); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Render(func(w io.Writer) error {
+		return GenerateReverse(w, cases, `""`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != expect.Len() {
+		t.Errorf("expected %d bytes, got %d", expect.Len(), b.Len())
+	}
+
+	var actual bytes.Buffer
+	if _, err := b.WriteTo(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.String() != expect.String() {
+		t.Errorf("expected %q, got %q", expect.String(), actual.String())
+	}
+}
+
+// TestRenderError tests that partial output is discarded on error.
+func TestRenderError(t *testing.T) {
+	b, err := Render(func(w io.Writer) error {
+		return Generate(w, map[string]string{"foo": "1", "FOO": "2"}, "0", Insensitive)
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+	if b != nil {
+		t.Errorf("expected nil buffer, got %q", b.String())
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrEmptyKey is returned by code generators which do not support matching
+// an empty string.
+var ErrEmptyKey = errors.New("empty string is not a valid key")
+
+// LongestMatch is a flag, which can be passed to GenerateReplacer, to specify
+// that when more than one key matches at the same position in the input, the
+// longest should be used.
+var LongestMatch = new(Flag)
+
+// GenerateReplacer outputs a complete Go function named fn, which replaces
+// every occurrence of the keys in cases within its input with the
+// corresponding value, in a single pass.  The generated function has the
+// signature:
+//
+//	func fn(input string) string
+//
+// The values in cases must be Go expressions of type string.  This can be
+// used to redact sensitive data, by mapping each key to a replacement
+// category:
+//
+//	fastmatch.GenerateReplacer(w, "redact", map[string]string{
+//		"hunter2":           `"[PASSWORD]"`,
+//		"alice@example.com": `"[EMAIL]"`,
+//	}, fastmatch.LongestMatch)
+//
+// The input is scanned from left to right, and matches do not overlap.  By
+// default, when keys overlap, the shortest key which matches at a given
+// position is used, and keys which overlap with different values are
+// reported as ambiguous.  With the LongestMatch flag, the longest key is
+// used instead.
+//
+// The flags Insensitive and Equivalent are supported, as is Params, which
+// adds parameters to the signature of the generated function.  Flags which
+// change the length of a match (such as Ignore) are not.  Keys may not be
+// empty.  If no matches are found, the input is returned without allocating.
+//
+// Bytes which cannot begin any of the keys are skipped over in a tight loop,
+// without calling the matcher, so scanning a large input containing few
+// matches is not much slower than a search for a single byte.
+func GenerateReplacer(w io.Writer, fn string, cases map[string]string, flags ...*Flag) error {
+	longest := false
+	var unsupported []string
+	for _, flag := range flags {
+		if flag == LongestMatch {
+			longest = true
+		} else if flag != Insensitive && flag != Normalize && len(flag.equivalent) == 0 && flag.goVersion == "" && flag.params == "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateReplacer"}
+	}
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+
+	// The matcher returns the replacement and the length of the key,
+	// which isn't changed by any of the flags we support.
+	matchCases := make(map[string]string, len(cases))
+	lengthSet := make(map[int]bool)
+	for key, value := range cases {
+		if key == "" {
+			return ErrEmptyKey
+		}
+		matchCases[key] = fmt.Sprintf("%s, %d", value, len(key))
+		lengthSet[len(key)] = true
+	}
+	lengths := make([]int, 0, len(lengthSet))
+	for l := range lengthSet {
+		lengths = append(lengths, l)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) string {", fn, fs.extraParams()); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tfastmatchMatch := func(input string) (string, int) {")
+	matchFlags := flags
+	if !longest {
+		matchFlags = append([]*Flag{HasPrefix}, flags...)
+	}
+	if err := Generate(w, matchCases, `"", 0`, matchFlags...); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	first := fs.firstBytes(cases)
+	if len(first) > 1 && len(first) < 256 {
+		table := make([]byte, 256)
+		for _, b := range first {
+			table[b] = 1
+		}
+		writeLookupTable(w, "\t", "fastmatchFirst", table)
+	}
+
+	fmt.Fprintln(w, "\tvar out []byte")
+	fmt.Fprintln(w, "\tlast := 0")
+	fmt.Fprintln(w, "\tfor i := 0; i < len(input); {")
+	writeSkipAhead(w, "\t\t", first)
+	if longest {
+		// Try each key length, from longest to shortest.  With Go
+		// 1.21, the input is clamped to the longest key once, rather
+		// than checking the remaining length for each.
+		fmt.Fprintln(w, "\t\tvar replacement string")
+		fmt.Fprintln(w, "\t\tvar n int")
+		check, probe := "l <= len(input)-i", "input[i : i+l]"
+		if fs.atLeastGo(21) {
+			fmt.Fprintf(w, "\t\trest := input[i:min(len(input), i+%d)]", lengths[0])
+			fmt.Fprintln(w)
+			check, probe = "l <= len(rest)", "rest[:l]"
+		}
+		fmt.Fprint(w, "\t\tfor _, l := range [...]int{")
+		for n, l := range lengths {
+			if n > 0 {
+				fmt.Fprint(w, ", ")
+			}
+			fmt.Fprint(w, l)
+		}
+		fmt.Fprintln(w, "} {")
+		fmt.Fprintf(w, "\t\t\tif %s {", check)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "\t\t\t\tif replacement, n = fastmatchMatch(%s); n > 0 {", probe)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\t\t\tbreak")
+		fmt.Fprintln(w, "\t\t\t\t}")
+		fmt.Fprintln(w, "\t\t\t}")
+		fmt.Fprintln(w, "\t\t}")
+	} else {
+		fmt.Fprintln(w, "\t\treplacement, n := fastmatchMatch(input[i:])")
+	}
+	fmt.Fprintln(w, "\t\tif n == 0 {")
+	fmt.Fprintln(w, "\t\t\ti++")
+	fmt.Fprintln(w, "\t\t\tcontinue")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\tout = append(out, input[last:i]...)")
+	fmt.Fprintln(w, "\t\tout = append(out, replacement...)")
+	fmt.Fprintln(w, "\t\ti += n")
+	fmt.Fprintln(w, "\t\tlast = i")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tif out == nil {")
+	fmt.Fprintln(w, "\t\treturn input")
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn string(append(out, input[last:]...))")
+
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// firstBytes returns the sorted set of bytes (including equivalents) which
+// can begin a match for one of the keys in cases.
+func (fs *flagSet) firstBytes(cases map[string]string) []byte {
+	seen := make([]bool, 256)
+	for key := range cases {
+		key = fs.normalizeKey(key)
+		if key == "" {
+			// Can match anywhere.
+			for b := range seen {
+				seen[b] = true
+			}
+			break
+		}
+		for _, b := range fs.equiv.byteEquivalents(key[0]) {
+			seen[b] = true
+		}
+	}
+
+	var first []byte
+	for b, ok := range seen {
+		if ok {
+			first = append(first, byte(b))
+		}
+	}
+	return first
+}
+
+// writeSkipAhead outputs a loop which advances i until input[i] is one of
+// the bytes in first, so that code which scans the input for matches
+// doesn't need to call the matcher at every position.  If i reaches the end
+// of the input, the loop enclosing the generated code is exited.
+//
+// If first contains more than one byte, the caller must have output a
+// lookup table named fastmatchFirst, using writeLookupTable.
+func writeSkipAhead(w io.Writer, indent string, first []byte) {
+	switch len(first) {
+	case 256:
+		return // every byte can begin a match
+	case 1:
+		fmt.Fprintf(w, "%sfor i < len(input) && input[i] != %s {", indent, quoteByte(first[0]))
+	default:
+		fmt.Fprintf(w, "%sfor i < len(input) && fastmatchFirst[input[i]] == 0 {", indent)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, indent+"\ti++")
+	fmt.Fprintln(w, indent+"}")
+	fmt.Fprintln(w, indent+"if i == len(input) {")
+	fmt.Fprintln(w, indent+"\tbreak")
+	fmt.Fprintln(w, indent+"}")
+}
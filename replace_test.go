@@ -0,0 +1,159 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// generateReplacer creates a runnable program which calls a function created
+// by GenerateReplacer.
+func generateReplacer(cases map[string]string, flags ...*Flag) (func(), error) {
+	return generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		if err := GenerateReplacer(w, "replace", cases, flags...); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(replace(os.Args[1]))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+}
+
+// TestReplacer tests replacing keys within a string.
+func TestReplacer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateReplacer(map[string]string{
+		"hunter2": `"[PASSWORD]"`,
+		"secret":  `"[TOKEN]"`,
+		"x":       `""`,
+	}, Insensitive)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "nothing to see here", "nothing to see here")
+	expectMatch(t, "my password is hunter2", "my password is [PASSWORD]")
+	expectMatch(t, "SECRET=Secret;secre", "[TOKEN]=[TOKEN];secre")
+	expectMatch(t, "xoxox", "oo")
+}
+
+// TestReplacerLongestMatch tests the LongestMatch flag.
+func TestReplacerLongestMatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	// The output differs if the min builtin or range over an integer is
+	// available, so test each.
+	for _, version := range []string{"1.0", "1.21", "1.22"} {
+		cleanup, err := generateReplacer(map[string]string{
+			"token":         `"[TOKEN]"`,
+			"token_secret":  `"[SECRET]"`,
+			"example.com":   `"[DOMAIN]"`,
+			"a@example.com": `"[EMAIL]"`,
+		}, LongestMatch, TargetGoVersion(version))
+		defer cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectMatch(t, "token token_secret", "[TOKEN] [SECRET]")
+		expectMatch(t, "mail a@example.com", "mail [EMAIL]")
+		expectMatch(t, "visit example.com", "visit [DOMAIN]")
+		expectMatch(t, "toke", "toke")
+	}
+}
+
+// TestReplacerBadInput tests that GenerateReplacer rejects unsupported flags,
+// empty keys, and overlapping keys without LongestMatch.
+func TestReplacerBadInput(t *testing.T) {
+	err := GenerateReplacer(ioutil.Discard, "replace", map[string]string{"a": `"b"`}, Ignore('.'), HasPrefix)
+	if err == nil {
+		t.Error("expected error for unsupported flags")
+	} else if expect := `GenerateReplacer does not support flags: "HasPrefix" and "Ignore"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+
+	if err := GenerateReplacer(ioutil.Discard, "replace", map[string]string{"": `"b"`}); err != ErrEmptyKey {
+		t.Errorf("expected ErrEmptyKey, got %v", err)
+	}
+
+	err = GenerateReplacer(ioutil.Discard, "replace", map[string]string{
+		"foo":    `"a"`,
+		"foobar": `"b"`,
+	})
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
+
+// TestFirstBytes tests determining which bytes can begin a match.
+func TestFirstBytes(t *testing.T) {
+	fs, err := parseFlags(Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := fs.firstBytes(map[string]string{"foo": "1", "bar": "2", "-x": "3"})
+	if expect := "-BFbf"; string(first) != expect {
+		t.Errorf("expected %q, got %q", expect, first)
+	}
+}
+
+// TestReplacerSkipAhead tests skipping over bytes which cannot begin a
+// match, using both a single byte and a lookup table.
+func TestReplacerSkipAhead(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, cases := range []map[string]string{
+		{"$secret": `"[SECRET]"`, "$token": `"[TOKEN]"`},
+		{"$secret": `"[SECRET]"`, "%token": `"[TOKEN]"`},
+	} {
+		cleanup, err := generateReplacer(cases)
+		defer cleanup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectMatch(t, "no matches here", "no matches here")
+		expectMatch(t, "$secret", "[SECRET]")
+		expectMatch(t, "a $secret, $$secret and $secre", "a [SECRET], $[SECRET] and $secre")
+		expectMatch(t, "ends with $", "ends with $")
+	}
+}
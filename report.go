@@ -0,0 +1,122 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Result summarizes the code output by Generate, for the Report flag.  This
+// lets a tool which wraps Generate log what was output, or enforce a policy,
+// such as failing a build if the code grows too large.
+type Result struct {
+	// Bytes is the number of bytes of code written, not including the
+	// function signature written by the caller.
+	Bytes int64
+
+	// Keys is the number of distinct keys matched.  As for Progress,
+	// this may differ from the number supplied.
+	Keys int
+
+	// Strategies describes how the generated code matches its input:
+	// "state machine", "lookup table", "trie", "linear", "inline",
+	// "constant time", or "cached" (if the code was read from CacheDir).
+	// There is usually one entry, but flags such as ExactOnly output
+	// more than one matcher, and each is listed in order.
+	Strategies []string
+
+	// MaxState is the largest value of the state variable in any state
+	// machine, and Chains is the number of times a state machine was
+	// continued by another because it ran out of states (see MaxState).
+	// Both are zero if no state machine was output.
+	MaxState uint64
+	Chains   int
+
+	// Warnings describes flags which were accepted but had no effect.
+	Warnings []string
+
+	// depth is the number of nested calls to Generate in progress which
+	// output part of the same code, so that only the outermost one
+	// resets the Result.
+	depth int
+}
+
+// Report returns a flag, which can be passed to Generate, to fill in r once
+// code has been generated.  r is reset at the start of each call to
+// Generate.  Functions which call Generate to output part of their code,
+// such as GenerateExactOrPrefix, report on that part only.
+func Report(r *Result) *Flag {
+	return &Flag{result: r}
+}
+
+// reportStrategy records a matcher output for the Report flag.
+func (fs *flagSet) reportStrategy(strategy string, keys int) {
+	if fs.result != nil {
+		fs.result.Strategies = append(fs.result.Strategies, strategy)
+		fs.result.Keys += keys
+	}
+}
+
+// reportStates records the states used by a state machine for the Report
+// flag.
+func (fs *flagSet) reportStates(state *stateMachine) {
+	if fs.result == nil {
+		return
+	}
+	if max := state.next - 1; max > fs.result.MaxState {
+		fs.result.MaxState = max
+	}
+	if state.continued != nil {
+		fs.result.Chains++
+	}
+}
+
+// warnings returns the Warnings for the Report flag.
+func (fs *flagSet) warnings(flags []*Flag) []string {
+	var warnings []string
+	for _, flag := range flags {
+		if flag == Normalize {
+			warnings = append(warnings, "Normalize is not yet implemented")
+		}
+	}
+	if fs.strategy != LinearStrategy {
+		var ignored sortableRunes
+		for r := range fs.equiv {
+			if r >= utf8.RuneSelf {
+				ignored = append(ignored, r)
+			}
+		}
+		if len(ignored) > 0 {
+			sort.Sort(ignored)
+			warnings = append(warnings, "multi-byte runes in Equivalent are ignored without FoldEquivalents: "+quoteRunes(ignored))
+		}
+	}
+	return warnings
+}
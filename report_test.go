@@ -0,0 +1,101 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReport tests the Result filled in by the Report flag.
+func TestReport(t *testing.T) {
+	words := map[string]string{"foo": "1", "bar": "2", "bazqux": "3"}
+	letters := map[string]string{"a": "1", "b": "2", "c": "3"}
+	for _, test := range []struct {
+		cases      map[string]string
+		flags      []*Flag
+		strategies []string
+		chains     bool
+	}{
+		{letters, nil, []string{"lookup table"}, false},
+		{words, nil, []string{"state machine"}, false},
+		{words, []*Flag{MaxState(MinMaxState)}, []string{"state machine"}, true},
+		{words, []*Flag{Strategy(TrieStrategy)}, []string{"trie"}, false},
+		{words, []*Flag{Strategy(LinearStrategy)}, []string{"linear"}, false},
+		{words, []*Flag{HasPrefix, ExactOnly("foo")}, []string{"state machine", "state machine"}, false},
+	} {
+		cases := test.cases
+		var r Result
+		var b bytes.Buffer
+		if err := Generate(&b, cases, "0", append(test.flags, Report(&r))...); err != nil {
+			t.Fatalf("%s: %s", flagNames(test.flags), err)
+		}
+		if r.Bytes != int64(b.Len()) {
+			t.Errorf("%s: expected %d bytes, got %d", flagNames(test.flags), b.Len(), r.Bytes)
+		}
+		if r.Keys != len(cases) {
+			t.Errorf("%s: expected %d keys, got %d", flagNames(test.flags), len(cases), r.Keys)
+		}
+		if !reflect.DeepEqual(r.Strategies, test.strategies) {
+			t.Errorf("%s: expected strategies %q, got %q", flagNames(test.flags), test.strategies, r.Strategies)
+		}
+		if (r.Chains > 0) != test.chains {
+			t.Errorf("%s: unexpected %d chains", flagNames(test.flags), r.Chains)
+		}
+		if stateMachine := test.strategies[0] == "state machine"; (r.MaxState > 0) != stateMachine {
+			t.Errorf("%s: unexpected MaxState %d", flagNames(test.flags), r.MaxState)
+		}
+		if len(r.Warnings) > 0 {
+			t.Errorf("%s: unexpected warnings %q", flagNames(test.flags), r.Warnings)
+		}
+	}
+}
+
+// TestReportWarnings tests that flags which have no effect are reported.
+func TestReportWarnings(t *testing.T) {
+	var r Result
+	var b bytes.Buffer
+	if err := Generate(&b, map[string]string{"foo": "1"}, "0", Equivalent('o', 'ö'), Normalize, Report(&r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings) != 2 || !strings.Contains(fmt.Sprint(r.Warnings), `'\u00f6'`) {
+		t.Errorf("expected warnings for Normalize and Equivalent, got %q", r.Warnings)
+	}
+
+	// The Result is reset by each call.
+	if err := Generate(&b, map[string]string{"foo": "1"}, "0", Report(&r)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Warnings) != 0 || len(r.Strategies) != 1 {
+		t.Errorf("expected Result to be reset, got %+v", r)
+	}
+}
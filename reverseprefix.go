@@ -0,0 +1,119 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ReversePrefix is a flag, which can be passed to GenerateReverse, to output
+// a function which looks up keys by the text of their value expressions,
+// rather than by value.  The input is a string, which may be the full text
+// of a value expression or any unambiguous prefix of it.  For example, given
+// the cases:
+//
+//	map[string]string{
+//		"GET":  "MethodGet",
+//		"POST": "MethodPost",
+//	}
+//
+// the generated function returns "GET" for an input of "MethodGet" or
+// "MethodG", and none for "Method" (which is a prefix of both).  This is
+// intended for debugging tools, which map partial constant names back to
+// the strings they are parsed from.
+//
+// Other flags supported by Generate (such as Insensitive) may be passed
+// along with this one, and apply to matching the input against the value
+// expressions, except for HasPrefix and HasSuffix.
+var ReversePrefix = new(Flag)
+
+// generateReversePrefix implements GenerateReverse when the ReversePrefix
+// flag is specified.
+func generateReversePrefix(w io.Writer, cases map[string]string, none string, flags []*Flag) error {
+	matchFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag == HasPrefix || flag == HasSuffix {
+			return &ErrBadFlags{cannotCombine: []string{"ReversePrefix", flagName(flag)}}
+		}
+		if flag != ReversePrefix {
+			matchFlags = append(matchFlags, flag)
+		}
+	}
+	fs, err := parseFlags(matchFlags...)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Values are compared in canonical form, so that (for instance)
+	// prefixes which only differ by case are considered the same when
+	// Insensitive is specified.
+	full := make(map[string]string, len(cases)) // canonical value -> key
+	prefixes := make(map[string][]string)       // canonical prefix -> keys
+	e := new(ErrAmbiguous)
+	for _, key := range keys {
+		value := fs.canonicalize(cases[key])
+		if other, found := full[value]; found {
+			e.add(nil, cases[other], cases[key])
+			continue
+		}
+		full[value] = key
+
+		for i := 1; i < len(value); i++ {
+			if utf8.RuneStart(value[i]) {
+				prefixes[value[:i]] = append(prefixes[value[:i]], key)
+			}
+		}
+	}
+	if len(e.keys) > 0 {
+		return e
+	}
+
+	// A full value expression takes precedence over a prefix of another,
+	// and prefixes shared by more than one value expression return none.
+	reverseCases := make(map[string]string, len(full)+len(prefixes))
+	for value, key := range full {
+		reverseCases[value] = strconv.Quote(key)
+	}
+	for prefix, keys := range prefixes {
+		if _, found := full[prefix]; !found && len(keys) == 1 {
+			reverseCases[prefix] = strconv.Quote(keys[0])
+		}
+	}
+
+	return Generate(w, reverseCases, none, matchFlags...)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestReversePrefix tests looking up keys by a prefix of their value
+// expressions.
+func TestReversePrefix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func lookup(input string) string {")
+		err := GenerateReverse(w, map[string]string{
+			"GET":     "MethodGet",
+			"POST":    "MethodPost",
+			"PUT":     "MethodPut",
+			"OPTIONS": "MethodOptions",
+			"OPT":     "MethodOpt",
+		}, `"?"`, ReversePrefix, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(lookup(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "MethodGet", "GET")
+	expectMatch(t, "methodg", "GET")
+	expectMatch(t, "MethodPo", "POST")
+	expectMatch(t, "MethodP", "?")
+	expectMatch(t, "Method", "?")
+	expectMatch(t, "MethodOpt", "OPT")
+	expectMatch(t, "MethodOpti", "OPTIONS")
+	expectMatch(t, "MethodGets", "?")
+}
+
+// TestReversePrefixErrors tests the errors returned with ReversePrefix.
+func TestReversePrefixErrors(t *testing.T) {
+	err := GenerateReverse(ioutil.Discard, map[string]string{"a": "Foo", "b": "FOO"}, `""`, ReversePrefix, Insensitive)
+	if expect := `ambiguous matches: "FOO", "Foo"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+
+	err = GenerateReverse(ioutil.Discard, map[string]string{"a": "Foo"}, `""`, ReversePrefix, HasPrefix)
+	if _, ok := err.(*ErrBadFlags); !ok {
+		t.Errorf("expected *ErrBadFlags, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// routeNode is a node in the tree of path segments built by
+// GenerateRouter.
+type routeNode struct {
+	route    string // route ending at this node, or "" if none
+	static   map[string]*routeNode
+	param    *routeNode // child for a parameter segment, or nil
+	matchVar string     // name of the closure matching static children
+}
+
+// GenerateRouter outputs Go code for a function named fn, which routes a
+// URL path to one of several routes, and captures the segments of the path
+// which are parameters.  retType is the type of the values.  The generated
+// function has the signature:
+//
+//	func fn(input string, params []string) (retType, []string)
+//
+// Each key in routes is a path beginning with '/', such as
+// "/users/{id}/posts".  The path is split on '/' into segments, and a
+// segment of the form "{name}" is a parameter, which matches any non-empty
+// segment of the input.  When the input matches a route, the value for the
+// route is returned, and the segments of the input which matched its
+// parameters are appended to params (in order) and returned.  Otherwise,
+// none and params are returned.  If params has enough capacity, the
+// function doesn't allocate memory.
+//
+// The input must match every segment of the route, so "/users/" (which ends
+// with an empty segment) is a different route from "/users".  Where a
+// segment of the input matches both a fixed segment and a parameter, the
+// fixed segment is preferred, unless the rest of the input then fails to
+// match any route.  For instance, given the routes "/users/new" and
+// "/users/{id}/edit", the input "/users/new" matches the former, and
+// "/users/new/edit" the latter.  Routes which differ only in the names of
+// their parameters are reported as conflicting.
+//
+// The fixed segments at each position are matched by closures output by
+// Generate, so flags such as Insensitive apply to each segment separately.
+// Flags which change what part of a segment is matched, such as HasPrefix
+// or StopUpon, and flags which add code to the generated function, such as
+// Coverage or Preprocess, are not supported.
+func GenerateRouter(w io.Writer, fn, retType string, routes map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag == HasPrefix || flag == HasSuffix || flag == ASCIIOnly || len(flag.stop) > 0 ||
+			flag.coverage != nil || flag.preprocess != "" || flag.tooShort != "" || flag.tooLong != "" ||
+			flag.maxDepth != 0 || flag.params != "" {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateRouter"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(routes, none); err != nil {
+			return err
+		}
+	}
+
+	root, err := buildRouteTree(routes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string, params []string) (%s, []string) {", fn, retType); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	matchFlags := internalFlags(flags)
+	if err := writeRouteMatchers(w, root, matchFlags, new(int)); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\tif input == \"\" || input[0] != '/' {")
+	fmt.Fprintf(w, "\t\treturn %s, params", none)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\tpath0 := input[1:]")
+	writeRouteNode(w, root, routes, fs, 0, "\t")
+	fmt.Fprintf(w, "\treturn %s, params", none)
+	fmt.Fprintln(w)
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
+
+// isRouteParam returns true if the segment of a route is a parameter.
+func isRouteParam(segment string) bool {
+	return len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}'
+}
+
+// buildRouteTree parses routes into a tree of segments.
+func buildRouteTree(routes map[string]string) (*routeNode, error) {
+	keys := make([]string, 0, len(routes))
+	for route := range routes {
+		keys = append(keys, route)
+	}
+	sort.Strings(keys)
+
+	root := &routeNode{}
+	for _, route := range keys {
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("route must begin with '/': %s", strconv.Quote(route))
+		}
+		node := root
+		for _, segment := range strings.Split(route[1:], "/") {
+			if isRouteParam(segment) {
+				if node.param == nil {
+					node.param = &routeNode{}
+				}
+				node = node.param
+				continue
+			}
+			if strings.ContainsAny(segment, "{}") {
+				return nil, fmt.Errorf("invalid parameter segment %s in route %s",
+					strconv.Quote(segment), strconv.Quote(route))
+			}
+			if node.static == nil {
+				node.static = make(map[string]*routeNode)
+			}
+			child := node.static[segment]
+			if child == nil {
+				child = &routeNode{}
+				node.static[segment] = child
+			}
+			node = child
+		}
+		if node.route != "" {
+			return nil, fmt.Errorf("conflicting routes: %s and %s", strconv.Quote(node.route), strconv.Quote(route))
+		}
+		node.route = route
+	}
+	return root, nil
+}
+
+// sortedSegments returns the fixed segments which are children of node, in
+// the order their matcher returns them.
+func (node *routeNode) sortedSegments() []string {
+	segments := make([]string, 0, len(node.static))
+	for segment := range node.static {
+		segments = append(segments, segment)
+	}
+	sort.Strings(segments)
+	return segments
+}
+
+// writeRouteMatchers outputs a closure for each node with fixed segments as
+// children, which returns the index of the matching segment (starting at
+// one) in sortedSegments, or zero if none match.
+func writeRouteMatchers(w io.Writer, node *routeNode, flags []*Flag, count *int) error {
+	if len(node.static) > 0 {
+		*count++
+		node.matchVar = fmt.Sprintf("fastmatchSegment%d", *count)
+		cases := make(map[string]string, len(node.static))
+		for n, segment := range node.sortedSegments() {
+			cases[segment] = strconv.Itoa(n + 1)
+		}
+		if _, err := fmt.Fprintf(w, "\t%s := func(input string) int {", node.matchVar); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		if err := Generate(w, cases, "0", flags...); err != nil {
+			return err
+		}
+	}
+	for _, segment := range node.sortedSegments() {
+		if err := writeRouteMatchers(w, node.static[segment], flags, count); err != nil {
+			return err
+		}
+	}
+	if node.param != nil {
+		return writeRouteMatchers(w, node.param, flags, count)
+	}
+	return nil
+}
+
+// writeRouteNode outputs code which matches the next segment of pathN
+// (where N is depth) against the children of node.  The code returns if a
+// route is matched, and otherwise falls through.
+func writeRouteNode(w io.Writer, node *routeNode, routes map[string]string, fs *flagSet, depth int, indent string) {
+	fmt.Fprintf(w, "%[1]sseg%[2]d, path%[3]d, more%[2]d := path%[2]d, \"\", false", indent, depth, depth+1)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, indent+fs.forEachIndex(fmt.Sprintf("path%d", depth)))
+	fmt.Fprintf(w, "%[1]s\tif path%[2]d[i] == '/' {", indent, depth)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%[1]s\t\tseg%[2]d, path%[3]d, more%[2]d = path%[2]d[:i], path%[2]d[i+1:], true", indent, depth, depth+1)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, indent+"\t\tbreak")
+	fmt.Fprintln(w, indent+"\t}")
+	fmt.Fprintln(w, indent+"}")
+	fmt.Fprintf(w, "%s_ = path%d", indent, depth+1)
+	fmt.Fprintln(w)
+
+	if len(node.static) > 0 {
+		fmt.Fprintf(w, "%sswitch %s(seg%d) {", indent, node.matchVar, depth)
+		fmt.Fprintln(w)
+		for n, segment := range node.sortedSegments() {
+			fmt.Fprintf(w, "%scase %d: // %s", indent, n+1, strconv.Quote(segment))
+			fmt.Fprintln(w)
+			writeRouteChild(w, node.static[segment], routes, fs, depth, indent+"\t")
+		}
+		fmt.Fprintln(w, indent+"}")
+	}
+
+	if node.param != nil {
+		fmt.Fprintf(w, "%sif seg%d != \"\" {", indent, depth)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s\tparams = append(params, seg%d)", indent, depth)
+		fmt.Fprintln(w)
+		writeRouteChild(w, node.param, routes, fs, depth, indent+"\t")
+		fmt.Fprintln(w, indent+"\tparams = params[:len(params)-1]")
+		fmt.Fprintln(w, indent+"}")
+	}
+}
+
+// writeRouteChild outputs code which returns if child is the end of a route
+// and there are no more segments in the input, or else matches the next
+// segment against the children of child.
+func writeRouteChild(w io.Writer, child *routeNode, routes map[string]string, fs *flagSet, depth int, indent string) {
+	hasChildren := len(child.static) > 0 || child.param != nil
+	if child.route != "" {
+		fmt.Fprintf(w, "%sif !more%d {", indent, depth)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s\treturn %s, params", indent, routes[child.route])
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, indent+"}")
+		if hasChildren {
+			// Only reached if there are more segments.
+			writeRouteNode(w, child, routes, fs, depth+1, indent)
+		}
+	} else if hasChildren {
+		fmt.Fprintf(w, "%sif more%d {", indent, depth)
+		fmt.Fprintln(w)
+		writeRouteNode(w, child, routes, fs, depth+1, indent+"\t")
+		fmt.Fprintln(w, indent+"}")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestRouter tests routing paths, capturing parameters, and preferring
+// fixed segments over parameters.
+func TestRouter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "os", "testing"}, func(w io.Writer) error {
+		err := GenerateRouter(w, "route", "int", map[string]string{
+			"/":                        "1",
+			"/users":                   "2",
+			"/users/":                  "3",
+			"/users/new":               "4",
+			"/users/{id}":              "5",
+			"/users/{id}/edit":         "6",
+			"/users/{id}/posts/{post}": "7",
+			"/static/{file}":           "8",
+			"/{lang}/docs":             "9",
+		}, "0", Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tvar buf [4]string")
+		fmt.Fprintln(w, "\tvalue, params := route(os.Args[1], buf[:0])")
+		fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+		fmt.Fprintln(w, "\t\troute(os.Args[1], buf[:0])")
+		fmt.Fprintln(w, "\t})")
+		fmt.Fprintln(w, "\tfmt.Printf(\"%d %q %v\", value, params, allocs)")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for input, expect := range map[string]string{
+		"/":                    `1 [] 0`,
+		"/Users":               `2 [] 0`,
+		"/users/":              `3 [] 0`,
+		"/users/new":           `4 [] 0`,
+		"/users/42":            `5 ["42"] 0`,
+		"/users/new/edit":      `6 ["new"] 0`,
+		"/users/42/posts/7":    `7 ["42" "7"] 0`,
+		"/users/42/posts/":     `0 [] 0`,
+		"/static/app.js":       `8 ["app.js"] 0`,
+		"/static/":             `0 [] 0`,
+		"/fr/docs":             `9 ["fr"] 0`,
+		"/users/docs":          `5 ["docs"] 0`,
+		"/blog/docs":           `9 ["blog"] 0`,
+		"/users//edit":         `0 [] 0`,
+		"users":                `0 [] 0`,
+		"":                     `0 [] 0`,
+		"/nothing/here/at/all": `0 [] 0`,
+	} {
+		expectMatch(t, input, expect)
+	}
+}
+
+// TestRouterErrors tests invalid and conflicting routes, and unsupported
+// flags.
+func TestRouterErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		routes map[string]string
+		flags  []*Flag
+		expect string
+	}{
+		{map[string]string{"users": "1"}, nil, `route must begin with '/': "users"`},
+		{map[string]string{"/users/{id": "1"}, nil, `invalid parameter segment "{id" in route "/users/{id"`},
+		{map[string]string{"/users/{id}": "1", "/users/{name}": "2"}, nil, `conflicting routes: "/users/{id}" and "/users/{name}"`},
+		{map[string]string{"/users": "1", "/USERS": "2"}, []*Flag{Insensitive}, "ambiguous"},
+		{map[string]string{"/users": "1"}, []*Flag{HasPrefix}, `GenerateRouter does not support flags: "HasPrefix"`},
+	} {
+		err := GenerateRouter(ioutil.Discard, "route", "int", testCase.routes, "0", testCase.flags...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("%v: expected error containing %q, got %v", testCase.routes, testCase.expect, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// minRuneTable is the smallest number of runes (including equivalents) for
+// which GenerateRune outputs a lookup table instead of a switch statement.
+const minRuneTable = 16
+
+// GenerateRune outputs Go code to compare a single rune against a set of
+// cases.  This is intended for dispatching on the first character of a
+// token in a lexer, as a companion to a keyword matcher output by Generate:
+//
+//	fmt.Fprintln(w, "func operatorStart(input rune) bool {")
+//	fastmatch.GenerateRune(w, map[rune]string{
+//		'+': "true",
+//		'-': "true",
+//		'*': "true",
+//	}, "false")
+//
+// As with Generate, the caller is expected to write the method signature
+// before calling this function.  The rune to examine should be in a
+// variable named "input".
+//
+// The flags Insensitive and Equivalent are supported.  Keys with different
+// values which are equivalent to each other are reported as ambiguous.
+//
+// If there are many keys, and all of them are in the range U+0000 to U+00FF,
+// a 256-byte lookup table is output.  Otherwise, the generated code consists
+// of a switch statement, with a case clause for each unique value.
+func GenerateRune(w io.Writer, cases map[rune]string, none string, flags ...*Flag) error {
+	var unsupported []string
+	for _, flag := range flags {
+		if flag != Insensitive && len(flag.equivalent) == 0 {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GenerateRune"}
+	}
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+
+	keys := make(sortableRunes, 0, len(cases))
+	for r := range cases {
+		keys = append(keys, r)
+	}
+	sort.Sort(keys)
+
+	// Expand each key to include its equivalents, checking for
+	// ambiguity as we go.
+	owner := make(map[rune]rune, len(cases))
+	e := new(ErrAmbiguous)
+	for _, key := range keys {
+		for _, r := range fs.equiv.lookup(key) {
+			if other, found := owner[r]; found && cases[other] != cases[key] {
+				e.add(nil, string(other), string(key))
+				continue
+			}
+			owner[r] = key
+		}
+	}
+	if len(e.keys) > 0 {
+		return e
+	}
+
+	// Runes are grouped by the value they return.
+	values := make([]string, 0, len(cases))
+	byValue := make(map[string]sortableRunes, len(cases))
+	dense := len(owner) >= minRuneTable
+	for r, key := range owner {
+		value := cases[key]
+		if len(byValue[value]) == 0 {
+			values = append(values, value)
+		}
+		byValue[value] = append(byValue[value], r)
+		if r < 0 || r > 0xff {
+			dense = false
+		}
+	}
+	sort.Strings(values)
+	if len(values) > 255 {
+		dense = false
+	}
+
+	if dense {
+		table := make([]byte, 256)
+		for n, value := range values {
+			for _, r := range byValue[value] {
+				table[r] = byte(n + 1)
+			}
+		}
+		writeLookupTable(w, "\t", "fastmatchRuneTable", table)
+		if _, err := fmt.Fprintln(w, "\tif input >= 0 && input <= 0xff {"); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "\t\tswitch fastmatchRuneTable[input] {")
+		for n, value := range values {
+			fmt.Fprintf(w, "\t\tcase %d:", n+1)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "\t\t\treturn", value)
+		}
+		fmt.Fprintln(w, "\t\t}")
+		fmt.Fprintln(w, "\t}")
+	} else if len(values) > 0 {
+		if _, err := fmt.Fprintln(w, "\tswitch input {"); err != nil {
+			return err
+		}
+		for _, value := range values {
+			sort.Sort(byValue[value])
+			fmt.Fprintf(w, "\tcase %s:", quoteRunes(byValue[value]))
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "\t\treturn", value)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// testGenerateRune compiles a program which matches the first rune of its
+// argument using the supplied cases.
+func testGenerateRune(t *testing.T, cases map[rune]string, tests map[rune]string, flags ...*Flag) {
+	cleanup, err := generateProgram([]string{"fmt", "os", "unicode/utf8"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input rune) int {")
+		if err := GenerateRune(w, cases, "0", flags...); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tr, _ := utf8.DecodeRuneInString(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(match(r))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for input, expect := range tests {
+		expectMatch(t, string(input), expect)
+	}
+}
+
+// TestGenerateRuneSwitch tests matching a small number of runes.
+func TestGenerateRuneSwitch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	testGenerateRune(t, map[rune]string{
+		'+':      "1",
+		'-':      "1",
+		'x':      "2",
+		'\u00e9': "3",
+		'\u4e16': "4",
+	}, map[rune]string{
+		'+':      "1",
+		'-':      "1",
+		'x':      "2",
+		'X':      "2",
+		'\u00e9': "3",
+		'\u00c9': "3",
+		'\u4e16': "4",
+		'y':      "0",
+	}, Insensitive, Equivalent('\u00e9', '\u00c9'))
+}
+
+// TestGenerateRuneTable tests matching enough runes to use a lookup table.
+func TestGenerateRuneTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cases := make(map[rune]string)
+	for _, r := range Lowercase {
+		cases[r] = "1"
+	}
+	for _, r := range Numbers {
+		cases[r] = "2"
+	}
+	cases['\u00ff'] = "3"
+
+	testGenerateRune(t, cases, map[rune]string{
+		'a':      "1",
+		'Q':      "1",
+		'7':      "2",
+		'\u00ff': "3",
+		'\u0100': "0",
+		'!':      "0",
+	}, Insensitive)
+}
+
+// TestGenerateRuneErrors tests that GenerateRune reports ambiguity and
+// unsupported flags.
+func TestGenerateRuneErrors(t *testing.T) {
+	err := GenerateRune(ioutil.Discard, map[rune]string{'a': "1", 'A': "2"}, "0", Insensitive)
+	if expect := `ambiguous matches: "A", "a"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+
+	err = GenerateRune(ioutil.Discard, map[rune]string{'a': "1"}, "0", HasPrefix)
+	if expect := `GenerateRune does not support flags: "HasPrefix"`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+}
@@ -99,8 +99,11 @@ func makeEquivalents(flags ...*Flag) runeEquivalents {
 	equiv := make(dedupedRuneEquivalents)
 
 	for _, f := range flags {
-		if f == Insensitive {
+		if f == Insensitive || f == IdentifierCase || f == InsensitiveUnicode || f == InsensitiveTurkish {
 			for lower := 'a'; lower <= 'z'; lower++ {
+				if lower == 'i' && f == InsensitiveTurkish {
+					continue
+				}
 				upper := 'A' + (lower - 'a')
 				equiv.set(lower, upper)
 				equiv.set(upper, lower)
@@ -201,3 +204,49 @@ possibilities:
 
 	return runes
 }
+
+// RuneClasses describes which runes are treated identically by code output
+// with a given set of Flags, such as Insensitive or Equivalent.  It is for
+// use by other code generators which accept the same flags, so that they
+// treat runes the same way Generate does.
+type RuneClasses struct {
+	equiv runeEquivalents
+}
+
+// NewRuneClasses returns the RuneClasses for a set of flags.  Flags which
+// don't make runes equivalent are ignored, but ErrBadFlags is returned for
+// any combination of flags Generate would reject.
+func NewRuneClasses(flags ...*Flag) (*RuneClasses, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+	return &RuneClasses{equiv: fs.equiv}, nil
+}
+
+// Class returns a sorted slice of the runes equivalent to r, including r
+// itself.
+func (c *RuneClasses) Class(r rune) []rune {
+	return append([]rune(nil), c.equiv.lookup(r)...)
+}
+
+// Equivalent returns true if r1 and r2 are equivalent.  Every rune is
+// equivalent to itself.
+func (c *RuneClasses) Equivalent(r1, r2 rune) bool {
+	return c.equiv.isEquiv(r1, r2)
+}
+
+// Expand returns a sorted, de-duplicated slice of the runes in rs and
+// everything equivalent to them.  Runes equivalent to any of those in
+// exclude are omitted.
+func (c *RuneClasses) Expand(rs []rune, exclude ...[]rune) []rune {
+	return c.equiv.expand(rs, exclude...)
+}
+
+// UniqueAtOffset returns the bytes (converted to runes) found at a given
+// byte offset in keys, sorted, and with only the first of each set of
+// equivalents.  Keys which are too short are skipped.  This is what
+// Generate examines when deciding which cases to output at each offset.
+func (c *RuneClasses) UniqueAtOffset(keys []string, offset int) []rune {
+	return c.equiv.uniqueAtOffset(keys, offset)
+}
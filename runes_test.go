@@ -157,3 +157,33 @@ func TestQuoteRunes(t *testing.T) {
 		}
 	}
 }
+
+// TestRuneClasses tests the public API for rune equivalence.
+func TestRuneClasses(t *testing.T) {
+	c, err := NewRuneClasses(Insensitive, Equivalent('-', '_'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if class := c.Class('e'); !reflect.DeepEqual([]rune{'E', 'e'}, class) {
+		t.Errorf("expected ['E', 'e'], got %q", class)
+	}
+	class := c.Class('-')
+	class[0] = 'x'
+	if !reflect.DeepEqual([]rune{'-', '_'}, c.Class('-')) {
+		t.Error("modifying the result of Class should not modify the RuneClasses")
+	}
+	if !c.Equivalent('_', '-') || c.Equivalent('a', 'b') {
+		t.Error("wrong result from Equivalent")
+	}
+	if rs := c.Expand([]rune{'a', '-', '.'}, []rune{'_'}); !reflect.DeepEqual([]rune{'.', 'A', 'a'}, rs) {
+		t.Errorf("expected ['.', 'A', 'a'], got %q", rs)
+	}
+	if rs := c.UniqueAtOffset([]string{"ab", "Ac", "a_c", "a-d", "x"}, 1); !reflect.DeepEqual([]rune{'_', 'b', 'c'}, rs) {
+		t.Errorf("expected ['_', 'b', 'c'], got %q", rs)
+	}
+
+	if _, err := NewRuneClasses(HasPrefix, HasSuffix); err == nil {
+		t.Error("expected error for invalid flags")
+	}
+}
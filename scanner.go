@@ -0,0 +1,185 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Delimiters specifies how GeneratePairScanner splits its input into keys
+// and values.
+type Delimiters struct {
+	// Pairs lists the bytes which separate one key and value from the
+	// next, such as "&" for a URL query string, or ";" for a Cookie
+	// header.
+	Pairs string
+
+	// KeyValue separates a key from its value, such as '='.  A pair
+	// without it is a key with an empty value.  The delimiters must be
+	// ASCII.
+	KeyValue byte
+}
+
+// QueryDelimiters are the Delimiters for URL query strings and form data,
+// such as "a=1&b=2".
+var QueryDelimiters = Delimiters{Pairs: "&", KeyValue: '='}
+
+// GeneratePairScanner outputs Go code for a function named fn, which walks
+// input consisting of key/value pairs, such as "k1=v1&k2=v2", and runs
+// code for each key in cases which it encounters.  The generated function
+// has the signature:
+//
+//	func fn(input string)
+//
+// The values in cases are Go statements (rather than expressions), in which
+// the key and the value from the input are available as the string
+// variables key and value.  These typically store the value in a struct, or
+// pass it to a callback, which can be declared as a parameter of fn with
+// Params.  For example:
+//
+//	fastmatch.GeneratePairScanner(w, "scanQuery", fastmatch.QueryDelimiters,
+//		map[string]string{
+//			"q":    "dst.Query = value",
+//			"page": "dst.Page = value",
+//		}, "", fastmatch.Params("dst *Search"))
+//
+// unknown is run for keys which don't match any key in cases, and may be
+// empty.  Empty pairs are skipped.  Keys and values are not unescaped, so
+// (for instance) a key of "a%62" does not match "ab".
+//
+// Keys are matched by a closure output by Generate, so the flags supported
+// by Generate, such as Insensitive, apply to the keys.  Ignore can be used
+// to skip whitespace around keys, as in Cookie headers ("a=1; b=2").  Flags
+// which refer to the values, such as ValueType, TooShort, and TooLong, are
+// not supported, nor is Coverage.
+func GeneratePairScanner(w io.Writer, fn string, delims Delimiters, cases map[string]string, unknown string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	var unsupported []string
+	for _, flag := range flags {
+		if flag.valueType != "" || flag.tooShort != "" || flag.tooLong != "" || flag.coverage != nil {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "GeneratePairScanner"}
+	}
+	if delims.Pairs == "" || delims.KeyValue == 0 {
+		return errors.New("both pair and key/value delimiters are required")
+	}
+	for i := 0; i < len(delims.Pairs); i++ {
+		if delims.Pairs[i] >= utf8.RuneSelf {
+			return errors.New("delimiters must be ASCII")
+		}
+	}
+	if delims.KeyValue >= utf8.RuneSelf {
+		return errors.New("delimiters must be ASCII")
+	}
+	if strings.IndexByte(delims.Pairs, delims.KeyValue) != -1 {
+		return fmt.Errorf("%s is both a pair and key/value delimiter", strconv.QuoteRune(rune(delims.KeyValue)))
+	}
+
+	// The closure matching keys returns the index (starting at one) of
+	// the statement to run.
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	indexes := make(map[string]string, len(keys))
+	for n, key := range keys {
+		indexes[key] = strconv.Itoa(n + 1)
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string%s) {", fn, fs.extraParams()); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tfastmatchKey := func(input string) int {")
+	if err := Generate(w, indexes, "0", internalFlags(flags)...); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\tfor input != \"\" {")
+	fmt.Fprintln(w, "\t\tpair := input")
+	fmt.Fprintln(w, "\t\tinput = \"\"")
+	if len(delims.Pairs) == 1 {
+		fmt.Fprintln(w, "\t\t"+fs.forEachIndex("pair"))
+		fmt.Fprintf(w, "\t\t\tif pair[i] == %s {", strconv.QuoteRune(rune(delims.Pairs[0])))
+	} else {
+		// The loop is ended by assigning to i, so it can't range
+		// over an integer.
+		fmt.Fprintln(w, "\t\tfor i := 0; i < len(pair); i++ {")
+		fmt.Fprintln(w, "\t\t\tswitch pair[i] {")
+		fmt.Fprintf(w, "\t\t\tcase %s:", quoteRunes([]rune(delims.Pairs)))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\t\tpair, input = pair[:i], pair[i+1:]")
+	if len(delims.Pairs) == 1 {
+		fmt.Fprintln(w, "\t\t\t\tbreak")
+	} else {
+		fmt.Fprintln(w, "\t\t\t\ti = len(pair)")
+	}
+	fmt.Fprintln(w, "\t\t\t}")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\tif pair == \"\" {")
+	fmt.Fprintln(w, "\t\t\tcontinue")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\tkey, value := pair, \"\"")
+	fmt.Fprintln(w, "\t\t"+fs.forEachIndex("pair"))
+	fmt.Fprintf(w, "\t\t\tif pair[i] == %s {", strconv.QuoteRune(rune(delims.KeyValue)))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t\t\t\tkey, value = pair[:i], pair[i+1:]")
+	fmt.Fprintln(w, "\t\t\t\tbreak")
+	fmt.Fprintln(w, "\t\t\t}")
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t\t_, _ = key, value")
+	fmt.Fprintln(w, "\t\tswitch fastmatchKey(key) {")
+	for n, key := range keys {
+		fmt.Fprintf(w, "\t\tcase %d: // %s", n+1, strconv.Quote(key))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "\t\t\t"+cases[key])
+	}
+	if unknown != "" {
+		fmt.Fprintln(w, "\t\tdefault:")
+		fmt.Fprintln(w, "\t\t\t"+unknown)
+	}
+	fmt.Fprintln(w, "\t\t}")
+	fmt.Fprintln(w, "\t}")
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
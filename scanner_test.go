@@ -0,0 +1,112 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestPairScanner tests scanning a query string and Cookie header.
+func TestPairScanner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	for _, testCase := range []struct {
+		delims Delimiters
+		flags  []*Flag
+		expect map[string]string
+	}{
+		{QueryDelimiters, []*Flag{Insensitive}, map[string]string{
+			"q=go&page=2":        `{Query:go Page:2 Other:[]}`,
+			"PAGE=3&x=1&&q":      `{Query: Page:3 Other:[x]}`,
+			"q=a=b&q=c":          `{Query:c Page: Other:[]}`,
+			"qq=1&pag=2&page2=3": `{Query: Page: Other:[qq pag page2]}`,
+		}},
+		{Delimiters{Pairs: ";,", KeyValue: '='}, []*Flag{Ignore(' ')}, map[string]string{
+			"q=go; page=2":   `{Query:go Page:2 Other:[]}`,
+			" q = x ,page=1": `{Query: x  Page:1 Other:[]}`,
+		}},
+	} {
+		cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+			fmt.Fprintln(w, "type search struct {")
+			fmt.Fprintln(w, "\tQuery, Page string")
+			fmt.Fprintln(w, "\tOther      []string")
+			fmt.Fprintln(w, "}")
+			fmt.Fprintln(w)
+			err := GeneratePairScanner(w, "scan", testCase.delims, map[string]string{
+				"q":    "dst.Query = value",
+				"page": "dst.Page = value",
+			}, "dst.Other = append(dst.Other, key)", append(testCase.flags, Params("dst *search"))...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "func main() {")
+			fmt.Fprintln(w, "\tdst := search{Other: []string{}}")
+			fmt.Fprintln(w, "\tscan(os.Args[1], &dst)")
+			fmt.Fprintln(w, "\tfmt.Printf(\"%+v\", dst)")
+			_, err = fmt.Fprintln(w, "}")
+			return err
+		})
+		if err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+
+		for input, expect := range testCase.expect {
+			expectMatch(t, input, expect)
+		}
+		cleanup()
+	}
+}
+
+// TestPairScannerErrors tests invalid delimiters and unsupported flags.
+func TestPairScannerErrors(t *testing.T) {
+	cases := map[string]string{"q": "_ = value"}
+	for _, testCase := range []struct {
+		delims Delimiters
+		flags  []*Flag
+		expect string
+	}{
+		{Delimiters{Pairs: "&"}, nil, "both pair and key/value delimiters are required"},
+		{Delimiters{Pairs: "&=", KeyValue: '='}, nil, "'=' is both a pair and key/value delimiter"},
+		{Delimiters{Pairs: "\u00a7", KeyValue: '='}, nil, "delimiters must be ASCII"},
+		{QueryDelimiters, []*Flag{TooShort("0")}, `GeneratePairScanner does not support flags: "TooShort"`},
+	} {
+		err := GeneratePairScanner(ioutil.Discard, "scan", testCase.delims, cases, "", testCase.flags...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("expected error containing %q, got %v", testCase.expect, err)
+		}
+	}
+}
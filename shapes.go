@@ -0,0 +1,233 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Token shapes are pseudo-keys, which can be used in the cases map passed to
+// Generate to match classes of input that can't be enumerated, such as
+// numbers.  This allows a lexer to classify both keywords and literals using
+// a single generated function, instead of falling back to regexp:
+//
+//	fastmatch.Generate(w, map[string]string{
+//		"if":                   "If",
+//		"for":                  "For",
+//		fastmatch.IntegerToken: "Int",
+//		fastmatch.HexToken:     "Hex",
+//	}, "Ident")
+//
+// Regular keys take precedence over token shapes, so a keyword which also
+// happens to have the shape of (for instance) a hex literal will match the
+// keyword.  The token shapes are mutually exclusive, so they are never
+// ambiguous with each other.
+//
+// Flags passed to Generate apply only to the regular keys.  Token shapes are
+// always compared against the entire input, as supplied.
+const (
+	// IntegerToken matches one or more ASCII digits.
+	IntegerToken = "\x00integer"
+
+	// HexToken matches "0x" or "0X" followed by one or more hex digits.
+	HexToken = "\x00hex"
+
+	// DateToken matches an ISO 8601 calendar date in the form
+	// YYYY-MM-DD.  The month must be between 01 and 12, and the day
+	// between 01 and 31.  (It does not check the number of days in the
+	// month.)
+	DateToken = "\x00date"
+)
+
+// tokenShape describes the generated code for one of the token shapes.
+type tokenShape struct {
+	key, name, code string
+
+	// example is an input matching the shape, for use by GenerateTest.
+	example string
+
+	// regexp is an equivalent regular expression, for use by
+	// ExportRegexp.
+	regexp string
+}
+
+// tokenShapes lists the token shapes, in the order they're checked.
+var tokenShapes = []tokenShape{
+	{IntegerToken, "IntegerToken", integerTokenCode, "42", `[0-9]+`},
+	{HexToken, "HexToken", hexTokenCode, "0x2a", `0[Xx][0-9A-Fa-f]+`},
+	{DateToken, "DateToken", dateTokenCode, "2006-01-02", `[0-9]{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12][0-9]|3[01])`},
+}
+
+const integerTokenCode = `	fastmatchIntegerToken := func(input string) bool {
+		if len(input) == 0 {
+			return false
+		}
+		for i := 0; i < len(input); i++ {
+			if input[i] < '0' || input[i] > '9' {
+				return false
+			}
+		}
+		return true
+	}
+`
+
+const hexTokenCode = `	fastmatchHexToken := func(input string) bool {
+		if len(input) < 3 || input[0] != '0' || (input[1] != 'x' && input[1] != 'X') {
+			return false
+		}
+		for i := 2; i < len(input); i++ {
+			c := input[i]
+			if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+				return false
+			}
+		}
+		return true
+	}
+`
+
+const dateTokenCode = `	fastmatchDateToken := func(input string) bool {
+		if len(input) != 10 || input[4] != '-' || input[7] != '-' {
+			return false
+		}
+		for _, i := range [...]int{0, 1, 2, 3, 5, 6, 8, 9} {
+			if input[i] < '0' || input[i] > '9' {
+				return false
+			}
+		}
+		month := int(input[5]-'0')*10 + int(input[6]-'0')
+		day := int(input[8]-'0')*10 + int(input[9]-'0')
+		return month >= 1 && month <= 12 && day >= 1 && day <= 31
+	}
+`
+
+// splitShapes separates token shapes from the regular keys in cases.  The
+// returned shapes are in the order they should be checked.  If there are no
+// token shapes, cases is returned as-is.
+func splitShapes(cases map[string]string) (map[string]string, []tokenShape) {
+	var shapes []tokenShape
+	for _, shape := range tokenShapes {
+		if _, found := cases[shape.key]; found {
+			shapes = append(shapes, shape)
+		}
+	}
+	if len(shapes) == 0 {
+		return cases, nil
+	}
+
+	keywords := make(map[string]string, len(cases)-len(shapes))
+	for key, value := range cases {
+		keywords[key] = value
+	}
+	for _, shape := range shapes {
+		delete(keywords, shape.key)
+	}
+	return keywords, shapes
+}
+
+// generateShapes implements Generate when the cases include token shapes.
+// The regular keys are matched by a closure returning a number for each
+// unique value, which is followed by checks for each token shape.
+func generateShapes(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	keywords, shapes := splitShapes(cases)
+
+	// Keys with the same value share a number, so that they aren't
+	// reported as ambiguous if they match the same input.
+	var values []string
+	valueNums := make(map[string]string, len(keywords))
+	for _, value := range keywords {
+		if valueNums[value] == "" {
+			values = append(values, value)
+			valueNums[value] = "-1"
+		}
+	}
+	sort.Strings(values)
+	for n, value := range values {
+		valueNums[value] = strconv.Itoa(n + 1)
+	}
+	numCases := make(map[string]string, len(keywords))
+	keys := make([]string, 0, len(keywords))
+	for key, value := range keywords {
+		numCases[key] = valueNums[value]
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if len(keywords) > 0 {
+		if _, err := fmt.Fprintln(w, "\tfastmatchKeyword := func(input string) int {"); err != nil {
+			return err
+		}
+		if err := Generate(w, numCases, "0", internalFlags(flags)...); err != nil {
+			return err
+		}
+	}
+	for _, shape := range shapes {
+		if _, err := fmt.Fprint(w, shape.code); err != nil {
+			return err
+		}
+	}
+
+	if len(keywords) > 0 {
+		fmt.Fprintln(w, "\tswitch fastmatchKeyword(input) {")
+		for n, value := range values {
+			fmt.Fprintf(w, "\tcase %d:", n+1)
+			fmt.Fprintln(w)
+			if lines != nil {
+				for _, key := range keys {
+					if keywords[key] == value {
+						fs.cover(key, lines.lines)
+					}
+				}
+			}
+			fmt.Fprintln(w, "\t\treturn", value)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	for _, shape := range shapes {
+		fmt.Fprintf(w, "\tif fastmatch%s(input) {", shape.name)
+		fmt.Fprintln(w)
+		if lines != nil {
+			fs.cover(shape.key, lines.lines)
+		}
+		fmt.Fprintln(w, "\t\treturn", cases[shape.key])
+		fmt.Fprintln(w, "\t}")
+	}
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err := fmt.Fprintln(w, "}") // end of func
+	return err
+}
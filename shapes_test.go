@@ -0,0 +1,104 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestSplitShapes tests separating token shapes from regular keys.
+func TestSplitShapes(t *testing.T) {
+	keywords, shapes := splitShapes(map[string]string{
+		"if":         "1",
+		DateToken:    "2",
+		IntegerToken: "3",
+	})
+	if len(keywords) != 1 || keywords["if"] != "1" {
+		t.Errorf("unexpected keywords: %q", keywords)
+	}
+	if len(shapes) != 2 || shapes[0].key != IntegerToken || shapes[1].key != DateToken {
+		t.Errorf("unexpected shapes: %v", shapes)
+	}
+}
+
+// TestShapesAmbiguity tests that keys which match the same input are only
+// reported as ambiguous if their values differ.
+func TestShapesAmbiguity(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{
+		"if":         "1",
+		"IF":         "1",
+		IntegerToken: "2",
+	}, "0", Insensitive)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	err = Generate(ioutil.Discard, map[string]string{
+		"if":         "1",
+		"IF":         "2",
+		IntegerToken: "3",
+	}, "0", Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
+
+// TestShapesCoverage tests that token shapes appear in the coverage
+// manifest.
+func TestShapesCoverage(t *testing.T) {
+	cases := map[string]string{
+		"if":         "1",
+		"for":        "2",
+		HexToken:     "3",
+		IntegerToken: "4",
+	}
+	m := &CoverageManifest{Line: 5}
+	var b bytes.Buffer
+	if err := Generate(&b, cases, "0", Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+
+	seen := make(map[string]bool, len(cases))
+	for _, r := range m.Keys {
+		seen[r.Key] = true
+		actual := strings.TrimSpace(lines[r.StartLine-m.Line])
+		if expect := "return " + cases[r.Key]; actual != expect {
+			t.Errorf("expected %q at line %d for %q, got %q", expect, r.StartLine, r.Key, actual)
+		}
+	}
+	for key := range cases {
+		if !seen[key] {
+			t.Errorf("%q missing from manifest", key)
+		}
+	}
+}
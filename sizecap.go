@@ -0,0 +1,110 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrOutputTooLarge is returned by a SizeCappedWriter, and thus by Generate
+// when the MaxOutputBytes flag is specified, if the generated code would
+// exceed the limit.
+type ErrOutputTooLarge struct {
+	// Limit is the maximum number of bytes which could be written.
+	Limit int64
+}
+
+// Error implements the error interface.
+func (e *ErrOutputTooLarge) Error() string {
+	return fmt.Sprintf("generated code exceeds the limit of %d bytes", e.Limit)
+}
+
+// SizeCappedWriter is an io.Writer which passes writes through to another
+// io.Writer, until a limit on the total number of bytes is reached.  A write
+// which would exceed the limit is discarded entirely, and it and every
+// subsequent write return ErrOutputTooLarge.  This protects a build from
+// writing an unreasonably large file when a code generator is fed bad input
+// data.  See also MaxOutputBytes.
+type SizeCappedWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+	err     error
+}
+
+// NewSizeCappedWriter returns a SizeCappedWriter which writes up to limit
+// bytes to w.
+func NewSizeCappedWriter(w io.Writer, limit int64) *SizeCappedWriter {
+	return &SizeCappedWriter{w: w, limit: limit}
+}
+
+// Write implements io.Writer.
+func (cw *SizeCappedWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	if int64(len(p)) > cw.limit-cw.written {
+		cw.err = &ErrOutputTooLarge{Limit: cw.limit}
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+// Written returns the number of bytes which have been written.
+func (cw *SizeCappedWriter) Written() int64 {
+	return cw.written
+}
+
+// stopped returns the error which should end generation early: ctx.Err()
+// from GenerateContext, or ErrOutputTooLarge once the limit from
+// MaxOutputBytes has been exceeded.  Not every write is checked for errors,
+// so code which writes in a loop calls this rather than carry on writing
+// output which will only be discarded.
+func (fs *flagSet) stopped() error {
+	if err := fs.ctx.Err(); err != nil {
+		return err
+	}
+	if fs.capped != nil {
+		return fs.capped.err
+	}
+	return nil
+}
+
+// MaxOutputBytes returns a flag, which can be passed to Generate, to stop
+// generating code once more than n bytes have been written, returning
+// ErrOutputTooLarge.  (The caller should then discard the partial output,
+// such as by using Render.)  The limit applies to the code written by each
+// call to Generate, not including the function signature written by the
+// caller.  If n is not positive, there is no limit.
+func MaxOutputBytes(n int64) *Flag {
+	return &Flag{maxOutput: n}
+}
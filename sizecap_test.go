@@ -0,0 +1,143 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestSizeCappedWriter tests that writes beyond the limit are discarded.
+func TestSizeCappedWriter(t *testing.T) {
+	var b bytes.Buffer
+	cw := NewSizeCappedWriter(&b, 5)
+	if n, err := cw.Write([]byte("abc")); n != 3 || err != nil {
+		t.Fatalf("expected 3 bytes written, got %d, %v", n, err)
+	}
+	if n, err := cw.Write([]byte("def")); n != 0 || err == nil {
+		t.Fatalf("expected error, got %d bytes written", n)
+	} else if e, ok := err.(*ErrOutputTooLarge); !ok || e.Limit != 5 {
+		t.Fatalf("expected *ErrOutputTooLarge with limit 5, got %v", err)
+	}
+	if _, err := cw.Write([]byte("g")); err == nil {
+		t.Error("expected error to persist after limit was exceeded")
+	}
+	if b.String() != "abc" || cw.Written() != 3 {
+		t.Errorf("expected \"abc\" written, got %q (%d)", b.String(), cw.Written())
+	}
+}
+
+// TestMaxOutputBytes tests limiting the size of the code output by
+// Generate.
+func TestMaxOutputBytes(t *testing.T) {
+	cases := map[string]string{"foo": "1", "bar": "2", "bazqux": "3"}
+	var expect bytes.Buffer
+	if err := Generate(&expect, cases, "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flags := range [][]*Flag{
+		nil,
+		{StreamOutput(16, nil)},
+		{HasPrefix, ExactOnly("foo")},
+	} {
+		var b bytes.Buffer
+		err := Generate(&b, cases, "0", append(flags, MaxOutputBytes(100))...)
+		if _, ok := err.(*ErrOutputTooLarge); !ok {
+			t.Errorf("%s: expected *ErrOutputTooLarge, got %v", flagNames(flags), err)
+		}
+		if b.Len() > 100 {
+			t.Errorf("%s: expected at most 100 bytes, got %d", flagNames(flags), b.Len())
+		}
+	}
+
+	b, err := Render(func(w io.Writer) error {
+		return Generate(w, cases, "0", MaxOutputBytes(int64(expect.Len())))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expect.String() {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect.String(), b.String())
+	}
+}
+
+// TestMaxOutputBytesEarly tests that each strategy stops writing code once
+// the limit has been exceeded, rather than only noticing at the end.
+func TestMaxOutputBytesEarly(t *testing.T) {
+	cases := make(map[string]string, 100)
+	for n := 0; n < 100; n++ {
+		cases[fmt.Sprintf("key%d", n)] = fmt.Sprint(n)
+	}
+
+	for name, gen := range map[string]func(w io.Writer, fs *flagSet) error{
+		"StateMachineStrategy": func(w io.Writer, fs *flagSet) error {
+			return generate(w, cases, "-1", fs, nil)
+		},
+		"TrieStrategy": func(w io.Writer, fs *flagSet) error {
+			return generateTrie(w, cases, "-1", fs, nil)
+		},
+		"LinearStrategy": func(w io.Writer, fs *flagSet) error {
+			return generateLinear(w, cases, "-1", fs, nil)
+		},
+		"ConstantTime": func(w io.Writer, fs *flagSet) error {
+			return generateConstantTime(w, cases, "-1", fs, nil)
+		},
+	} {
+		fs, err := parseFlags()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect bytes.Buffer
+		if err := gen(&expect, fs); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		// The limit has already been exceeded, so whatever gen writes
+		// would be discarded.
+		fs, err = parseFlags()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs.capped = NewSizeCappedWriter(ioutil.Discard, 0)
+		fs.capped.Write([]byte("x"))
+		var b bytes.Buffer
+		if err := gen(&b, fs); err == nil {
+			t.Errorf("%s: expected *ErrOutputTooLarge, got nil", name)
+		} else if _, ok := err.(*ErrOutputTooLarge); !ok {
+			t.Errorf("%s: expected *ErrOutputTooLarge, got %v", name, err)
+		}
+		if b.Len() >= expect.Len()/2 {
+			t.Errorf("%s: wrote %d of %d bytes after the limit was exceeded", name, b.Len(), expect.Len())
+		}
+	}
+}
@@ -30,16 +30,34 @@ package fastmatch
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math"
+	"sort"
 )
 
-// The maximum allowable state value.  Can be overridden for testing.
-var maxState uint64 = math.MaxUint64
+// MaxState returns a flag, which can be passed to Generate, to limit the
+// largest intermediate state value computed by the generated code.  By
+// default, states are summed in a uint64; once there are too many possible
+// states to fit, a second state machine is chained after the first, which
+// continues from the first machine's state.  A smaller limit causes
+// chaining to happen sooner, which is mainly useful for exercising and
+// tuning that code path.
+//
+// n must be at least MinMaxState; ErrBadFlags is returned otherwise.  If n
+// is so small that the states at a single offset in the keys don't fit,
+// Generate returns an error.
+func MaxState(n uint64) *Flag {
+	return &Flag{maxState: n, maxStateSet: true}
+}
+
+// MinMaxState is the smallest value accepted by MaxState.
+const MinMaxState = 16
 
 // stateMachine holds the mapping between a match and the intermediate state
 // changes (runes encountered) leading up to a match.
 type stateMachine struct {
+	maxState  uint64
 	next      uint64
 	base      uint64
 	final     map[string][]uint64
@@ -66,9 +84,10 @@ func (state *stateMachine) foreachNoMore(f func(int, rune, string)) {
 // newStateMachine initializes a stateMachine.
 func newStateMachine(keys []string) *stateMachine {
 	state := &stateMachine{
-		next:  1,
-		base:  1,
-		final: make(map[string][]uint64, len(keys)),
+		maxState: math.MaxUint64,
+		next:     1,
+		base:     1,
+		final:    make(map[string][]uint64, len(keys)),
 	}
 	for _, key := range keys {
 		state.final[key] = make([]uint64, 0, len(key))
@@ -77,13 +96,13 @@ func newStateMachine(keys []string) *stateMachine {
 }
 
 // makeNextStateMachine initializes an additional state machine once we've
-// exceeded the number of intermediate states which fit in a uint64.
-func (state *stateMachine) makeNextStateMachine(realOffset int) {
+// exceeded the number of intermediate states which fit in a uint64 (or the
+// limit set by MaxState).
+func (state *stateMachine) makeNextStateMachine(realOffset int) error {
 	offset := realOffset - state.offset
 	if offset < 1 {
-		// This should only be possible during testing, when maxState
-		// != math.MaxUint64.
-		panic("maxState too small")
+		// This is only possible with a small MaxState.
+		return fmt.Errorf("MaxState(%d) is too small for the keys at offset %d", state.maxState, realOffset)
 	}
 
 	// The current switch statement is incomplete, so truncate any
@@ -101,13 +120,21 @@ func (state *stateMachine) makeNextStateMachine(realOffset int) {
 	})
 
 	// Now create the next state machine, copying remaining keys to it.
+	// Keys are visited in sorted order, so that collapsed state values
+	// are numbered the same way every time.
 	state.continued = &stateMachine{
+		maxState:  state.maxState,
 		next:      1,
 		offset:    realOffset,
 		final:     make(map[string][]uint64, len(state.final)-len(finishedKeys)),
 		collapsed: make(map[string]uint64, len(state.final)-len(finishedKeys)),
 	}
+	keys := make([]string, 0, len(state.final))
 	for key := range state.final {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
 		if finishedKeys[key] {
 			continue
 		}
@@ -135,12 +162,14 @@ func (state *stateMachine) makeNextStateMachine(realOffset int) {
 		state.continued.final[key] = append(make([]uint64, 0, len(key)-realOffset+1), after)
 	}
 	state.continued.base = state.continued.next
+	return nil
 }
 
 // indexKeys assigns a unique state value to each possible state change.  For
 // partial matching, this method also notes where the state should be checked
-// against possible final values.
-func (state *stateMachine) indexKeys(equiv runeEquivalents, partialMatch bool) {
+// against possible final values.  If ctx is canceled, indexing stops and
+// ctx.Err() is returned.
+func (state *stateMachine) indexKeys(ctx context.Context, equiv runeEquivalents, partialMatch bool) error {
 	longestKey := 0
 	keys := make([]string, 0, len(state.final))
 	for key := range state.final {
@@ -149,12 +178,16 @@ func (state *stateMachine) indexKeys(equiv runeEquivalents, partialMatch bool) {
 			longestKey = len(key)
 		}
 	}
+	sort.Strings(keys)
 
 	needShift := true
 	state.possible = make([][]rune, longestKey-state.offset)
 	state.changes = make([]map[rune]uint64, longestKey-state.offset)
 	state.noMore = make([]map[rune][]string, longestKey-state.offset)
 	for realOffset := state.offset; realOffset < longestKey; realOffset++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		offset := realOffset - state.offset
 
 		state.possible[offset] = equiv.uniqueAtOffset(keys, realOffset)
@@ -180,10 +213,11 @@ func (state *stateMachine) indexKeys(equiv runeEquivalents, partialMatch bool) {
 					}
 				}
 				if needIncr {
-					if state.base > maxState-state.next {
-						state.makeNextStateMachine(realOffset)
-						state.continued.indexKeys(equiv, partialMatch)
-						return
+					if state.base > state.maxState-state.next {
+						if err := state.makeNextStateMachine(realOffset); err != nil {
+							return err
+						}
+						return state.continued.indexKeys(ctx, equiv, partialMatch)
 					}
 					state.changes[offset][r] = state.next
 					state.next += state.base
@@ -212,6 +246,7 @@ func (state *stateMachine) indexKeys(equiv runeEquivalents, partialMatch bool) {
 			}
 		}
 	}
+	return nil
 }
 
 // remove removes a string from a slice of strings if present, in the same
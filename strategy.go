@@ -0,0 +1,88 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+// MatchStrategy specifies how the code output by Generate goes about
+// matching its input.  See Strategy.
+type MatchStrategy int
+
+const (
+	// AutoStrategy lets Generate choose.  Currently, a lookup table is
+	// used when all of the keys are short enough (see LargeLookupTable),
+	// and a state machine otherwise.
+	AutoStrategy MatchStrategy = iota
+
+	// StateMachineStrategy examines the input one byte at a time,
+	// adding a value to an integer for each byte which distinguishes
+	// one key from another, and then compares the final integer to the
+	// value expected for each key.  This produces a flat series of
+	// switch statements, and supports all flags.
+	StateMachineStrategy
+
+	// TrieStrategy outputs nested switch statements following a prefix
+	// tree of the keys.  This avoids the state arithmetic entirely,
+	// which often produces denser switch statements that the compiler
+	// can turn into jump tables, at the cost of more code when many keys
+	// share suffixes but not prefixes.  It does not support StopUpon,
+	// Ignore, IgnoreExcept, NoTrailingMark, or ConstantTime.
+	TrieStrategy
+
+	// LinearStrategy compares the input to each key in turn, after
+	// applying any flags to the input at runtime.  This is much slower
+	// than the other strategies, but the generated code is simple enough
+	// to audit by hand (for instance, during a security review of what
+	// a matcher accepts), and can serve as a reference implementation
+	// when testing the code generated by the other strategies.  It
+	// supports all flags except ConstantTime and Inline, and allocates
+	// memory if any flags apply to the input.
+	LinearStrategy
+)
+
+// String returns the name of a MatchStrategy.
+func (s MatchStrategy) String() string {
+	switch s {
+	case AutoStrategy:
+		return "AutoStrategy"
+	case StateMachineStrategy:
+		return "StateMachineStrategy"
+	case TrieStrategy:
+		return "TrieStrategy"
+	case LinearStrategy:
+		return "LinearStrategy"
+	}
+	return "unknown"
+}
+
+// Strategy is a flag, which can be passed to Generate, to choose how the
+// generated code performs matching.  CompareStrategies can help determine
+// which is best for a given set of keys.  If more than one Strategy is
+// specified, the last one wins.
+func Strategy(s MatchStrategy) *Flag {
+	return &Flag{strategy: s}
+}
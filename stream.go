@@ -0,0 +1,95 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bufio"
+	"io"
+)
+
+// defaultStreamSize is the buffer size used by StreamOutput if none is
+// specified.
+const defaultStreamSize = 64 * 1024
+
+// StreamOutput returns a flag, which can be passed to Generate, to buffer
+// the generated code in chunks of at most bufSize bytes before writing it to
+// the io.Writer.  This is intended for very large outputs, such as a matcher
+// for an entire dictionary, which are written directly to a file: the code is
+// never held in memory in its entirety, but the file is still written
+// efficiently, rather than a line at a time.  If bufSize is zero or negative,
+// a default of 64 KiB is used.
+//
+// If progress is not nil, it is called after each chunk is written, with the
+// total number of bytes written so far.  Since the size of the output is not
+// known in advance, this is most useful for logging that a long-running
+// generator is still making progress.
+//
+// The final chunk is written before Generate returns, unless an error is
+// returned.
+func StreamOutput(bufSize int, progress func(written int64)) *Flag {
+	if bufSize <= 0 {
+		bufSize = defaultStreamSize
+	}
+	return &Flag{streamSize: bufSize, progress: progress}
+}
+
+// BufferOutput is a flag, which can be passed to Generate, to hold the
+// generated code in memory until it is complete.  Nothing is written to the
+// io.Writer if an error (such as ErrAmbiguous) is detected part way through,
+// so a file being generated is never left with a partial function in it.
+// This is the opposite of StreamOutput, with which it cannot be combined.
+//
+// BufferOutput only applies to the code written by Generate.  To discard
+// the output of other functions on error, or to also discard the function
+// signature, use Render.
+var BufferOutput = new(Flag)
+
+// progressWriter is an io.Writer which reports the number of bytes written
+// to a callback.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	progress func(int64)
+}
+
+// Write implements io.Writer.
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.written += int64(n)
+	if pw.progress != nil {
+		pw.progress(pw.written)
+	}
+	return n, err
+}
+
+// streamWriter wraps w according to the StreamOutput flag.  The caller must
+// call Flush on the returned writer once done.
+func (fs *flagSet) streamWriter(w io.Writer) *bufio.Writer {
+	return bufio.NewWriterSize(&progressWriter{w: w, progress: fs.progress}, fs.streamSize)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// chunkRecorder is an io.Writer which records the size of each write.
+type chunkRecorder struct {
+	bytes.Buffer
+	chunks []int
+}
+
+// Write implements io.Writer.
+func (cr *chunkRecorder) Write(p []byte) (int, error) {
+	cr.chunks = append(cr.chunks, len(p))
+	return cr.Buffer.Write(p)
+}
+
+// TestStreamOutput tests that output is written in bounded chunks, and that
+// progress is reported after each.
+func TestStreamOutput(t *testing.T) {
+	cases := make(map[string]string, 1000)
+	for n := 0; n < 1000; n++ {
+		cases[fmt.Sprintf("key%d", n)] = fmt.Sprint(n)
+	}
+	cases[IntegerToken] = "-2"
+
+	var unbuffered bytes.Buffer
+	if err := Generate(&unbuffered, cases, "-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var cr chunkRecorder
+	var reported []int64
+	progress := func(written int64) {
+		reported = append(reported, written)
+	}
+	if err := Generate(&cr, cases, "-1", StreamOutput(4096, progress)); err != nil {
+		t.Fatal(err)
+	}
+	if cr.Len() != unbuffered.Len() {
+		t.Errorf("expected %d bytes of output, got %d", unbuffered.Len(), cr.Len())
+	}
+	if len(cr.chunks) < 2 || len(cr.chunks) != len(reported) {
+		t.Fatalf("expected progress for each of %d chunks, got %d", len(cr.chunks), len(reported))
+	}
+	var total int64
+	for n, size := range cr.chunks {
+		if size > 4096 {
+			t.Errorf("chunk %d is %d bytes", n, size)
+		}
+		total += int64(size)
+		if reported[n] != total {
+			t.Errorf("expected progress %d after chunk %d, got %d", total, n, reported[n])
+		}
+	}
+
+	if flag := StreamOutput(0, nil); flag.streamSize != defaultStreamSize {
+		t.Errorf("expected default buffer size, got %d", flag.streamSize)
+	}
+}
+
+// TestBufferOutput tests that nothing is written if an error is detected
+// after some of the code has been generated.
+func TestBufferOutput(t *testing.T) {
+	cases := map[string]string{"abc": "1", "x": "2", "X": "3"}
+	var partial bytes.Buffer
+	if err := Generate(&partial, cases, "0", Insensitive); err == nil {
+		t.Fatal("expected error for ambiguous keys")
+	} else if partial.Len() == 0 {
+		t.Fatal("expected partial output without BufferOutput")
+	}
+
+	var cr chunkRecorder
+	err := Generate(&cr, cases, "0", Insensitive, BufferOutput)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+	if cr.Len() != 0 {
+		t.Errorf("expected no output, got:\n%s", cr.String())
+	}
+
+	delete(cases, "X")
+	var unbuffered bytes.Buffer
+	if err := Generate(&unbuffered, cases, "0"); err != nil {
+		t.Fatal(err)
+	}
+	cr = chunkRecorder{}
+	if err := Generate(&cr, cases, "0", BufferOutput); err != nil {
+		t.Fatal(err)
+	}
+	if len(cr.chunks) != 1 || cr.String() != unbuffered.String() {
+		t.Errorf("expected output in one chunk, got %d:\n%s", len(cr.chunks), cr.String())
+	}
+
+	if _, ok := Generate(&cr, cases, "0", BufferOutput, StreamOutput(0, nil)).(*ErrBadFlags); !ok {
+		t.Error("expected *ErrBadFlags combining BufferOutput and StreamOutput")
+	}
+}
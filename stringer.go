@@ -0,0 +1,131 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// StringerCases builds a cases map from the values of a type with a String
+// method, such as an enum type whose String method was generated by the
+// stringer tool.  The key for each value is the result of its String method,
+// and the value is a Go expression converting the underlying constant to
+// typ, such as "Color(2)".  If typ is "", the name of each value's type is
+// used, unqualified by its package name.
+//
+// This allows the enum type itself to be the source of truth: the same cases
+// map can be passed to Generate, to parse a string into a value, and to
+// GenerateReverse, to convert a value back into a string, without listing
+// the names a second time:
+//
+//	cases, err := fastmatch.StringerCases("", ColorRed, ColorGreen, ColorBlue)
+//	fmt.Fprintln(w, "func parseColor(input string) Color {")
+//	fastmatch.Generate(w, cases, "0", fastmatch.Insensitive)
+//
+// The underlying type must be a boolean, numeric, or string type.  An error
+// is returned if any value's String method returns "" (see ErrEmptyKey), or
+// if two different values have the same String.
+func StringerCases(typ string, values ...fmt.Stringer) (map[string]string, error) {
+	m := make(map[fmt.Stringer]string, len(values))
+	for _, value := range values {
+		expr, err := stringerExpr(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		m[value] = expr
+	}
+	return StringerMap(m)
+}
+
+// StringerMap converts a map keyed by values with a String method into a
+// cases map, suitable for passing to Generate.  The key for each entry is the
+// result of its String method, and the value is unchanged.  This is useful
+// when the values to return aren't the keys themselves, such as when mapping
+// an enum type's values to a related type.
+//
+// An error is returned if any String method returns "" (see ErrEmptyKey), or
+// if two keys have the same String but different values.
+func StringerMap(m map[fmt.Stringer]string) (map[string]string, error) {
+	cases := make(map[string]string, len(m))
+	var conflicts []string
+	for stringer, value := range m {
+		key := stringer.String()
+		if key == "" {
+			return nil, ErrEmptyKey
+		}
+		if existing, found := cases[key]; found && existing != value {
+			if existing > value {
+				existing, value = value, existing
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%q is the String of both %s and %s", key, existing, value))
+			continue
+		}
+		cases[key] = value
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("conflicting values: %s", conflicts[0])
+	}
+	return cases, nil
+}
+
+// stringerExpr returns a Go expression for value, converted to typ.
+func stringerExpr(typ string, value fmt.Stringer) (string, error) {
+	v := reflect.ValueOf(value)
+	if typ == "" {
+		typ = v.Type().Name()
+		if typ == "" {
+			return "", fmt.Errorf("cannot derive expression for unnamed type %s", v.Type())
+		}
+	}
+
+	var literal string
+	switch v.Kind() {
+	case reflect.Bool:
+		literal = strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		literal = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		literal = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0) {
+			return "", fmt.Errorf("cannot derive expression for %s value %v", v.Type(), v.Float())
+		}
+		literal = strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	case reflect.String:
+		literal = strconv.Quote(v.String())
+	default:
+		return "", fmt.Errorf("cannot derive expression for %s, which is not a boolean, numeric, or string type", v.Type())
+	}
+	return typ + "(" + literal + ")", nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// testColor is an enum type with a String method, for testing
+// StringerCases.
+type testColor int
+
+const (
+	testRed testColor = iota + 1
+	testGreen
+	testBlue
+)
+
+func (c testColor) String() string {
+	switch c {
+	case testRed:
+		return "red"
+	case testGreen:
+		return "green"
+	case testBlue:
+		return "blue"
+	}
+	return ""
+}
+
+// testName is a string type with a String method, for testing
+// StringerCases.
+type testName string
+
+func (n testName) String() string {
+	return string(n)
+}
+
+// TestStringerCases tests the expressions derived from each value.
+func TestStringerCases(t *testing.T) {
+	for _, testCase := range []struct {
+		typ    string
+		values []fmt.Stringer
+		expect map[string]string
+	}{
+		{"", []fmt.Stringer{testRed, testBlue}, map[string]string{"red": "testColor(1)", "blue": "testColor(3)"}},
+		{"colors.Color", []fmt.Stringer{testGreen}, map[string]string{"green": "colors.Color(2)"}},
+		{"", []fmt.Stringer{testName("a\"b")}, map[string]string{"a\"b": `testName("a\"b")`}},
+		{"", []fmt.Stringer{testRed, testRed}, map[string]string{"red": "testColor(1)"}},
+	} {
+		cases, err := StringerCases(testCase.typ, testCase.values...)
+		if err != nil {
+			t.Errorf("%v: %s", testCase.values, err)
+			continue
+		}
+		if !reflect.DeepEqual(cases, testCase.expect) {
+			t.Errorf("%v: expected %v, got %v", testCase.values, testCase.expect, cases)
+		}
+	}
+}
+
+// TestStringerCasesErrors tests that values which can't be converted to a
+// cases map are rejected.
+func TestStringerCasesErrors(t *testing.T) {
+	if _, err := StringerCases("", testRed, testColor(0)); err != ErrEmptyKey {
+		t.Errorf("expected ErrEmptyKey, got %v", err)
+	}
+	if _, err := StringerCases("", testName("x"), &testBuffer{}); err == nil {
+		t.Error("expected error from pointer type")
+	}
+
+	_, err := StringerMap(map[fmt.Stringer]string{
+		testRed:           "1",
+		testName("red"):   "2",
+		testName("green"): "3",
+	})
+	if expect := `conflicting values: "red" is the String of both 1 and 2`; err == nil || err.Error() != expect {
+		t.Errorf("expected %q, got %v", expect, err)
+	}
+	if _, err := StringerMap(map[fmt.Stringer]string{testRed: "1", testName("red"): "1"}); err != nil {
+		t.Errorf("unexpected error from identical values: %s", err)
+	}
+}
+
+// testBuffer is a pointer type with a String method, for which no constant
+// expression can be derived.
+type testBuffer struct{}
+
+func (*testBuffer) String() string {
+	return "buffer"
+}
+
+// TestStringerCasesRoundTrip tests that the cases map can be used with both
+// Generate and GenerateReverse.
+func TestStringerCasesRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cases, err := StringerCases("testColor", testRed, testGreen, testBlue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup, err := generateProgram([]string{"fmt", "os"}, func(w io.Writer) error {
+		fmt.Fprintln(w, "type testColor int")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func parse(input string) testColor {")
+		if err := Generate(w, cases, "0", Insensitive); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func name(input testColor) string {")
+		if err := GenerateReverse(w, cases, `"unknown"`); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tc := parse(os.Args[1])")
+		fmt.Fprintln(w, "\tfmt.Println(int(c), name(c))")
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "Green", "2 green")
+	expectMatch(t, "blue", "3 blue")
+	expectMatch(t, "purple", "0 unknown")
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// GenerateTemplateFunc outputs Go code for a function named fn, which
+// behaves as if generated by Generate, but accepts any value as input, so
+// that it can be called from a text/template or html/template template.
+// retType is the type of the values.  The following are output:
+//
+//	func fn(arg interface{}) retType
+//	func fnFuncs() map[string]interface{}
+//
+// Strings and byte slices are matched as-is.  Other values, including
+// named string types such as template.HTML, are converted using fmt.Sprint,
+// so the caller must import the fmt package (in addition to anything
+// returned by Imports).  A nil argument returns none without being matched.
+//
+// fnFuncs returns a map registering fn under its own name, which can be
+// passed to the Funcs method of either kind of template:
+//
+//	tmpl := template.New("page").Funcs(keywordFuncs())
+//	// {{if eq (keyword .Name) "If"}}...{{end}}
+//
+// Any parameters declared by Params follow arg, and must be supplied as
+// additional arguments in the template.  NoImports is not supported, since
+// the generated code needs the fmt package.
+func GenerateTemplateFunc(w io.Writer, fn, retType string, cases map[string]string, none string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+	if fs.noImports {
+		return &ErrBadFlags{unsupported: []string{"NoImports"}, unsupportedBy: "GenerateTemplateFunc"}
+	}
+	if fs.valueType != "" {
+		if err := fs.checkValueType(cases, none); err != nil {
+			return err
+		}
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintf(w, "// %sFuncs returns a FuncMap, for text/template or html/template, which", fn); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "// registers %s.", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %sFuncs() map[string]interface{} {", fn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\treturn map[string]interface{}{%s: %s}", strconv.Quote(fn), fn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(arg interface{}%s) %s {", fn, fs.extraParams(), retType)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tvar input string")
+	fmt.Fprintln(w, "\tswitch arg := arg.(type) {")
+	fmt.Fprintln(w, "\tcase string:")
+	fmt.Fprintln(w, "\t\tinput = arg")
+	fmt.Fprintln(w, "\tcase []byte:")
+	fmt.Fprintln(w, "\t\tinput = string(arg)")
+	fmt.Fprintln(w, "\tcase nil:")
+	fmt.Fprintln(w, "\t\treturn", none)
+	fmt.Fprintln(w, "\tdefault:")
+	fmt.Fprintln(w, "\t\tinput = fmt.Sprint(arg)")
+	fmt.Fprintln(w, "\t}")
+
+	return fs.generateNested(w, lines, cases, none, internalFlags(flags))
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestTemplateFunc tests that the generated function can be called from a
+// template, with several types of argument.
+func TestTemplateFunc(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateProgram([]string{"fmt", "html/template", "os"}, func(w io.Writer) error {
+		err := GenerateTemplateFunc(w, "keyword", "string", map[string]string{
+			"if":  `"If"`,
+			"for": `"For"`,
+			"42":  `"Answer"`,
+		}, `"Ident"`, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "type name string")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tdata := map[string]interface{}{")
+		fmt.Fprintln(w, "\t\t\"String\": os.Args[1],")
+		fmt.Fprintln(w, "\t\t\"Bytes\":  []byte(os.Args[1]),")
+		fmt.Fprintln(w, "\t\t\"Named\":  name(os.Args[1]),")
+		fmt.Fprintln(w, "\t\t\"HTML\":   template.HTML(os.Args[1]),")
+		fmt.Fprintln(w, "\t\t\"Int\":    42,")
+		fmt.Fprintln(w, "\t}")
+		fmt.Fprintln(w, "\tconst src = `{{keyword .String}} {{keyword .Bytes}} {{keyword .Named}} {{keyword .HTML}} {{keyword .Int}} {{keyword .Missing}}`")
+		fmt.Fprintln(w, "\ttmpl := template.Must(template.New(\"t\").Funcs(keywordFuncs()).Parse(src))")
+		fmt.Fprintln(w, "\tif err := tmpl.Execute(os.Stdout, data); err != nil {")
+		fmt.Fprintln(w, "\t\tfmt.Println(err)")
+		fmt.Fprintln(w, "\t}")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "if", "If If If If Answer Ident")
+	expectMatch(t, "FOR", "For For For For Answer Ident")
+	expectMatch(t, "x", "Ident Ident Ident Ident Answer Ident")
+}
+
+// TestTemplateFuncCoverage tests that coverage refers to the lines of the
+// generated function, and that unsupported flags are rejected.
+func TestTemplateFuncCoverage(t *testing.T) {
+	m := &CoverageManifest{Line: 5}
+	var b bytes.Buffer
+	if err := GenerateTemplateFunc(&b, "f", "int", map[string]string{"a": "1"}, "0", Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	if len(m.Keys) != 1 {
+		t.Fatalf("expected 1 coverage entry, got %d", len(m.Keys))
+	}
+	if n := m.Keys[0].StartLine - m.Line; n < 0 || n >= len(lines) || strings.TrimSpace(lines[n]) != "return 1" {
+		t.Errorf("coverage line %d does not refer to return statement:\n%s", m.Keys[0].StartLine, b.String())
+	}
+
+	err := GenerateTemplateFunc(ioutil.Discard, "f", "int", map[string]string{"a": "1"}, "0", NoImports)
+	if _, ok := err.(*ErrBadFlags); !ok {
+		t.Errorf("expected *ErrBadFlags, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"unicode"
+)
+
+// NoTrailingMark is a flag, which can be passed to Generate along with
+// HasPrefix, to specify that a key should not match if the rune following it
+// in the input would extend the last character of the key, such as a
+// combining accent, zero-width joiner, variation selector, or emoji skin
+// tone modifier.  Thus, "e" does not match the beginning of "é" (decomposed),
+// nor does "\U0001f44d" (thumbs up) match the beginning of
+// "\U0001f44d\U0001f3fd" (thumbs up with medium skin tone).
+//
+// Only the rune following the match is checked; the generated code does not
+// otherwise step through the input by grapheme cluster, and this is not an
+// implementation of Unicode Standard Annex #29.  Composed and decomposed
+// forms of the same character are not considered equivalent unless the NFC
+// flag is also specified.
+//
+// The generated code requires the "unicode" package; see Imports.
+// NoTrailingMark requires HasPrefix, and cannot be combined with HasSuffix.
+var NoTrailingMark = new(Flag)
+
+// extendsCluster returns true if a rune continues the grapheme cluster of the
+// rune preceding it.  This must be kept in sync with extendsClusterFunc.
+func extendsCluster(r rune) bool {
+	return unicode.Is(unicode.M, r) ||
+		r == '\u200d' || // zero-width joiner
+		(r >= '\ufe00' && r <= '\ufe0f') || // variation selectors
+		(r >= '\U0001f3fb' && r <= '\U0001f3ff') || // emoji modifiers
+		(r >= '\U000e0020' && r <= '\U000e007f') // tags
+}
+
+// extendsClusterFunc is the name of the closure emitted by Generate (when
+// NoTrailingMark is specified) to test whether the remainder of the input
+// begins with a rune that extends the preceding grapheme cluster.
+const extendsClusterFunc = "fastmatchExtendsCluster"
+
+// extendsClusterCode is the implementation of extendsClusterFunc, which is
+// emitted at the beginning of the generated code.
+const extendsClusterCode = `	fastmatchExtendsCluster := func(s string) bool {
+		for _, r := range s {
+			return unicode.Is(unicode.M, r) ||
+				r == '\u200d' ||
+				(r >= '\ufe00' && r <= '\ufe0f') ||
+				(r >= '\U0001f3fb' && r <= '\U0001f3ff') ||
+				(r >= '\U000e0020' && r <= '\U000e007f')
+		}
+		return false
+	}
+`
+
+// continuationExtendsCluster returns true if the portion of a longer key
+// following a shorter key begins with a rune that extends the shorter key's
+// final grapheme cluster.
+func continuationExtendsCluster(shorter, longer string) bool {
+	for _, r := range longer[len(shorter):] {
+		return extendsCluster(r)
+	}
+	return false
+}
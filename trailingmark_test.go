@@ -0,0 +1,120 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestExtendsCluster tests detection of runes which extend a grapheme
+// cluster.
+func TestExtendsCluster(t *testing.T) {
+	for _, r := range []rune{'\u0301', '\u200d', '\ufe0f', '\U0001f3fd'} {
+		if !extendsCluster(r) {
+			t.Errorf("%q should extend a grapheme cluster", r)
+		}
+	}
+	for _, r := range []rune{'a', ' ', '\u00e9', '\U0001f44d'} {
+		if extendsCluster(r) {
+			t.Errorf("%q should not extend a grapheme cluster", r)
+		}
+	}
+}
+
+// TestNoTrailingMarkAmbiguity tests that keys which differ by a combining
+// character are not ambiguous when the NoTrailingMark flag is specified.
+func TestNoTrailingMarkAmbiguity(t *testing.T) {
+	cases := map[string]string{
+		"e":                    "1",
+		"e\u0301":              "2",
+		"\U0001f44d":           "3",
+		"\U0001f44d\U0001f3fd": "4",
+	}
+	if err := Generate(ioutil.Discard, cases, "0", HasPrefix); err == nil {
+		t.Error("expected ambiguity without NoTrailingMark flag")
+	}
+	if err := Generate(ioutil.Discard, cases, "0", HasPrefix, NoTrailingMark); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestNoTrailingMarkFlags tests that NoTrailingMark requires HasPrefix.
+func TestNoTrailingMarkFlags(t *testing.T) {
+	cases := map[string]string{"e": "1"}
+	for expect, flags := range map[string][]*Flag{
+		`flag "NoTrailingMark" requires "HasPrefix"`:                     {NoTrailingMark},
+		`flags are mutually exclusive: "HasSuffix" and "NoTrailingMark"`: {NoTrailingMark, HasSuffix},
+	} {
+		err := Generate(ioutil.Discard, cases, "0", flags...)
+		if err == nil || err.Error() != expect {
+			t.Errorf("expected %q, got %v", expect, err)
+		}
+	}
+}
+
+// TestNoTrailingMark tests a prefix matcher which doesn't match if the next
+// rune would extend the last character of the key.
+func TestNoTrailingMark(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	imports := append([]string{"fmt", "os"}, Imports(HasPrefix, NoTrailingMark)...)
+	cleanup, err := generateProgram(imports, func(w io.Writer) error {
+		fmt.Fprintln(w, "func match(input string) int {")
+		err := Generate(w, map[string]string{
+			"e":                    "1",
+			"e\u0301t\u00e9":       "2",
+			"\U0001f44d":           "3",
+			"\U0001f44d\U0001f3fd": "4",
+		}, "0", HasPrefix, NoTrailingMark)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tfmt.Println(match(os.Args[1]))")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "eat", "1")
+	expectMatch(t, "e\u0301t\u00e9", "2")
+	expectMatch(t, "e\u0301", "0")
+	expectMatch(t, "\U0001f44d!", "3")
+	expectMatch(t, "\U0001f44d\U0001f3fd!", "4")
+	expectMatch(t, "\U0001f44d\U0001f3ff", "0")
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io"
+	"sort"
+)
+
+// TransformKeys returns a flag, which can be passed to Generate, to apply fn
+// to each key before generating code to match it.  Unlike NormalizeInput,
+// fn is not applied to the input at runtime, so it is useful for keys which
+// are stored in a different form than the input they should match.  For
+// example, the following matches input such as "--verbose" against a list of
+// option names:
+//
+//	fastmatch.Generate(w, options, "nil",
+//		fastmatch.TransformKeys(func(s string) string { return "--" + s }))
+//
+// If more than one TransformKeys flag is specified, they are applied in
+// order.  Keys which are the same once transformed are ambiguous if their
+// values differ.  Errors and coverage refer to the keys as they were
+// supplied, and the keys passed to ExactOnly are transformed like the rest.
+// GenerateTest uses the transformed keys as the inputs to test.
+//
+// Only Generate (and functions which call it to match the keys) applies
+// fn; functions which otherwise examine the keys, such as
+// GenerateExactOrPrefix, see them untransformed.
+func TransformKeys(fn func(string) string) *Flag {
+	return &Flag{transform: fn}
+}
+
+// transformKey applies each of the TransformKeys functions to key.
+func transformKey(key string, transforms []func(string) string) string {
+	for _, fn := range transforms {
+		key = fn(key)
+	}
+	return key
+}
+
+// transformKeys returns a new cases map, with each key replaced by its
+// transformed version.  The original keys are recorded in fs.keyOrigins.
+func (fs *flagSet) transformKeys(cases map[string]string) (map[string]string, error) {
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	transformed := make(map[string]string, len(cases))
+	fs.keyOrigins = make(map[string]string, len(cases))
+	e := new(ErrAmbiguous)
+	for _, key := range keys {
+		newKey := transformKey(key, fs.transforms)
+		if other, found := fs.keyOrigins[newKey]; found {
+			if cases[other] != cases[key] {
+				e.add(nil, other, key)
+			}
+			continue
+		}
+		transformed[newKey] = cases[key]
+		fs.keyOrigins[newKey] = key
+	}
+	if len(e.keys) > 0 {
+		return nil, e
+	}
+
+	exactOnly := make([]string, len(fs.exactOnly))
+	for n, key := range fs.exactOnly {
+		exactOnly[n] = transformKey(key, fs.transforms)
+	}
+	fs.exactOnly = exactOnly
+	return transformed, nil
+}
+
+// generateTransformed implements generateCases when TransformKeys is
+// specified.  The flags passed on omit TransformKeys, so that any calls to
+// Generate made with the transformed keys don't transform them again.
+func generateTransformed(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	cases, err := fs.transformKeys(cases)
+	if err != nil {
+		return err
+	}
+	otherFlags := make([]*Flag, 0, len(flags))
+	for _, flag := range flags {
+		if flag.transform == nil {
+			otherFlags = append(otherFlags, flag)
+		}
+	}
+	fs.transforms = nil
+
+	err = generateCases(w, cases, none, fs, otherFlags)
+	if e, ok := err.(*ErrAmbiguous); ok {
+		e.rename(fs.keyOrigins)
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestTransformKeys tests matching keys which have been transformed at
+// generation time.
+func TestTransformKeys(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"verbose": "1",
+		"quiet":   "2",
+		"help":    "3",
+	}, "0", TransformKeys(func(s string) string { return "--" + s }), HasPrefix, ExactOnly("help"))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "--verbose", "1")
+	expectMatch(t, "--quiet=yes", "2")
+	expectMatch(t, "--help", "3")
+	expectMatch(t, "--helpful", "0")
+	expectMatch(t, "verbose", "0")
+}
+
+// TestTransformKeysErrors tests that errors and coverage refer to the
+// original keys.
+func TestTransformKeysErrors(t *testing.T) {
+	lower := TransformKeys(strings.ToLower)
+
+	err := Generate(ioutil.Discard, map[string]string{"Foo": "1", "FOO": "2", "bar": "3"}, "0", lower)
+	if e, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	} else if msg := e.Error(); !strings.Contains(msg, `"Foo"`) || !strings.Contains(msg, `"FOO"`) {
+		t.Errorf("expected original keys in error, got %s", msg)
+	}
+
+	err = Generate(ioutil.Discard, map[string]string{"Foo": "1", "foobar": "2"}, "0", lower, HasPrefix)
+	if e, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	} else if msg := e.Error(); !strings.Contains(msg, `"Foo"`) {
+		t.Errorf("expected original keys in error, got %s", msg)
+	}
+
+	m := new(CoverageManifest)
+	if err := Generate(ioutil.Discard, map[string]string{"Foo": "1", "Bar": "2"}, "0", lower, Coverage(m)); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range m.Keys {
+		if r.Key != "Foo" && r.Key != "Bar" {
+			t.Errorf("expected original key in coverage, got %q", r.Key)
+		}
+	}
+
+	if Fingerprint(map[string]string{"Foo": "1"}, "0", lower) != Fingerprint(map[string]string{"foo": "1"}, "0", lower) {
+		t.Error("expected keys to be fingerprinted once transformed")
+	}
+}
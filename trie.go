@@ -0,0 +1,398 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// trieNode is a node in a prefix tree of keys.  Each edge is labeled with a
+// single byte.
+type trieNode struct {
+	children map[byte]*trieNode
+	edges    []byte // sorted keys of children
+
+	// key is the key ending at this node, if any.
+	key      string
+	terminal bool
+}
+
+// buildTrie constructs a prefix tree from keys, which maps each key in
+// canonical form (see flagSet.canonicalize) to the original key.
+func buildTrie(keys map[string]string) *trieNode {
+	root := &trieNode{children: make(map[byte]*trieNode)}
+	for canonical, key := range keys {
+		node := root
+		for n := 0; n < len(canonical); n++ {
+			child := node.children[canonical[n]]
+			if child == nil {
+				child = &trieNode{children: make(map[byte]*trieNode)}
+				node.children[canonical[n]] = child
+				node.edges = append(node.edges, canonical[n])
+			}
+			node = child
+		}
+		node.key = key
+		node.terminal = true
+	}
+	root.sortEdges()
+	return root
+}
+
+// sortEdges sorts the edges of a node and all of its descendants.
+func (node *trieNode) sortEdges() {
+	sort.Slice(node.edges, func(a, b int) bool { return node.edges[a] < node.edges[b] })
+	for _, child := range node.children {
+		child.sortEdges()
+	}
+}
+
+// walk calls f for every node in the trie, in depth-first order, with the
+// depth of the node (the root being zero).
+func (node *trieNode) walk(depth int, f func(*trieNode, int)) {
+	f(node, depth)
+	for _, b := range node.edges {
+		node.children[b].walk(depth+1, f)
+	}
+}
+
+// canonicalKeys applies flags to the keys in cases, returning a map from
+// canonical form to original key.
+func (fs *flagSet) canonicalKeys(cases map[string]string) map[string]string {
+	keys := make(map[string]string, len(cases))
+	for key := range cases {
+		canonical := fs.canonicalize(key)
+		if other, found := keys[canonical]; !found || key < other {
+			keys[canonical] = key
+		}
+	}
+	return keys
+}
+
+// WriteTrie outputs a human-readable view of the prefix tree formed by the
+// keys in cases, after applying flags.  Chains of nodes with a single child
+// are collapsed onto one line, and keys which end at a node are shown along
+// with their values.  For example, the keys "bar", "baz", "foo", and
+// "foobar" produce:
+//
+//	"ba"
+//	  "r": "bar" = 2
+//	  "z": "baz" = 3
+//	"foo": "foo" = 1
+//	  "bar": "foobar" = 4
+//
+// Insensitive and Equivalent are taken into account by mapping each rune to
+// the lowest rune it is equivalent to.
+func WriteTrie(w io.Writer, cases map[string]string, flags ...*Flag) error {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return err
+	}
+
+	root := buildTrie(fs.canonicalKeys(cases))
+	var write func(node *trieNode, depth int) error
+	write = func(node *trieNode, depth int) error {
+		for _, b := range node.edges {
+			label := []byte{b}
+			child := node.children[b]
+			for len(child.edges) == 1 && !child.terminal {
+				label = append(label, child.edges[0])
+				child = child.children[child.edges[0]]
+			}
+
+			line := strings.Repeat("  ", depth) + strconv.Quote(string(label))
+			if child.terminal {
+				line += fmt.Sprintf(": %s = %s", strconv.Quote(child.key), cases[child.key])
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+			if err := write(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if root.terminal {
+		if _, err := fmt.Fprintf(w, `"": %s = %s`+"\n", strconv.Quote(root.key), cases[root.key]); err != nil {
+			return err
+		}
+	}
+	return write(root, 0)
+}
+
+// StrategyReport compares the code generated for a set of keys using the
+// additive state machine (what Generate currently outputs) to a prefix tree
+// of nested switch statements.  It is returned by CompareStrategies.
+type StrategyReport struct {
+	Keys, LongestKey int
+
+	// StateMachineBytes is the size of the code output by Generate, and
+	// TrieBytes is an estimate of the size of the equivalent nested
+	// switch statements.
+	StateMachineBytes, TrieBytes int
+
+	// StateMachineCases and TrieCases count case clauses, which is a
+	// rough measure of the size of the compiled code.
+	StateMachineCases, TrieCases int
+
+	// StateMachineDepth and TrieDepth are the maximum number of switch
+	// statements evaluated while matching any input.
+	StateMachineDepth, TrieDepth int
+
+	// TrieNodes is the number of nodes in the prefix tree, excluding the
+	// root.
+	TrieNodes int
+}
+
+// CompareStrategies generates code for cases using Generate, and estimates
+// the size of the same matcher implemented as a prefix tree, to help decide
+// which is more appropriate for a given set of keys.  Any error returned by
+// Generate (such as ambiguity) is passed back to the caller.
+func CompareStrategies(cases map[string]string, none string, flags ...*Flag) (*StrategyReport, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := Generate(&b, cases, none, internalFlags(flags)...); err != nil {
+		return nil, err
+	}
+
+	r := &StrategyReport{
+		Keys:              len(cases),
+		StateMachineBytes: b.Len(),
+		StateMachineCases: strings.Count(b.String(), "\tcase ") + strings.Count(b.String(), "\tdefault:"),
+	}
+	for key := range cases {
+		if len(key) > r.LongestKey {
+			r.LongestKey = len(key)
+		}
+	}
+	// One switch on the length of the input (or the lookup table), one
+	// per byte, and one comparing the final state.
+	r.StateMachineDepth = r.LongestKey + 2
+
+	// The trie estimate is based on code of the form:
+	//
+	//	switch input[0] {
+	//	case 'a', 'A':
+	//		if len(input) == 1 {
+	//			return value
+	//		}
+	//		switch input[1] {
+	//		...
+	//		}
+	//	}
+	//
+	// with a length check before each switch.
+	equivCase := func(b byte) string {
+		return "case " + quoteRunes(fs.equiv.lookup(rune(b))) + ":\n"
+	}
+	root := buildTrie(fs.canonicalKeys(cases))
+	root.walk(0, func(node *trieNode, depth int) {
+		indent := depth + 1
+		if node != root {
+			r.TrieNodes++
+			r.TrieCases++
+		}
+		if node.terminal {
+			ret := fmt.Sprintf("if len(input) == %d {\n", depth)
+			r.TrieBytes += indent + len(ret)
+			r.TrieBytes += indent + 1 + len("return \n") + len(cases[node.key])
+			r.TrieBytes += indent + len("}\n")
+		}
+		if len(node.edges) > 0 {
+			sw := fmt.Sprintf("if len(input) > %d {\nswitch input[%d] {\n", depth, depth)
+			r.TrieBytes += 2*indent + len(sw)
+			for _, b := range node.edges {
+				r.TrieBytes += indent + len(equivCase(b))
+			}
+			r.TrieBytes += 2 * (indent + len("}\n"))
+		}
+		if len(node.edges) > 0 && depth+1 > r.TrieDepth {
+			r.TrieDepth = depth + 1
+		}
+	})
+	r.TrieBytes += len("\treturn \n}\n") + len(none)
+
+	return r, nil
+}
+
+// String formats a StrategyReport as a table.
+func (r *StrategyReport) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d keys, longest %d bytes, %d trie nodes\n", r.Keys, r.LongestKey, r.TrieNodes)
+	fmt.Fprintf(&b, "%-14s %13s %13s\n", "", "state machine", "trie")
+	fmt.Fprintf(&b, "%-14s %13d %13d\n", "source bytes", r.StateMachineBytes, r.TrieBytes)
+	fmt.Fprintf(&b, "%-14s %13d %13d\n", "case clauses", r.StateMachineCases, r.TrieCases)
+	fmt.Fprintf(&b, "%-14s %13d %13d\n", "branch depth", r.StateMachineDepth, r.TrieDepth)
+	return b.String()
+}
+
+// canonicalBytes returns the form of a key used to build the prefix tree
+// for TrieStrategy: normalized, and with each ASCII byte replaced by the
+// lowest byte it is equivalent to.  Unlike canonicalize, this operates on
+// bytes, since that's what the generated code examines.
+func (fs *flagSet) canonicalBytes(key string) string {
+	b := []byte(fs.normalizeKey(key))
+	for n := range b {
+		b[n] = fs.equiv.byteEquivalents(b[n])[0]
+	}
+	if fs.backwards {
+		return reverseBytes(string(b))
+	}
+	return string(b)
+}
+
+// generateTrie implements Generate for TrieStrategy.
+func generateTrie(w io.Writer, cases map[string]string, none string, fs *flagSet, flags []*Flag) error {
+	var unsupported []string
+	for _, flag := range flags {
+		if len(flag.stop) > 0 || len(flag.ignore) > 0 || len(flag.ignoreExcept) > 0 ||
+			flag == NoTrailingMark || flag == ConstantTime {
+			unsupported = append(unsupported, flagName(flag))
+		}
+	}
+	if len(unsupported) > 0 {
+		return &ErrBadFlags{unsupported: unsupported, unsupportedBy: "TrieStrategy"}
+	}
+
+	// The state machine has already solved the problem of detecting
+	// ambiguity, so use it to validate the keys.
+	validateFlags := append(internalFlags(flags), Strategy(StateMachineStrategy))
+	if err := Generate(ioutil.Discard, cases, none, validateFlags...); err != nil {
+		return err
+	}
+
+	keys := make(map[string]string, len(cases))
+	for key := range cases {
+		canonical := fs.canonicalBytes(key)
+		if other, found := keys[canonical]; !found || key < other {
+			keys[canonical] = key
+		}
+	}
+	root := buildTrie(keys)
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	for _, flag := range fs.normalize {
+		if _, err := fmt.Fprintf(w, "\tinput = %s(input)", fs.normalizeCall(flag)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	// writeReturn outputs the return statement for the key ending at
+	// node, noting it for the Coverage flag.
+	writeReturn := func(indent string, node *trieNode) {
+		if lines != nil {
+			// Other keys may have been collapsed into this one.
+			canonical := fs.canonicalBytes(node.key)
+			origKeys := make([]string, 0, 1)
+			for key := range cases {
+				if fs.canonicalBytes(key) == canonical {
+					origKeys = append(origKeys, key)
+				}
+			}
+			sort.Strings(origKeys)
+			for _, key := range origKeys {
+				fs.cover(key, lines.lines)
+			}
+		}
+		fmt.Fprintln(w, indent+"return", cases[node.key])
+	}
+
+	// Large tries can take a while to write out, so cancellation and the
+	// output limit are checked at each node.
+	var err error
+	var write func(node *trieNode, depth int, indent string)
+	write = func(node *trieNode, depth int, indent string) {
+		if err != nil {
+			return
+		}
+		if err = fs.stopped(); err != nil {
+			return
+		}
+		if node.terminal {
+			if fs.partialMatch {
+				// Shortest match wins; any longer keys were
+				// either pruned or reported as ambiguous.
+				writeReturn(indent, node)
+				return
+			}
+			fmt.Fprintf(w, "%sif len(input) == %d {", indent, depth)
+			fmt.Fprintln(w)
+			writeReturn(indent+"\t", node)
+			fmt.Fprintln(w, indent+"}")
+		}
+		if len(node.edges) == 0 {
+			return
+		}
+
+		at := fmt.Sprintf("input[%d]", depth)
+		if fs.backwards {
+			at = fmt.Sprintf("input[len(input)-%d]", depth+1)
+		}
+		fmt.Fprintf(w, "%sif len(input) > %d {", indent, depth)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s\tswitch %s {", indent, at)
+		fmt.Fprintln(w)
+		for _, b := range node.edges {
+			var labels []string
+			for _, b2 := range fs.equiv.byteEquivalents(b) {
+				labels = append(labels, quoteByte(b2))
+			}
+			fmt.Fprintf(w, "%s\tcase %s:", indent, strings.Join(labels, ", "))
+			fmt.Fprintln(w)
+			write(node.children[b], depth+1, indent+"\t\t")
+		}
+		fmt.Fprintln(w, indent+"\t}")
+		fmt.Fprintln(w, indent+"}")
+	}
+	write(root, 0, "\t")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\treturn", none)
+
+	_, err = fmt.Fprintln(w, "}") // end of func
+	return err
+}
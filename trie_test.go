@@ -0,0 +1,191 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var trieCases = map[string]string{
+	"bar":    "2",
+	"baz":    "3",
+	"foo":    "1",
+	"foobar": "4",
+}
+
+// TestWriteTrie tests the human-readable prefix tree output.
+func TestWriteTrie(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteTrie(&b, trieCases); err != nil {
+		t.Fatal(err)
+	}
+	expect := `"ba"
+  "r": "bar" = 2
+  "z": "baz" = 3
+"foo": "foo" = 1
+  "bar": "foobar" = 4
+`
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+// TestWriteTrieFlags tests that flags are applied before building the tree.
+func TestWriteTrieFlags(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteTrie(&b, map[string]string{
+		"foo":   "1",
+		"Fo-ox": "2",
+		"":      "3",
+	}, Insensitive, Ignore('-'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `"": "" = 3
+"FOO": "foo" = 1
+  "X": "Fo-ox" = 2
+`
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
+
+// TestCompareStrategies tests the comparison between the state machine and
+// trie.
+func TestCompareStrategies(t *testing.T) {
+	r, err := CompareStrategies(trieCases, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Keys != 4 || r.LongestKey != 6 {
+		t.Errorf("expected 4 keys, longest 6, got %d, %d", r.Keys, r.LongestKey)
+	}
+	if r.TrieNodes != 10 || r.TrieCases != 10 {
+		t.Errorf("expected 10 trie nodes and cases, got %d and %d", r.TrieNodes, r.TrieCases)
+	}
+	if r.TrieDepth != 6 || r.StateMachineDepth != 8 {
+		t.Errorf("expected depth 6 and 8, got %d and %d", r.TrieDepth, r.StateMachineDepth)
+	}
+	if r.StateMachineBytes == 0 || r.TrieBytes == 0 || r.StateMachineCases == 0 {
+		t.Errorf("expected non-zero sizes, got %+v", r)
+	}
+	if !strings.HasPrefix(r.String(), "4 keys, longest 6 bytes, 10 trie nodes\n") {
+		t.Errorf("unexpected report:\n%s", r)
+	}
+
+	if _, err := CompareStrategies(map[string]string{"a": "1", "A": "2"}, "0", Insensitive); err == nil {
+		t.Error("expected error for ambiguous keys")
+	}
+}
+
+// TestTrieStrategy tests matching using TrieStrategy.
+func TestTrieStrategy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", trieCases, "0", Insensitive, Strategy(TrieStrategy))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "BAR", "2")
+	expectMatch(t, "baz", "3")
+	expectMatch(t, "FooBar", "4")
+	expectMatch(t, "fooba", "0")
+	expectMatch(t, "foobarx", "0")
+	expectMatch(t, "fo", "0")
+	expectMatch(t, "", "0")
+}
+
+// TestTrieStrategyPrefix tests TrieStrategy with HasPrefix.
+func TestTrieStrategyPrefix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo": "1",
+		"bar": "2",
+		"baz": "3",
+	}, "0", HasPrefix, Strategy(TrieStrategy))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "foobar", "1")
+	expectMatch(t, "bazooka", "3")
+	expectMatch(t, "ba", "0")
+}
+
+// TestTrieStrategySuffix tests TrieStrategy with HasSuffix.
+func TestTrieStrategySuffix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	cleanup, err := generateRunnable(t, match, "int", map[string]string{
+		"foo":    "1",
+		"bar":    "2",
+		"\u00e9": "3",
+	}, "0", HasSuffix, Strategy(TrieStrategy))
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectMatch(t, "foo", "1")
+	expectMatch(t, "barfoo", "1")
+	expectMatch(t, "foobar", "2")
+	expectMatch(t, "caf\u00e9", "3")
+	expectMatch(t, "oo", "0")
+}
+
+// TestTrieStrategyBadInput tests that TrieStrategy rejects unsupported flags
+// and ambiguous keys.
+func TestTrieStrategyBadInput(t *testing.T) {
+	err := Generate(new(bytes.Buffer), trieCases, "0", Strategy(TrieStrategy), Ignore('-'))
+	if err == nil {
+		t.Error("expected error for unsupported flag")
+	} else if expect := `TrieStrategy does not support flags: "Ignore"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+
+	err = Generate(new(bytes.Buffer), map[string]string{"a": "1", "A": "2"}, "0", Strategy(TrieStrategy), Insensitive)
+	if _, ok := err.(*ErrAmbiguous); !ok {
+		t.Errorf("expected *ErrAmbiguous, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+)
+
+// ValueType returns a flag, which can be passed to Generate, to specify that
+// each value expression (and none) should be checked to be a valid
+// expression of type typ before any code is output.  Otherwise, a typo in a
+// value is only discovered when the generated code is compiled, with an error
+// pointing into the generated file rather than at the offending key.
+//
+// typ is a Go type expression, such as "int" or "Token".  decls is zero or
+// more fragments of Go source, which are type-checked along with the
+// values; these should declare the type and any constants referenced by the
+// values, and may begin with import declarations.  If Params is also
+// specified, the values may refer to input and to the parameters.  For
+// example:
+//
+//	fastmatch.ValueType("Token", "type Token int", "const (If Token = iota; For)")
+//
+// This is only useful when each value is a single expression, so it is not
+// supported by GenerateGroups.
+func ValueType(typ string, decls ...string) *Flag {
+	return &Flag{valueType: typ, valueDecls: decls}
+}
+
+// ErrValueType is returned by Generate when ValueType was specified, and one
+// or more of the values are not valid expressions of that type.
+type ErrValueType struct {
+	// errs maps each key with an invalid value to the error.  The none
+	// expression is reported under the key "".
+	errs map[string]string
+
+	values map[string]string
+}
+
+func (e *ErrValueType) Error() string {
+	keys := make([]string, 0, len(e.errs))
+	for key := range e.errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for n, key := range keys {
+		if n > 0 {
+			b.WriteString("; ")
+		}
+		if key == "" {
+			b.WriteString("none value")
+		} else {
+			b.WriteString("value for ")
+			b.WriteString(strconv.Quote(key))
+		}
+		fmt.Fprintf(&b, " (%s): %s", e.values[key], e.errs[key])
+	}
+	return b.String()
+}
+
+// checkValueType type-checks each value in cases (and none) against the
+// ValueType flag.
+func (fs *flagSet) checkValueType(cases map[string]string, none string) error {
+	keys := make([]string, 0, len(cases))
+	for key := range cases {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Each value is assigned to a blank variable on its own line(s), so
+	// that errors can be mapped back to the key.
+	var b bytes.Buffer
+	b.WriteString("package fastmatch\n")
+	for _, decl := range fs.valueDecls {
+		b.WriteString(decl)
+		b.WriteByte('\n')
+	}
+	if fs.params != "" || fs.chain != "" {
+		// The values need to be inside a function to refer to the
+		// parameters (or, for Chain, the input).
+		fmt.Fprintf(&b, "func _(input string%s) {\n", fs.extraParams())
+	}
+	var lineKeys []string // indexed by line number
+	for line := bytes.Count(b.Bytes(), []byte{'\n'}) + 1; line > 0; line-- {
+		lineKeys = append(lineKeys, "")
+	}
+	declLines := len(lineKeys)
+	values := make(map[string]string, len(keys)+1)
+	for _, key := range append([]string{""}, keys...) {
+		value := none
+		if key != "" {
+			value = cases[key]
+		}
+		fmt.Fprintf(&b, "var _ %s = %s\n", fs.valueType, value)
+		for len(lineKeys) <= bytes.Count(b.Bytes(), []byte{'\n'}) {
+			lineKeys = append(lineKeys, key)
+		}
+		values[key] = value
+	}
+	if fs.params != "" || fs.chain != "" {
+		// A syntax error in the last value may be reported at the
+		// closing brace.
+		b.WriteString("}\n")
+		lineKeys = append(lineKeys, lineKeys[len(lineKeys)-1])
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", b.Bytes(), parser.SkipObjectResolution)
+	e := &ErrValueType{errs: make(map[string]string), values: values}
+	record := func(pos token.Position, msg string) error {
+		if pos.Line < declLines || pos.Line >= len(lineKeys) {
+			return fmt.Errorf("invalid ValueType declarations: %s", msg)
+		}
+		if key := lineKeys[pos.Line]; e.errs[key] == "" {
+			e.errs[key] = msg
+		}
+		return nil
+	}
+	if err != nil {
+		list, ok := err.(scanner.ErrorList)
+		if !ok {
+			return err
+		}
+		for _, err := range list {
+			if err := record(err.Pos, err.Msg); err != nil {
+				return err
+			}
+		}
+		return e
+	}
+
+	var declErr error
+	conf := types.Config{
+		Importer: importer.Default(),
+		Error: func(err error) {
+			if err, ok := err.(types.Error); ok && declErr == nil {
+				declErr = record(fset.Position(err.Pos), err.Msg)
+			}
+		},
+	}
+	conf.Check("fastmatch", fset, []*ast.File{f}, nil)
+	if declErr != nil {
+		return declErr
+	}
+	if len(e.errs) > 0 {
+		return e
+	}
+	return nil
+}
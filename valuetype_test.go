@@ -0,0 +1,126 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+var valueTypeTests = []struct {
+	cases  map[string]string
+	none   string
+	flag   *Flag
+	expect string
+}{
+	{
+		cases:  map[string]string{"a": "1", "b": "2"},
+		none:   "-1",
+		flag:   ValueType("int"),
+		expect: "",
+	}, {
+		cases:  map[string]string{"a": "1", "b": `"2"`},
+		none:   "-1",
+		flag:   ValueType("int"),
+		expect: `value for "b" ("2"): cannot use "2" (untyped string constant) as int value in variable declaration`,
+	}, {
+		cases:  map[string]string{"if": "If", "for": "Fro"},
+		none:   "Illegal",
+		flag:   ValueType("Token", "type Token int", "const (Illegal Token = iota; If; For)"),
+		expect: `value for "for" (Fro): undefined: Fro`,
+	}, {
+		cases:  map[string]string{"if": "If"},
+		none:   "0",
+		flag:   ValueType("Token", "type Token int", "const If Token = 1"),
+		expect: "",
+	}, {
+		cases:  map[string]string{"if": "If", "for": "For"},
+		none:   "nil",
+		flag:   ValueType("Token", "type Token int", "const (If Token = iota; For)"),
+		expect: `none value (nil): cannot use nil as Token value in variable declaration`,
+	}, {
+		cases:  map[string]string{"if": "If +", "for": "For"},
+		none:   "0",
+		flag:   ValueType("Token", "type Token int", "const (If Token = iota; For)"),
+		expect: `value for "if" (If +): expected operand, found 'EOF'`,
+	}, {
+		cases:  map[string]string{"a": "time.Second"},
+		none:   "0",
+		flag:   ValueType("time.Duration", `import "time"`),
+		expect: "",
+	},
+}
+
+// TestValueType tests checking value expressions against a type.
+func TestValueType(t *testing.T) {
+	for _, testCase := range valueTypeTests {
+		err := Generate(ioutil.Discard, testCase.cases, testCase.none, testCase.flag)
+		if testCase.expect == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error: %s", testCase.cases, err)
+			}
+			continue
+		}
+		if _, ok := err.(*ErrValueType); !ok {
+			t.Errorf("%q: expected *ErrValueType, got %v", testCase.cases, err)
+		} else if err.Error() != testCase.expect {
+			t.Errorf("%q: expected %q, got %q", testCase.cases, testCase.expect, err.Error())
+		}
+	}
+}
+
+// TestValueTypeDecls tests that errors in the declarations passed to
+// ValueType are not blamed on a key.
+func TestValueTypeDecls(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{"a": "1"}, "0", ValueType("Token", "type Token nonexistent"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if _, ok := err.(*ErrValueType); ok {
+		t.Errorf("expected error in declarations, got %q", err)
+	}
+}
+
+// TestValueTypeInternal tests that values substituted by other generators
+// aren't checked against ValueType.
+func TestValueTypeInternal(t *testing.T) {
+	flag := ValueType("string")
+	if err := Generate(ioutil.Discard, map[string]string{"if": `"If"`, IntegerToken: `"Int"`}, `""`, flag); err != nil {
+		t.Error(err)
+	}
+	if err := Generate(ioutil.Discard, map[string]string{"if": `"If"`}, `""`, flag, ConstantTime); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := Merge(ConflictError, []CaseSet{{"a", map[string]string{"if": "1"}}}, flag); err != nil {
+		t.Error(err)
+	}
+	if err := GenerateComposite(ioutil.Discard, map[CompositeKey]string{{"a", "b"}: "1"}, `""`, []*Flag{flag}, nil); err == nil {
+		t.Error("expected error from GenerateComposite")
+	}
+}
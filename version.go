@@ -0,0 +1,100 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TargetGoVersion is a flag, which can be passed to Generate and the other
+// code generators, to specify the oldest version of Go which needs to be
+// able to compile the generated code.  The version should be in the same
+// format as the go directive in a go.mod file (e.g. "1.22"), optionally
+// prefixed with "go" as in the output of runtime.Version.
+//
+// By default, the generated code only uses language features and library
+// functions present in Go 1.0.  Targeting a newer version allows the code
+// generators to emit more concise code, such as using the min builtin
+// (added in Go 1.21) instead of an equivalent comparison, or ranging over an
+// integer (added in Go 1.22) instead of a three-clause for loop.
+//
+// An ErrBadFlags is returned by the code generators if the version cannot
+// be parsed.
+func TargetGoVersion(version string) *Flag {
+	return &Flag{goVersion: version}
+}
+
+// parseGoVersion converts a Go version string to its minor version number,
+// e.g. "go1.22.1" to 22.  It returns false if the version is not a Go 1.x
+// release.
+func parseGoVersion(version string) (int, bool) {
+	v := strings.TrimPrefix(version, "go")
+	if !strings.HasPrefix(v, "1.") {
+		return 0, false
+	}
+	v = v[2:]
+
+	// Anything after the minor version (patch releases, "rc1", etc.)
+	// doesn't affect which language features are available.
+	end := 0
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	switch rest := v[end:]; {
+	case rest == "", rest[0] == '.', strings.HasPrefix(rest, "rc"), strings.HasPrefix(rest, "beta"):
+	default:
+		return 0, false
+	}
+
+	minor, err := strconv.Atoi(v[:end])
+	if err != nil {
+		return 0, false
+	}
+	return minor, true
+}
+
+// atLeastGo returns true if the generated code may use features from Go
+// 1.minor.
+func (fs *flagSet) atLeastGo(minor int) bool {
+	return fs.goMinor >= minor
+}
+
+// forEachIndex returns the first line of a loop which sets i to each index
+// of s, using range over an integer if the generated code may use Go 1.22.
+// The body of the loop must not assign to i.
+func (fs *flagSet) forEachIndex(s string) string {
+	if fs.atLeastGo(22) {
+		return "for i := range len(" + s + ") {"
+	}
+	return "for i := 0; i < len(" + s + "); i++ {"
+}
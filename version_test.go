@@ -0,0 +1,90 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var parseGoVersionTests = []struct {
+	version string
+	minor   int
+	ok      bool
+}{
+	{"1.21", 21, true},
+	{"go1.22", 22, true},
+	{"go1.22.3", 22, true},
+	{"go1.23rc1", 23, true},
+	{"1.0", 0, true},
+	{"2.0", 0, false},
+	{"1.", 0, false},
+	{"1.x", 0, false},
+	{"1.21-foo", 0, false},
+	{"", 0, false},
+}
+
+// TestParseGoVersion tests parsing of the version passed to
+// TargetGoVersion.
+func TestParseGoVersion(t *testing.T) {
+	for _, testCase := range parseGoVersionTests {
+		minor, ok := parseGoVersion(testCase.version)
+		if minor != testCase.minor || ok != testCase.ok {
+			t.Errorf("expected %d, %v parsing %q, got %d, %v", testCase.minor, testCase.ok, testCase.version, minor, ok)
+		}
+	}
+}
+
+// TestTargetGoVersionBad tests that an invalid version is reported as an
+// error.
+func TestTargetGoVersionBad(t *testing.T) {
+	err := Generate(ioutil.Discard, map[string]string{"foo": "1"}, "0", TargetGoVersion("latest"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if expect := `invalid TargetGoVersion: "latest"`; err.Error() != expect {
+		t.Errorf("expected %q, got %q", expect, err.Error())
+	}
+}
+
+// TestTargetGoVersionRangeInt tests that loops over an index range over an
+// integer only when the target version supports it.
+func TestTargetGoVersionRangeInt(t *testing.T) {
+	for version, expect := range map[string]bool{"1.21": false, "1.22": true} {
+		var buf bytes.Buffer
+		if err := Generate(&buf, map[string]string{"foo": "1"}, "0", ASCIIOnly, TargetGoVersion(version)); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), "for i := range len(input) {"); got != expect {
+			t.Errorf("expected range over an integer to be %v for %s, got %v", expect, version, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// GenerateVocabulary outputs Go code to intern strings from a fixed
+// vocabulary, such as the label names in a metrics pipeline, as small
+// integer IDs.  The following are output:
+//
+//	func internFn(input string) (typ, bool)
+//	func lookupFn(id typ) string
+//
+// internFn returns the ID of the string in vocab which the input matches,
+// and true, or zero and false if there is no match.  lookupFn returns the
+// string from vocab for an ID, or "" for zero or an unknown ID.  Neither
+// allocates memory (unless the flags call functions which do, such as
+// NormalizeInput), and lookupFn returns a string constant, so interned
+// strings need not keep the input alive.  typ must be an integer type.
+//
+// IDs are assigned sequentially, starting at one, in the order of vocab.
+// To keep the IDs stable when the vocabulary changes, new strings should be
+// added at the end.  The mapping of strings to IDs is also returned, for use
+// by the caller at generation time.
+//
+// The flags are passed to Generate, so with Insensitive, for example,
+// internFn returns the same ID for "Foo" as for "foo", and lookupFn returns
+// the string as it appears in vocab.  Strings which are ambiguous with each
+// other when the flags are applied are an error.
+func GenerateVocabulary(w io.Writer, internFn, lookupFn, typ string, vocab []string, flags ...*Flag) (map[string]int, error) {
+	fs, err := parseFlags(flags...)
+	if err != nil {
+		return nil, err
+	}
+	if fs.params != "" {
+		return nil, &ErrBadFlags{unsupported: []string{"Params"}, unsupportedBy: "GenerateVocabulary"}
+	}
+
+	ids := make(map[string]int, len(vocab))
+	cases := make(map[string]string, len(vocab))
+	for n, s := range vocab {
+		if _, found := ids[s]; found {
+			return nil, fmt.Errorf("duplicate key: %s", strconv.Quote(s))
+		}
+		ids[s] = n + 1
+		cases[s] = fmt.Sprintf("%d, true", n+1)
+	}
+
+	var lines *lineCounter
+	if fs.coverage != nil {
+		lines = &lineCounter{w: w}
+		w = lines
+	}
+
+	if _, err := fmt.Fprintf(w, "func %s(input string) (%s, bool) {", internFn, typ); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w)
+
+	if err := fs.generateNested(w, lines, cases, "0, false", internalFlags(flags)); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "var %sStrings = [...]string{\"\"", lookupFn)
+	for _, s := range vocab {
+		fmt.Fprintf(w, ", %s", strconv.Quote(s))
+	}
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "func %s(id %s) string {", lookupFn, typ)
+	fmt.Fprintln(w)
+	// The conversion to uint64 makes negative IDs (if typ is signed)
+	// out of range.
+	fmt.Fprintf(w, "\tif uint64(id) < uint64(len(%sStrings)) {", lookupFn)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "\t\treturn %sStrings[id]", lookupFn)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "\treturn \"\"")
+	_, err = fmt.Fprintln(w, "}") // end of func
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2014-2016 Dave Pifke.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, is permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its
+//    contributors may be used to endorse or promote products derived from
+//    this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package fastmatch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestVocabulary tests interning and looking up strings, and that neither
+// allocates.
+func TestVocabulary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiled tests in short mode")
+	}
+
+	var ids map[string]int
+	cleanup, err := generateProgram([]string{"fmt", "os", "testing"}, func(w io.Writer) error {
+		var err error
+		ids, err = GenerateVocabulary(w, "intern", "lookup", "int16", []string{"job", "instance", "le"}, Insensitive)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "func main() {")
+		fmt.Fprintln(w, "\tid, ok := intern(os.Args[1])")
+		fmt.Fprintln(w, "\tallocs := testing.AllocsPerRun(10, func() {")
+		fmt.Fprintln(w, "\t\tid, _ := intern(os.Args[1])")
+		fmt.Fprintln(w, "\t\t_ = lookup(id)")
+		fmt.Fprintln(w, "\t})")
+		fmt.Fprintln(w, "\tfmt.Printf(\"%d %t %q %q %q %v\", id, ok, lookup(id), lookup(-1), lookup(4), allocs)")
+		_, err = fmt.Fprintln(w, "}")
+		return err
+	})
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 3 || ids["job"] != 1 || ids["instance"] != 2 || ids["le"] != 3 {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+	expectMatch(t, "job", `1 true "job" "" "" 0`)
+	expectMatch(t, "INSTANCE", `2 true "instance" "" "" 0`)
+	expectMatch(t, "le", `3 true "le" "" "" 0`)
+	expectMatch(t, "lee", `0 false "" "" "" 0`)
+}
+
+// TestVocabularyErrors tests duplicate and ambiguous strings.
+func TestVocabularyErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		vocab  []string
+		expect string
+	}{
+		{[]string{"a", "b", "a"}, `duplicate key: "a"`},
+		{[]string{"a", "A"}, "ambiguous"},
+	} {
+		ids, err := GenerateVocabulary(ioutil.Discard, "intern", "lookup", "int", testCase.vocab, Insensitive)
+		if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+			t.Errorf("%q: expected error containing %q, got %v", testCase.vocab, testCase.expect, err)
+		}
+		if ids != nil {
+			t.Errorf("%q: expected no IDs on error", testCase.vocab)
+		}
+	}
+}